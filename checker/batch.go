@@ -0,0 +1,63 @@
+package checker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"xray-checker/models"
+)
+
+// batchSleep waits between batches; tests replace it.
+var batchSleep = time.Sleep
+
+// SetBatching makes CheckAllProxies check proxies in groups of size, waiting
+// delay between groups, to spread load across the check interval. A size of
+// zero or less checks everything at once.
+func (pc *ProxyChecker) SetBatching(size int, delay time.Duration) {
+	pc.batchSize = size
+	pc.batchDelay = delay
+}
+
+// splitBatches cuts proxies into consecutive groups of at most size.
+func splitBatches(proxies []*models.ProxyConfig, size int) [][]*models.ProxyConfig {
+	if size <= 0 || size >= len(proxies) {
+		return [][]*models.ProxyConfig{proxies}
+	}
+	batches := make([][]*models.ProxyConfig, 0, (len(proxies)+size-1)/size)
+	for start := 0; start < len(proxies); start += size {
+		batches = append(batches, proxies[start:min(start+size, len(proxies))])
+	}
+	return batches
+}
+
+// checkBatches checks each batch with the concurrency limit, in order. All
+// checks use generation gen, so results of a batch that finishes after the
+// proxy list changed are dropped; remaining batches are skipped then.
+func (pc *ProxyChecker) checkBatches(batches [][]*models.ProxyConfig, duplicates map[*models.ProxyConfig][]*models.ProxyConfig, gen uint64) {
+	for i, batch := range batches {
+		if i > 0 {
+			if pc.batchDelay > 0 {
+				batchSleep(pc.batchDelay)
+			}
+			if atomic.LoadUint64(&pc.generation) != gen {
+				return
+			}
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, pc.checkConcurrency)
+		for _, proxy := range batch {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(p *models.ProxyConfig) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				pc.checkProxyInternal(p, gen, true)
+				for _, dup := range duplicates[p] {
+					pc.shareCheckResult(p, dup, gen)
+				}
+			}(proxy)
+		}
+		wg.Wait()
+	}
+}
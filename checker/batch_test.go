@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"xray-checker/models"
+)
+
+func TestSplitBatches(t *testing.T) {
+	proxies := make([]*models.ProxyConfig, 5)
+	for i := range proxies {
+		proxies[i] = &models.ProxyConfig{Name: fmt.Sprintf("p%d", i)}
+	}
+	batches := splitBatches(proxies, 2)
+	if len(batches) != 3 || len(batches[0]) != 2 || len(batches[2]) != 1 || batches[2][0].Name != "p4" {
+		t.Fatalf("unexpected batches %v", batches)
+	}
+	if got := splitBatches(proxies, 0); len(got) != 1 || len(got[0]) != 5 {
+		t.Fatalf("expected a single batch without a size, got %d", len(got))
+	}
+}
+
+func TestCheckAllProxiesInBatches(t *testing.T) {
+	initTestMetrics()
+	pc, first, _ := newSOCKSCheckFixture(t)
+	proxies := []*models.ProxyConfig{first}
+	for i := 1; i < 5; i++ {
+		p := *first
+		p.Name = fmt.Sprintf("socks-%d", i)
+		p.UUID = fmt.Sprintf("11111111-1111-1111-1111-%012d", i)
+		p.StableID = p.GenerateStableID()
+		proxies = append(proxies, &p)
+	}
+	pc.UpdateProxies(proxies)
+	pc.SetBatching(2, 250*time.Millisecond)
+
+	checked := func() int {
+		n := 0
+		for _, p := range proxies {
+			if _, ok := pc.GetLastCheckedByStableID(p.StableID); ok {
+				n++
+			}
+		}
+		return n
+	}
+	var atSleep []int
+	var delays []time.Duration
+	prev := batchSleep
+	batchSleep = func(d time.Duration) {
+		delays = append(delays, d)
+		atSleep = append(atSleep, checked())
+	}
+	t.Cleanup(func() { batchSleep = prev })
+
+	pc.CheckAllProxies()
+	if len(delays) != 2 || delays[0] != 250*time.Millisecond {
+		t.Fatalf("expected 2 inter-batch delays of 250ms, got %v", delays)
+	}
+	if atSleep[0] != 2 || atSleep[1] != 4 {
+		t.Fatalf("expected batch boundaries after 2 and 4 proxies, got %v", atSleep)
+	}
+	if got := checked(); got != 5 {
+		t.Fatalf("expected all 5 proxies checked, got %d", got)
+	}
+}
+
+func TestCheckAllProxiesStopsBatchesOnGenerationChange(t *testing.T) {
+	initTestMetrics()
+	pc, first, _ := newSOCKSCheckFixture(t)
+	second := *first
+	second.Name = "socks-2"
+	second.UUID = "22222222-2222-2222-2222-222222222222"
+	second.StableID = second.GenerateStableID()
+	pc.UpdateProxies([]*models.ProxyConfig{first, &second})
+	pc.SetBatching(1, time.Millisecond)
+
+	prev := batchSleep
+	batchSleep = func(time.Duration) { pc.UpdateProxies([]*models.ProxyConfig{first, &second}) }
+	t.Cleanup(func() { batchSleep = prev })
+
+	pc.CheckAllProxies()
+	if _, ok := pc.GetLastCheckedByStableID(second.StableID); ok {
+		t.Fatal("expected the batch after a proxy list change to be skipped")
+	}
+}
@@ -0,0 +1,75 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"xray-checker/models"
+)
+
+// CheckOrder is the order CheckAllProxies checks proxies in.
+type CheckOrder string
+
+const (
+	// CheckOrderConfig checks proxies in the order they were loaded.
+	CheckOrderConfig CheckOrder = "config"
+	// CheckOrderLatency checks the fastest known proxies first.
+	CheckOrderLatency CheckOrder = "latency"
+)
+
+// ParseCheckOrder maps a config value to a CheckOrder. Empty means
+// CheckOrderConfig.
+func ParseCheckOrder(value string) (CheckOrder, error) {
+	switch order := CheckOrder(strings.ToLower(strings.TrimSpace(value))); order {
+	case "":
+		return CheckOrderConfig, nil
+	case CheckOrderConfig, CheckOrderLatency:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unknown check order %q, expected config or latency", value)
+	}
+}
+
+// SetCheckOrder sets the order of each check iteration. With batching the
+// order is applied before splitting, so the first batches hold the fastest
+// proxies.
+func (pc *ProxyChecker) SetCheckOrder(order CheckOrder) {
+	pc.checkOrder = order
+}
+
+// knownLatency returns the latency a proxy is ordered by: its moving average
+// when smoothing is enabled (restored from the latency store after a
+// restart), else its last successful latency.
+func (pc *ProxyChecker) knownLatency(proxy *models.ProxyConfig) (time.Duration, bool) {
+	metricKey := metricKeyForProxy(proxy)
+	if ema, ok := pc.emaLatency.Load(metricKey); ok && ema.(time.Duration) > 0 {
+		return ema.(time.Duration), true
+	}
+	if latency, ok := pc.latencyMetrics.Load(metricKey); ok && latency.(time.Duration) > 0 {
+		return latency.(time.Duration), true
+	}
+	return 0, false
+}
+
+// orderProxies sorts proxies in place by the configured check order.
+// Proxies without a known latency keep their relative order after the rest.
+func (pc *ProxyChecker) orderProxies(proxies []*models.ProxyConfig) {
+	if pc.checkOrder != CheckOrderLatency {
+		return
+	}
+	latencies := make(map[*models.ProxyConfig]time.Duration, len(proxies))
+	for _, proxy := range proxies {
+		if latency, ok := pc.knownLatency(proxy); ok {
+			latencies[proxy] = latency
+		}
+	}
+	sort.SliceStable(proxies, func(i, j int) bool {
+		li, iKnown := latencies[proxies[i]]
+		lj, jKnown := latencies[proxies[j]]
+		if iKnown != jKnown {
+			return iKnown
+		}
+		return iKnown && li < lj
+	})
+}
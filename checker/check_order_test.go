@@ -0,0 +1,61 @@
+package checker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"xray-checker/models"
+)
+
+func TestOrderProxiesByStoredLatency(t *testing.T) {
+	var proxies []*models.ProxyConfig
+	for i := 0; i < 4; i++ {
+		p := &models.ProxyConfig{Protocol: "vless", Server: "1.1.1.1", Port: 443 + i, Name: fmt.Sprintf("p%d", i), UUID: fmt.Sprintf("11111111-1111-1111-1111-%012d", i)}
+		p.StableID = p.GenerateStableID()
+		proxies = append(proxies, p)
+	}
+	store, _ := NewLatencyStore("")
+	if err := store.Save(CheckerLatencySection, map[string]time.Duration{
+		proxies[1].StableID: 300 * time.Millisecond,
+		proxies[2].StableID: 80 * time.Millisecond,
+		proxies[3].StableID: 150 * time.Millisecond,
+	}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	pc := NewProxyChecker(proxies, 10000, "", 1, "", "", 1, 1, "status", 1)
+	pc.SetLatencySmoothing(0.5)
+	pc.SetLatencyStore(store)
+
+	names := func(list []*models.ProxyConfig) string {
+		out := ""
+		for _, p := range list {
+			out += p.Name + " "
+		}
+		return out
+	}
+
+	ordered := append([]*models.ProxyConfig(nil), proxies...)
+	pc.orderProxies(ordered)
+	if got := names(ordered); got != "p0 p1 p2 p3 " {
+		t.Fatalf("config order must be kept by default, got %s", got)
+	}
+
+	pc.SetCheckOrder(CheckOrderLatency)
+	pc.orderProxies(ordered)
+	if got := names(ordered); got != "p2 p3 p1 p0 " {
+		t.Fatalf("expected fastest first and unknown last, got %s", got)
+	}
+}
+
+func TestParseCheckOrder(t *testing.T) {
+	if order, err := ParseCheckOrder(""); err != nil || order != CheckOrderConfig {
+		t.Fatalf("empty must mean config, got %q (%v)", order, err)
+	}
+	if order, err := ParseCheckOrder("Latency"); err != nil || order != CheckOrderLatency {
+		t.Fatalf("got %q (%v)", order, err)
+	}
+	if _, err := ParseCheckOrder("random"); err == nil {
+		t.Fatal("expected an error for an unknown order")
+	}
+}
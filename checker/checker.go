@@ -2,6 +2,7 @@ package checker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 
 	"xray-checker/logger"
 	"xray-checker/metrics"
@@ -25,23 +27,71 @@ type ProxyChecker struct {
 	httpClient       *http.Client
 	currentMetrics   sync.Map
 	latencyMetrics   sync.Map
+	lastChecked      sync.Map
+	lastErrors       sync.Map // metric key -> reason of the last failed check
+	emaLatency       sync.Map // metric key -> smoothed latency of successful checks
+	degraded         sync.Map // metric key -> true when the last check succeeded over maxLatency
+	maxLatency       time.Duration
+	emaAlpha         float64
+	latencyStore     *LatencyStore
 	ipInitialized    bool
 	ipCheckTimeout   int
+	connectTimeout   time.Duration
+	addressFamily    AddressFamily
 	genMethodURL     string
 	downloadURL      string
 	downloadTimeout  int
 	downloadMinSize  int64
+	downloadSHA256   []byte
+	downloadMaxSize  int64
 	checkMethod      string
+	shadowMethod     string
 	checkConcurrency int
 	mu               sync.RWMutex
 	generation       uint64
 	generationSkips  uint64
+	resultsVersion   atomic.Uint64 // bumped whenever a recorded check result changes
 	badSinceMu       sync.RWMutex
 	badSince         map[string]time.Time
+	sentinelURL      string
+	localDown        atomic.Bool
+	paused           atomic.Bool
+	keepAlive        bool
+	dnsCheckDomain   string
+	transports       sync.Map             // proxy port -> *http.Transport, used with keepAlive
+	addedAt          map[string]time.Time // stable ID -> when the proxy first appeared, guarded by mu
+	offlineGrace     time.Duration
+	udpCheck         bool
+	udpResolver      string
+	stunServer       string
+	udpExitIPs       sync.Map // metric key -> UDP exit IP found by the STUN probe
+	streaks          sync.Map // metric key -> statusStreak
+	debounceUp       int
+	debounceDown     int
+	httpVersion      HTTPVersion
+	dedupChecks      bool
+	quietSuccess     bool
+	batchSize        int
+	batchDelay       time.Duration
+	checkOrder       CheckOrder
+	statusCodes      StatusCodes
+	statusURLRules   []CheckURLRule
+	downloadURLRules []CheckURLRule
 }
 
 const badLatencyThreshold = time.Millisecond * 1000
 
+var (
+	// ErrProxyNotFound is returned when no proxy matches the lookup.
+	ErrProxyNotFound = errors.New("proxy not found")
+	// ErrStatusUnknown is returned for a known proxy that has not been checked yet.
+	ErrStatusUnknown = errors.New("metric not found")
+)
+
+// maxIPResponseSize bounds how much of an IP check response is read; an IP
+// address fits comfortably, a misconfigured endless stream does not.
+const maxIPResponseSize = 4 << 10
+
 func BadLatencyThreshold() time.Duration {
 	return badLatencyThreshold
 }
@@ -51,7 +101,7 @@ func NewProxyChecker(proxies []*models.ProxyConfig, startPort int, ipCheckURL st
 		checkConcurrency = 32
 	}
 
-	return &ProxyChecker{
+	pc := &ProxyChecker{
 		proxies:   proxies,
 		startPort: startPort,
 		ipCheck:   ipCheckURL,
@@ -67,6 +117,114 @@ func NewProxyChecker(proxies []*models.ProxyConfig, startPort int, ipCheckURL st
 		checkConcurrency: checkConcurrency,
 		badSince:         make(map[string]time.Time),
 	}
+	pc.addedAt = trackAddedAt(nil, proxies, time.Now())
+	publishProxyInfo(proxies)
+	return pc
+}
+
+// SetPaused toggles maintenance mode. While paused, check iterations are
+// skipped and the last known statuses keep being served.
+func (pc *ProxyChecker) SetPaused(paused bool) {
+	pc.paused.Store(paused)
+}
+
+func (pc *ProxyChecker) IsPaused() bool {
+	return pc.paused.Load()
+}
+
+// SetSentinelURL sets the URL fetched directly (not through a proxy) before
+// each check iteration to verify local connectivity. Empty disables the check.
+func (pc *ProxyChecker) SetSentinelURL(sentinelURL string) {
+	pc.sentinelURL = sentinelURL
+}
+
+// SetKeepAlive enables reusing one keep-alive transport per proxy port across
+// check iterations instead of dialing fresh connections every time. Off by
+// default: a fresh connection per check avoids false positives from stale
+// pooled connections.
+func (pc *ProxyChecker) SetKeepAlive(enabled bool) {
+	pc.keepAlive = enabled
+}
+
+// SetOfflineGrace sets how long a newly added proxy that fails its checks is
+// reported as pending rather than offline. Zero disables the grace period.
+func (pc *ProxyChecker) SetOfflineGrace(grace time.Duration) {
+	pc.offlineGrace = grace
+}
+
+// SetLatencySmoothing enables an exponential moving average of successful
+// check latencies with smoothing factor alpha in (0, 1]. Zero disables it.
+func (pc *ProxyChecker) SetLatencySmoothing(alpha float64) {
+	pc.emaAlpha = alpha
+}
+
+// recordLatencyEMA folds a successful check's latency into the proxy's
+// moving average. The first sample seeds the average.
+func (pc *ProxyChecker) recordLatencyEMA(metricKey string, latency time.Duration) {
+	if pc.emaAlpha <= 0 {
+		return
+	}
+	ema := latency
+	if prev, ok := pc.emaLatency.Load(metricKey); ok {
+		ema = time.Duration((1.0-pc.emaAlpha)*float64(prev.(time.Duration)) + pc.emaAlpha*float64(latency))
+	}
+	pc.emaLatency.Store(metricKey, ema)
+}
+
+// SetMaxLatency sets the slowest successful check that still counts as
+// online. Slower successes are recorded as failed and reported as degraded.
+// Zero disables the cap.
+func (pc *ProxyChecker) SetMaxLatency(max time.Duration) {
+	pc.maxLatency = max
+}
+
+// SetQuietSuccess moves per-proxy success lines to debug level, so info logs
+// only show failures and the iteration summary.
+func (pc *ProxyChecker) SetQuietSuccess(enabled bool) {
+	pc.quietSuccess = enabled
+}
+
+// InOfflineGrace reports whether the proxy was added less than the offline
+// grace period ago.
+func (pc *ProxyChecker) InOfflineGrace(stableID string) bool {
+	if pc.offlineGrace <= 0 {
+		return false
+	}
+	pc.mu.RLock()
+	added, ok := pc.addedAt[stableID]
+	pc.mu.RUnlock()
+	return ok && time.Since(added) < pc.offlineGrace
+}
+
+// LocalConnectivityDown reports whether the sentinel check of the last
+// iteration failed.
+func (pc *ProxyChecker) LocalConnectivityDown() bool {
+	return pc.localDown.Load()
+}
+
+func (pc *ProxyChecker) checkLocalConnectivity() bool {
+	if pc.sentinelURL == "" {
+		return true
+	}
+	transport := pc.directTransport(false)
+	transport.Proxy = nil
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Second * time.Duration(pc.ipCheckTimeout),
+	}
+	resp, err := client.Get(pc.sentinelURL)
+	if err != nil {
+		logger.Debug("Sentinel check failed: %v", err)
+		return false
+	}
+	resp.Body.Close()
+	// Captive portals and filtering proxies answer with redirects or error
+	// pages; only a 2xx means the network is really reachable.
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logger.Debug("Sentinel check failed: status %d", resp.StatusCode)
+		return false
+	}
+	return true
 }
 
 func (pc *ProxyChecker) GetCurrentIP() (string, error) {
@@ -80,7 +238,7 @@ func (pc *ProxyChecker) GetCurrentIP() (string, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIPResponseSize))
 	if err != nil {
 		return "", fmt.Errorf("error reading response: %v", err)
 	}
@@ -115,11 +273,13 @@ func (pc *ProxyChecker) checkProxyInternal(proxy *models.ProxyConfig, expectedGe
 		return atomic.LoadUint64(&pc.generation) == expectedGeneration
 	}
 
-	setFailedStatus := func() {
+	setFailedStatus := func(reason string) {
 		if !isGenerationValid() {
 			atomic.AddUint64(&pc.generationSkips, 1)
 			return
 		}
+		pc.lastErrors.Store(metricKey, sanitizeReason(reason))
+		pc.degraded.Delete(metricKey)
 		metrics.RecordProxyStatus(
 			proxy.Protocol,
 			fmt.Sprintf("%s:%d", proxy.Server, proxy.Port),
@@ -128,7 +288,9 @@ func (pc *ProxyChecker) checkProxyInternal(proxy *models.ProxyConfig, expectedGe
 			0,
 		)
 		pc.currentMetrics.Store(metricKey, false)
+		pc.lastChecked.Store(metricKey, time.Now())
 		pc.markBad(metricKey)
+		pc.resultsVersion.Add(1)
 	}
 
 	setFailedLatency := func() {
@@ -144,24 +306,22 @@ func (pc *ProxyChecker) checkProxyInternal(proxy *models.ProxyConfig, expectedGe
 		)
 		pc.latencyMetrics.Store(metricKey, time.Duration(0))
 		pc.markBad(metricKey)
+		pc.resultsVersion.Add(1)
 	}
 
-	proxyURL := fmt.Sprintf("socks5://127.0.0.1:%d", pc.startPort+proxy.Index)
+	proxyURL := fmt.Sprintf("socks5://127.0.0.1:%d", proxy.InboundPort(pc.startPort))
 	proxyURLParsed, err := url.Parse(proxyURL)
 	if err != nil {
 		logger.Error("Error parsing proxy URL %s: %v", proxyURL, err)
-		setFailedStatus()
+		setFailedStatus(err.Error())
 		setFailedLatency()
 
 		return
 	}
 
 	client := &http.Client{
-		Transport: &http.Transport{
-			Proxy:             http.ProxyURL(proxyURLParsed),
-			DisableKeepAlives: true,
-		},
-		Timeout: time.Second * time.Duration(pc.ipCheckTimeout),
+		Transport: pc.transportFor(proxy.InboundPort(pc.startPort), proxyURLParsed),
+		Timeout:   time.Second * time.Duration(pc.ipCheckTimeout),
 	}
 
 	var checkSuccess bool
@@ -169,20 +329,56 @@ func (pc *ProxyChecker) checkProxyInternal(proxy *models.ProxyConfig, expectedGe
 	var logMessage string
 	var latency time.Duration
 
-	if pc.checkMethod == "ip" {
-		checkSuccess, logMessage, latency, checkErr = pc.checkByIP(client)
-	} else if pc.checkMethod == "status" {
-		checkSuccess, logMessage, latency, checkErr = pc.checkByGen(client)
-	} else if pc.checkMethod == "download" {
-		checkSuccess, logMessage, latency, checkErr = pc.checkByDownload(client)
-	} else {
-		logger.Error("Invalid check method: %s", pc.checkMethod)
+	method := pc.methodFor(proxy)
+	if !ValidCheckMethod(method) {
+		logger.Error("Invalid check method: %s", method)
+		return
+	}
+	checkSuccess, logMessage, latency, checkErr = pc.runCheckMethod(method, client, proxy, proxyURLParsed.Host)
+
+	if checkErr == nil && checkSuccess && pc.wantsUDPCheck(proxy.Protocol) {
+		udpExitIP, udpErr := pc.probeUDP(proxyURLParsed.Host)
+		if udpErr != nil {
+			checkSuccess = false
+			logMessage = fmt.Sprintf("%s | UDP: %v", logMessage, udpErr)
+		} else if udpExitIP != "" {
+			logMessage += " | UDP: ok, exit IP " + udpExitIP
+		} else {
+			logMessage += " | UDP: ok"
+		}
+		if isGenerationValid() {
+			pc.recordUDPExitIP(metricKey, udpExitIP)
+		}
+	}
+
+	pc.runShadowCheck(method, client, proxy, proxyURLParsed.Host, checkErr == nil && checkSuccess)
+
+	if checkErr != nil {
+		checkErr = classifyTimeout(checkErr)
+	}
+	passed := checkErr == nil && checkSuccess && (pc.maxLatency <= 0 || latency <= pc.maxLatency)
+	// A result for a replaced proxy list must not move the streaks of the
+	// current one.
+	if !isGenerationValid() {
+		atomic.AddUint64(&pc.generationSkips, 1)
+		return
+	}
+	if streak, held := pc.debounce(metricKey, passed); held {
+		if passed {
+			logger.Info("%s | Pending up | %d/%d passing checks | %s | Latency: %s", proxy.Name, streak, pc.debounceUp, logMessage, latency)
+		} else {
+			reason := logMessage
+			if checkErr != nil {
+				reason = checkErr.Error()
+			}
+			logger.Warn("%s | Pending down | %d/%d failed checks | %s", proxy.Name, streak, pc.debounceDown, reason)
+		}
 		return
 	}
 
 	if checkErr != nil {
 		logger.Error("%s | %v", proxy.Name, checkErr)
-		setFailedStatus()
+		setFailedStatus(checkErr.Error())
 		setFailedLatency()
 
 		return
@@ -190,10 +386,31 @@ func (pc *ProxyChecker) checkProxyInternal(proxy *models.ProxyConfig, expectedGe
 
 	if !checkSuccess {
 		logger.Error("%s | Failed | %s | Latency: %s", proxy.Name, logMessage, latency)
-		setFailedStatus()
+		setFailedStatus(logMessage)
 		setFailedLatency()
+	} else if pc.maxLatency > 0 && latency > pc.maxLatency {
+		logger.Warn("%s | Degraded | %s | Latency: %s exceeds %s", proxy.Name, logMessage, latency, pc.maxLatency)
+		setFailedStatus(fmt.Sprintf("Latency %s exceeds %s", latency.Round(time.Millisecond), pc.maxLatency))
+		if !isGenerationValid() {
+			return
+		}
+		// Keep the measured latency so the API shows how slow the proxy is.
+		metrics.RecordProxyLatency(
+			proxy.Protocol,
+			fmt.Sprintf("%s:%d", proxy.Server, proxy.Port),
+			proxy.Name,
+			proxy.SubName,
+			latency,
+		)
+		pc.latencyMetrics.Store(metricKey, latency)
+		pc.degraded.Store(metricKey, true)
+		pc.resultsVersion.Add(1)
 	} else {
-		logger.Result("%s | Success | %s | Latency: %s", proxy.Name, logMessage, latency)
+		if pc.quietSuccess {
+			logger.Debug("%s | Success | %s | Latency: %s", proxy.Name, logMessage, latency)
+		} else {
+			logger.Result("%s | Success | %s | Latency: %s", proxy.Name, logMessage, latency)
+		}
 		if !isGenerationValid() {
 			atomic.AddUint64(&pc.generationSkips, 1)
 			return
@@ -214,16 +431,65 @@ func (pc *ProxyChecker) checkProxyInternal(proxy *models.ProxyConfig, expectedGe
 		)
 
 		pc.latencyMetrics.Store(metricKey, latency)
+		pc.recordLatencyEMA(metricKey, latency)
 		pc.currentMetrics.Store(metricKey, true)
+		pc.lastChecked.Store(metricKey, time.Now())
+		pc.lastErrors.Delete(metricKey)
+		pc.degraded.Delete(metricKey)
 		if latency > badLatencyThreshold {
 			pc.markBad(metricKey)
 		} else {
 			pc.clearBad(metricKey)
 		}
+		pc.resultsVersion.Add(1)
 	}
 }
 
+// transportFor returns the transport used to reach the proxy listening on
+// port. Without keep-alive every call gets a fresh transport.
+func (pc *ProxyChecker) transportFor(port int, proxyURL *url.URL) *http.Transport {
+	if !pc.keepAlive {
+		proxyFunc, dial := pc.proxyDial(proxyURL)
+		return &http.Transport{
+			Proxy:                 proxyFunc,
+			DialContext:           dial,
+			TLSHandshakeTimeout:   pc.connectTimeout,
+			ResponseHeaderTimeout: pc.connectTimeout,
+			DisableKeepAlives:     true,
+			Protocols:             pc.protocols(),
+		}
+	}
+	if cached, ok := pc.transports.Load(port); ok {
+		return cached.(*http.Transport)
+	}
+	proxyFunc, dial := pc.proxyDial(proxyURL)
+	transport := &http.Transport{
+		Proxy:                 proxyFunc,
+		DialContext:           dial,
+		TLSHandshakeTimeout:   pc.connectTimeout,
+		ResponseHeaderTimeout: pc.connectTimeout,
+		MaxIdleConnsPerHost:   1,
+		IdleConnTimeout:       90 * time.Second,
+		Protocols:             pc.protocols(),
+	}
+	actual, _ := pc.transports.LoadOrStore(port, transport)
+	return actual.(*http.Transport)
+}
+
+// closeTransports drops cached keep-alive transports. Ports are reassigned
+// when the proxy list changes, so pooled connections must not outlive it.
+func (pc *ProxyChecker) closeTransports() {
+	pc.transports.Range(func(key, value interface{}) bool {
+		value.(*http.Transport).CloseIdleConnections()
+		pc.transports.Delete(key)
+		return true
+	})
+}
+
 func (pc *ProxyChecker) markBad(metricKey string) {
+	if pc.localDown.Load() {
+		return
+	}
 	pc.badSinceMu.Lock()
 	defer pc.badSinceMu.Unlock()
 	if _, exists := pc.badSince[metricKey]; !exists {
@@ -245,6 +511,72 @@ func (pc *ProxyChecker) GetBadSince(proxy *models.ProxyConfig) (time.Time, bool)
 	return ts, ok
 }
 
+// GetBadSinceByStableID is GetBadSince for callers that only have the stable ID.
+func (pc *ProxyChecker) GetBadSinceByStableID(stableID string) (time.Time, bool) {
+	metricKey := pc.metricKeyByStableID(stableID)
+	if metricKey == "" {
+		return time.Time{}, false
+	}
+	pc.badSinceMu.RLock()
+	defer pc.badSinceMu.RUnlock()
+	ts, ok := pc.badSince[metricKey]
+	return ts, ok
+}
+
+// ValidCheckMethod reports whether method is one of ip, status, download or
+// dns.
+func ValidCheckMethod(method string) bool {
+	switch method {
+	case "ip", "status", "download", "dns":
+		return true
+	}
+	return false
+}
+
+// methodFor returns the check method for proxy: its own hint when that is a
+// valid method, else the global one.
+func (pc *ProxyChecker) methodFor(proxy *models.ProxyConfig) string {
+	hint := proxy.CheckMethodHint()
+	if hint == "" || hint == pc.checkMethod {
+		return pc.checkMethod
+	}
+	if !ValidCheckMethod(hint) {
+		logger.Debug("%s | Ignoring invalid check method hint %q, using %s", proxy.Name, hint, pc.checkMethod)
+		return pc.checkMethod
+	}
+	return hint
+}
+
+// needsCurrentIP reports whether the shadow method or any of proxies uses the
+// ip method, which compares against the checker's own public IP.
+func (pc *ProxyChecker) needsCurrentIP(proxies []*models.ProxyConfig) bool {
+	if pc.shadowMethod == "ip" {
+		return true
+	}
+	for _, proxy := range proxies {
+		if pc.methodFor(proxy) == "ip" {
+			return true
+		}
+	}
+	return false
+}
+
+// runCheckMethod runs one check of the given method through client. The
+// method must be valid.
+func (pc *ProxyChecker) runCheckMethod(method string, client *http.Client, proxy *models.ProxyConfig, proxyAddr string) (bool, string, time.Duration, error) {
+	switch method {
+	case "ip":
+		return pc.checkByIP(client)
+	case "status":
+		return pc.checkByGen(client, checkURLFor(pc.statusURLRules, proxy, pc.genMethodURL))
+	case "download":
+		return pc.checkByDownload(client, checkURLFor(pc.downloadURLRules, proxy, pc.downloadURL))
+	case "dns":
+		return pc.checkByDNS(proxyAddr)
+	}
+	return false, "", 0, fmt.Errorf("invalid check method: %s", method)
+}
+
 func (pc *ProxyChecker) checkByIP(client *http.Client) (bool, string, time.Duration, error) {
 	req, err := http.NewRequest("GET", pc.ipCheck, nil)
 	if err != nil {
@@ -266,7 +598,7 @@ func (pc *ProxyChecker) checkByIP(client *http.Client) (bool, string, time.Durat
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIPResponseSize))
 	if err != nil {
 		return false, "", ttfb, err
 	}
@@ -276,9 +608,10 @@ func (pc *ProxyChecker) checkByIP(client *http.Client) (bool, string, time.Durat
 	return proxyIP != pc.currentIP, logMessage, ttfb, nil
 }
 
-func (pc *ProxyChecker) checkByGen(client *http.Client) (bool, string, time.Duration, error) {
+func (pc *ProxyChecker) checkByGen(client *http.Client, statusURL string) (bool, string, time.Duration, error) {
+	client = pc.statusClient(client)
 	for attempt := 1; attempt <= 2; attempt++ {
-		req, err := http.NewRequest("GET", pc.genMethodURL, nil)
+		req, err := http.NewRequest("GET", statusURL, nil)
 		if err != nil {
 			return false, "", 0, err
 		}
@@ -303,18 +636,18 @@ func (pc *ProxyChecker) checkByGen(client *http.Client) (bool, string, time.Dura
 		defer resp.Body.Close()
 
 		logMessage := fmt.Sprintf("Status: %d", resp.StatusCode)
-		return resp.StatusCode >= 200 && resp.StatusCode < 300, logMessage, ttfb, nil
+		return pc.statusAccepted(resp.StatusCode), logMessage, ttfb, nil
 	}
 
 	return false, "", 0, fmt.Errorf("status check failed after retry")
 }
 
-func (pc *ProxyChecker) checkByDownload(client *http.Client) (bool, string, time.Duration, error) {
-	if pc.downloadURL == "" {
+func (pc *ProxyChecker) checkByDownload(client *http.Client, downloadURL string) (bool, string, time.Duration, error) {
+	if downloadURL == "" {
 		return false, "Download URL not configured", 0, fmt.Errorf("download URL not configured")
 	}
 
-	req, err := http.NewRequest("GET", pc.downloadURL, nil)
+	req, err := http.NewRequest("GET", downloadURL, nil)
 	if err != nil {
 		return false, "", 0, err
 	}
@@ -343,6 +676,17 @@ func (pc *ProxyChecker) checkByDownload(client *http.Client) (bool, string, time
 		return false, fmt.Sprintf("HTTP status: %d", resp.StatusCode), ttfb, nil
 	}
 
+	// The digest describes the file at the global URL; URLs picked by
+	// --proxy-download-url-map serve other files and only get the size check.
+	if pc.downloadSHA256 != nil && downloadURL == pc.downloadURL {
+		totalBytes, err := pc.verifyDownloadHash(resp.Body)
+		if err != nil {
+			return false, err.Error(), ttfb, nil
+		}
+		success := totalBytes >= pc.downloadMinSize
+		return success, fmt.Sprintf("Downloaded: %d bytes (min: %d), SHA-256 ok", totalBytes, pc.downloadMinSize), ttfb, nil
+	}
+
 	totalBytes := int64(0)
 	buffer := make([]byte, 8192)
 
@@ -386,23 +730,95 @@ func (pc *ProxyChecker) ClearMetrics() {
 		pc.latencyMetrics.Delete(key)
 		return true
 	})
+
+	pc.lastChecked.Range(func(key, _ interface{}) bool {
+		pc.lastChecked.Delete(key)
+		return true
+	})
+
+	pc.lastErrors.Range(func(key, _ interface{}) bool {
+		pc.lastErrors.Delete(key)
+		return true
+	})
+
+	pc.emaLatency.Range(func(key, _ interface{}) bool {
+		pc.emaLatency.Delete(key)
+		return true
+	})
+
+	pc.degraded.Range(func(key, _ interface{}) bool {
+		pc.degraded.Delete(key)
+		return true
+	})
+
+	pc.udpExitIPs.Range(func(key, _ interface{}) bool {
+		pc.udpExitIPs.Delete(key)
+		return true
+	})
+
+	pc.streaks.Range(func(key, _ interface{}) bool {
+		pc.streaks.Delete(key)
+		return true
+	})
+	pc.resultsVersion.Add(1)
 }
 
 func (pc *ProxyChecker) UpdateProxies(newProxies []*models.ProxyConfig) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 	atomic.AddUint64(&pc.generation, 1)
+	var carried map[string]time.Duration
+	if pc.latencyStore != nil {
+		// Persisted averages outlive restarts, so they outlive updates too.
+		carried = pc.emaByStableIDLocked()
+	}
 	pc.ClearMetrics()
+	pc.closeTransports()
+	pc.addedAt = trackAddedAt(pc.addedAt, newProxies, time.Now())
 	pc.proxies = newProxies
+	pc.restoreEMALocked(carried)
+	publishProxyInfo(newProxies)
 }
 
-func (pc *ProxyChecker) CheckAllProxies() {
-	if pc.checkMethod == "ip" {
-		if _, err := pc.GetCurrentIP(); err != nil {
-			logger.Warn("Error getting current IP: %v", err)
-			return
+// publishProxyInfo exports the info series of the loaded proxies, dropping
+// those of removed ones.
+func publishProxyInfo(proxies []*models.ProxyConfig) {
+	infos := make([]metrics.ProxyInfo, 0, len(proxies))
+	for _, proxy := range proxies {
+		infos = append(infos, metrics.ProxyInfo{
+			StableID: proxy.StableID,
+			Name:     proxy.Name,
+			SubName:  proxy.SubName,
+			Protocol: proxy.Protocol,
+			Server:   proxy.Server,
+		})
+	}
+	metrics.SetProxyInfo(infos)
+}
+
+// trackAddedAt keeps the first-seen time of proxies that are still present and
+// stamps new ones with now.
+func trackAddedAt(prev map[string]time.Time, proxies []*models.ProxyConfig, now time.Time) map[string]time.Time {
+	next := make(map[string]time.Time, len(proxies))
+	for _, proxy := range proxies {
+		if proxy.StableID == "" {
+			proxy.StableID = proxy.GenerateStableID()
+		}
+		if added, ok := prev[proxy.StableID]; ok {
+			next[proxy.StableID] = added
+		} else {
+			next[proxy.StableID] = now
 		}
 	}
+	return next
+}
+
+func (pc *ProxyChecker) CheckAllProxies() {
+	localDown := !pc.checkLocalConnectivity()
+	pc.localDown.Store(localDown)
+	if localDown {
+		logger.Warn("Local connectivity down, skipping bad marking and cleanup")
+	}
 
 	pc.mu.RLock()
 	proxiesToCheck := make([]*models.ProxyConfig, len(pc.proxies))
@@ -414,24 +830,34 @@ func (pc *ProxyChecker) CheckAllProxies() {
 		return
 	}
 
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, pc.checkConcurrency)
-	for _, proxy := range proxiesToCheck {
-		sem <- struct{}{}
-		wg.Add(1)
-		go func(p *models.ProxyConfig, gen uint64) {
-			defer wg.Done()
-			defer func() { <-sem }()
-			pc.checkProxyInternal(p, gen, true)
-		}(proxy, currentGeneration)
+	if pc.needsCurrentIP(proxiesToCheck) {
+		if _, err := pc.GetCurrentIP(); err != nil {
+			logger.Warn("Error getting current IP: %v", err)
+			return
+		}
+	}
+
+	var duplicates map[*models.ProxyConfig][]*models.ProxyConfig
+	if pc.dedupChecks {
+		proxiesToCheck, duplicates = groupDuplicates(proxiesToCheck)
 	}
-	wg.Wait()
+	pc.orderProxies(proxiesToCheck)
+
+	pc.checkBatches(splitBatches(proxiesToCheck, pc.batchSize), duplicates, currentGeneration)
+	pc.saveLatencyStore()
 
 	if skipped := atomic.SwapUint64(&pc.generationSkips, 0); skipped > 0 {
 		logger.Debug("Skipped metric updates due to generation change: %d", skipped)
 	}
 }
 
+// StateVersion identifies the current check results: the proxy list
+// generation and a counter bumped whenever a result is recorded, so it
+// changes as results come in during an iteration, not just at its end.
+func (pc *ProxyChecker) StateVersion() (uint64, uint64) {
+	return atomic.LoadUint64(&pc.generation), pc.resultsVersion.Load()
+}
+
 func (pc *ProxyChecker) GetProxyStatus(name string) (bool, time.Duration, error) {
 	pc.mu.RLock()
 	var metricKey string
@@ -447,30 +873,102 @@ func (pc *ProxyChecker) GetProxyStatus(name string) (bool, time.Duration, error)
 }
 
 func (pc *ProxyChecker) GetProxyStatusByStableID(stableID string) (bool, time.Duration, error) {
+	return pc.getStatusByMetricKey(pc.metricKeyByStableID(stableID))
+}
+
+// GetLastCheckedByStableID returns when the proxy's status was last recorded.
+func (pc *ProxyChecker) GetLastCheckedByStableID(stableID string) (time.Time, bool) {
+	metricKey := pc.metricKeyByStableID(stableID)
+	if metricKey == "" {
+		return time.Time{}, false
+	}
+	ts, ok := pc.lastChecked.Load(metricKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	return ts.(time.Time), true
+}
+
+// GetLastErrorByStableID returns why the proxy's last check failed, or ""
+// when it has not failed since its last success.
+func (pc *ProxyChecker) GetLastErrorByStableID(stableID string) string {
+	metricKey := pc.metricKeyByStableID(stableID)
+	if metricKey == "" {
+		return ""
+	}
+	reason, ok := pc.lastErrors.Load(metricKey)
+	if !ok {
+		return ""
+	}
+	return reason.(string)
+}
+
+// GetEMALatencyByStableID returns the moving average of the proxy's
+// successful check latencies, when smoothing is enabled and it has succeeded.
+func (pc *ProxyChecker) GetEMALatencyByStableID(stableID string) (time.Duration, bool) {
+	metricKey := pc.metricKeyByStableID(stableID)
+	if metricKey == "" {
+		return 0, false
+	}
+	ema, ok := pc.emaLatency.Load(metricKey)
+	if !ok {
+		return 0, false
+	}
+	return ema.(time.Duration), true
+}
+
+// IsDegradedByStableID reports whether the proxy's last check succeeded but
+// exceeded the latency cap.
+func (pc *ProxyChecker) IsDegradedByStableID(stableID string) bool {
+	metricKey := pc.metricKeyByStableID(stableID)
+	if metricKey == "" {
+		return false
+	}
+	_, ok := pc.degraded.Load(metricKey)
+	return ok
+}
+
+// maxReasonLength bounds a stored failure reason in runes.
+const maxReasonLength = 256
+
+// sanitizeReason flattens a failure reason to one line of valid UTF-8 and
+// caps its length.
+func sanitizeReason(reason string) string {
+	reason = strings.ToValidUTF8(reason, "")
+	reason = strings.Join(strings.FieldsFunc(reason, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	if runes := []rune(reason); len(runes) > maxReasonLength {
+		reason = string(runes[:maxReasonLength-1]) + "…"
+	}
+	if reason == "" {
+		reason = "check failed"
+	}
+	return reason
+}
+
+func (pc *ProxyChecker) metricKeyByStableID(stableID string) string {
 	pc.mu.RLock()
-	var metricKey string
+	defer pc.mu.RUnlock()
 	for _, proxy := range pc.proxies {
 		if proxy.StableID == "" {
 			proxy.StableID = proxy.GenerateStableID()
 		}
 		if proxy.StableID == stableID {
-			metricKey = metricKeyForProxy(proxy)
-			break
+			return metricKeyForProxy(proxy)
 		}
 	}
-	pc.mu.RUnlock()
-
-	return pc.getStatusByMetricKey(metricKey)
+	return ""
 }
 
 func (pc *ProxyChecker) getStatusByMetricKey(metricKey string) (bool, time.Duration, error) {
 	if metricKey == "" {
-		return false, 0, fmt.Errorf("proxy not found")
+		return false, 0, ErrProxyNotFound
 	}
 
 	status, ok := pc.currentMetrics.Load(metricKey)
 	if !ok {
-		return false, 0, fmt.Errorf("metric not found")
+		return false, 0, ErrStatusUnknown
 	}
 
 	latency, _ := pc.latencyMetrics.Load(metricKey)
@@ -1,12 +1,31 @@
 package checker
 
 import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"xray-checker/logger"
 	"xray-checker/metrics"
 	"xray-checker/models"
 )
 
+var testMetricsOnce sync.Once
+
+func initTestMetrics() {
+	testMetricsOnce.Do(func() { metrics.InitMetrics("test", "test") })
+}
+
 func TestGetProxyStatusByStableIDWithDuplicateNames(t *testing.T) {
 	p1 := &models.ProxyConfig{
 		Protocol: "vless",
@@ -48,7 +67,7 @@ func TestGetProxyStatusByStableIDWithDuplicateNames(t *testing.T) {
 }
 
 func TestCheckAllProxiesStatusModeDoesNotRequireCurrentIP(t *testing.T) {
-	metrics.InitMetrics("test")
+	initTestMetrics()
 
 	p := &models.ProxyConfig{
 		Protocol: "vless",
@@ -78,3 +97,501 @@ func TestCheckAllProxiesStatusModeDoesNotRequireCurrentIP(t *testing.T) {
 		t.Fatal("expected status metric to be recorded in status mode")
 	}
 }
+
+func TestIPCheckBoundsResponseBody(t *testing.T) {
+	var written atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := []byte(strings.Repeat("1", 32<<10))
+		for i := 0; i < 1024; i++ { // up to 32MB
+			n, err := w.Write(chunk)
+			written.Add(int64(n))
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	pc := NewProxyChecker(nil, 10000, server.URL, 5, "", "", 1, 1, "ip", 1)
+
+	ip, err := pc.GetCurrentIP()
+	if err != nil {
+		t.Fatalf("GetCurrentIP failed: %v", err)
+	}
+	if len(ip) > maxIPResponseSize {
+		t.Fatalf("expected at most %d bytes read, got %d", maxIPResponseSize, len(ip))
+	}
+
+	_, msg, _, err := pc.checkByIP(&http.Client{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("checkByIP failed: %v", err)
+	}
+	if len(msg) > 2*maxIPResponseSize+64 {
+		t.Fatalf("checkByIP read beyond the limit: message length %d", len(msg))
+	}
+	if written.Load() >= 32<<20 {
+		t.Fatalf("server streamed the whole body; client did not stop reading")
+	}
+}
+
+func TestGetProxyStatusByStableIDUnchecked(t *testing.T) {
+	p := &models.ProxyConfig{
+		Protocol: "vless",
+		Server:   "1.1.1.1",
+		Port:     443,
+		Name:     "fresh",
+		UUID:     "11111111-1111-1111-1111-111111111111",
+	}
+	p.StableID = p.GenerateStableID()
+
+	pc := NewProxyChecker([]*models.ProxyConfig{p}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 2)
+
+	if _, _, err := pc.GetProxyStatusByStableID(p.StableID); !errors.Is(err, ErrStatusUnknown) {
+		t.Fatalf("expected ErrStatusUnknown for unchecked proxy, got %v", err)
+	}
+	if _, _, err := pc.GetProxyStatusByStableID("missing"); !errors.Is(err, ErrProxyNotFound) {
+		t.Fatalf("expected ErrProxyNotFound, got %v", err)
+	}
+}
+
+func TestGetBadSinceByStableID(t *testing.T) {
+	p := &models.ProxyConfig{
+		Protocol: "vless",
+		Server:   "1.1.1.1",
+		Port:     443,
+		Name:     "bad",
+		UUID:     "11111111-1111-1111-1111-111111111111",
+	}
+	p.StableID = p.GenerateStableID()
+
+	pc := NewProxyChecker([]*models.ProxyConfig{p}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 2)
+	if _, ok := pc.GetBadSinceByStableID(p.StableID); ok {
+		t.Fatal("expected no bad-since for a healthy proxy")
+	}
+
+	pc.markBad(metricKeyForProxy(p))
+	since, ok := pc.GetBadSinceByStableID(p.StableID)
+	if !ok || since.IsZero() {
+		t.Fatal("expected bad-since after marking the proxy bad")
+	}
+
+	pc.clearBad(metricKeyForProxy(p))
+	if _, ok := pc.GetBadSinceByStableID(p.StableID); ok {
+		t.Fatal("expected bad-since to be cleared")
+	}
+}
+
+func TestCheckAllProxiesSkipsBadMarkingWhenLocalDown(t *testing.T) {
+	initTestMetrics()
+
+	p := &models.ProxyConfig{
+		Protocol: "vless",
+		Server:   "1.1.1.1",
+		Port:     443,
+		Name:     "p1",
+		UUID:     "11111111-1111-1111-1111-111111111111",
+	}
+	p.StableID = p.GenerateStableID()
+
+	pc := NewProxyChecker([]*models.ProxyConfig{p}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 2)
+	pc.SetSentinelURL("http://127.0.0.1:1/sentinel")
+	pc.CheckAllProxies()
+
+	if !pc.LocalConnectivityDown() {
+		t.Fatal("expected local connectivity to be reported down")
+	}
+	if _, ok := pc.currentMetrics.Load(metricKeyForProxy(p)); !ok {
+		t.Fatal("expected status to be recorded even when local connectivity is down")
+	}
+	if _, ok := pc.GetBadSince(p); ok {
+		t.Fatal("expected proxy not to be marked bad while local connectivity is down")
+	}
+
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer portal.Close()
+
+	pc.SetSentinelURL(portal.URL)
+	pc.CheckAllProxies()
+	if !pc.LocalConnectivityDown() {
+		t.Fatal("expected a non-2xx sentinel response to count as connectivity down")
+	}
+
+	sentinel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer sentinel.Close()
+
+	pc.SetSentinelURL(sentinel.URL)
+	pc.CheckAllProxies()
+
+	if pc.LocalConnectivityDown() {
+		t.Fatal("expected local connectivity to be reported up")
+	}
+	if _, ok := pc.GetBadSince(p); !ok {
+		t.Fatal("expected failing proxy to be marked bad once local connectivity is up")
+	}
+}
+
+func TestKeepAliveReusesProxyConnections(t *testing.T) {
+	initTestMetrics()
+
+	for _, tc := range []struct {
+		keepAlive bool
+		wantDials int64
+	}{
+		{keepAlive: false, wantDials: 3},
+		{keepAlive: true, wantDials: 1},
+	} {
+		pc, p, dials := newSOCKSCheckFixture(t)
+		pc.SetKeepAlive(tc.keepAlive)
+		for i := 0; i < 3; i++ {
+			pc.CheckProxy(p)
+		}
+		if online, _, err := pc.GetProxyStatusByStableID(p.StableID); err != nil || !online {
+			t.Fatalf("keepAlive=%v: expected proxy online, got online=%v err=%v", tc.keepAlive, online, err)
+		}
+		if got := dials.Load(); got != tc.wantDials {
+			t.Fatalf("keepAlive=%v: expected %d proxy connections, got %d", tc.keepAlive, tc.wantDials, got)
+		}
+	}
+}
+
+func BenchmarkCheckProxyNoKeepAlive(b *testing.B) {
+	benchmarkCheckProxy(b, false)
+}
+
+func BenchmarkCheckProxyKeepAlive(b *testing.B) {
+	benchmarkCheckProxy(b, true)
+}
+
+func benchmarkCheckProxy(b *testing.B, keepAlive bool) {
+	initTestMetrics()
+	pc, p, _ := newSOCKSCheckFixture(b)
+	pc.SetKeepAlive(keepAlive)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pc.CheckProxy(p)
+	}
+}
+
+// newSOCKSCheckFixture returns a status-mode checker whose single proxy is a
+// minimal in-process SOCKS5 server in front of a 204 endpoint. The counter
+// tracks connections accepted by the SOCKS server.
+func newSOCKSCheckFixture(tb testing.TB) (*ProxyChecker, *models.ProxyConfig, *atomic.Int64) {
+	tb.Helper()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tb.Cleanup(target.Close)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("listen failed: %v", err)
+	}
+	tb.Cleanup(func() { ln.Close() })
+
+	dials := &atomic.Int64{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			dials.Add(1)
+			go serveSOCKS5(conn)
+		}
+	}()
+
+	p := &models.ProxyConfig{
+		Protocol: "vless",
+		Server:   "1.1.1.1",
+		Port:     443,
+		Name:     "socks",
+		UUID:     "11111111-1111-1111-1111-111111111111",
+	}
+	p.StableID = p.GenerateStableID()
+	port := ln.Addr().(*net.TCPAddr).Port
+	pc := NewProxyChecker([]*models.ProxyConfig{p}, port, "", 5, target.URL, "", 1, 1, "status", 1)
+	return pc, p, dials
+}
+
+func serveSOCKS5(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	cmd := buf[1]
+	var host string
+	switch buf[3] {
+	case 1:
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return
+		}
+		host = net.IP(buf[:4]).String()
+	case 3:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return
+		}
+		n := int(buf[0])
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return
+		}
+		host = string(buf[:n])
+	default:
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	port := binary.BigEndian.Uint16(buf[:2])
+	if cmd == socks5CmdUDPAssociate {
+		serveSOCKS5UDP(conn)
+		return
+	}
+
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func TestUpdateProxiesKeepsAddedAt(t *testing.T) {
+	old := &models.ProxyConfig{Protocol: "vless", Server: "1.1.1.1", Port: 443, Name: "old", UUID: "11111111-1111-1111-1111-111111111111"}
+	pc := NewProxyChecker([]*models.ProxyConfig{old}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	pc.SetOfflineGrace(time.Minute)
+
+	past := time.Now().Add(-time.Hour)
+	pc.addedAt[old.StableID] = past
+
+	added := &models.ProxyConfig{Protocol: "vless", Server: "2.2.2.2", Port: 443, Name: "new", UUID: "22222222-2222-2222-2222-222222222222"}
+	pc.UpdateProxies([]*models.ProxyConfig{old, added})
+
+	if !pc.addedAt[old.StableID].Equal(past) {
+		t.Fatalf("existing proxy must keep its added time, got %v", pc.addedAt[old.StableID])
+	}
+	if pc.InOfflineGrace(old.StableID) {
+		t.Fatal("proxy added an hour ago must be past the grace period")
+	}
+	if !pc.InOfflineGrace(added.StableID) {
+		t.Fatal("newly added proxy must be within the grace period")
+	}
+
+	pc.UpdateProxies([]*models.ProxyConfig{added})
+	if _, ok := pc.addedAt[old.StableID]; ok {
+		t.Fatal("removed proxy must be dropped from added times")
+	}
+}
+
+func TestCheckProxyRecordsFailureReason(t *testing.T) {
+	initTestMetrics()
+	pc, p, _ := newSOCKSCheckFixture(t)
+	healthyURL := pc.genMethodURL
+
+	forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(forbidden.Close)
+	pc.genMethodURL = forbidden.URL
+
+	pc.CheckProxy(p)
+	if got := pc.GetLastErrorByStableID(p.StableID); got != "Status: 403" {
+		t.Fatalf("expected the HTTP status as failure reason, got %q", got)
+	}
+
+	pc.genMethodURL = healthyURL
+	pc.CheckProxy(p)
+	if got := pc.GetLastErrorByStableID(p.StableID); got != "" {
+		t.Fatalf("a successful check must clear the failure reason, got %q", got)
+	}
+}
+
+func TestSanitizeReason(t *testing.T) {
+	if got := sanitizeReason("dial tcp:\n\tconnection\x00 refused"); got != "dial tcp: connection refused" {
+		t.Fatalf("unexpected sanitized reason %q", got)
+	}
+	if got := []rune(sanitizeReason(strings.Repeat("x", 1000))); len(got) != maxReasonLength {
+		t.Fatalf("expected reason capped at %d runes, got %d", maxReasonLength, len(got))
+	}
+}
+
+func TestLatencyEMAConverges(t *testing.T) {
+	p := &models.ProxyConfig{
+		Protocol: "vless",
+		Server:   "1.1.1.1",
+		Port:     443,
+		Name:     "smooth",
+		UUID:     "11111111-1111-1111-1111-111111111111",
+	}
+	p.StableID = p.GenerateStableID()
+	pc := NewProxyChecker([]*models.ProxyConfig{p}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 2)
+	metricKey := metricKeyForProxy(p)
+
+	pc.recordLatencyEMA(metricKey, 100*time.Millisecond)
+	if _, ok := pc.GetEMALatencyByStableID(p.StableID); ok {
+		t.Fatal("expected no smoothed latency while smoothing is disabled")
+	}
+
+	pc.SetLatencySmoothing(0.5)
+	pc.recordLatencyEMA(metricKey, 400*time.Millisecond)
+	if ema, _ := pc.GetEMALatencyByStableID(p.StableID); ema != 400*time.Millisecond {
+		t.Fatalf("expected the first sample to seed the average, got %s", ema)
+	}
+	pc.recordLatencyEMA(metricKey, 200*time.Millisecond)
+	if ema, _ := pc.GetEMALatencyByStableID(p.StableID); ema != 300*time.Millisecond {
+		t.Fatalf("expected 300ms after one step, got %s", ema)
+	}
+
+	for i := 0; i < 20; i++ {
+		pc.recordLatencyEMA(metricKey, 100*time.Millisecond)
+	}
+	ema, ok := pc.GetEMALatencyByStableID(p.StableID)
+	if !ok || ema < 100*time.Millisecond || ema > 101*time.Millisecond {
+		t.Fatalf("expected the average to converge to 100ms, got %s", ema)
+	}
+
+	pc.UpdateProxies([]*models.ProxyConfig{p})
+	if _, ok := pc.GetEMALatencyByStableID(p.StableID); ok {
+		t.Fatal("expected the average to reset with the proxy list")
+	}
+}
+
+func TestCheckProxyMarksSlowSuccessDegraded(t *testing.T) {
+	initTestMetrics()
+	pc, p, _ := newSOCKSCheckFixture(t)
+	fastURL := pc.genMethodURL
+
+	// Scaled down from a 9s success against a 3s cap.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(90 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(slow.Close)
+	pc.genMethodURL = slow.URL
+	pc.SetMaxLatency(30 * time.Millisecond)
+
+	pc.CheckProxy(p)
+	online, latency, err := pc.GetProxyStatusByStableID(p.StableID)
+	if err != nil || online {
+		t.Fatalf("expected a slow success to count as offline, got online=%v err=%v", online, err)
+	}
+	if !pc.IsDegradedByStableID(p.StableID) {
+		t.Fatal("expected the proxy to be marked degraded")
+	}
+	if latency < 90*time.Millisecond {
+		t.Fatalf("expected the measured latency to be kept, got %s", latency)
+	}
+	if reason := pc.GetLastErrorByStableID(p.StableID); !strings.Contains(reason, "exceeds 30ms") {
+		t.Fatalf("expected the cap in the failure reason, got %q", reason)
+	}
+
+	pc.genMethodURL = fastURL
+	pc.CheckProxy(p)
+	if online, _, _ := pc.GetProxyStatusByStableID(p.StableID); !online || pc.IsDegradedByStableID(p.StableID) {
+		t.Fatalf("expected a fast success to clear the degraded state, online=%v", online)
+	}
+}
+
+func TestCheckProxyQuietSuccess(t *testing.T) {
+	initTestMetrics()
+	pc, p, _ := newSOCKSCheckFixture(t)
+
+	out := captureLogs(t, func() { pc.CheckProxy(p) })
+	if !strings.Contains(out, "Success") {
+		t.Fatalf("expected success line by default, got %q", out)
+	}
+
+	pc.SetQuietSuccess(true)
+	out = captureLogs(t, func() { pc.CheckProxy(p) })
+	if strings.Contains(out, "Success") {
+		t.Fatalf("expected success line to be suppressed at info, got %q", out)
+	}
+
+	pc.genMethodURL = "http://127.0.0.1:1/unreachable"
+	out = captureLogs(t, func() { pc.CheckProxy(p) })
+	if !strings.Contains(out, p.Name) {
+		t.Fatalf("expected failure to be logged, got %q", out)
+	}
+}
+
+// captureLogs runs fn with stdout and stderr redirected and returns what the
+// logger wrote at info level.
+func captureLogs(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	logger.SetLevel(logger.LevelInfo)
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		logger.SetLevel(logger.LevelInfo)
+	}()
+
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestPerProxyCheckMethodOverridesGlobal(t *testing.T) {
+	initTestMetrics()
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	// The global status check succeeds; a download check cannot.
+	pc.downloadURL = "http://127.0.0.1:1/file"
+
+	pc.CheckProxy(proxy)
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || !status {
+		t.Fatalf("expected the global status method to pass, got status=%v err=%v", status, err)
+	}
+
+	proxy.Name = "socks [m=download]"
+	pc.CheckProxy(proxy)
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || status {
+		t.Fatalf("expected the name hint to switch to download, got status=%v err=%v", status, err)
+	}
+
+	proxy.Name = "socks [m=bogus]"
+	if got := pc.methodFor(proxy); got != "status" {
+		t.Fatalf("expected an invalid hint to fall back to the global method, got %q", got)
+	}
+}
+
+func TestCheckAllProxiesFetchesBaselineIPForPerProxyIPMethod(t *testing.T) {
+	initTestMetrics()
+
+	ipServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("198.51.100.7"))
+	}))
+	defer ipServer.Close()
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	pc.ipCheck = ipServer.URL
+	proxy.Name = "socks [m=ip]"
+
+	pc.CheckAllProxies()
+	if pc.currentIP != "198.51.100.7" {
+		t.Fatalf("expected the baseline IP to be fetched for an [m=ip] proxy, got %q", pc.currentIP)
+	}
+}
@@ -0,0 +1,69 @@
+package checker
+
+// Transitional states reported while a status change is held back by the
+// debounce thresholds.
+const (
+	PendingUp   = "pending-up"
+	PendingDown = "pending-down"
+)
+
+// statusStreak counts the consecutive passing and failing checks of one
+// proxy. At most one of them is non-zero.
+type statusStreak struct {
+	passes   int
+	failures int
+}
+
+// SetStatusDebounce requires up consecutive passing checks before a proxy is
+// reported online and down consecutive failing checks before an online proxy
+// is reported offline. Values below 1 count as 1, which reports every result
+// as it comes.
+func (pc *ProxyChecker) SetStatusDebounce(up, down int) {
+	pc.debounceUp = max(up, 1)
+	pc.debounceDown = max(down, 1)
+}
+
+// debounce records a check result for metricKey and reports whether it must
+// be held back, along with the length of the current streak. Held results
+// leave status, latency and metrics as they were.
+func (pc *ProxyChecker) debounce(metricKey string, passed bool) (int, bool) {
+	var streak statusStreak
+	if value, ok := pc.streaks.Load(metricKey); ok {
+		streak = value.(statusStreak)
+	}
+	if passed {
+		streak = statusStreak{passes: streak.passes + 1}
+	} else {
+		streak = statusStreak{failures: streak.failures + 1}
+	}
+	pc.streaks.Store(metricKey, streak)
+	pc.resultsVersion.Add(1)
+
+	online, _ := pc.currentMetrics.Load(metricKey)
+	if passed {
+		return streak.passes, online != true && streak.passes < max(pc.debounceUp, 1)
+	}
+	return streak.failures, online == true && streak.failures < max(pc.debounceDown, 1)
+}
+
+// GetPendingStateByStableID returns PendingUp while passing checks are held
+// back, PendingDown while failing checks are held back, and "" otherwise.
+func (pc *ProxyChecker) GetPendingStateByStableID(stableID string) string {
+	metricKey := pc.metricKeyByStableID(stableID)
+	if metricKey == "" {
+		return ""
+	}
+	value, ok := pc.streaks.Load(metricKey)
+	if !ok {
+		return ""
+	}
+	streak := value.(statusStreak)
+	online, _ := pc.currentMetrics.Load(metricKey)
+	switch {
+	case online == true && streak.failures > 0:
+		return PendingDown
+	case online != true && streak.passes > 0:
+		return PendingUp
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"sync/atomic"
+	"testing"
+	"xray-checker/models"
+)
+
+func TestStatusDebounceUp(t *testing.T) {
+	initTestMetrics()
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	pc.SetStatusDebounce(2, 1)
+
+	pc.CheckProxy(proxy)
+	if _, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err == nil {
+		t.Fatal("a first passing check must not report the proxy yet")
+	}
+	if state := pc.GetPendingStateByStableID(proxy.StableID); state != PendingUp {
+		t.Fatalf("expected %s after one pass, got %q", PendingUp, state)
+	}
+
+	pc.CheckProxy(proxy)
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || !status {
+		t.Fatalf("expected online after two passes, got status=%v err=%v", status, err)
+	}
+	if state := pc.GetPendingStateByStableID(proxy.StableID); state != "" {
+		t.Fatalf("expected no pending state once online, got %q", state)
+	}
+}
+
+func TestStatusDebounceDown(t *testing.T) {
+	initTestMetrics()
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	pc.SetStatusDebounce(1, 2)
+
+	pc.CheckProxy(proxy)
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || !status {
+		t.Fatalf("expected online after one pass with debounce-up 1, got status=%v err=%v", status, err)
+	}
+
+	pc.genMethodURL = "http://127.0.0.1:1/generate_204"
+	pc.CheckProxy(proxy)
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || !status {
+		t.Fatalf("one failure must keep the proxy online, got status=%v err=%v", status, err)
+	}
+	if state := pc.GetPendingStateByStableID(proxy.StableID); state != PendingDown {
+		t.Fatalf("expected %s after one failure, got %q", PendingDown, state)
+	}
+
+	pc.CheckProxy(proxy)
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || status {
+		t.Fatalf("expected offline after two failures, got status=%v err=%v", status, err)
+	}
+	if state := pc.GetPendingStateByStableID(proxy.StableID); state != "" {
+		t.Fatalf("expected no pending state once offline, got %q", state)
+	}
+}
+
+func TestStatusDebounceIgnoresStaleResults(t *testing.T) {
+	initTestMetrics()
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	pc.SetStatusDebounce(2, 1)
+
+	stale := atomic.LoadUint64(&pc.generation) + 1
+	pc.checkProxyInternal(proxy, stale, true)
+	if state := pc.GetPendingStateByStableID(proxy.StableID); state != "" {
+		t.Fatalf("a result from another generation must not start a streak, got %q", state)
+	}
+}
+
+func TestStatusDebounceSharedWithDuplicates(t *testing.T) {
+	initTestMetrics()
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	dup := *proxy
+	dup.Name = "socks copy"
+	dup.SubName = "other"
+	dup.Index = 1
+	pc.UpdateProxies([]*models.ProxyConfig{proxy, &dup})
+	pc.SetDedupChecks(true)
+	pc.SetStatusDebounce(2, 1)
+
+	pc.CheckAllProxies()
+	if state := pc.GetPendingStateByStableID(dup.StableID); state != PendingUp {
+		t.Fatalf("expected the duplicate to share %s, got %q", PendingUp, state)
+	}
+}
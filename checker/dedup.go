@@ -0,0 +1,96 @@
+package checker
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"xray-checker/metrics"
+	"xray-checker/models"
+)
+
+// SetDedupChecks makes CheckAllProxies check only one proxy per group of
+// duplicates (proxies with the same stable ID, e.g. the same node listed by
+// several subscriptions) and copy its result to the others.
+func (pc *ProxyChecker) SetDedupChecks(enabled bool) {
+	pc.dedupChecks = enabled
+}
+
+// groupDuplicates splits proxies into the ones to check and, per checked
+// proxy, the duplicates that share its result. Order is kept; the first proxy
+// of a group represents it.
+func groupDuplicates(proxies []*models.ProxyConfig) ([]*models.ProxyConfig, map[*models.ProxyConfig][]*models.ProxyConfig) {
+	representatives := make([]*models.ProxyConfig, 0, len(proxies))
+	duplicates := make(map[*models.ProxyConfig][]*models.ProxyConfig)
+	byID := make(map[string]*models.ProxyConfig, len(proxies))
+	for _, proxy := range proxies {
+		if proxy.StableID == "" {
+			proxy.StableID = proxy.GenerateStableID()
+		}
+		if rep, ok := byID[proxy.StableID]; ok {
+			duplicates[rep] = append(duplicates[rep], proxy)
+			continue
+		}
+		byID[proxy.StableID] = proxy
+		representatives = append(representatives, proxy)
+	}
+	return representatives, duplicates
+}
+
+// shareCheckResult copies the last check result of src to dst, including its
+// Prometheus series and debounce streak. It does nothing when the proxy list
+// changed since generation gen, and copies only the streak when src has no
+// result yet.
+func (pc *ProxyChecker) shareCheckResult(src, dst *models.ProxyConfig, gen uint64) {
+	if atomic.LoadUint64(&pc.generation) != gen {
+		atomic.AddUint64(&pc.generationSkips, 1)
+		return
+	}
+	srcKey, dstKey := metricKeyForProxy(src), metricKeyForProxy(dst)
+	// The streak goes first: a result held back by debouncing leaves no
+	// status yet, and the duplicate must still show as pending.
+	copySyncMapEntry(&pc.streaks, srcKey, dstKey)
+	defer pc.resultsVersion.Add(1)
+	status, ok := pc.currentMetrics.Load(srcKey)
+	if !ok {
+		return
+	}
+	online := status.(bool)
+	latency := time.Duration(0)
+	if value, ok := pc.latencyMetrics.Load(srcKey); ok {
+		latency = value.(time.Duration)
+	}
+
+	address := fmt.Sprintf("%s:%d", dst.Server, dst.Port)
+	statusValue := 0.0
+	if online {
+		statusValue = 1
+	}
+	metrics.RecordProxyStatus(dst.Protocol, address, dst.Name, dst.SubName, statusValue)
+	metrics.RecordProxyLatency(dst.Protocol, address, dst.Name, dst.SubName, latency)
+
+	pc.currentMetrics.Store(dstKey, online)
+	pc.latencyMetrics.Store(dstKey, latency)
+	copySyncMapEntry(&pc.lastChecked, srcKey, dstKey)
+	copySyncMapEntry(&pc.lastErrors, srcKey, dstKey)
+	copySyncMapEntry(&pc.emaLatency, srcKey, dstKey)
+	copySyncMapEntry(&pc.degraded, srcKey, dstKey)
+	copySyncMapEntry(&pc.udpExitIPs, srcKey, dstKey)
+
+	pc.badSinceMu.RLock()
+	_, bad := pc.badSince[srcKey]
+	pc.badSinceMu.RUnlock()
+	if bad {
+		pc.markBad(dstKey)
+	} else {
+		pc.clearBad(dstKey)
+	}
+}
+
+func copySyncMapEntry(m *sync.Map, srcKey, dstKey string) {
+	if value, ok := m.Load(srcKey); ok {
+		m.Store(dstKey, value)
+	} else {
+		m.Delete(dstKey)
+	}
+}
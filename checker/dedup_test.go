@@ -0,0 +1,82 @@
+package checker
+
+import (
+	"sync/atomic"
+	"testing"
+	"xray-checker/models"
+)
+
+func TestDedupChecksShareResult(t *testing.T) {
+	initTestMetrics()
+	pc, p, dials := newSOCKSCheckFixture(t)
+
+	// The duplicate's inbound port has no listener, so checking it directly
+	// would fail.
+	dup := *p
+	dup.Name = "socks copy"
+	dup.SubName = "other"
+	dup.Index = 1
+	pc.UpdateProxies([]*models.ProxyConfig{p, &dup})
+	pc.SetDedupChecks(true)
+
+	pc.CheckAllProxies()
+	if got := dials.Load(); got != 1 {
+		t.Fatalf("expected one check for the duplicate group, got %d dials", got)
+	}
+	for _, proxy := range []*models.ProxyConfig{p, &dup} {
+		online, latency, err := pc.GetProxyStatus(proxy.Name)
+		if err != nil || !online {
+			t.Fatalf("expected %s to share the online result, got online=%v err=%v", proxy.Name, online, err)
+		}
+		if latency <= 0 {
+			t.Fatalf("expected %s to share the latency, got %s", proxy.Name, latency)
+		}
+	}
+	if _, ok := pc.GetLastCheckedByStableID(dup.StableID); !ok {
+		t.Fatal("expected the duplicate to have a last-checked time")
+	}
+
+	pc.SetDedupChecks(false)
+	pc.CheckAllProxies()
+	if online, _, _ := pc.GetProxyStatus(dup.Name); online {
+		t.Fatal("expected the duplicate to be checked on its own port without dedup")
+	}
+}
+
+func TestShareCheckResultCopiesUDPExitIP(t *testing.T) {
+	initTestMetrics()
+	pc, p, _ := newSOCKSCheckFixture(t)
+	dup := *p
+	dup.Name = "socks copy"
+	dup.Index = 1
+	pc.UpdateProxies([]*models.ProxyConfig{p, &dup})
+
+	srcKey := metricKeyForProxy(p)
+	pc.currentMetrics.Store(srcKey, true)
+	pc.recordUDPExitIP(srcKey, "198.51.100.7")
+	pc.shareCheckResult(p, &dup, atomic.LoadUint64(&pc.generation))
+	if got := pc.GetUDPExitIPByStableID(dup.StableID); got != "198.51.100.7" {
+		t.Fatalf("expected the duplicate to share the UDP exit IP, got %q", got)
+	}
+
+	pc.recordUDPExitIP(srcKey, "")
+	pc.shareCheckResult(p, &dup, atomic.LoadUint64(&pc.generation))
+	if got := pc.GetUDPExitIPByStableID(dup.StableID); got != "" {
+		t.Fatalf("expected a forgotten UDP exit IP to be cleared on the duplicate, got %q", got)
+	}
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	a := &models.ProxyConfig{Protocol: "vless", Server: "a.example.com", Port: 443, Name: "a", UUID: "11111111-1111-1111-1111-111111111111"}
+	b := &models.ProxyConfig{Protocol: "vless", Server: "b.example.com", Port: 443, Name: "b", UUID: "11111111-1111-1111-1111-111111111111"}
+	a2 := *a
+	a2.Name = "a again"
+
+	reps, dups := groupDuplicates([]*models.ProxyConfig{a, b, &a2})
+	if len(reps) != 2 || reps[0] != a || reps[1] != b {
+		t.Fatalf("expected a and b to be checked, got %v", reps)
+	}
+	if len(dups[a]) != 1 || dups[a][0] != &a2 || len(dups[b]) != 0 {
+		t.Fatalf("expected a's copy to share a's result, got %v", dups)
+	}
+}
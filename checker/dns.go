@@ -0,0 +1,180 @@
+package checker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrProxyConnect means the local SOCKS inbound could not be reached or
+	// rejected the handshake, i.e. the check never got to the remote side.
+	ErrProxyConnect = errors.New("proxy connect failed")
+	// ErrDomainUnreachable means the proxy accepted the connection but could
+	// not resolve or reach the probe domain.
+	ErrDomainUnreachable = errors.New("domain unreachable through proxy")
+)
+
+const defaultDNSCheckPort = 80
+
+// SetDNSCheckDomain sets the domain (optionally host:port, port 80 by default)
+// used by the dns check method.
+func (pc *ProxyChecker) SetDNSCheckDomain(domain string) {
+	pc.dnsCheckDomain = strings.TrimSpace(domain)
+}
+
+// checkByDNS connects through the proxy to the probe domain by name, so the
+// proxy has to resolve it, and sends a HEAD request. Latency covers
+// resolution, connect and the first response bytes.
+func (pc *ProxyChecker) checkByDNS(proxyAddr string) (bool, string, time.Duration, error) {
+	host, port, err := splitDNSCheckDomain(pc.dnsCheckDomain)
+	if err != nil {
+		return false, "", 0, err
+	}
+
+	start := time.Now()
+	deadline := start.Add(time.Second * time.Duration(pc.ipCheckTimeout))
+	conn, err := dialSOCKS5(proxyAddr, host, port, deadline)
+	if err != nil {
+		return false, "", 0, err
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "HEAD / HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n\r\n", host); err != nil {
+		return false, "", 0, fmt.Errorf("%w: %s: %v", ErrDomainUnreachable, host, err)
+	}
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return false, "", 0, fmt.Errorf("%w: %s: %v", ErrDomainUnreachable, host, err)
+	}
+	latency := time.Since(start)
+
+	if string(head) != "HTTP/" {
+		return false, fmt.Sprintf("Domain: %s | Unexpected response", host), latency, nil
+	}
+	return true, fmt.Sprintf("Domain: %s | Resolved and connected", host), latency, nil
+}
+
+func splitDNSCheckDomain(domain string) (string, int, error) {
+	if domain == "" {
+		return "", 0, fmt.Errorf("DNS check domain not configured")
+	}
+	host, portStr, err := net.SplitHostPort(domain)
+	if err != nil {
+		return domain, defaultDNSCheckPort, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid DNS check port: %s", portStr)
+	}
+	return host, port, nil
+}
+
+// dialSOCKS5 opens a CONNECT tunnel to host:port through the SOCKS5 proxy at
+// proxyAddr. Domain names are sent unresolved so the proxy does the lookup.
+func dialSOCKS5(proxyAddr, host string, port int, deadline time.Time) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", proxyAddr, time.Until(deadline))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if err := socks5Connect(conn, host, port); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func socks5Connect(conn net.Conn, host string, port int) error {
+	_, err := socks5Command(conn, socks5CmdConnect, host, port)
+	return err
+}
+
+const (
+	socks5CmdConnect      = 1
+	socks5CmdUDPAssociate = 3
+)
+
+// socks5Command performs the no-auth handshake and sends cmd for host:port.
+// It returns the address the proxy bound for the request.
+func socks5Command(conn net.Conn, cmd byte, host string, port int) (*net.UDPAddr, error) {
+	if _, err := conn.Write([]byte{5, 1, 0}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+	if reply[0] != 5 || reply[1] != 0 {
+		return nil, fmt.Errorf("%w: unsupported SOCKS auth reply %v", ErrProxyConnect, reply)
+	}
+
+	req, err := socks5AppendAddr([]byte{5, cmd, 0}, host, port)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrDomainUnreachable, host, err)
+	}
+	if header[1] != 0 {
+		return nil, fmt.Errorf("%w: %s: SOCKS reply code %d", ErrDomainUnreachable, host, header[1])
+	}
+
+	var addr []byte
+	switch header[3] {
+	case 1:
+		addr = make([]byte, net.IPv4len)
+	case 4:
+		addr = make([]byte, net.IPv6len)
+	case 3:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+		}
+		addr = make([]byte, int(n[0]))
+	default:
+		return nil, fmt.Errorf("%w: unknown SOCKS address type %d", ErrProxyConnect, header[3])
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(conn, addr); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+	if _, err := io.ReadFull(conn, portBytes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+
+	bound := &net.UDPAddr{Port: int(binary.BigEndian.Uint16(portBytes))}
+	if header[3] != 3 {
+		bound.IP = net.IP(addr)
+	}
+	return bound, nil
+}
+
+// socks5AppendAddr appends a SOCKS5 address (ATYP, address, port) to buf.
+// Domain names are sent unresolved.
+func socks5AppendAddr(buf []byte, host string, port int) ([]byte, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			buf = append(append(buf, 1), ip4...)
+		} else {
+			buf = append(append(buf, 4), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return nil, fmt.Errorf("SOCKS target domain too long: %s", host)
+		}
+		buf = append(append(buf, 3, byte(len(host))), host...)
+	}
+	return binary.BigEndian.AppendUint16(buf, uint16(port)), nil
+}
@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestCheckByDNS(t *testing.T) {
+	pc, _, _ := newSOCKSCheckFixture(t)
+	proxyAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(pc.startPort))
+	target, err := url.Parse(pc.genMethodURL)
+	if err != nil {
+		t.Fatalf("parse target failed: %v", err)
+	}
+
+	pc.SetDNSCheckDomain("localhost:" + target.Port())
+	ok, msg, latency, err := pc.checkByDNS(proxyAddr)
+	if err != nil || !ok {
+		t.Fatalf("expected DNS check to pass, got ok=%v msg=%q err=%v", ok, msg, err)
+	}
+	if latency <= 0 {
+		t.Fatalf("expected positive latency, got %s", latency)
+	}
+
+	pc.SetDNSCheckDomain("does-not-exist.invalid")
+	if _, _, _, err := pc.checkByDNS(proxyAddr); !errors.Is(err, ErrDomainUnreachable) {
+		t.Fatalf("expected ErrDomainUnreachable for unresolvable domain, got %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	closedAddr := ln.Addr().String()
+	ln.Close()
+	if _, _, _, err := pc.checkByDNS(closedAddr); !errors.Is(err, ErrProxyConnect) {
+		t.Fatalf("expected ErrProxyConnect for unreachable proxy, got %v", err)
+	}
+}
+
+func TestSplitDNSCheckDomain(t *testing.T) {
+	host, port, err := splitDNSCheckDomain("example.com")
+	if err != nil || host != "example.com" || port != defaultDNSCheckPort {
+		t.Fatalf("unexpected split: %s %d %v", host, port, err)
+	}
+	host, port, err = splitDNSCheckDomain("example.com:8080")
+	if err != nil || host != "example.com" || port != 8080 {
+		t.Fatalf("unexpected split: %s %d %v", host, port, err)
+	}
+	if _, _, err := splitDNSCheckDomain("example.com:99999"); err == nil {
+		t.Fatal("expected invalid port to be rejected")
+	}
+	if _, _, err := splitDNSCheckDomain(""); err == nil {
+		t.Fatal("expected empty domain to be rejected")
+	}
+}
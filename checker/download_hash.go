@@ -0,0 +1,49 @@
+package checker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseSHA256 decodes a hex SHA-256 digest. Empty means no digest.
+func ParseSHA256(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	sum, err := hex.DecodeString(value)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("expected %d hex characters, got %q", 2*sha256.Size, value)
+	}
+	return sum, nil
+}
+
+// SetDownloadSHA256 makes download checks of the global download URL read the
+// whole body, up to maxSize bytes, and fail unless its SHA-256 equals sum.
+// This catches exits that inject into or rewrite content. A nil sum disables
+// the check.
+func (pc *ProxyChecker) SetDownloadSHA256(sum []byte, maxSize int64) {
+	pc.downloadSHA256 = sum
+	pc.downloadMaxSize = maxSize
+}
+
+// verifyDownloadHash hashes the whole body and compares it with the expected
+// digest. It returns the number of bytes read.
+func (pc *ProxyChecker) verifyDownloadHash(body io.Reader) (int64, error) {
+	hash := sha256.New()
+	total, err := io.Copy(hash, io.LimitReader(body, pc.downloadMaxSize+1))
+	if err != nil {
+		return total, fmt.Errorf("download error after %d bytes: %w", total, err)
+	}
+	if total > pc.downloadMaxSize {
+		return total, fmt.Errorf("download exceeds max size %d bytes", pc.downloadMaxSize)
+	}
+	if sum := hash.Sum(nil); !bytes.Equal(sum, pc.downloadSHA256) {
+		return total, fmt.Errorf("SHA-256 mismatch: got %x", sum)
+	}
+	return total, nil
+}
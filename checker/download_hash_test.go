@@ -0,0 +1,74 @@
+package checker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"xray-checker/models"
+)
+
+func TestCheckByDownloadVerifiesSHA256(t *testing.T) {
+	body := bytes.Repeat([]byte("xray-checker"), 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	sum := sha256.Sum256(body)
+	expected, err := ParseSHA256(hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("parse digest: %v", err)
+	}
+	pc := &ProxyChecker{downloadURL: server.URL, downloadTimeout: 5, downloadMinSize: 1024}
+
+	pc.SetDownloadSHA256(expected, 1<<20)
+	ok, msg, _, err := pc.checkByDownload(&http.Client{}, server.URL)
+	if err != nil || !ok || !strings.Contains(msg, "SHA-256 ok") {
+		t.Fatalf("expected matching digest to pass, got ok=%v msg=%q err=%v", ok, msg, err)
+	}
+
+	tampered := sha256.Sum256(append(body, '!'))
+	pc.SetDownloadSHA256(tampered[:], 1<<20)
+	ok, msg, _, err = pc.checkByDownload(&http.Client{}, server.URL)
+	if err != nil || ok || !strings.Contains(msg, "SHA-256 mismatch") {
+		t.Fatalf("expected mismatch to fail, got ok=%v msg=%q err=%v", ok, msg, err)
+	}
+
+	pc.SetDownloadSHA256(expected, 100)
+	ok, msg, _, _ = pc.checkByDownload(&http.Client{}, server.URL)
+	if ok || !strings.Contains(msg, "exceeds max size") {
+		t.Fatalf("expected oversized body to fail, got ok=%v msg=%q", ok, msg)
+	}
+}
+
+func TestCheckByDownloadSkipsSHA256ForMappedURLs(t *testing.T) {
+	body := bytes.Repeat([]byte("mapped"), 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	global := sha256.Sum256([]byte("the global file"))
+	pc := &ProxyChecker{downloadURL: "http://127.0.0.1:1/global.bin", downloadTimeout: 5, downloadMinSize: 1024}
+	pc.SetDownloadSHA256(global[:], 1<<20)
+	pc.SetDownloadURLRules([]CheckURLRule{{Tag: "EU", URL: server.URL + "/eu.bin"}})
+
+	target := checkURLFor(pc.downloadURLRules, &models.ProxyConfig{Name: "node [EU]"}, pc.downloadURL)
+	ok, msg, _, err := pc.checkByDownload(&http.Client{}, target)
+	if err != nil || !ok || strings.Contains(msg, "SHA-256") {
+		t.Fatalf("expected a mapped URL to get only the size check, got ok=%v msg=%q err=%v", ok, msg, err)
+	}
+}
+
+func TestParseSHA256(t *testing.T) {
+	if sum, err := ParseSHA256(""); err != nil || sum != nil {
+		t.Fatalf("expected empty digest to disable the check, got %x (%v)", sum, err)
+	}
+	if _, err := ParseSHA256("abc"); err == nil {
+		t.Fatal("expected an error for a short digest")
+	}
+}
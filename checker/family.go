@@ -0,0 +1,66 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AddressFamily is the network used for dials that leave the host.
+type AddressFamily string
+
+const (
+	// AddressFamilyAny dials over IPv4 or IPv6.
+	AddressFamilyAny AddressFamily = "tcp"
+	// AddressFamilyIPv4 dials over IPv4 only.
+	AddressFamilyIPv4 AddressFamily = "tcp4"
+	// AddressFamilyIPv6 dials over IPv6 only.
+	AddressFamilyIPv6 AddressFamily = "tcp6"
+)
+
+// ParseAddressFamily maps a config value to an AddressFamily. Empty means
+// AddressFamilyAny.
+func ParseAddressFamily(value string) (AddressFamily, error) {
+	switch family := AddressFamily(strings.ToLower(strings.TrimSpace(value))); family {
+	case "":
+		return AddressFamilyAny, nil
+	case AddressFamilyAny, AddressFamilyIPv4, AddressFamilyIPv6:
+		return family, nil
+	default:
+		return "", fmt.Errorf("unknown address family %q, expected tcp, tcp4 or tcp6", value)
+	}
+}
+
+// dialNetwork performs the dials of direct requests; tests replace it to see
+// which network is used.
+var dialNetwork = func(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// SetAddressFamily forces the direct requests (IP baseline, sentinel and
+// self-test) onto one address family. Checks through a proxy dial the
+// loopback inbound, so their family is set on the xray outbounds instead
+// (see xray.UseAddressFamily).
+func (pc *ProxyChecker) SetAddressFamily(family AddressFamily) {
+	pc.addressFamily = family
+	pc.httpClient.Transport = pc.directTransport(true)
+}
+
+// directTransport returns a transport for requests that do not go through a
+// proxy, dialing over the configured address family.
+func (pc *ProxyChecker) directTransport(keepAlive bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = !keepAlive
+	network := string(pc.addressFamily)
+	if network == "" {
+		network = string(AddressFamilyAny)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialNetwork(ctx, dialer, network, addr)
+	}
+	return transport
+}
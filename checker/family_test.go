@@ -0,0 +1,46 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetAddressFamilyForcesDirectDialNetwork(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.1"))
+	}))
+	t.Cleanup(target.Close)
+
+	var networks []string
+	orig := dialNetwork
+	dialNetwork = func(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+		networks = append(networks, network)
+		return dialer.DialContext(ctx, network, addr)
+	}
+	t.Cleanup(func() { dialNetwork = orig })
+
+	pc := NewProxyChecker(nil, 10000, target.URL, 1, "", "", 1, 1, "ip", 1)
+	pc.SetAddressFamily(AddressFamilyIPv4)
+	if err := fetchDirect(pc.httpClient, target.URL, is2xx); err != nil {
+		t.Fatalf("direct request failed: %v", err)
+	}
+	if len(networks) != 1 || networks[0] != "tcp4" {
+		t.Fatalf("expected one tcp4 dial, got %v", networks)
+	}
+}
+
+func TestParseAddressFamily(t *testing.T) {
+	cases := map[string]AddressFamily{"": AddressFamilyAny, "tcp": AddressFamilyAny, "TCP4": AddressFamilyIPv4, " tcp6 ": AddressFamilyIPv6}
+	for in, want := range cases {
+		got, err := ParseAddressFamily(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseAddressFamily(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseAddressFamily("ipv5"); err == nil {
+		t.Fatal("expected error for unknown family")
+	}
+}
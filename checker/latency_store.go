@@ -0,0 +1,181 @@
+package checker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"xray-checker/logger"
+)
+
+// CheckerLatencySection is the LatencyStore section holding the checker's
+// moving averages, keyed by stable ID.
+const CheckerLatencySection = "checker"
+
+// LatencyStore keeps smoothed latencies on disk so moving averages survive
+// restarts. Values live in named sections of one file, so every smoothed
+// ranking can share it instead of keeping its own.
+type LatencyStore struct {
+	mu       sync.Mutex
+	path     string
+	sections map[string]map[string]int64 // section -> key -> milliseconds
+}
+
+// NewLatencyStore loads the store saved at path. A missing file starts
+// empty; an empty path keeps the store in memory only. The store is always
+// usable: when the file cannot be read or parsed it starts empty, the error
+// reports why, and the next save overwrites the file.
+func NewLatencyStore(path string) (*LatencyStore, error) {
+	s := &LatencyStore{path: path, sections: make(map[string]map[string]int64)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var sections map[string]map[string]int64
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return s, fmt.Errorf("invalid latency store %s: %w", path, err)
+	}
+	if sections != nil {
+		s.sections = sections
+	}
+	return s, nil
+}
+
+// Load returns the latencies saved in section.
+func (s *LatencyStore) Load(section string) map[string]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]time.Duration, len(s.sections[section]))
+	for key, ms := range s.sections[section] {
+		values[key] = time.Duration(ms) * time.Millisecond
+	}
+	return values
+}
+
+// Set replaces section with values in memory; they reach the disk with the
+// next Save of any section.
+func (s *LatencyStore) Set(section string, values map[string]time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setLocked(section, values)
+}
+
+// Save replaces section with values and writes the store.
+func (s *LatencyStore) Save(section string, values map[string]time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setLocked(section, values)
+	return s.saveLocked()
+}
+
+func (s *LatencyStore) setLocked(section string, values map[string]time.Duration) {
+	stored := make(map[string]int64, len(values))
+	for key, latency := range values {
+		stored[key] = latency.Milliseconds()
+	}
+	s.sections[section] = stored
+}
+
+func (s *LatencyStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	payload, err := json.MarshalIndent(s.sections, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// Write a temp file and rename it so a crash mid-write never leaves a
+	// truncated store behind.
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// SetLatencyStore restores the saved moving averages of the loaded proxies
+// and saves the store, with every section set since, after every check
+// iteration. Averages also carry over
+// proxy list updates; entries of proxies that are no longer loaded are
+// dropped on the next save.
+func (pc *ProxyChecker) SetLatencyStore(store *LatencyStore) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.latencyStore = store
+	pc.restoreEMALocked(store.Load(CheckerLatencySection))
+}
+
+// restoreEMALocked seeds the moving averages of the loaded proxies from
+// values keyed by stable ID. pc.mu must be held.
+func (pc *ProxyChecker) restoreEMALocked(values map[string]time.Duration) {
+	for _, proxy := range pc.proxies {
+		if ema, ok := values[proxy.StableID]; ok && ema > 0 {
+			pc.emaLatency.Store(metricKeyForProxy(proxy), ema)
+		}
+	}
+}
+
+// emaByStableIDLocked returns the moving averages of the loaded proxies keyed
+// by stable ID. pc.mu must be held.
+func (pc *ProxyChecker) emaByStableIDLocked() map[string]time.Duration {
+	values := make(map[string]time.Duration, len(pc.proxies))
+	for _, proxy := range pc.proxies {
+		if ema, ok := pc.emaLatency.Load(metricKeyForProxy(proxy)); ok {
+			values[proxy.StableID] = ema.(time.Duration)
+		}
+	}
+	return values
+}
+
+func (pc *ProxyChecker) saveLatencyStore() {
+	pc.mu.RLock()
+	store := pc.latencyStore
+	var values map[string]time.Duration
+	if store != nil {
+		values = pc.emaByStableIDLocked()
+	}
+	pc.mu.RUnlock()
+	if store == nil {
+		return
+	}
+	if err := store.Save(CheckerLatencySection, values); err != nil {
+		logger.Warn("Failed to save latency store: %v", err)
+	}
+}
+
+// LatencyStore returns the store set by SetLatencyStore, or nil.
+func (pc *ProxyChecker) LatencyStore() *LatencyStore {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.latencyStore
+}
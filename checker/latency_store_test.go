@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"xray-checker/models"
+)
+
+func TestLatencyStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "latency.json")
+	store, err := NewLatencyStore(path)
+	if err != nil {
+		t.Fatalf("NewLatencyStore: %v", err)
+	}
+	if err := store.Save(CheckerLatencySection, map[string]time.Duration{"a": 120 * time.Millisecond}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save("other", map[string]time.Duration{"b": 80 * time.Millisecond}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded, err := NewLatencyStore(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.Load(CheckerLatencySection)["a"]; got != 120*time.Millisecond {
+		t.Fatalf("checker section: got %s", got)
+	}
+	if got := reloaded.Load("other")["b"]; got != 80*time.Millisecond {
+		t.Fatalf("sections must share the file, got %s", got)
+	}
+}
+
+func TestLatencyStoreSetWaitsForSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "latency.json")
+	store, _ := NewLatencyStore(path)
+
+	store.Set("other", map[string]time.Duration{"b": 80 * time.Millisecond})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Set must not write the file, stat err=%v", err)
+	}
+
+	if err := store.Save(CheckerLatencySection, map[string]time.Duration{"a": 120 * time.Millisecond}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	reloaded, _ := NewLatencyStore(path)
+	if got := reloaded.Load("other")["b"]; got != 80*time.Millisecond {
+		t.Fatalf("Save must write sections set before it, got %s", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected only the store file after a save, got %d entries", len(entries))
+	}
+}
+
+func TestLatencyStoreCorruptFileStartsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "latency.json")
+	if err := os.WriteFile(path, []byte("{truncated"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	store, err := NewLatencyStore(path)
+	if err == nil {
+		t.Fatal("expected an error for a corrupt store")
+	}
+	if store == nil || len(store.Load(CheckerLatencySection)) != 0 {
+		t.Fatal("a corrupt store must start empty")
+	}
+	if err := store.Save(CheckerLatencySection, map[string]time.Duration{"a": 50 * time.Millisecond}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := NewLatencyStore(path); err != nil {
+		t.Fatalf("the next save must replace the corrupt file, got %v", err)
+	}
+}
+
+func TestSetLatencyStoreRestoresAndDropsRemovedProxies(t *testing.T) {
+	kept := &models.ProxyConfig{Protocol: "vless", Server: "1.1.1.1", Port: 443, Name: "kept", UUID: "11111111-1111-1111-1111-111111111111"}
+	kept.StableID = kept.GenerateStableID()
+	path := filepath.Join(t.TempDir(), "latency.json")
+
+	store, _ := NewLatencyStore(path)
+	if err := store.Save(CheckerLatencySection, map[string]time.Duration{
+		kept.StableID: 150 * time.Millisecond,
+		"removed":     90 * time.Millisecond,
+	}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded, _ := NewLatencyStore(path)
+	pc := NewProxyChecker([]*models.ProxyConfig{kept}, 10000, "", 1, "", "", 1, 1, "status", 1)
+	pc.SetLatencySmoothing(0.5)
+	pc.SetLatencyStore(reloaded)
+	if ema, ok := pc.GetEMALatencyByStableID(kept.StableID); !ok || ema != 150*time.Millisecond {
+		t.Fatalf("expected restored EMA of 150ms, got %s (%v)", ema, ok)
+	}
+
+	pc.UpdateProxies([]*models.ProxyConfig{kept})
+	if ema, ok := pc.GetEMALatencyByStableID(kept.StableID); !ok || ema != 150*time.Millisecond {
+		t.Fatalf("EMA must survive a proxy update, got %s (%v)", ema, ok)
+	}
+
+	pc.saveLatencyStore()
+	saved, _ := NewLatencyStore(path)
+	values := saved.Load(CheckerLatencySection)
+	if _, ok := values["removed"]; ok {
+		t.Fatal("entries of proxies no longer loaded must be dropped")
+	}
+	if values[kept.StableID] != 150*time.Millisecond {
+		t.Fatalf("expected the kept proxy to be saved, got %v", values)
+	}
+}
@@ -0,0 +1,57 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HTTPVersion selects the HTTP protocols the check client speaks to check
+// targets through the proxy.
+type HTTPVersion string
+
+const (
+	// HTTPVersionAuto uses HTTP/1.1, switching to HTTP/2 when a TLS target
+	// negotiates it.
+	HTTPVersionAuto HTTPVersion = "auto"
+	// HTTPVersion1 uses HTTP/1.1 only.
+	HTTPVersion1 HTTPVersion = "http1"
+	// HTTPVersion2 uses HTTP/2 only: h2 over TLS and h2c with prior
+	// knowledge for http:// targets.
+	HTTPVersion2 HTTPVersion = "http2"
+)
+
+// ParseHTTPVersion maps a config value to an HTTPVersion. Empty means
+// HTTPVersionAuto.
+func ParseHTTPVersion(value string) (HTTPVersion, error) {
+	switch version := HTTPVersion(strings.ToLower(strings.TrimSpace(value))); version {
+	case "":
+		return HTTPVersionAuto, nil
+	case HTTPVersionAuto, HTTPVersion1, HTTPVersion2:
+		return version, nil
+	default:
+		return "", fmt.Errorf("unknown HTTP version %q, expected auto, http1 or http2", value)
+	}
+}
+
+// SetHTTPVersion sets the HTTP protocols used for ip, status and download
+// checks.
+func (pc *ProxyChecker) SetHTTPVersion(version HTTPVersion) {
+	pc.httpVersion = version
+}
+
+// protocols returns the transport protocol set for the configured HTTP
+// version, or nil to keep the transport defaults.
+func (pc *ProxyChecker) protocols() *http.Protocols {
+	var p http.Protocols
+	switch pc.httpVersion {
+	case HTTPVersion1:
+		p.SetHTTP1(true)
+	case HTTPVersion2:
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+	default:
+		return nil
+	}
+	return &p
+}
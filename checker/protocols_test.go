@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckProxyH2CTarget(t *testing.T) {
+	initTestMetrics()
+	pc, p, _ := newSOCKSCheckFixture(t)
+
+	// The target only answers requests that arrive over HTTP/2.
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	target.Config.Protocols = new(http.Protocols)
+	target.Config.Protocols.SetHTTP1(true)
+	target.Config.Protocols.SetUnencryptedHTTP2(true)
+	target.Start()
+	t.Cleanup(target.Close)
+	pc.genMethodURL = target.URL
+
+	pc.CheckProxy(p)
+	if online, _, _ := pc.GetProxyStatusByStableID(p.StableID); online {
+		t.Fatal("expected the default transport to reach the target over HTTP/1.1 and fail")
+	}
+
+	pc.SetHTTPVersion(HTTPVersion2)
+	pc.CheckProxy(p)
+	if online, _, err := pc.GetProxyStatusByStableID(p.StableID); !online {
+		t.Fatalf("expected the h2c check to pass, err=%v reason=%q", err, pc.GetLastErrorByStableID(p.StableID))
+	}
+}
+
+func TestParseHTTPVersion(t *testing.T) {
+	for input, want := range map[string]HTTPVersion{
+		"":       HTTPVersionAuto,
+		"auto":   HTTPVersionAuto,
+		"HTTP1":  HTTPVersion1,
+		" http2": HTTPVersion2,
+	} {
+		got, err := ParseHTTPVersion(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseHTTPVersion(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseHTTPVersion("h3"); err == nil {
+		t.Fatal("expected an error for an unknown HTTP version")
+	}
+}
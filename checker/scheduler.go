@@ -0,0 +1,89 @@
+package checker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron"
+)
+
+// CheckScheduler runs a job on a fixed interval that can be changed at
+// runtime. It drives the proxy checks and the subscription updates.
+type CheckScheduler struct {
+	mu        sync.Mutex
+	scheduler *gocron.Scheduler
+	job       func()
+	interval  int
+}
+
+func NewCheckScheduler(intervalSeconds int, job func()) *CheckScheduler {
+	return &CheckScheduler{
+		scheduler: gocron.NewScheduler(time.UTC),
+		job:       job,
+		interval:  intervalSeconds,
+	}
+}
+
+// Start schedules the job, running it immediately, and starts the scheduler.
+func (cs *CheckScheduler) Start() error {
+	return cs.start(false)
+}
+
+// StartDelayed is like Start, but the first run happens one interval from
+// now.
+func (cs *CheckScheduler) StartDelayed() error {
+	return cs.start(true)
+}
+
+func (cs *CheckScheduler) start(waitFirst bool) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	schedule := cs.scheduler.Every(cs.interval).Seconds()
+	if waitFirst {
+		schedule = schedule.WaitForSchedule()
+	}
+	if _, err := schedule.Do(cs.job); err != nil {
+		return err
+	}
+	cs.scheduler.StartAsync()
+	return nil
+}
+
+// Reschedule replaces the job with one running every intervalSeconds. The
+// next run happens one full interval from now.
+func (cs *CheckScheduler) Reschedule(intervalSeconds int) error {
+	if intervalSeconds <= 0 {
+		return fmt.Errorf("check interval must be positive")
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.scheduler.Clear()
+	if _, err := cs.scheduler.Every(intervalSeconds).Seconds().WaitForSchedule().Do(cs.job); err != nil {
+		return err
+	}
+	cs.interval = intervalSeconds
+	return nil
+}
+
+func (cs *CheckScheduler) Interval() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.interval
+}
+
+// NextRun returns when the job runs next, or the zero time when it is not
+// scheduled.
+func (cs *CheckScheduler) NextRun() time.Time {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	_, next := cs.scheduler.NextRun()
+	return next
+}
+
+func (cs *CheckScheduler) Stop() {
+	cs.scheduler.Stop()
+}
@@ -0,0 +1,50 @@
+package checker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckSchedulerReschedule(t *testing.T) {
+	var runs atomic.Int32
+	cs := NewCheckScheduler(3600, func() { runs.Add(1) })
+	if err := cs.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer cs.Stop()
+
+	waitFor := func(want int32) {
+		deadline := time.Now().Add(3 * time.Second)
+		for runs.Load() < want && time.Now().Before(deadline) {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	waitFor(1)
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("expected an immediate first run, got %d", got)
+	}
+
+	if err := cs.Reschedule(0); err == nil {
+		t.Fatal("expected error for non-positive interval")
+	}
+	if cs.Interval() != 3600 {
+		t.Fatalf("interval changed after invalid reschedule: %d", cs.Interval())
+	}
+
+	if err := cs.Reschedule(1); err != nil {
+		t.Fatalf("reschedule failed: %v", err)
+	}
+	if cs.Interval() != 1 {
+		t.Fatalf("expected interval 1, got %d", cs.Interval())
+	}
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("reschedule must not trigger an immediate run, got %d runs", got)
+	}
+
+	waitFor(2)
+	if got := runs.Load(); got < 2 {
+		t.Fatalf("expected the job to run on the new interval, got %d runs", got)
+	}
+}
@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SelfTestResult is the outcome of fetching one configured URL directly,
+// without a proxy.
+type SelfTestResult struct {
+	Name string
+	URL  string
+	Err  error
+}
+
+// SelfTest fetches the IP check URL and the check URL of the configured
+// method directly. A broken URL would otherwise make every proxy fail with
+// the same confusing error. The status check URL is judged like the real
+// check: by the accepted status codes and with the same redirect policy.
+func (pc *ProxyChecker) SelfTest() []SelfTestResult {
+	results := []SelfTestResult{{Name: "IP check URL", URL: pc.ipCheck}}
+	results[0].Err = fetchDirect(pc.httpClient, pc.ipCheck, is2xx)
+	switch pc.checkMethod {
+	case "status":
+		result := SelfTestResult{Name: "status check URL", URL: pc.genMethodURL}
+		result.Err = fetchDirect(pc.statusClient(pc.httpClient), pc.genMethodURL, pc.statusAccepted)
+		results = append(results, result)
+	case "download":
+		result := SelfTestResult{Name: "download URL", URL: pc.downloadURL}
+		result.Err = fetchDirect(pc.httpClient, pc.downloadURL, is2xx)
+		results = append(results, result)
+	}
+	return results
+}
+
+func is2xx(code int) bool {
+	return code >= 200 && code < 300
+}
+
+func fetchDirect(client *http.Client, target string, accepted func(int) bool) error {
+	if target == "" {
+		return fmt.Errorf("not configured")
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxIPResponseSize))
+	if !accepted(resp.StatusCode) {
+		return fmt.Errorf("HTTP status: %d", resp.StatusCode)
+	}
+	return nil
+}
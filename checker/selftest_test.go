@@ -0,0 +1,63 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSelfTestReportsBadURLs(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.1"))
+	}))
+	t.Cleanup(ok.Close)
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(notFound.Close)
+
+	pc := NewProxyChecker(nil, 10000, ok.URL, 2, notFound.URL, "", 1, 1, "status", 1)
+	results := pc.SelfTest()
+	if len(results) != 2 {
+		t.Fatalf("expected IP and status URL results, got %+v", results)
+	}
+	if results[0].Err != nil {
+		t.Fatalf("expected IP check URL to pass, got %v", results[0].Err)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "404") {
+		t.Fatalf("expected status URL to fail with 404, got %v", results[1].Err)
+	}
+
+	pc = NewProxyChecker(nil, 10000, "http://127.0.0.1:1/ip", 2, "", "", 1, 1, "ip", 1)
+	results = pc.SelfTest()
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("expected unreachable IP check URL to fail, got %+v", results)
+	}
+}
+
+func TestSelfTestUsesAcceptedStatusCodes(t *testing.T) {
+	ip := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.1"))
+	}))
+	t.Cleanup(ip.Close)
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.1:1/unreachable", http.StatusFound)
+	}))
+	t.Cleanup(redirect.Close)
+
+	pc := NewProxyChecker(nil, 10000, ip.URL, 2, redirect.URL, "", 1, 1, "status", 1)
+	codes, err := ParseStatusCodes([]string{"200-399"})
+	if err != nil {
+		t.Fatalf("ParseStatusCodes: %v", err)
+	}
+	pc.SetStatusCodes(codes)
+	results := pc.SelfTest()
+	if len(results) != 2 || results[1].Err != nil {
+		t.Fatalf("expected an accepted 302 to pass without following it, got %+v", results)
+	}
+
+	pc.SetStatusCodes(nil)
+	results = pc.SelfTest()
+	if results[1].Err == nil {
+		t.Fatal("expected the followed redirect to an unreachable URL to fail with the default codes")
+	}
+}
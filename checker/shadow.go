@@ -0,0 +1,41 @@
+package checker
+
+import (
+	"net/http"
+	"xray-checker/logger"
+	"xray-checker/models"
+)
+
+// SetShadowMethod sets a second check method run after every check to
+// validate a method change before cutover. Its results are only compared
+// with the primary method and logged; they never touch status, metrics or
+// the API. Empty, or the primary method itself, disables it.
+func (pc *ProxyChecker) SetShadowMethod(method string) {
+	pc.shadowMethod = method
+}
+
+// runShadowCheck runs the shadow method and logs when it disagrees with the
+// result of primaryMethod, the method the proxy was checked with.
+func (pc *ProxyChecker) runShadowCheck(primaryMethod string, client *http.Client, proxy *models.ProxyConfig, proxyAddr string, primaryOnline bool) {
+	if pc.shadowMethod == "" || pc.shadowMethod == primaryMethod {
+		return
+	}
+	success, message, latency, err := pc.runCheckMethod(pc.shadowMethod, client, proxy, proxyAddr)
+	shadowOnline := err == nil && success
+	if shadowOnline == primaryOnline {
+		logger.Debug("%s | Shadow %s agrees: %s | Latency: %s", proxy.Name, pc.shadowMethod, onlineLabel(shadowOnline), latency)
+		return
+	}
+	if err != nil {
+		message = classifyTimeout(err).Error()
+	}
+	logger.Warn("%s | Shadow disagrees: %s says %s, %s says %s | %s",
+		proxy.Name, primaryMethod, onlineLabel(primaryOnline), pc.shadowMethod, onlineLabel(shadowOnline), message)
+}
+
+func onlineLabel(online bool) string {
+	if online {
+		return "online"
+	}
+	return "offline"
+}
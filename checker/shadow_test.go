@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"xray-checker/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestShadowCheckDoesNotAlterMetrics(t *testing.T) {
+	initTestMetrics()
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	// The primary status check succeeds; the shadow download check cannot.
+	pc.downloadURL = "http://127.0.0.1:1/file"
+	pc.SetShadowMethod("download")
+
+	logs := captureLogs(t, func() { pc.CheckProxy(proxy) })
+
+	if !strings.Contains(logs, "Shadow disagrees: status says online, download says offline") {
+		t.Fatalf("expected a disagreement line, got:\n%s", logs)
+	}
+	status, _, err := pc.GetProxyStatusByStableID(proxy.StableID)
+	if err != nil || !status {
+		t.Fatalf("shadow failure must not change the status, got status=%v err=%v", status, err)
+	}
+	if lastErr := pc.GetLastErrorByStableID(proxy.StableID); lastErr != "" {
+		t.Fatalf("shadow failure must not set a last error, got %q", lastErr)
+	}
+	gauge := metrics.GetProxyStatusMetric().WithLabelValues(proxy.Protocol, "1.1.1.1:443", proxy.Name, proxy.SubName, "test")
+	if got := testutil.ToFloat64(gauge); got != 1 {
+		t.Fatalf("status metric must come from the primary method, got %v", got)
+	}
+}
+
+func TestCheckAllProxiesFetchesBaselineIPForShadowIPMethod(t *testing.T) {
+	initTestMetrics()
+
+	ipServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("198.51.100.8"))
+	}))
+	defer ipServer.Close()
+
+	pc, _, _ := newSOCKSCheckFixture(t)
+	pc.ipCheck = ipServer.URL
+	pc.SetShadowMethod("ip")
+
+	pc.CheckAllProxies()
+	if pc.currentIP != "198.51.100.8" {
+		t.Fatalf("expected the baseline IP to be fetched for the shadow ip method, got %q", pc.currentIP)
+	}
+}
@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StatusCodeRange is an inclusive range of HTTP status codes.
+type StatusCodeRange struct {
+	Min int
+	Max int
+}
+
+// StatusCodes is the set of response codes a status check accepts.
+type StatusCodes []StatusCodeRange
+
+// DefaultStatusCodes accepts any 2xx response.
+var DefaultStatusCodes = StatusCodes{{Min: 200, Max: 299}}
+
+// ParseStatusCodes parses entries such as "204", "200-299" or "3xx". No
+// entries means DefaultStatusCodes.
+func ParseStatusCodes(values []string) (StatusCodes, error) {
+	var codes StatusCodes
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		var r StatusCodeRange
+		var err error
+		if class, ok := strings.CutSuffix(value, "xx"); ok {
+			var digit int
+			digit, err = strconv.Atoi(class)
+			r = StatusCodeRange{Min: digit * 100, Max: digit*100 + 99}
+		} else if low, high, ok := strings.Cut(value, "-"); ok {
+			r.Min, err = strconv.Atoi(strings.TrimSpace(low))
+			if err == nil {
+				r.Max, err = strconv.Atoi(strings.TrimSpace(high))
+			}
+		} else {
+			r.Min, err = strconv.Atoi(value)
+			r.Max = r.Min
+		}
+		if err != nil || r.Min < 100 || r.Max > 599 || r.Min > r.Max {
+			return nil, fmt.Errorf("invalid status code %q, expected a code, a range like 200-299 or a class like 2xx", value)
+		}
+		codes = append(codes, r)
+	}
+	if len(codes) == 0 {
+		return DefaultStatusCodes, nil
+	}
+	return codes, nil
+}
+
+// Contains reports whether code is accepted.
+func (s StatusCodes) Contains(code int) bool {
+	for _, r := range s {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsRedirect reports whether any 3xx code is accepted. Redirects are
+// then not followed, so the redirect response itself is judged.
+func (s StatusCodes) acceptsRedirect() bool {
+	for _, r := range s {
+		if r.Min <= 399 && r.Max >= 300 {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStatusCodes sets the response codes the status check treats as
+// success.
+func (pc *ProxyChecker) SetStatusCodes(codes StatusCodes) {
+	pc.statusCodes = codes
+}
+
+// statusClient returns client, or a copy that does not follow redirects when
+// redirects are accepted as success.
+func (pc *ProxyChecker) statusClient(client *http.Client) *http.Client {
+	if !pc.statusCodes.acceptsRedirect() {
+		return client
+	}
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &noRedirect
+}
+
+func (pc *ProxyChecker) statusAccepted(code int) bool {
+	if len(pc.statusCodes) == 0 {
+		return DefaultStatusCodes.Contains(code)
+	}
+	return pc.statusCodes.Contains(code)
+}
@@ -0,0 +1,71 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	codes, err := ParseStatusCodes([]string{"204", " 300-302 ", "4XX"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := StatusCodes{{Min: 204, Max: 204}, {Min: 300, Max: 302}, {Min: 400, Max: 499}}
+	if !reflect.DeepEqual(codes, want) {
+		t.Fatalf("got %+v, want %+v", codes, want)
+	}
+
+	if codes, err := ParseStatusCodes(nil); err != nil || !reflect.DeepEqual(codes, DefaultStatusCodes) {
+		t.Fatalf("no entries must mean 2xx, got %+v, %v", codes, err)
+	}
+	for _, bad := range []string{"abc", "99", "600", "302-300", "xx"} {
+		if _, err := ParseStatusCodes([]string{bad}); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCheckByGenAcceptsConfiguredStatusCodes(t *testing.T) {
+	initTestMetrics()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/204":
+			w.WriteHeader(http.StatusNoContent)
+		case "/301":
+			http.Redirect(w, r, "/500", http.StatusMovedPermanently)
+		case "/200":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(target.Close)
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	codes, err := ParseStatusCodes([]string{"204", "301"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	pc.SetStatusCodes(codes)
+
+	cases := []struct {
+		path   string
+		online bool
+	}{
+		{"/204", true},
+		{"/301", true},
+		{"/200", false},
+		{"/500", false},
+	}
+	for _, tc := range cases {
+		pc.genMethodURL = target.URL + tc.path
+		pc.CheckProxy(proxy)
+		status, _, err := pc.GetProxyStatusByStableID(proxy.StableID)
+		if err != nil || status != tc.online {
+			t.Fatalf("%s: expected online=%v, got %v (err %v)", tc.path, tc.online, status, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package checker
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	stunMagicCookie      = 0x2112A442
+	stunBindingRequest   = 0x0001
+	stunBindingSuccess   = 0x0101
+	stunAttrMapped       = 0x0001
+	stunAttrXORMapped    = 0x0020
+	stunHeaderSize       = 20
+	stunTransactionIDLen = 12
+)
+
+// SetUDPSTUNServer makes the UDP probe a STUN binding request to server
+// (host:port) instead of a DNS query. Besides checking that UDP passes, it
+// learns the proxy's UDP exit IP, which can differ from the TCP one. An empty
+// server keeps the DNS probe.
+func (pc *ProxyChecker) SetUDPSTUNServer(server string) {
+	pc.stunServer = strings.TrimSpace(server)
+}
+
+// probeUDP runs the configured UDP probe through the proxy at proxyAddr. The
+// exit IP is only known with a STUN server.
+func (pc *ProxyChecker) probeUDP(proxyAddr string) (string, error) {
+	if pc.stunServer == "" {
+		_, err := pc.checkByUDP(proxyAddr)
+		return "", err
+	}
+	ip, _, err := pc.checkBySTUN(proxyAddr)
+	if err != nil {
+		return "", err
+	}
+	return ip.String(), nil
+}
+
+// checkBySTUN sends a STUN binding request to the STUN server through the
+// proxy at proxyAddr and returns the address the server saw it from.
+func (pc *ProxyChecker) checkBySTUN(proxyAddr string) (net.IP, time.Duration, error) {
+	txID := make([]byte, stunTransactionIDLen)
+	if _, err := rand.Read(txID); err != nil {
+		return nil, 0, err
+	}
+	reply, latency, err := pc.udpExchange(proxyAddr, pc.stunServer, stunRequest(txID))
+	if err != nil {
+		return nil, 0, err
+	}
+	ip, err := parseSTUNResponse(reply, txID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrUDPUnreachable, err)
+	}
+	return ip, latency, nil
+}
+
+// stunRequest builds an attribute-less STUN binding request (RFC 5389).
+func stunRequest(txID []byte) []byte {
+	msg := binary.BigEndian.AppendUint16(nil, stunBindingRequest)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	msg = binary.BigEndian.AppendUint32(msg, stunMagicCookie)
+	return append(msg, txID...)
+}
+
+// parseSTUNResponse returns the mapped IP of a binding success response to
+// the request with txID, preferring XOR-MAPPED-ADDRESS over the legacy
+// MAPPED-ADDRESS.
+func parseSTUNResponse(msg, txID []byte) (net.IP, error) {
+	if len(msg) < stunHeaderSize {
+		return nil, fmt.Errorf("short STUN response")
+	}
+	if binary.BigEndian.Uint16(msg) != stunBindingSuccess {
+		return nil, fmt.Errorf("unexpected STUN message type %#04x", binary.BigEndian.Uint16(msg))
+	}
+	if binary.BigEndian.Uint32(msg[4:]) != stunMagicCookie || !bytes.Equal(msg[8:stunHeaderSize], txID) {
+		return nil, fmt.Errorf("STUN response does not match the request")
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:]))
+	if len(msg) < stunHeaderSize+length {
+		return nil, fmt.Errorf("truncated STUN response")
+	}
+
+	var mapped net.IP
+	attrs := msg[stunHeaderSize : stunHeaderSize+length]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs)
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:]))
+		if len(attrs) < 4+attrLen {
+			break
+		}
+		value := attrs[4 : 4+attrLen]
+		switch attrType {
+		case stunAttrXORMapped:
+			if ip := stunAddress(value, msg[4:stunHeaderSize]); ip != nil {
+				return ip, nil
+			}
+		case stunAttrMapped:
+			mapped = stunAddress(value, nil)
+		}
+		// Attributes are padded to a multiple of 4 bytes.
+		next := 4 + (attrLen+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+	if mapped == nil {
+		return nil, fmt.Errorf("STUN response has no mapped address")
+	}
+	return mapped, nil
+}
+
+// stunAddress decodes a (XOR-)MAPPED-ADDRESS value. xorKey is the magic
+// cookie followed by the transaction ID for XOR-MAPPED-ADDRESS, nil otherwise.
+func stunAddress(value, xorKey []byte) net.IP {
+	if len(value) < 4 {
+		return nil
+	}
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil
+	}
+	if len(value) < 4+size {
+		return nil
+	}
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	if xorKey != nil {
+		for i := range ip {
+			ip[i] ^= xorKey[i]
+		}
+	}
+	return ip
+}
+
+// recordUDPExitIP remembers the UDP exit IP a probe found for metricKey; an
+// empty ip forgets it.
+func (pc *ProxyChecker) recordUDPExitIP(metricKey, ip string) {
+	defer pc.resultsVersion.Add(1)
+	if ip == "" {
+		pc.udpExitIPs.Delete(metricKey)
+		return
+	}
+	pc.udpExitIPs.Store(metricKey, ip)
+}
+
+// GetUDPExitIPByStableID returns the UDP exit IP the last STUN probe found
+// for the proxy, or "" when it is unknown.
+func (pc *ProxyChecker) GetUDPExitIPByStableID(stableID string) string {
+	metricKey := pc.metricKeyByStableID(stableID)
+	if metricKey == "" {
+		return ""
+	}
+	ip, ok := pc.udpExitIPs.Load(metricKey)
+	if !ok {
+		return ""
+	}
+	return ip.(string)
+}
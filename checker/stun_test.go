@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+)
+
+// startFakeSTUN answers binding requests with the sender's address as
+// XOR-MAPPED-ADDRESS.
+func startFakeSTUN(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen udp failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if n < stunHeaderSize || binary.BigEndian.Uint16(buf) != stunBindingRequest {
+				continue
+			}
+			conn.WriteToUDP(stunTestResponse(buf[8:stunHeaderSize], stunAttrXORMapped, addr), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func stunTestResponse(txID []byte, attrType uint16, addr *net.UDPAddr) []byte {
+	ip := addr.IP.To4()
+	port := uint16(addr.Port)
+	if attrType == stunAttrXORMapped {
+		port ^= stunMagicCookie >> 16
+		cookie := binary.BigEndian.AppendUint32(nil, stunMagicCookie)
+		xored := make([]byte, len(ip))
+		for i := range ip {
+			xored[i] = ip[i] ^ cookie[i]
+		}
+		ip = xored
+	}
+	attr := binary.BigEndian.AppendUint16(nil, attrType)
+	attr = binary.BigEndian.AppendUint16(attr, 8)
+	attr = append(attr, 0, 0x01)
+	attr = binary.BigEndian.AppendUint16(attr, port)
+	attr = append(attr, ip...)
+
+	msg := binary.BigEndian.AppendUint16(nil, stunBindingSuccess)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(attr)))
+	msg = binary.BigEndian.AppendUint32(msg, stunMagicCookie)
+	msg = append(msg, txID...)
+	return append(msg, attr...)
+}
+
+func TestCheckProxyRecordsSTUNExitIP(t *testing.T) {
+	initTestMetrics()
+	pc, p, _ := newSOCKSCheckFixture(t)
+	p.Protocol = "shadowsocks"
+	pc.SetUDPCheck(true, "")
+	pc.SetUDPSTUNServer(startFakeSTUN(t))
+
+	pc.CheckProxy(p)
+	if status, _, err := pc.GetProxyStatusByStableID(p.StableID); err != nil || !status {
+		t.Fatalf("expected the proxy online, got %v (%v)", status, err)
+	}
+	if got := pc.GetUDPExitIPByStableID(p.StableID); got != "127.0.0.1" {
+		t.Fatalf("expected UDP exit IP 127.0.0.1, got %q", got)
+	}
+}
+
+func TestParseSTUNResponse(t *testing.T) {
+	txID := []byte("abcdefghijkl")
+	addr := &net.UDPAddr{IP: net.IPv4(198, 51, 100, 7), Port: 40000}
+
+	if ip, err := parseSTUNResponse(stunTestResponse(txID, stunAttrXORMapped, addr), txID); err != nil || !ip.Equal(addr.IP) {
+		t.Fatalf("XOR-MAPPED-ADDRESS: got %v (%v)", ip, err)
+	}
+	if ip, err := parseSTUNResponse(stunTestResponse(txID, stunAttrMapped, addr), txID); err != nil || !ip.Equal(addr.IP) {
+		t.Fatalf("MAPPED-ADDRESS: got %v (%v)", ip, err)
+	}
+	if _, err := parseSTUNResponse(stunTestResponse([]byte("other-txn-id"), stunAttrXORMapped, addr), txID); err == nil {
+		t.Fatal("a response to another transaction must be rejected")
+	}
+}
+
+func TestCheckBySTUNSilentServer(t *testing.T) {
+	pc, _, _ := newSOCKSCheckFixture(t)
+	silent, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen udp failed: %v", err)
+	}
+	silentAddr := silent.LocalAddr().String()
+	silent.Close()
+
+	pc.ipCheckTimeout = 1
+	pc.SetUDPSTUNServer(silentAddr)
+	if _, _, err := pc.checkBySTUN(net.JoinHostPort("127.0.0.1", strconv.Itoa(pc.startPort))); !errors.Is(err, ErrUDPUnreachable) {
+		t.Fatalf("expected ErrUDPUnreachable from a silent STUN server, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package checker
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProxyStatus is a proxy's last check result.
+type ProxyStatus struct {
+	Online  bool
+	Latency time.Duration
+}
+
+// StatusSnapshot returns the last check result of every checked proxy, keyed
+// by its metric key.
+func (pc *ProxyChecker) StatusSnapshot() map[string]ProxyStatus {
+	snapshot := make(map[string]ProxyStatus)
+	pc.currentMetrics.Range(func(key, value interface{}) bool {
+		status := ProxyStatus{Online: value.(bool)}
+		if latency, ok := pc.latencyMetrics.Load(key); ok {
+			status.Latency = latency.(time.Duration)
+		}
+		snapshot[key.(string)] = status
+		return true
+	})
+	return snapshot
+}
+
+// IterationSummary condenses the outcome of one check iteration.
+type IterationSummary struct {
+	Total      int
+	Online     int
+	Offline    int
+	Changed    int
+	AvgLatency time.Duration
+	Took       time.Duration
+}
+
+// SummarizeIteration compares the snapshots taken before and after an
+// iteration. Changed counts proxies whose online state flipped; proxies
+// without an earlier result are not counted as changed.
+func SummarizeIteration(before, after map[string]ProxyStatus, took time.Duration) IterationSummary {
+	summary := IterationSummary{Total: len(after), Took: took}
+	var latencySum time.Duration
+	for key, status := range after {
+		if status.Online {
+			summary.Online++
+			latencySum += status.Latency
+		} else {
+			summary.Offline++
+		}
+		if prev, ok := before[key]; ok && prev.Online != status.Online {
+			summary.Changed++
+		}
+	}
+	if summary.Online > 0 {
+		summary.AvgLatency = latencySum / time.Duration(summary.Online)
+	}
+	return summary
+}
+
+func (s IterationSummary) String() string {
+	return fmt.Sprintf("Check iteration complete: %d/%d online, %d offline, avg %dms, %d changed, took %.1fs",
+		s.Online, s.Total, s.Offline, s.AvgLatency.Milliseconds(), s.Changed, s.Took.Seconds())
+}
@@ -0,0 +1,33 @@
+package checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeIteration(t *testing.T) {
+	before := map[string]ProxyStatus{
+		"a": {Online: true, Latency: 100 * time.Millisecond},
+		"b": {Online: true, Latency: 200 * time.Millisecond},
+		"c": {Online: false},
+	}
+	after := map[string]ProxyStatus{
+		"a": {Online: true, Latency: 120 * time.Millisecond},
+		"b": {Online: false},
+		"c": {Online: true, Latency: 180 * time.Millisecond},
+		"d": {Online: false},
+	}
+
+	got := SummarizeIteration(before, after, 8300*time.Millisecond)
+	want := IterationSummary{Total: 4, Online: 2, Offline: 2, Changed: 2, AvgLatency: 150 * time.Millisecond, Took: 8300 * time.Millisecond}
+	if got != want {
+		t.Fatalf("unexpected summary %+v, want %+v", got, want)
+	}
+	if line := got.String(); line != "Check iteration complete: 2/4 online, 2 offline, avg 150ms, 2 changed, took 8.3s" {
+		t.Fatalf("unexpected summary line %q", line)
+	}
+
+	if empty := SummarizeIteration(nil, nil, 0); empty.AvgLatency != 0 || empty.Total != 0 {
+		t.Fatalf("expected an empty summary, got %+v", empty)
+	}
+}
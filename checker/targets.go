@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"xray-checker/models"
+)
+
+// CheckURLRule overrides the check URL for proxies of one subscription or
+// proxies whose name carries a tag (see models.HasNameTag).
+type CheckURLRule struct {
+	SubName string
+	Tag     string
+	URL     string
+}
+
+func (r CheckURLRule) matches(proxy *models.ProxyConfig) bool {
+	if r.SubName != "" {
+		return proxy.SubName == r.SubName
+	}
+	return models.HasNameTag(proxy.Name, r.Tag)
+}
+
+// ParseCheckURLRules parses rules written as "<tag>=<url>" or
+// "sub:<subscription name>=<url>".
+func ParseCheckURLRules(specs []string) ([]CheckURLRule, error) {
+	rules := make([]CheckURLRule, 0, len(specs))
+	for _, spec := range specs {
+		key, target, ok := strings.Cut(spec, "=")
+		key = strings.TrimSpace(key)
+		target = strings.TrimSpace(target)
+		if !ok || key == "" || target == "" {
+			return nil, fmt.Errorf("invalid check URL rule %q, expected <tag>=<url> or sub:<name>=<url>", spec)
+		}
+		if u, err := url.Parse(target); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL in check URL rule %q", spec)
+		}
+
+		rule := CheckURLRule{URL: target}
+		if sub, isSub := strings.CutPrefix(key, "sub:"); isSub {
+			rule.SubName = strings.TrimSpace(sub)
+			if rule.SubName == "" {
+				return nil, fmt.Errorf("empty subscription name in check URL rule %q", spec)
+			}
+		} else {
+			rule.Tag = key
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// SetStatusCheckURLRules sets per-proxy overrides of the status check URL.
+// The first matching rule wins; unmatched proxies use the global URL.
+func (pc *ProxyChecker) SetStatusCheckURLRules(rules []CheckURLRule) {
+	pc.statusURLRules = rules
+}
+
+// SetDownloadURLRules sets per-proxy overrides of the download check URL.
+// The first matching rule wins; unmatched proxies use the global URL.
+func (pc *ProxyChecker) SetDownloadURLRules(rules []CheckURLRule) {
+	pc.downloadURLRules = rules
+}
+
+func checkURLFor(rules []CheckURLRule, proxy *models.ProxyConfig, fallback string) string {
+	for _, rule := range rules {
+		if rule.matches(proxy) {
+			return rule.URL
+		}
+	}
+	return fallback
+}
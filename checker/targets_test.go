@@ -0,0 +1,40 @@
+package checker
+
+import (
+	"testing"
+	"xray-checker/models"
+)
+
+func TestCheckURLForSelectsByTagAndSubscription(t *testing.T) {
+	rules, err := ParseCheckURLRules([]string{
+		"sub:asia=https://asia.example.com/generate_204",
+		"EU=https://eu.example.com/generate_204?region=eu",
+	})
+	if err != nil {
+		t.Fatalf("ParseCheckURLRules failed: %v", err)
+	}
+	const global = "https://global.example.com/generate_204"
+
+	cases := []struct {
+		proxy *models.ProxyConfig
+		want  string
+	}{
+		{&models.ProxyConfig{Name: "DE [EU] 01"}, "https://eu.example.com/generate_204?region=eu"},
+		{&models.ProxyConfig{Name: "EU node", SubName: "asia"}, "https://asia.example.com/generate_204"},
+		{&models.ProxyConfig{Name: "NEUTRAL"}, global},
+		{&models.ProxyConfig{Name: "US 01", SubName: "america"}, global},
+	}
+	for _, tc := range cases {
+		if got := checkURLFor(rules, tc.proxy, global); got != tc.want {
+			t.Errorf("checkURLFor(%q, sub %q) = %q, want %q", tc.proxy.Name, tc.proxy.SubName, got, tc.want)
+		}
+	}
+}
+
+func TestParseCheckURLRulesRejectsInvalid(t *testing.T) {
+	for _, spec := range []string{"EU", "=https://eu.example.com", "EU=", "EU=not a url", "sub:=https://eu.example.com"} {
+		if _, err := ParseCheckURLRules([]string{spec}); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	netproxy "golang.org/x/net/proxy"
+)
+
+// SetConnectTimeout bounds connecting through the proxy: the TCP dial to the
+// local inbound, the SOCKS CONNECT to the check target and the TLS handshake
+// each get the timeout, and so does the wait for the first response byte
+// once the request is sent. The overall check timeout still caps the whole
+// request, so a node that connects fast but stalls mid-response fails on
+// that instead. Zero leaves connecting bounded only by the overall timeout.
+func (pc *ProxyChecker) SetConnectTimeout(timeout time.Duration) {
+	pc.connectTimeout = timeout
+}
+
+// proxyDial returns the Proxy and DialContext settings of a transport going
+// through the SOCKS inbound at proxyURL. Without a connect timeout the
+// transport speaks SOCKS itself. With one, the CONNECT is done here: the
+// transport would bound it only by the overall request timeout.
+func (pc *ProxyChecker) proxyDial(proxyURL *url.URL) (func(*http.Request) (*url.URL, error), func(ctx context.Context, network, addr string) (net.Conn, error)) {
+	if pc.connectTimeout <= 0 {
+		return http.ProxyURL(proxyURL), nil
+	}
+	timeout := pc.connectTimeout
+	// SOCKS5 only fails for unknown forward dialers.
+	dialer, _ := netproxy.SOCKS5("tcp", proxyURL.Host, nil, &net.Dialer{Timeout: timeout})
+	socks := dialer.(netproxy.ContextDialer)
+	return nil, func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return socks.DialContext(ctx, network, addr)
+	}
+}
+
+// classifyTimeout tells connect and first byte timeouts apart from response
+// timeouts so the last error says which phase stalled. Other errors are
+// returned unchanged.
+func classifyTimeout(err error) error {
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return err
+	}
+	var opErr *net.OpError
+	if (errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "socks connect")) || strings.Contains(err.Error(), "TLS handshake timeout") {
+		return fmt.Errorf("connect timeout: %w", err)
+	}
+	// The wait for response headers is bounded by the connect timeout too.
+	if strings.Contains(err.Error(), "timeout awaiting response headers") {
+		return fmt.Errorf("first byte timeout: %w", err)
+	}
+	return fmt.Errorf("response timeout: %w", err)
+}
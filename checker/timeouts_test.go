@@ -0,0 +1,141 @@
+package checker
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"xray-checker/models"
+)
+
+func TestCheckProxyResponseTimeoutSeparateFromConnect(t *testing.T) {
+	initTestMetrics()
+
+	// The target accepts and answers the handshake at once but never sends
+	// a response within the overall timeout.
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	pc.genMethodURL = slow.URL
+	pc.ipCheckTimeout = 1
+	pc.SetConnectTimeout(5 * time.Second)
+
+	start := time.Now()
+	pc.CheckProxy(proxy)
+	elapsed := time.Since(start)
+
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || status {
+		t.Fatalf("a stalled response must fail the check, got status=%v err=%v", status, err)
+	}
+	if lastErr := pc.GetLastErrorByStableID(proxy.StableID); !strings.HasPrefix(lastErr, "response timeout") {
+		t.Fatalf("expected a response timeout, got %q", lastErr)
+	}
+	if elapsed >= 4*time.Second {
+		t.Fatalf("check must end on the 1s response timeout, took %s", elapsed)
+	}
+}
+
+func TestCheckProxyConnectTimeoutBoundsStalledUpstream(t *testing.T) {
+	initTestMetrics()
+
+	// The inbound accepts the SOCKS greeting but never answers the CONNECT,
+	// like an xray outbound whose upstream does not respond.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 262)
+				if _, err := conn.Read(buf); err != nil {
+					return
+				}
+				conn.Write([]byte{0x05, 0x00})
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	p := &models.ProxyConfig{Protocol: "vless", Server: "1.1.1.1", Port: 443, Name: "stalled", UUID: "11111111-1111-1111-1111-111111111111"}
+	p.StableID = p.GenerateStableID()
+	pc := NewProxyChecker([]*models.ProxyConfig{p}, ln.Addr().(*net.TCPAddr).Port, "", 10, "http://example.com", "", 1, 1, "status", 1)
+	pc.SetConnectTimeout(300 * time.Millisecond)
+
+	start := time.Now()
+	pc.CheckProxy(p)
+	elapsed := time.Since(start)
+
+	if lastErr := pc.GetLastErrorByStableID(p.StableID); !strings.HasPrefix(lastErr, "connect timeout") {
+		t.Fatalf("expected a connect timeout, got %q", lastErr)
+	}
+	if elapsed >= 5*time.Second {
+		t.Fatalf("a stalled CONNECT must end on the connect timeout, took %s", elapsed)
+	}
+}
+
+func TestCheckProxyConnectTimeoutBoundsFirstByte(t *testing.T) {
+	initTestMetrics()
+
+	release := make(chan struct{})
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(silent.Close)
+	t.Cleanup(func() { close(release) })
+
+	pc, proxy, _ := newSOCKSCheckFixture(t)
+	pc.genMethodURL = silent.URL
+	pc.ipCheckTimeout = 10
+	pc.SetConnectTimeout(300 * time.Millisecond)
+
+	start := time.Now()
+	pc.CheckProxy(proxy)
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Fatalf("a target that never answers must fail on the connect timeout, took %s", elapsed)
+	}
+	if status, _, err := pc.GetProxyStatusByStableID(proxy.StableID); err != nil || status {
+		t.Fatalf("expected the check to fail, got status=%v err=%v", status, err)
+	}
+	if got := pc.GetLastErrorByStableID(proxy.StableID); !strings.HasPrefix(got, "first byte timeout") {
+		t.Fatalf("expected the error to be labelled a first byte timeout, got %q", got)
+	}
+}
+
+func TestClassifyTimeout(t *testing.T) {
+	dialTimeout := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	readTimeout := &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	refused := errors.New("connection refused")
+
+	if got := classifyTimeout(dialTimeout).Error(); !strings.HasPrefix(got, "connect timeout") {
+		t.Fatalf("dial timeout classified as %q", got)
+	}
+	if got := classifyTimeout(readTimeout).Error(); !strings.HasPrefix(got, "response timeout") {
+		t.Fatalf("read timeout classified as %q", got)
+	}
+	if got := classifyTimeout(refused); got != refused {
+		t.Fatalf("non-timeout errors must be unchanged, got %v", got)
+	}
+}
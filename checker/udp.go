@@ -0,0 +1,158 @@
+package checker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUDPUnreachable means the proxy accepted a UDP association but the probe
+// query got no valid answer through it.
+var ErrUDPUnreachable = errors.New("UDP unreachable through proxy")
+
+// udpProbeName is the name queried by the UDP probe.
+const udpProbeName = "example.com"
+
+// udpProtocols are the protocols whose traffic is UDP-based or that are
+// commonly used for UDP, so a TCP-only check can report them healthy while UDP
+// is broken.
+var udpProtocols = map[string]bool{
+	"shadowsocks": true,
+	"hysteria":    true,
+	"hysteria2":   true,
+	"tuic":        true,
+}
+
+// SetUDPCheck enables an extra DNS-over-UDP probe through UDP-capable proxies
+// after their regular check passes. resolver is host:port of the DNS server
+// queried; an empty resolver disables the probe unless a STUN server is set
+// (see SetUDPSTUNServer).
+func (pc *ProxyChecker) SetUDPCheck(enabled bool, resolver string) {
+	pc.udpCheck = enabled
+	pc.udpResolver = strings.TrimSpace(resolver)
+}
+
+func (pc *ProxyChecker) wantsUDPCheck(protocol string) bool {
+	return pc.udpCheck && (pc.udpResolver != "" || pc.stunServer != "") && udpProtocols[strings.ToLower(protocol)]
+}
+
+// checkByUDP opens a SOCKS5 UDP association on the proxy at proxyAddr and
+// sends a DNS query for udpProbeName to the configured resolver. It succeeds
+// when a DNS response with the query's ID comes back.
+func (pc *ProxyChecker) checkByUDP(proxyAddr string) (time.Duration, error) {
+	id := uint16(time.Now().UnixNano())
+	payload, latency, err := pc.udpExchange(proxyAddr, pc.udpResolver, dnsQuery(id, udpProbeName))
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) < 12 || binary.BigEndian.Uint16(payload) != id || payload[2]&0x80 == 0 {
+		return 0, fmt.Errorf("%w: unexpected DNS response", ErrUDPUnreachable)
+	}
+	return latency, nil
+}
+
+// udpExchange sends request to target (host:port) through a SOCKS5 UDP
+// association on the proxy at proxyAddr and returns the first reply.
+func (pc *ProxyChecker) udpExchange(proxyAddr, target string, request []byte) ([]byte, time.Duration, error) {
+	targetHost, targetPortStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid UDP probe target %q: %v", target, err)
+	}
+	targetPort, err := strconv.Atoi(targetPortStr)
+	if err != nil || targetPort <= 0 || targetPort > 65535 {
+		return nil, 0, fmt.Errorf("invalid UDP probe target port: %s", targetPortStr)
+	}
+
+	start := time.Now()
+	deadline := start.Add(time.Second * time.Duration(pc.ipCheckTimeout))
+	ctrl, err := net.DialTimeout("tcp", proxyAddr, time.Until(deadline))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+	// The association lives as long as the control connection.
+	defer ctrl.Close()
+	_ = ctrl.SetDeadline(deadline)
+
+	relay, err := socks5Command(ctrl, socks5CmdUDPAssociate, "0.0.0.0", 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	if relay.IP == nil || relay.IP.IsUnspecified() {
+		relay.IP = ctrl.RemoteAddr().(*net.TCPAddr).IP
+	}
+
+	conn, err := net.DialUDP("udp", nil, relay)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrProxyConnect, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(deadline)
+
+	packet, err := socks5AppendAddr([]byte{0, 0, 0}, targetHost, targetPort)
+	if err != nil {
+		return nil, 0, err
+	}
+	packet = append(packet, request...)
+	if _, err := conn.Write(packet); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrUDPUnreachable, err)
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrUDPUnreachable, err)
+	}
+	latency := time.Since(start)
+
+	payload, err := socks5UDPPayload(buf[:n])
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrUDPUnreachable, err)
+	}
+	return payload, latency, nil
+}
+
+// dnsQuery builds a recursive DNS query for the A record of name.
+func dnsQuery(id uint16, name string) []byte {
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = append(msg, 0x01, 0x00) // RD
+	msg = append(msg, 0, 1, 0, 0, 0, 0, 0, 0)
+	for _, label := range strings.Split(strings.Trim(name, "."), ".") {
+		msg = append(append(msg, byte(len(label))), label...)
+	}
+	msg = append(msg, 0)
+	return append(msg, 0, 1, 0, 1) // A, IN
+}
+
+// socks5UDPPayload strips the SOCKS5 UDP request header from a relayed
+// datagram.
+func socks5UDPPayload(packet []byte) ([]byte, error) {
+	if len(packet) < 4 {
+		return nil, fmt.Errorf("short SOCKS UDP packet")
+	}
+	if packet[2] != 0 {
+		return nil, fmt.Errorf("fragmented SOCKS UDP packet")
+	}
+	offset := 4
+	switch packet[3] {
+	case 1:
+		offset += net.IPv4len
+	case 4:
+		offset += net.IPv6len
+	case 3:
+		if len(packet) < 5 {
+			return nil, fmt.Errorf("short SOCKS UDP packet")
+		}
+		offset += 1 + int(packet[4])
+	default:
+		return nil, fmt.Errorf("unknown SOCKS address type %d", packet[3])
+	}
+	offset += 2
+	if len(packet) < offset {
+		return nil, fmt.Errorf("short SOCKS UDP packet")
+	}
+	return packet[offset:], nil
+}
@@ -0,0 +1,121 @@
+package checker
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+)
+
+// serveSOCKS5UDP answers a UDP ASSOCIATE on conn with a loopback relay that
+// forwards IPv4-addressed datagrams and relays one reply for each.
+func serveSOCKS5UDP(conn net.Conn) {
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer relay.Close()
+	port := relay.LocalAddr().(*net.UDPAddr).Port
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, byte(port >> 8), byte(port)}); err != nil {
+		return
+	}
+	go func() {
+		io.Copy(io.Discard, conn)
+		relay.Close()
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, client, err := relay.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if n < 10 || buf[3] != 1 {
+			continue
+		}
+		header := append([]byte(nil), buf[:10]...)
+		target := &net.UDPAddr{IP: net.IP(buf[4:8]), Port: int(buf[8])<<8 | int(buf[9])}
+		upstream, err := net.DialUDP("udp", nil, target)
+		if err != nil {
+			continue
+		}
+		upstream.Write(buf[10:n])
+		reply := make([]byte, 1500)
+		m, err := upstream.Read(reply)
+		upstream.Close()
+		if err != nil {
+			continue
+		}
+		relay.WriteToUDP(append(header, reply[:m]...), client)
+	}
+}
+
+// startFakeDNS answers every query with the same message flagged as a
+// response.
+func startFakeDNS(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen udp failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if n > 2 {
+				buf[2] |= 0x80
+			}
+			conn.WriteToUDP(buf[:n], addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestCheckByUDP(t *testing.T) {
+	pc, _, _ := newSOCKSCheckFixture(t)
+	proxyAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(pc.startPort))
+
+	pc.SetUDPCheck(true, startFakeDNS(t))
+	latency, err := pc.checkByUDP(proxyAddr)
+	if err != nil {
+		t.Fatalf("expected UDP probe to pass, got %v", err)
+	}
+	if latency <= 0 {
+		t.Fatalf("expected positive latency, got %s", latency)
+	}
+
+	// Nothing answers on a closed port, so the probe times out.
+	silent, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen udp failed: %v", err)
+	}
+	silentAddr := silent.LocalAddr().String()
+	silent.Close()
+	pc.ipCheckTimeout = 1
+	pc.SetUDPCheck(true, silentAddr)
+	if _, err := pc.checkByUDP(proxyAddr); !errors.Is(err, ErrUDPUnreachable) {
+		t.Fatalf("expected ErrUDPUnreachable from a silent resolver, got %v", err)
+	}
+}
+
+func TestWantsUDPCheck(t *testing.T) {
+	pc, p, _ := newSOCKSCheckFixture(t)
+	pc.SetUDPCheck(true, "127.0.0.1:1")
+
+	if pc.wantsUDPCheck(p.Protocol) {
+		t.Fatalf("%s is TCP-based and must keep the TCP check only", p.Protocol)
+	}
+	if !pc.wantsUDPCheck("shadowsocks") {
+		t.Fatal("shadowsocks must get the UDP probe")
+	}
+	pc.SetUDPCheck(false, "127.0.0.1:1")
+	if pc.wantsUDPCheck("shadowsocks") {
+		t.Fatal("the UDP probe must stay off unless enabled")
+	}
+}
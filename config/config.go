@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/alecthomas/kong"
 )
@@ -11,40 +13,89 @@ var Version string
 
 func Parse(version string) {
 	Version = version
-	ctx := kong.Parse(&CLIConfig,
+	parser := kong.Must(&CLIConfig, parserOptions(version)...)
+	_, err := parser.Parse(os.Args[1:])
+	parser.FatalIfErrorf(err)
+}
+
+func parserOptions(version string) []kong.Option {
+	return []kong.Option{
 		kong.Name("xray-checker"),
 		kong.Description("Xray Checker: A Prometheus exporter for monitoring Xray proxies"),
 		kong.Vars{
 			"version": version,
 		},
-	)
-	_ = ctx
+		kong.Resolvers(&profileResolver{}),
+	}
 }
 
 type CLI struct {
 	Subscription struct {
-		URLs           []string `name:"subscription-url" help:"URL(s) of the subscription (can be specified multiple times)" required:"true" env:"SUBSCRIPTION_URL"`
-		Update         bool     `name:"subscription-update" help:"Whether to recheck the subscription" default:"true" env:"SUBSCRIPTION_UPDATE"`
-		UpdateInterval int      `name:"subscription-update-interval" help:"Interval for subscription updates in seconds" default:"300" env:"SUBSCRIPTION_UPDATE_INTERVAL"`
+		URLs              []string `name:"subscription-url" help:"URL(s) of the subscription (can be specified multiple times)" required:"true" env:"SUBSCRIPTION_URL"`
+		Update            bool     `name:"subscription-update" help:"Whether to recheck the subscription" default:"true" env:"SUBSCRIPTION_UPDATE"`
+		UpdateInterval    int      `name:"subscription-update-interval" help:"Interval for subscription updates in seconds" default:"300" env:"SUBSCRIPTION_UPDATE_INTERVAL"`
+		StatePath         string   `name:"subscription-state-path" help:"Path to remote sources state file; relative paths are resolved against the download directory (default: .remote_sources.json next to it)" default:"" env:"SUBSCRIPTION_STATE_PATH"`
+		Manifest          string   `name:"subscription-manifest" help:"Path to a manifest of remote sources (JSON array of {url,name,headers,interval,enabled} or one URL per line); applied at startup and replaces the remote source list" default:"" env:"SUBSCRIPTION_MANIFEST"`
+		Watch             bool     `name:"subscription-watch" help:"Reload immediately when local file:// or folder:// sources change" default:"false" env:"SUBSCRIPTION_WATCH"`
+		WatchDebounce     int      `name:"subscription-watch-debounce" help:"Debounce for local source change events in milliseconds" default:"1000" env:"SUBSCRIPTION_WATCH_DEBOUNCE"`
+		Insecure          bool     `name:"subscription-insecure" help:"Skip TLS certificate verification when fetching subscriptions and remote sources (INSECURE: for self-signed internal hosts only; proxy checks and geo files still verify)" default:"false" env:"SUBSCRIPTION_INSECURE"`
+		StartupRetries    int      `name:"subscription-startup-retries" help:"Times to retry a failed subscription fetch at startup before starting without proxies" default:"3" env:"SUBSCRIPTION_STARTUP_RETRIES"`
+		StartupRetryDelay int      `name:"subscription-startup-retry-delay" help:"Delay between startup subscription fetch retries in seconds" default:"5" env:"SUBSCRIPTION_STARTUP_RETRY_DELAY"`
 	} `embed:"" prefix:""`
 
 	Proxy struct {
-		CheckInterval    int    `name:"proxy-check-interval" help:"Interval for proxy checks in seconds" default:"300" env:"PROXY_CHECK_INTERVAL"`
-		CheckConcurrency int    `name:"proxy-check-concurrency" help:"Maximum number of concurrent proxy checks" default:"16" env:"PROXY_CHECK_CONCURRENCY"`
-		CheckMethod      string `name:"proxy-check-method" help:"Method for checking proxy, ip, status or download" default:"ip" env:"PROXY_CHECK_METHOD"`
-		IpCheckUrl       string `name:"proxy-ip-check-url" help:"Service URL for IP checking" default:"https://api.ipify.org?format=text" env:"PROXY_IP_CHECK_URL"`
-		StatusCheckUrl   string `name:"proxy-status-check-url" help:"Response status generator, used by check-method=status" default:"http://cp.cloudflare.com/generate_204" env:"PROXY_STATUS_CHECK_URL"`
-		DownloadUrl      string `name:"proxy-download-url" help:"URL for file download checking, used by check-method=download" default:"https://proof.ovh.net/files/1Mb.dat" env:"PROXY_DOWNLOAD_URL"`
-		DownloadTimeout  int    `name:"proxy-download-timeout" help:"Timeout for download checking in seconds" default:"60" env:"PROXY_DOWNLOAD_TIMEOUT"`
-		DownloadMinSize  int64  `name:"proxy-download-min-size" help:"Minimum bytes to download for successful check" default:"51200" env:"PROXY_DOWNLOAD_MIN_SIZE"`
-		Timeout          int    `name:"proxy-timeout" help:"Timeout for IP checking in seconds" default:"30" env:"PROXY_TIMEOUT"`
-		SimulateLatency  bool   `name:"simulate-latency" help:"Whether to add latency to the response" default:"true" env:"SIMULATE_LATENCY"`
-		ResolveDomains   bool   `name:"proxy-resolve-domains" help:"Resolve proxy server domains into IPs and expand configs" env:"PROXY_RESOLVE_DOMAINS"`
+		CheckInterval      int      `name:"proxy-check-interval" help:"Interval for proxy checks in seconds" default:"300" env:"PROXY_CHECK_INTERVAL"`
+		CheckConcurrency   int      `name:"proxy-check-concurrency" help:"Maximum number of concurrent proxy checks" default:"16" env:"PROXY_CHECK_CONCURRENCY"`
+		CheckBatchSize     int      `name:"proxy-check-batch-size" help:"Check proxies in batches of this size, one after another (0 checks all at once)" default:"0" env:"PROXY_CHECK_BATCH_SIZE"`
+		CheckBatchDelay    int      `name:"proxy-check-batch-delay" help:"Delay between check batches in milliseconds" default:"0" env:"PROXY_CHECK_BATCH_DELAY"`
+		CheckOrder         string   `name:"proxy-check-order" help:"Order proxies are checked in each iteration: config (as loaded) or latency (fastest known first, applied before batching)" default:"config" env:"PROXY_CHECK_ORDER"`
+		CheckMethod        string   `name:"proxy-check-method" help:"Method for checking proxy, ip, status, download or dns" default:"ip" env:"PROXY_CHECK_METHOD"`
+		ShadowCheckMethod  string   `name:"proxy-shadow-check-method" help:"Second check method (ip, status, download or dns) run alongside the primary one; disagreements are logged, its results never affect status or metrics" default:"" env:"PROXY_SHADOW_CHECK_METHOD"`
+		SelfTest           bool     `name:"proxy-self-test" help:"Fetch the IP and check URLs directly at startup and report unreachable ones" default:"true" env:"PROXY_SELF_TEST"`
+		SelfTestFatal      bool     `name:"proxy-self-test-fatal" help:"Exit when the startup self-test fails instead of only warning" default:"false" env:"PROXY_SELF_TEST_FATAL"`
+		IpCheckUrl         string   `name:"proxy-ip-check-url" help:"Service URL for IP checking" default:"https://api.ipify.org?format=text" env:"PROXY_IP_CHECK_URL"`
+		StatusCheckUrl     string   `name:"proxy-status-check-url" help:"Response status generator, used by check-method=status" default:"http://cp.cloudflare.com/generate_204" env:"PROXY_STATUS_CHECK_URL"`
+		StatusCodes        []string `name:"proxy-status-codes" help:"Response codes counted as success by check-method=status: codes (204), ranges (200-299) or classes (3xx); accepting a 3xx code stops redirects from being followed" default:"2xx" env:"PROXY_STATUS_CODES"`
+		StatusCheckURLs    []string `name:"proxy-status-check-url-map" help:"Status check URL for matching proxies as <tag>=<url> or sub:<subscription>=<url>, first match wins (can be specified multiple times)" env:"PROXY_STATUS_CHECK_URL_MAP"`
+		CheckHTTPVersion   string   `name:"proxy-check-http-version" help:"HTTP version used for ip, status and download checks: auto (HTTP/1.1, HTTP/2 when a TLS target negotiates it), http1 or http2 (h2 over TLS, h2c for http:// targets)" default:"auto" env:"PROXY_CHECK_HTTP_VERSION"`
+		DownloadUrl        string   `name:"proxy-download-url" help:"URL for file download checking, used by check-method=download" default:"https://proof.ovh.net/files/1Mb.dat" env:"PROXY_DOWNLOAD_URL"`
+		DownloadURLs       []string `name:"proxy-download-url-map" help:"Download URL for matching proxies as <tag>=<url> or sub:<subscription>=<url>, first match wins (can be specified multiple times)" env:"PROXY_DOWNLOAD_URL_MAP"`
+		DNSCheckDomain     string   `name:"proxy-dns-check-domain" help:"Domain (host or host:port, port 80 by default) resolved and connected to through the proxy, used by check-method=dns" default:"www.google.com" env:"PROXY_DNS_CHECK_DOMAIN"`
+		DownloadTimeout    int      `name:"proxy-download-timeout" help:"Timeout for download checking in seconds" default:"60" env:"PROXY_DOWNLOAD_TIMEOUT"`
+		DownloadMinSize    int64    `name:"proxy-download-min-size" help:"Minimum bytes to download for successful check" default:"51200" env:"PROXY_DOWNLOAD_MIN_SIZE"`
+		DownloadSHA256     string   `name:"proxy-download-sha256" help:"Expected SHA-256 (hex) of the file at --proxy-download-url; when set, download checks of that URL read the whole body and fail on mismatch (URLs from --proxy-download-url-map are not hashed)" default:"" env:"PROXY_DOWNLOAD_SHA256"`
+		DownloadMaxSize    int64    `name:"proxy-download-max-size" help:"Maximum bytes read when verifying --proxy-download-sha256" default:"10485760" env:"PROXY_DOWNLOAD_MAX_SIZE"`
+		Timeout            int      `name:"proxy-timeout" help:"Overall timeout in seconds for ip, status and dns checks, covering connect and the full response" default:"30" env:"PROXY_TIMEOUT"`
+		ConnectTimeout     int      `name:"proxy-connect-timeout" help:"Timeout in seconds for connecting through the proxy (dial, SOCKS CONNECT, TLS handshake and first response byte); 0 leaves it bounded only by the overall timeout" default:"0" env:"PROXY_CONNECT_TIMEOUT"`
+		AddressFamily      string   `name:"proxy-address-family" help:"Address family used to reach proxy servers and for direct requests: tcp (IPv4 or IPv6), tcp4 (IPv4 only) or tcp6 (IPv6 only)" default:"tcp" env:"PROXY_ADDRESS_FAMILY"`
+		SimulateLatency    bool     `name:"simulate-latency" help:"Whether to add latency to the response" default:"true" env:"SIMULATE_LATENCY"`
+		IncludeProtocols   []string `name:"proxy-include-protocols" help:"Only load proxies of these protocols (vless, vmess, trojan, shadowsocks); empty loads all" env:"PROXY_INCLUDE_PROTOCOLS"`
+		ExcludeProtocols   []string `name:"proxy-exclude-protocols" help:"Skip proxies of these protocols when loading subscriptions" env:"PROXY_EXCLUDE_PROTOCOLS"`
+		MaxProxies         int      `name:"proxy-max" help:"Load at most this many proxies (0 loads all)" default:"0" env:"PROXY_MAX"`
+		MaxStrategy        string   `name:"proxy-max-strategy" help:"Which proxies --proxy-max keeps: first, random or round-robin (next window on each subscription update; starts over from the first window on restart, so it never rotates with --subscription-update=false)" default:"first" env:"PROXY_MAX_STRATEGY"`
+		StableIDCollisions string   `name:"proxy-stable-id-collisions" help:"What to do with proxies sharing a stable ID: warn (keep all, they share status), suffix (give later ones -2, -3... IDs) or drop (keep the first)" default:"warn" env:"PROXY_STABLE_ID_COLLISIONS"`
+		UDPCheck           bool     `name:"proxy-udp-check" help:"Also probe UDP (a DNS query through the proxy) for UDP-capable protocols such as shadowsocks" default:"false" env:"PROXY_UDP_CHECK"`
+		UDPCheckResolver   string   `name:"proxy-udp-check-resolver" help:"DNS server (host:port) queried by the UDP probe" default:"1.1.1.1:53" env:"PROXY_UDP_CHECK_RESOLVER"`
+		UDPCheckSTUN       string   `name:"proxy-udp-check-stun" help:"STUN server (host:port) queried by the UDP probe instead of the DNS resolver; it also reports each proxy's UDP exit IP as udpExitIp in the API" default:"" env:"PROXY_UDP_CHECK_STUN"`
+		DedupChecks        bool     `name:"proxy-dedup-checks" help:"Check each node once when several subscriptions list it (same stable ID) and share the result with its duplicates" default:"false" env:"PROXY_DEDUP_CHECKS"`
+		KeepAlive          bool     `name:"proxy-keep-alive" help:"Reuse a keep-alive connection per proxy across checks instead of dialing fresh each time" default:"false" env:"PROXY_KEEP_ALIVE"`
+		LatencyEMAAlpha    float64  `name:"proxy-latency-ema-alpha" help:"Smoothing factor (0-1] of the latency moving average exposed as emaLatencyMs in the public API; 0 disables" default:"0" env:"PROXY_LATENCY_EMA_ALPHA"`
+		LatencyStatePath   string   `name:"proxy-latency-state" help:"File persisting latency moving averages (the checker's and the top BL selector's) across restarts; empty keeps them in memory only" default:"" env:"PROXY_LATENCY_STATE"`
+		MaxLatency         int      `name:"proxy-max-latency" help:"Slowest successful check in milliseconds that still counts as online; slower proxies are reported as degraded (0 disables)" default:"0" env:"PROXY_MAX_LATENCY"`
+		DebounceUp         int      `name:"proxy-debounce-up" help:"Consecutive passing checks required before a proxy is reported online (reported as pending-up meanwhile)" default:"1" env:"PROXY_DEBOUNCE_UP"`
+		DebounceDown       int      `name:"proxy-debounce-down" help:"Consecutive failing checks required before an online proxy is reported offline (reported as pending-down meanwhile)" default:"1" env:"PROXY_DEBOUNCE_DOWN"`
+		OfflineGrace       int      `name:"proxy-offline-grace" help:"Seconds a newly added proxy that fails checks is counted as pending instead of offline (0 disables)" default:"0" env:"PROXY_OFFLINE_GRACE"`
+		SentinelURL        string   `name:"proxy-sentinel-url" help:"URL fetched directly before each check to detect local connectivity loss; only a 2xx answer counts as connected (empty disables)" default:"http://cp.cloudflare.com/generate_204" env:"PROXY_SENTINEL_URL"`
+		ResolveDomains     bool     `name:"proxy-resolve-domains" help:"Resolve proxy server domains into IPs" env:"PROXY_RESOLVE_DOMAINS"`
+		ResolveMode        string   `name:"proxy-resolve-mode" help:"How to use a domain with several IPs: first, expand (one proxy per IP) or round-robin (next IP on each subscription update, not each check; starts over on restart)" default:"first" env:"PROXY_RESOLVE_MODE"`
 	} `embed:"" prefix:""`
 
 	Xray struct {
-		StartPort int    `name:"xray-start-port" help:"Start port for proxy configuration" default:"10000" env:"XRAY_START_PORT"`
-		LogLevel  string `name:"xray-log-level" help:"Xray log level (debug|info|warning|error|none)" default:"none" env:"XRAY_LOG_LEVEL"`
+		StartPort    int    `name:"xray-start-port" help:"Start port for proxy configuration" default:"10000" env:"XRAY_START_PORT"`
+		LogLevel     string `name:"xray-log-level" help:"Xray log level (debug|info|warning|error|none)" default:"none" env:"XRAY_LOG_LEVEL"`
+		BinaryPath   string `name:"xray-binary" help:"Path or name (looked up in PATH) of an xray-compatible binary to run instead of the embedded Xray core; empty uses the embedded core" default:"" env:"XRAY_BINARY"`
+		TemplatePath string `name:"xray-template" help:"JSON fragment merged into the generated Xray config (dns, routing rules, extra outbounds); it cannot replace the checker's inbounds or outbounds" default:"" env:"XRAY_TEMPLATE"`
+		PortMap      string `name:"xray-port-map" help:"File persisting the stable ID to local port map, so proxies keep their port across reloads and restarts (empty keeps it in memory only)" default:"xray_ports.json" env:"XRAY_PORT_MAP"`
 	} `embed:"" prefix:""`
 
 	Metrics struct {
@@ -59,23 +110,106 @@ type CLI struct {
 	} `embed:"" prefix:""`
 
 	Web struct {
-		ShowServerDetails bool   `name:"web-show-details" help:"Show server IP addresses and ports in web UI" default:"false" env:"WEB_SHOW_DETAILS"`
-		Public            bool   `name:"web-public" help:"Make dashboard public (requires --metrics-protected)" default:"false" env:"WEB_PUBLIC"`
-		CustomAssetsPath  string `name:"web-custom-assets-path" help:"Path to custom assets directory (logo.svg, favicon.ico, custom.css, index.html)" default:"" env:"WEB_CUSTOM_ASSETS_PATH"`
-		TopBLPath         string `name:"web-top-bl-path" help:"Path for top BL subscription endpoint" default:"/api/v1/public/subscriptions/top-bl" env:"WEB_TOP_BL_PATH"`
-		TopBLToken        string `name:"web-top-bl-token" help:"Token required in query param token for top BL subscription endpoint" default:"" env:"WEB_TOP_BL_TOKEN"`
+		ShowServerDetails        bool     `name:"web-show-details" help:"Show server IP addresses and ports in web UI" default:"false" env:"WEB_SHOW_DETAILS"`
+		Public                   bool     `name:"web-public" help:"Make dashboard public (requires --metrics-protected)" default:"false" env:"WEB_PUBLIC"`
+		CustomAssetsPath         string   `name:"web-custom-assets-path" help:"Path to custom assets directory (logo.svg, favicon.ico, custom.css, index.html)" default:"" env:"WEB_CUSTOM_ASSETS_PATH"`
+		TopBL                    bool     `name:"web-top-bl" help:"Serve the top BL subscription endpoint; when disabled its route is not registered and returns 404" default:"true" env:"WEB_TOP_BL"`
+		TopBLPath                string   `name:"web-top-bl-path" help:"Path for top BL subscription endpoint" default:"/api/v1/public/subscriptions/top-bl" env:"WEB_TOP_BL_PATH"`
+		TopBLToken               string   `name:"web-top-bl-token" help:"Token required in query param token for top BL subscription endpoint" default:"" env:"WEB_TOP_BL_TOKEN"`
+		TopBLLatencyWeight       float64  `name:"web-top-bl-latency-weight" help:"Weight of smoothed latency in top BL ranking score" default:"1.0" env:"WEB_TOP_BL_LATENCY_WEIGHT"`
+		TopBLStabilityWeight     float64  `name:"web-top-bl-stability-weight" help:"Weight of recent loss ratio in top BL ranking score (0 ranks by latency only)" default:"1.0" env:"WEB_TOP_BL_STABILITY_WEIGHT"`
+		TopBLTag                 string   `name:"web-top-bl-tag" help:"Name tag (whole word, case-insensitive) marking BL nodes for the top BL subscription" default:"BL" env:"WEB_TOP_BL_TAG"`
+		TopCIDRTag               string   `name:"web-top-cidr-tag" help:"Name tag (whole word, case-insensitive) marking CIDR nodes for the top BL subscription" default:"CIDR" env:"WEB_TOP_CIDR_TAG"`
+		TopBLPins                []string `name:"web-top-bl-pins" help:"Stable ID or name tag of a node always included in the top BL subscription while online (can be specified multiple times)" env:"WEB_TOP_BL_PINS"`
+		TopBLPinsInQuota         bool     `name:"web-top-bl-pins-in-quota" help:"Count pinned nodes against the top BL subscription limit instead of listing them on top of it" default:"true" env:"WEB_TOP_BL_PINS_IN_QUOTA"`
+		SubscriptionNameOverride string   `name:"web-subscription-name" help:"Name shown as the public dashboard title instead of the one detected from the subscription" default:"" env:"WEB_SUBSCRIPTION_NAME"`
+		EndpointOrder            string   `name:"web-endpoint-order" help:"Dashboard endpoint order: status (online first, then latency, then name) or config" default:"status" env:"WEB_ENDPOINT_ORDER"`
+		AutoRefreshSeconds       int      `name:"web-auto-refresh" help:"Dashboard auto-refresh interval in seconds (0 keeps auto-refresh off by default)" default:"0" env:"WEB_AUTO_REFRESH"`
+		Docs                     bool     `name:"web-docs" help:"Serve Swagger UI at /api/v1/docs" default:"true" env:"WEB_DOCS"`
+		DocsAssetsURL            string   `name:"web-docs-assets-url" help:"Base URL to load Swagger UI assets from (e.g. https://cdn.jsdelivr.net/npm/swagger-ui-dist@5); empty uses bundled /static/ assets" default:"" env:"WEB_DOCS_ASSETS_URL"`
+		AccessLog                string   `name:"web-access-log" help:"Log every HTTP request (method, path, status, bytes, duration): off, info or debug" default:"off" env:"WEB_ACCESS_LOG"`
+		ReadOnly                 bool     `name:"web-read-only" help:"Reject API requests that change state (POST, PUT, DELETE) with 403; reading stays available" default:"false" env:"WEB_READ_ONLY"`
+		CORSOrigins              []string `name:"web-cors-origin" help:"Origin allowed to call the JSON API cross-origin (can be specified multiple times, * allows any; empty disables CORS)" env:"WEB_CORS_ORIGINS"`
 	} `embed:"" prefix:""`
 
-	Version  VersionFlag `name:"version" help:"Print version information and quit"`
-	RunOnce  bool        `name:"run-once" help:"Run one check cycle and exit" default:"false" env:"RUN_ONCE"`
-	LogLevel string      `name:"log-level" help:"Log level (debug|info|warn|error|none)" default:"info" env:"LOG_LEVEL"`
-	LogFile  string      `name:"log-file" help:"Path to log file (in addition to stdout/stderr)" default:"" env:"LOG_FILE"`
+	Outbound struct {
+		Timeout       int    `name:"outbound-timeout" help:"Timeout in seconds for subscription and remote source downloads" default:"30" env:"OUTBOUND_TIMEOUT"`
+		Proxy         string `name:"outbound-proxy" help:"Proxy URL (http, https or socks5) for subscription, remote source and geo file downloads; empty uses HTTP_PROXY/HTTPS_PROXY" default:"" env:"OUTBOUND_PROXY"`
+		UserAgent     string `name:"outbound-user-agent" help:"User-Agent for subscription, remote source and geo file downloads; empty keeps the built-in ones" default:"" env:"OUTBOUND_USER_AGENT"`
+		TLSCert       string `name:"outbound-tls-cert" help:"PEM client certificate presented to subscription, remote source and geo file hosts requiring mutual TLS (requires --outbound-tls-key)" default:"" env:"OUTBOUND_TLS_CERT"`
+		TLSKey        string `name:"outbound-tls-key" help:"PEM private key for --outbound-tls-cert" default:"" env:"OUTBOUND_TLS_KEY"`
+		TLSCA         string `name:"outbound-tls-ca" help:"PEM CA bundle trusted for subscription, remote source and geo file downloads in addition to the system roots" default:"" env:"OUTBOUND_TLS_CA"`
+		MaxConcurrent int    `name:"outbound-max-concurrent" help:"Maximum concurrent subscription, remote source and geo file downloads combined (0 for no limit)" default:"4" env:"OUTBOUND_MAX_CONCURRENT"`
+	} `embed:"" prefix:""`
+
+	Cleanup struct {
+		Enabled     bool    `name:"cleanup-bad-configs" help:"Remove configs that stay bad from local file sources (modifies your files)" default:"false" env:"CLEANUP_BAD_CONFIGS"`
+		Threshold   int     `name:"cleanup-threshold" help:"Seconds a config must stay bad before it is removed" default:"600" env:"CLEANUP_THRESHOLD"`
+		Quarantine  bool    `name:"cleanup-quarantine" help:"Move removed lines to a <file>.removed sidecar instead of deleting them" default:"true" env:"CLEANUP_QUARANTINE"`
+		MaxFraction float64 `name:"cleanup-max-fraction" help:"Skip a cleanup pass that would remove more than this fraction of all proxies (0 disables the guard)" default:"0.5" env:"CLEANUP_MAX_FRACTION"`
+	} `embed:"" prefix:""`
+
+	Profile         string      `name:"profile" help:"Named profile of flag overrides to apply from --profile-file; command-line flags still take precedence" default:"" env:"XRAY_CHECKER_PROFILE"`
+	ProfileFile     string      `name:"profile-file" help:"JSON file mapping profile names to flag values, e.g. {\"staging\": {\"proxy-check-interval\": 60}}" default:"profiles.json" env:"XRAY_CHECKER_PROFILE_FILE"`
+	Version         VersionFlag `name:"version" help:"Print version information and quit"`
+	RunOnce         bool        `name:"run-once" help:"Run one check cycle and exit" default:"false" env:"RUN_ONCE"`
+	LogLevel        string      `name:"log-level" help:"Log level (debug|info|warn|error|none)" default:"info" env:"LOG_LEVEL"`
+	LogLevels       string      `name:"log-levels" help:"Per-component log level overrides, e.g. subscription=debug,checker=warn (components: main, checker, subscription, web, xray, metrics)" default:"" env:"LOG_LEVELS"`
+	LogQuietSuccess bool        `name:"log-quiet-success" help:"Log successful proxy checks at debug level only; failures and the iteration summary stay at info" default:"false" env:"LOG_QUIET_SUCCESS"`
+	LogBufferSize   int         `name:"log-buffer-size" help:"Number of recent log lines kept in memory for the logs API" default:"1000" env:"LOG_BUFFER_SIZE"`
+	LogFile         string      `name:"log-file" help:"Path to log file (in addition to stdout/stderr)" default:"" env:"LOG_FILE"`
 }
 
 func (c *CLI) Validate() error {
 	if c.Web.Public && !c.Metrics.Protected {
 		return fmt.Errorf("--web-public requires --metrics-protected to be enabled")
 	}
+	if c.Cleanup.Enabled && c.Cleanup.Threshold <= 0 {
+		return fmt.Errorf("--cleanup-threshold must be positive")
+	}
+	if c.Proxy.LatencyEMAAlpha < 0 || c.Proxy.LatencyEMAAlpha > 1 {
+		return fmt.Errorf("--proxy-latency-ema-alpha must be between 0 and 1")
+	}
+	if c.Subscription.StartupRetries < 0 || c.Subscription.StartupRetryDelay < 0 {
+		return fmt.Errorf("--subscription-startup-retries and --subscription-startup-retry-delay must not be negative")
+	}
+	if c.Proxy.DownloadSHA256 != "" && c.Proxy.DownloadMaxSize <= 0 {
+		return fmt.Errorf("--proxy-download-max-size must be positive when --proxy-download-sha256 is set")
+	}
+	if c.LogBufferSize < 1 {
+		return fmt.Errorf("--log-buffer-size must be positive")
+	}
+	if c.Outbound.Timeout < 0 || c.Outbound.MaxConcurrent < 0 {
+		return fmt.Errorf("--outbound-timeout and --outbound-max-concurrent must not be negative")
+	}
+	if (c.Outbound.TLSCert == "") != (c.Outbound.TLSKey == "") {
+		return fmt.Errorf("--outbound-tls-cert and --outbound-tls-key must be set together")
+	}
+	if c.Proxy.CheckBatchSize < 0 || c.Proxy.CheckBatchDelay < 0 {
+		return fmt.Errorf("--proxy-check-batch-size and --proxy-check-batch-delay must not be negative")
+	}
+	if c.Proxy.DebounceUp < 1 || c.Proxy.DebounceDown < 1 {
+		return fmt.Errorf("--proxy-debounce-up and --proxy-debounce-down must be at least 1")
+	}
+	if c.Proxy.ConnectTimeout < 0 {
+		return fmt.Errorf("--proxy-connect-timeout must not be negative")
+	}
+	if c.Cleanup.MaxFraction < 0 || c.Cleanup.MaxFraction > 1 {
+		return fmt.Errorf("--cleanup-max-fraction must be between 0 and 1")
+	}
+	if c.Web.EndpointOrder != "status" && c.Web.EndpointOrder != "config" {
+		return fmt.Errorf("--web-endpoint-order must be status or config")
+	}
+	switch strings.ToLower(strings.TrimSpace(c.Proxy.MaxStrategy)) {
+	case "", "first", "random", "round-robin":
+	default:
+		return fmt.Errorf("--proxy-max-strategy must be first, random or round-robin")
+	}
+	switch strings.ToLower(strings.TrimSpace(c.Proxy.ResolveMode)) {
+	case "", "first", "expand", "round-robin":
+	default:
+		return fmt.Errorf("--proxy-resolve-mode must be first, expand or round-robin")
+	}
 	return nil
 }
 
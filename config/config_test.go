@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRejectsUnknownEndpointOrder(t *testing.T) {
+	if _, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--web-endpoint-order=config"); err != nil {
+		t.Fatalf("config order must be accepted: %v", err)
+	}
+	_, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--web-endpoint-order=latency")
+	if err == nil || !strings.Contains(err.Error(), "--web-endpoint-order") {
+		t.Fatalf("expected an unknown order to be rejected, got %v", err)
+	}
+}
+
+func TestValidateRejectsUnknownMaxStrategy(t *testing.T) {
+	if _, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--proxy-max-strategy=Round-Robin"); err != nil {
+		t.Fatalf("round-robin must be accepted: %v", err)
+	}
+	_, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--proxy-max-strategy=latency")
+	if err == nil || !strings.Contains(err.Error(), "--proxy-max-strategy") {
+		t.Fatalf("expected an unknown strategy to be rejected, got %v", err)
+	}
+}
+
+func TestValidateRejectsUnknownResolveMode(t *testing.T) {
+	if _, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--proxy-resolve-mode=expand"); err != nil {
+		t.Fatalf("expand must be accepted: %v", err)
+	}
+	_, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--proxy-resolve-mode=random")
+	if err == nil || !strings.Contains(err.Error(), "--proxy-resolve-mode") {
+		t.Fatalf("expected an unknown mode to be rejected, got %v", err)
+	}
+}
+
+func TestSentinelURLDefaultsToGenerate204(t *testing.T) {
+	cli, err := parseTestArgs(t, "--subscription-url=file:///base.txt")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cli.Proxy.SentinelURL == "" {
+		t.Fatal("the sentinel must be enabled by default so cleanup skips local outages")
+	}
+}
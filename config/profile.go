@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// profileResolver supplies flag values from the profile selected with
+// --profile. Kong only consults resolvers for flags missing from the command
+// line, so explicit flags win over the profile, which wins over environment
+// variables and defaults.
+type profileResolver struct {
+	loaded bool
+	values map[string]any
+	err    error
+}
+
+func (r *profileResolver) Validate(app *kong.Application) error { return nil }
+
+func (r *profileResolver) Resolve(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+	if !r.loaded {
+		r.values, r.err = loadProfile(ctx)
+		r.loaded = true
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.values[flag.Name], nil
+}
+
+// loadProfile reads the selected profile from the profile file. Keys are
+// flag names without the leading dashes.
+func loadProfile(ctx *kong.Context) (map[string]any, error) {
+	flags := make(map[string]*kong.Flag)
+	for _, flag := range ctx.Flags() {
+		flags[flag.Name] = flag
+	}
+	name, _ := ctx.FlagValue(flags["profile"]).(string)
+	if name == "" {
+		return nil, nil
+	}
+	path, _ := ctx.FlagValue(flags["profile-file"]).(string)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile file: %v", err)
+	}
+	var profiles map[string]map[string]any
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid profile file %s: %v", path, err)
+	}
+	values, ok := profiles[name]
+	if !ok {
+		known := make([]string, 0, len(profiles))
+		for profile := range profiles {
+			known = append(known, profile)
+		}
+		sort.Strings(known)
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(known, ", "))
+	}
+	for key := range values {
+		if _, ok := flags[key]; !ok || key == "profile" || key == "profile-file" || key == "version" {
+			return nil, fmt.Errorf("profile %q sets unknown flag %q", name, key)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func parseTestArgs(t *testing.T, args ...string) (CLI, error) {
+	t.Helper()
+	var cli CLI
+	parser, err := kong.New(&cli, parserOptions("test")...)
+	if err != nil {
+		t.Fatalf("kong.New: %v", err)
+	}
+	_, err = parser.Parse(args)
+	return cli, err
+}
+
+func writeProfiles(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	profiles := `{
+		"staging": {"proxy-check-interval": 60, "subscription-url": ["file:///staging.txt"], "proxy-check-method": "status"},
+		"broken": {"proxy-check-intervall": 60}
+	}`
+	if err := os.WriteFile(path, []byte(profiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProfileOverridesBaseFlags(t *testing.T) {
+	path := writeProfiles(t)
+	t.Setenv("PROXY_CHECK_INTERVAL", "120")
+
+	cli, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--profile-file="+path)
+	if err != nil {
+		t.Fatalf("parse without profile: %v", err)
+	}
+	if cli.Proxy.CheckInterval != 120 {
+		t.Fatalf("without a profile the environment applies, got %d", cli.Proxy.CheckInterval)
+	}
+
+	cli, err = parseTestArgs(t, "--subscription-url=file:///base.txt", "--profile=staging", "--profile-file="+path)
+	if err != nil {
+		t.Fatalf("parse with profile: %v", err)
+	}
+	if cli.Proxy.CheckInterval != 60 || cli.Proxy.CheckMethod != "status" {
+		t.Fatalf("profile must override the base interval and method, got %d %q", cli.Proxy.CheckInterval, cli.Proxy.CheckMethod)
+	}
+	if len(cli.Subscription.URLs) != 1 || cli.Subscription.URLs[0] != "file:///base.txt" {
+		t.Fatalf("command-line flags must win over the profile, got %v", cli.Subscription.URLs)
+	}
+
+	// The profile can also supply required flags and be picked from the
+	// environment.
+	t.Setenv("XRAY_CHECKER_PROFILE", "staging")
+	cli, err = parseTestArgs(t, "--profile-file="+path)
+	if err != nil {
+		t.Fatalf("parse with profile from env: %v", err)
+	}
+	if len(cli.Subscription.URLs) != 1 || cli.Subscription.URLs[0] != "file:///staging.txt" {
+		t.Fatalf("expected subscription URL from the profile, got %v", cli.Subscription.URLs)
+	}
+}
+
+func TestProfileRejectsUnknownNames(t *testing.T) {
+	path := writeProfiles(t)
+
+	_, err := parseTestArgs(t, "--subscription-url=file:///base.txt", "--profile=prod", "--profile-file="+path)
+	if err == nil || !strings.Contains(err.Error(), `unknown profile "prod"`) {
+		t.Fatalf("expected unknown profile error, got %v", err)
+	}
+
+	_, err = parseTestArgs(t, "--subscription-url=file:///base.txt", "--profile=broken", "--profile-file="+path)
+	if err == nil || !strings.Contains(err.Error(), "proxy-check-intervall") {
+		t.Fatalf("expected unknown flag error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultBufferSize is how many recent lines are kept in memory unless
+// SetBufferSize changes it.
+const DefaultBufferSize = 1000
+
+// Line is one log line kept in the in-memory buffer.
+type Line struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// lineBuffer is a bounded ring of recent lines plus the live subscribers
+// that receive every new line.
+type lineBuffer struct {
+	mu          sync.Mutex
+	lines       []Line
+	next        int
+	full        bool
+	subscribers map[chan Line]struct{}
+}
+
+var buffer = newLineBuffer(DefaultBufferSize)
+
+func newLineBuffer(size int) *lineBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &lineBuffer{lines: make([]Line, size), subscribers: make(map[chan Line]struct{})}
+}
+
+// SetBufferSize resizes the recent line buffer, keeping the newest lines.
+func SetBufferSize(size int) {
+	resized := newLineBuffer(size)
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	for _, line := range buffer.recentLocked(0) {
+		resized.appendLocked(line)
+	}
+	buffer.lines, buffer.next, buffer.full = resized.lines, resized.next, resized.full
+}
+
+// Recent returns up to limit of the newest buffered lines, oldest first.
+// A limit of zero or less returns the whole buffer.
+func Recent(limit int) []Line {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return buffer.recentLocked(limit)
+}
+
+// Subscribe returns a channel receiving every line logged from now on and a
+// function that ends the subscription. Lines are dropped for a subscriber
+// whose channel is full rather than blocking logging.
+func Subscribe(size int) (<-chan Line, func()) {
+	ch := make(chan Line, size)
+	buffer.mu.Lock()
+	buffer.subscribers[ch] = struct{}{}
+	buffer.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			buffer.mu.Lock()
+			delete(buffer.subscribers, ch)
+			buffer.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func record(l Level, message string) {
+	line := Line{Time: time.Now(), Level: l.String(), Message: message}
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	buffer.appendLocked(line)
+	for ch := range buffer.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+func (b *lineBuffer) appendLocked(line Line) {
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+func (b *lineBuffer) recentLocked(limit int) []Line {
+	count := b.next
+	if b.full {
+		count = len(b.lines)
+	}
+	if limit <= 0 || limit > count {
+		limit = count
+	}
+	out := make([]Line, 0, limit)
+	start := b.next - limit
+	if start < 0 {
+		start += len(b.lines)
+	}
+	for i := 0; i < limit; i++ {
+		out = append(out, b.lines[(start+i)%len(b.lines)])
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// componentLevels holds per-component level overrides. A component is the
+// package a log call comes from (checker, subscription, web, xray, main...).
+// It is nil when no overrides are set, which keeps logging on the fast path.
+var componentLevels atomic.Pointer[map[string]Level]
+
+// SetComponentLevels replaces the per-component level overrides. Components
+// without an override use the level set by SetLevel.
+func SetComponentLevels(levels map[string]Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(levels) == 0 {
+		componentLevels.Store(nil)
+	} else {
+		copied := make(map[string]Level, len(levels))
+		for component, l := range levels {
+			copied[strings.ToLower(component)] = l
+		}
+		componentLevels.Store(&copied)
+	}
+	applyOutputsLocked()
+}
+
+// ParseComponentLevels parses a spec like "subscription=debug,checker=info".
+func ParseComponentLevels(spec string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		component, value, ok := strings.Cut(part, "=")
+		component = strings.ToLower(strings.TrimSpace(component))
+		if !ok || component == "" {
+			return nil, fmt.Errorf("invalid component level %q, expected <component>=<level>", part)
+		}
+		l, err := parseLevelStrict(value)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", component, err)
+		}
+		levels[component] = l
+	}
+	return levels, nil
+}
+
+func parseLevelStrict(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "none", "off", "silent", "error", "err", "warn", "warning", "info", "debug":
+		return ParseLevel(strings.TrimSpace(s)), nil
+	default:
+		return LevelNone, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// enabled reports whether a message at l from the caller of the exported
+// logging function should be written.
+func enabled(l Level) bool {
+	overrides := componentLevels.Load()
+	if overrides == nil {
+		return level >= l
+	}
+	if componentLevel, ok := (*overrides)[callerComponent(3)]; ok {
+		return componentLevel >= l
+	}
+	return level >= l
+}
+
+// maxLevel is the most verbose level any component logs at.
+func maxLevel() Level {
+	highest := level
+	if overrides := componentLevels.Load(); overrides != nil {
+		for _, l := range *overrides {
+			highest = max(highest, l)
+		}
+	}
+	return highest
+}
+
+// callerComponent returns the package name of the function skip frames up.
+func callerComponent(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return ""
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	return componentOf(fn.Name())
+}
+
+// componentOf maps a qualified function name such as
+// "xray-checker/subscription.(*RemoteManager).refresh" to "subscription".
+func componentOf(funcName string) string {
+	name := funcName[strings.LastIndex(funcName, "/")+1:]
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
@@ -99,7 +99,7 @@ func SetFile(path string) error {
 }
 
 func applyOutputsLocked() {
-	if level == LevelNone {
+	if maxLevel() == LevelNone {
 		stdLogger.SetOutput(io.Discard)
 		errorLogger.SetOutput(io.Discard)
 		return
@@ -116,26 +116,26 @@ func applyOutputsLocked() {
 }
 
 func Debug(format string, v ...interface{}) {
-	if level >= LevelDebug {
-		stdLogger.Printf("[DEBUG] "+format, v...)
+	if enabled(LevelDebug) {
+		write(stdLogger, LevelDebug, "[DEBUG] ", format, v)
 	}
 }
 
 func Info(format string, v ...interface{}) {
-	if level >= LevelInfo {
-		stdLogger.Printf(format, v...)
+	if enabled(LevelInfo) {
+		write(stdLogger, LevelInfo, "", format, v)
 	}
 }
 
 func Warn(format string, v ...interface{}) {
-	if level >= LevelWarn {
-		stdLogger.Printf("[WARN] "+format, v...)
+	if enabled(LevelWarn) {
+		write(stdLogger, LevelWarn, "[WARN] ", format, v)
 	}
 }
 
 func Error(format string, v ...interface{}) {
-	if level >= LevelError {
-		errorLogger.Printf("[ERROR] "+format, v...)
+	if enabled(LevelError) {
+		write(errorLogger, LevelError, "[ERROR] ", format, v)
 	}
 }
 
@@ -144,15 +144,22 @@ func Fatal(format string, v ...interface{}) {
 }
 
 func Startup(format string, v ...interface{}) {
-	if level >= LevelInfo {
-		stdLogger.Printf(format, v...)
+	if enabled(LevelInfo) {
+		write(stdLogger, LevelInfo, "", format, v)
 		return
 	}
 	fmt.Printf(format+"\n", v...)
 }
 
 func Result(format string, v ...interface{}) {
-	if level >= LevelInfo {
-		stdLogger.Printf(format, v...)
+	if enabled(LevelInfo) {
+		write(stdLogger, LevelInfo, "", format, v)
 	}
 }
+
+// write prints the message and keeps it in the recent line buffer.
+func write(out *log.Logger, l Level, prefix, format string, v []interface{}) {
+	message := fmt.Sprintf(format, v...)
+	out.Print(prefix + message)
+	record(l, message)
+}
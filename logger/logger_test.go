@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestComponentLevelsFilterPerPackage(t *testing.T) {
+	t.Cleanup(func() {
+		SetComponentLevels(nil)
+		SetLevel(LevelInfo)
+	})
+	var buf bytes.Buffer
+	capture := func() {
+		buf.Reset()
+		stdLogger.SetOutput(&buf)
+	}
+
+	SetLevel(LevelNone)
+	SetComponentLevels(map[string]Level{"logger": LevelDebug})
+	capture()
+	Debug("from logger")
+	if !strings.Contains(buf.String(), "from logger") {
+		t.Fatalf("expected debug output for overridden component, got %q", buf.String())
+	}
+
+	SetLevel(LevelInfo)
+	SetComponentLevels(map[string]Level{"checker": LevelDebug, "logger": LevelWarn})
+	capture()
+	Debug("hidden debug")
+	Info("hidden info")
+	Warn("shown warn")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "shown warn") {
+		t.Fatalf("expected only warn output, got %q", out)
+	}
+}
+
+func TestParseComponentLevels(t *testing.T) {
+	levels, err := ParseComponentLevels(" subscription=debug, Checker=WARN ,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(levels) != 2 || levels["subscription"] != LevelDebug || levels["checker"] != LevelWarn {
+		t.Fatalf("unexpected levels: %v", levels)
+	}
+	for _, spec := range []string{"checker", "=debug", "checker=loud"} {
+		if _, err := ParseComponentLevels(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestComponentOf(t *testing.T) {
+	cases := map[string]string{
+		"xray-checker/subscription.(*RemoteManager).refresh":    "subscription",
+		"xray-checker/checker.(*ProxyChecker).CheckProxy.func1": "checker",
+		"main.main.func3": "main",
+	}
+	for funcName, want := range cases {
+		if got := componentOf(funcName); got != want {
+			t.Fatalf("componentOf(%q) = %q, want %q", funcName, got, want)
+		}
+	}
+}
+
+func TestRecentAndSubscribe(t *testing.T) {
+	t.Cleanup(func() { SetBufferSize(DefaultBufferSize) })
+	SetBufferSize(3)
+
+	lines, unsubscribe := Subscribe(8)
+	defer unsubscribe()
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		Info("%s", msg)
+	}
+
+	recent := Recent(0)
+	if len(recent) != 3 || recent[0].Message != "two" || recent[2].Message != "four" {
+		t.Fatalf("expected the 3 newest lines oldest first, got %+v", recent)
+	}
+	if got := Recent(2); len(got) != 2 || got[0].Message != "three" {
+		t.Fatalf("expected the 2 newest lines, got %+v", got)
+	}
+	for _, want := range []string{"one", "two", "three", "four"} {
+		if line := <-lines; line.Message != want || line.Level != "info" {
+			t.Fatalf("expected subscriber to get %q, got %+v", want, line)
+		}
+	}
+}
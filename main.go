@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 	"xray-checker/checker"
@@ -10,17 +11,18 @@ import (
 	"xray-checker/logger"
 	"xray-checker/metrics"
 	"xray-checker/models"
+	"xray-checker/outbound"
 	"xray-checker/subscription"
 	"xray-checker/web"
 	"xray-checker/xray"
 
-	"github.com/go-co-op/gocron"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var (
 	version   = "unknown"
+	commit    = ""
+	buildDate = ""
 	startTime = time.Now()
 )
 
@@ -33,8 +35,17 @@ func main() {
 
 	logLevel := logger.ParseLevel(config.CLIConfig.LogLevel)
 	logger.SetLevel(logLevel)
+	componentLevels, err := logger.ParseComponentLevels(config.CLIConfig.LogLevels)
+	if err != nil {
+		logger.Fatal("Invalid --log-levels: %v", err)
+	}
+	logger.SetComponentLevels(componentLevels)
+	logger.SetBufferSize(config.CLIConfig.LogBufferSize)
 
 	logger.Startup("Xray Checker %s", version)
+	if config.CLIConfig.Profile != "" {
+		logger.Startup("Config profile: %s", config.CLIConfig.Profile)
+	}
 	if logLevel == logger.LevelNone {
 		logger.Startup("Log level: none (silent mode)")
 	}
@@ -43,11 +54,43 @@ func main() {
 		logger.Fatal("Failed to initialize custom assets: %v", err)
 	}
 
+	if err := outbound.Configure(outbound.Options{
+		Timeout:               time.Duration(config.CLIConfig.Outbound.Timeout) * time.Second,
+		ProxyURL:              config.CLIConfig.Outbound.Proxy,
+		UserAgent:             config.CLIConfig.Outbound.UserAgent,
+		MaxConcurrent:         config.CLIConfig.Outbound.MaxConcurrent,
+		ClientCertFile:        config.CLIConfig.Outbound.TLSCert,
+		ClientKeyFile:         config.CLIConfig.Outbound.TLSKey,
+		CAFile:                config.CLIConfig.Outbound.TLSCA,
+		InsecureSubscriptions: config.CLIConfig.Subscription.Insecure,
+	}); err != nil {
+		logger.Fatal("Invalid outbound settings: %v", err)
+	}
+	if config.CLIConfig.Subscription.Insecure {
+		logger.Warn("TLS certificate verification is disabled for subscription fetches (--subscription-insecure); use it only for trusted self-signed hosts")
+	}
+
 	geoManager := xray.NewGeoFileManager("")
 	if err := geoManager.EnsureGeoFiles(); err != nil {
 		logger.Fatal("Failed to ensure geo files: %v", err)
 	}
 
+	portMap, err := xray.NewPortMap(config.CLIConfig.Xray.StartPort, config.CLIConfig.Xray.PortMap)
+	if err != nil {
+		logger.Fatal("Failed to load port map: %v", err)
+	}
+	xray.UsePortMap(portMap)
+
+	addressFamily, err := checker.ParseAddressFamily(config.CLIConfig.Proxy.AddressFamily)
+	if err != nil {
+		logger.Fatal("Invalid --proxy-address-family: %v", err)
+	}
+	xray.UseAddressFamily(string(addressFamily))
+
+	if err := xray.UseConfigTemplate(config.CLIConfig.Xray.TemplatePath); err != nil {
+		logger.Fatal("Invalid --xray-template: %v", err)
+	}
+
 	configFile := "xray_config.json"
 	proxyConfigs, err := subscription.InitializeConfiguration(configFile, version)
 	if err != nil {
@@ -55,6 +98,7 @@ func main() {
 	}
 
 	logger.Info("Loaded %d proxy configurations", len(*proxyConfigs))
+	web.SetProxiesLoaded(len(*proxyConfigs) > 0)
 
 	if config.CLIConfig.Web.Public {
 		if name := subscription.GetSubscriptionName(); name != "" {
@@ -74,7 +118,14 @@ func main() {
 		}
 	}
 
-	xrayRunner := xray.NewRunner(configFile)
+	if binary := config.CLIConfig.Xray.BinaryPath; binary != "" {
+		binaryVersion, err := xray.ValidateBinary(binary)
+		if err != nil {
+			logger.Fatal("Invalid --xray-binary: %v", err)
+		}
+		logger.Info("Using Xray binary %s: %s", binary, binaryVersion)
+	}
+	xrayRunner := xray.NewRunner(configFile, config.CLIConfig.Xray.BinaryPath)
 	xrayRunning := false
 	if len(*proxyConfigs) > 0 {
 		if err := xrayRunner.Start(); err != nil {
@@ -94,11 +145,17 @@ func main() {
 		}
 	}()
 
-	metrics.InitMetrics(config.CLIConfig.Metrics.Instance)
+	metrics.InitMetrics(config.CLIConfig.Metrics.Instance, version)
 
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(metrics.GetProxyStatusMetric())
 	registry.MustRegister(metrics.GetProxyLatencyMetric())
+	registry.MustRegister(metrics.GetSubscriptionFetchMetric())
+	registry.MustRegister(metrics.GetSubscriptionFetchErrorsMetric())
+	registry.MustRegister(metrics.GetSubscriptionFetchAgeMetric())
+	registry.MustRegister(metrics.GetRemoteSourceProxiesMetric())
+	registry.MustRegister(metrics.GetProxyInfoMetric())
+	registry.MustRegister(metrics.GetBuildInfoMetric())
 
 	proxyChecker := checker.NewProxyChecker(
 		*proxyConfigs,
@@ -112,25 +169,108 @@ func main() {
 		config.CLIConfig.Proxy.CheckMethod,
 		config.CLIConfig.Proxy.CheckConcurrency,
 	)
+	proxyChecker.SetSentinelURL(config.CLIConfig.Proxy.SentinelURL)
+	proxyChecker.SetKeepAlive(config.CLIConfig.Proxy.KeepAlive)
+	proxyChecker.SetDedupChecks(config.CLIConfig.Proxy.DedupChecks)
+	proxyChecker.SetQuietSuccess(config.CLIConfig.LogQuietSuccess)
+	if method := config.CLIConfig.Proxy.ShadowCheckMethod; method != "" {
+		if !checker.ValidCheckMethod(method) {
+			logger.Fatal("Invalid --proxy-shadow-check-method: %s", method)
+		}
+		proxyChecker.SetShadowMethod(method)
+	}
+	proxyChecker.SetAddressFamily(addressFamily)
+	proxyChecker.SetConnectTimeout(time.Duration(config.CLIConfig.Proxy.ConnectTimeout) * time.Second)
+	checkOrder, err := checker.ParseCheckOrder(config.CLIConfig.Proxy.CheckOrder)
+	if err != nil {
+		logger.Fatal("Invalid --proxy-check-order: %v", err)
+	}
+	proxyChecker.SetCheckOrder(checkOrder)
+	proxyChecker.SetBatching(config.CLIConfig.Proxy.CheckBatchSize, time.Duration(config.CLIConfig.Proxy.CheckBatchDelay)*time.Millisecond)
+	proxyChecker.SetDNSCheckDomain(config.CLIConfig.Proxy.DNSCheckDomain)
+	proxyChecker.SetUDPCheck(config.CLIConfig.Proxy.UDPCheck, config.CLIConfig.Proxy.UDPCheckResolver)
+	proxyChecker.SetUDPSTUNServer(config.CLIConfig.Proxy.UDPCheckSTUN)
+	proxyChecker.SetLatencySmoothing(config.CLIConfig.Proxy.LatencyEMAAlpha)
+	if path := config.CLIConfig.Proxy.LatencyStatePath; path != "" {
+		latencyStore, err := checker.NewLatencyStore(path)
+		if err != nil {
+			logger.Warn("Failed to load latency store, starting with empty averages: %v", err)
+		}
+		proxyChecker.SetLatencyStore(latencyStore)
+	}
+	proxyChecker.SetMaxLatency(time.Duration(config.CLIConfig.Proxy.MaxLatency) * time.Millisecond)
+	proxyChecker.SetOfflineGrace(time.Duration(config.CLIConfig.Proxy.OfflineGrace) * time.Second)
+	proxyChecker.SetStatusDebounce(config.CLIConfig.Proxy.DebounceUp, config.CLIConfig.Proxy.DebounceDown)
+
+	statusCodes, err := checker.ParseStatusCodes(config.CLIConfig.Proxy.StatusCodes)
+	if err != nil {
+		logger.Fatal("Invalid --proxy-status-codes: %v", err)
+	}
+	proxyChecker.SetStatusCodes(statusCodes)
+
+	httpVersion, err := checker.ParseHTTPVersion(config.CLIConfig.Proxy.CheckHTTPVersion)
+	if err != nil {
+		logger.Fatal("Invalid --proxy-check-http-version: %v", err)
+	}
+	proxyChecker.SetHTTPVersion(httpVersion)
+
+	downloadSHA256, err := checker.ParseSHA256(config.CLIConfig.Proxy.DownloadSHA256)
+	if err != nil {
+		logger.Fatal("Invalid --proxy-download-sha256: %v", err)
+	}
+	proxyChecker.SetDownloadSHA256(downloadSHA256, config.CLIConfig.Proxy.DownloadMaxSize)
+
+	statusURLRules, err := checker.ParseCheckURLRules(config.CLIConfig.Proxy.StatusCheckURLs)
+	if err != nil {
+		logger.Fatal("Invalid --proxy-status-check-url-map: %v", err)
+	}
+	proxyChecker.SetStatusCheckURLRules(statusURLRules)
+	downloadURLRules, err := checker.ParseCheckURLRules(config.CLIConfig.Proxy.DownloadURLs)
+	if err != nil {
+		logger.Fatal("Invalid --proxy-download-url-map: %v", err)
+	}
+	proxyChecker.SetDownloadURLRules(downloadURLRules)
 
 	remoteManager, remoteErr := subscription.GetRemoteManager()
 	if remoteErr != nil {
 		logger.Warn("Remote subscription manager unavailable: %v", remoteErr)
 	}
 	if remoteManager != nil {
+		remoteManager.RecordProxyCounts(*proxyConfigs)
 		stopRemote := make(chan struct{})
 		remoteManager.StartUpdateLoop(stopRemote)
 	}
 
+	if config.CLIConfig.Proxy.SelfTest {
+		runSelfTest(proxyChecker, config.CLIConfig.Proxy.SelfTestFatal)
+	}
+
 	var updateInProgress atomic.Bool
 
 	runCheckIteration := func() {
+		if proxyChecker.IsPaused() {
+			logger.Info("Skipping proxy check iteration: checks are paused")
+			return
+		}
 		if updateInProgress.Load() {
 			logger.Info("Skipping proxy check iteration: configuration update in progress")
 			return
 		}
+		if xrayRunning && !xrayRunner.Running() {
+			logger.Error("Xray is not running, restarting it")
+			if err := xrayRunner.Start(); err != nil {
+				logger.Error("Error restarting Xray: %v", err)
+			}
+		}
 		logger.Info("Starting proxy check iteration")
+		before := proxyChecker.StatusSnapshot()
+		started := time.Now()
 		proxyChecker.CheckAllProxies()
+		logger.Info("%s", checker.SummarizeIteration(before, proxyChecker.StatusSnapshot(), time.Since(started)))
+
+		if config.CLIConfig.Cleanup.Enabled && !proxyChecker.LocalConnectivityDown() {
+			cleanupBadFileConfigs(proxyChecker)
+		}
 
 		if config.CLIConfig.Metrics.PushURL != "" {
 			pushConfig, err := metrics.ParseURL(config.CLIConfig.Metrics.PushURL)
@@ -153,53 +293,80 @@ func main() {
 		return
 	}
 
-	checkScheduler := gocron.NewScheduler(time.UTC)
-	checkScheduler.Every(config.CLIConfig.Proxy.CheckInterval).Seconds().Do(func() {
-		runCheckIteration()
-	})
-	checkScheduler.StartAsync()
+	checkScheduler := checker.NewCheckScheduler(config.CLIConfig.Proxy.CheckInterval, runCheckIteration)
+	if err := checkScheduler.Start(); err != nil {
+		logger.Fatal("Error scheduling proxy checks: %v", err)
+	}
 
-	if config.CLIConfig.Subscription.Update {
-		updateScheduler := gocron.NewScheduler(time.UTC)
-		updateScheduler.Every(config.CLIConfig.Subscription.UpdateInterval).Seconds().WaitForSchedule().Do(func() {
-			logger.Info("Checking subscriptions for updates...")
-			newConfigs, err := subscription.ReadFromMultipleSources(config.CLIConfig.Subscription.URLs)
-			if err != nil {
-				if subscription.ShouldTreatAsEmptyResult(err) {
-					logger.Warn("Subscription source is empty/unavailable, clearing active proxies: %v", err)
-					if len(*proxyConfigs) > 0 {
-						updateInProgress.Store(true)
-						if err := clearConfiguration(proxyConfigs, xrayRunner, &xrayRunning, proxyChecker); err != nil {
-							logger.Error("Error clearing configuration: %v", err)
-						}
-						updateInProgress.Store(false)
+	var subscriptionUpdateMu sync.Mutex
+	checkSubscriptions := func() {
+		subscriptionUpdateMu.Lock()
+		defer subscriptionUpdateMu.Unlock()
+		if remoteManager != nil {
+			defer func() { remoteManager.RecordProxyCounts(*proxyConfigs) }()
+		}
+
+		logger.Info("Checking subscriptions for updates...")
+		newConfigs, err := subscription.ReadFromMultipleSources(config.CLIConfig.Subscription.URLs)
+		if err != nil {
+			if subscription.ShouldTreatAsEmptyResult(err) {
+				logger.Warn("Subscription source is empty/unavailable, clearing active proxies: %v", err)
+				if len(*proxyConfigs) > 0 {
+					updateInProgress.Store(true)
+					if err := clearConfiguration(proxyConfigs, xrayRunner, &xrayRunning, proxyChecker); err != nil {
+						logger.Error("Error clearing configuration: %v", err)
 					}
-					return
+					updateInProgress.Store(false)
 				}
-				logger.Error("Error fetching subscriptions: %v", err)
 				return
 			}
+			logger.Error("Error fetching subscriptions: %v", err)
+			return
+		}
+		newConfigs = subscription.FilterConfigs(newConfigs)
 
-			if config.CLIConfig.Proxy.ResolveDomains {
-				resolved, err := subscription.ResolveDomainsForConfigs(newConfigs)
-				if err != nil {
-					logger.Error("Error resolving domains: %v", err)
-				} else {
-					newConfigs = resolved
-				}
+		if config.CLIConfig.Proxy.ResolveDomains {
+			mode, err := subscription.ParseResolveMode(config.CLIConfig.Proxy.ResolveMode)
+			if err != nil {
+				logger.Error("Error resolving subscription domains: %v", err)
+				return
 			}
+			resolved, failures := subscription.ResolveDomainsForConfigs(newConfigs, mode)
+			if len(failures) > 0 {
+				logger.Warn("Failed to resolve %d of the subscription domains, continuing with the rest", len(failures))
+			}
+			newConfigs = resolved
+		}
 
-			if !xray.IsConfigsEqual(*proxyConfigs, newConfigs) {
-				updateInProgress.Store(true)
-				if err := updateConfiguration(newConfigs, proxyConfigs, xrayRunner, &xrayRunning, proxyChecker); err != nil {
-					logger.Error("Error updating configuration: %v", err)
-				}
-				updateInProgress.Store(false)
-			} else {
-				logger.Info("Subscriptions checked, no changes")
+		newConfigs = subscription.ResolveCollisions(newConfigs)
+
+		if !xray.IsConfigsEqual(*proxyConfigs, newConfigs) {
+			updateInProgress.Store(true)
+			if err := updateConfiguration(newConfigs, proxyConfigs, xrayRunner, &xrayRunning, proxyChecker); err != nil {
+				logger.Error("Error updating configuration: %v", err)
 			}
-		})
-		updateScheduler.StartAsync()
+			updateInProgress.Store(false)
+		} else {
+			logger.Info("Subscriptions checked, no changes")
+		}
+	}
+
+	var updateScheduler *checker.CheckScheduler
+	if config.CLIConfig.Subscription.Update {
+		updateScheduler = checker.NewCheckScheduler(config.CLIConfig.Subscription.UpdateInterval, checkSubscriptions)
+		if err := updateScheduler.StartDelayed(); err != nil {
+			logger.Fatal("Error scheduling subscription updates: %v", err)
+		}
+	}
+
+	if config.CLIConfig.Subscription.Watch {
+		debounce := time.Duration(config.CLIConfig.Subscription.WatchDebounce) * time.Millisecond
+		stopWatch := make(chan struct{})
+		if err := subscription.WatchFileSources(config.CLIConfig.Subscription.URLs, debounce, checkSubscriptions, stopWatch); err != nil {
+			logger.Warn("Local subscription watch unavailable, relying on scheduled updates: %v", err)
+		} else {
+			logger.Info("Watching local subscription files for changes")
+		}
 	}
 
 	mux, err := web.NewPrefixServeMux(config.CLIConfig.Metrics.BasePath)
@@ -207,36 +374,40 @@ func main() {
 		logger.Fatal("Error creating web server: %v", err)
 	}
 	mux.Handle("/health", web.HealthHandler())
+	mux.Handle("/ready", web.ReadyHandler())
 	mux.Handle("/static/", web.StaticHandler())
-	mux.Handle("/api/v1/public/proxies", web.APIPublicProxiesHandler(proxyChecker))
-	topBLPath := strings.TrimSpace(config.CLIConfig.Web.TopBLPath)
-	if topBLPath == "" {
-		topBLPath = "/api/v1/public/subscriptions/top-bl"
-	}
-	if !strings.HasPrefix(topBLPath, "/") {
-		topBLPath = "/" + topBLPath
-	}
-	mux.Handle(topBLPath, web.APITopBLSubscriptionHandler(proxyChecker, config.CLIConfig.Web.TopBLToken))
-
 	web.RegisterConfigEndpoints(*proxyConfigs, proxyChecker, config.CLIConfig.Xray.StartPort)
 
 	protectedHandler := http.NewServeMux()
-	protectedHandler.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	protectedHandler.Handle("/metrics", metrics.Handler(registry))
 	protectedHandler.Handle("/config/", web.ConfigStatusHandler(proxyChecker))
-	protectedHandler.Handle("/api/v1/proxies/", web.APIProxyHandler(proxyChecker, config.CLIConfig.Xray.StartPort))
-	protectedHandler.Handle("/api/v1/proxies", web.APIProxiesHandler(proxyChecker, config.CLIConfig.Xray.StartPort))
-	protectedHandler.Handle("/api/v1/config", web.APIConfigHandler(proxyChecker))
-	protectedHandler.Handle("/api/v1/status", web.APIStatusHandler(proxyChecker))
-	protectedHandler.Handle("/api/v1/system/info", web.APISystemInfoHandler(version, startTime))
-	protectedHandler.Handle("/api/v1/system/ip", web.APISystemIPHandler(proxyChecker))
-	protectedHandler.Handle("/api/v1/subscriptions/remote", web.APIRemoteSourcesHandler(remoteManager))
-	protectedHandler.Handle("/api/v1/subscriptions/remote/interval", web.APIRemoteIntervalHandler(remoteManager))
-	protectedHandler.Handle("/api/v1/subscriptions/remote/refresh", web.APIRemoteRefreshHandler(remoteManager))
-	protectedHandler.Handle("/api/v1/docs", web.APIDocsHandler())
-	protectedHandler.Handle("/api/v1/openapi.yaml", web.APIOpenAPIHandler())
+	for _, route := range web.APIRoutes(web.APIDependencies{
+		Version:         version,
+		Commit:          commit,
+		BuildDate:       buildDate,
+		StartTime:       startTime,
+		StartPort:       config.CLIConfig.Xray.StartPort,
+		ProxyChecker:    proxyChecker,
+		CheckScheduler:  checkScheduler,
+		UpdateScheduler: updateScheduler,
+		RemoteManager:   remoteManager,
+		TopBL:           config.CLIConfig.Web.TopBL,
+		TopBLPath:       config.CLIConfig.Web.TopBLPath,
+		TopBLToken:      config.CLIConfig.Web.TopBLToken,
+		Docs:            config.CLIConfig.Web.Docs,
+		DocsAssetsURL:   config.CLIConfig.Web.DocsAssetsURL,
+		ReadOnly:        config.CLIConfig.Web.ReadOnly,
+		XrayConfigPath:  configFile,
+	}) {
+		if route.Public {
+			mux.Handle(route.Pattern, route.Handler)
+		} else {
+			protectedHandler.Handle(route.Pattern, route.Handler)
+		}
+	}
 
 	if config.CLIConfig.Web.Public {
-		mux.Handle("/", web.IndexHandler(version, proxyChecker))
+		mux.Handle("/", web.IndexHandler(version, proxyChecker, checkScheduler))
 		mux.Handle("/config/", web.ConfigStatusHandler(proxyChecker))
 		middlewareHandler := web.BasicAuthMiddleware(
 			config.CLIConfig.Metrics.Username,
@@ -245,31 +416,40 @@ func main() {
 		mux.Handle("/metrics", middlewareHandler)
 		mux.Handle("/api/", middlewareHandler)
 	} else if config.CLIConfig.Metrics.Protected {
-		protectedHandler.Handle("/", web.IndexHandler(version, proxyChecker))
+		protectedHandler.Handle("/", web.IndexHandler(version, proxyChecker, checkScheduler))
 		middlewareHandler := web.BasicAuthMiddleware(
 			config.CLIConfig.Metrics.Username,
 			config.CLIConfig.Metrics.Password,
 		)(protectedHandler)
 		mux.Handle("/", middlewareHandler)
 	} else {
-		protectedHandler.Handle("/", web.IndexHandler(version, proxyChecker))
+		protectedHandler.Handle("/", web.IndexHandler(version, proxyChecker, checkScheduler))
 		mux.Handle("/", protectedHandler)
 	}
 
+	var handler http.Handler = mux
+	handler = web.CORSMiddleware(config.CLIConfig.Web.CORSOrigins, mux.Prefix()+"/api/")(handler)
+	handler = web.GzipMiddleware(handler)
+	accessLogLevel, err := web.ParseAccessLogLevel(config.CLIConfig.Web.AccessLog)
+	if err != nil {
+		logger.Fatal("Invalid --web-access-log: %v", err)
+	}
+	handler = web.AccessLogMiddleware(accessLogLevel)(handler)
+
 	if !config.CLIConfig.RunOnce {
 		logger.Info("Server listening on %s:%s%s",
 			config.CLIConfig.Metrics.Host,
 			config.CLIConfig.Metrics.Port,
 			config.CLIConfig.Metrics.BasePath,
 		)
-		if err := http.ListenAndServe(config.CLIConfig.Metrics.Host+":"+config.CLIConfig.Metrics.Port, mux); err != nil {
+		if err := http.ListenAndServe(config.CLIConfig.Metrics.Host+":"+config.CLIConfig.Metrics.Port, handler); err != nil {
 			logger.Fatal("Error starting server: %v", err)
 		}
 	}
 }
 
 func cleanupBadFileConfigs(proxyChecker *checker.ProxyChecker) {
-	const badDurationThreshold = time.Minute * 10
+	badDurationThreshold := time.Duration(config.CLIConfig.Cleanup.Threshold) * time.Second
 
 	badByFile := make(map[string]map[string]bool)
 	proxies := proxyChecker.GetProxies()
@@ -296,25 +476,59 @@ func cleanupBadFileConfigs(proxyChecker *checker.ProxyChecker) {
 		badByFile[proxy.SourcePath][strings.TrimSpace(proxy.SourceLine)] = true
 	}
 
+	toRemove := 0
+	for _, badLines := range badByFile {
+		toRemove += len(badLines)
+	}
+	if subscription.ExceedsCleanupLimit(toRemove, len(proxies), config.CLIConfig.Cleanup.MaxFraction) {
+		logger.Warn("Skipping cleanup: %d of %d proxies are bad, exceeding the %.0f%% limit; check local connectivity",
+			toRemove, len(proxies), config.CLIConfig.Cleanup.MaxFraction*100)
+		return
+	}
+
+	removeFn := subscription.RemoveBadConfigsFromFile
+	if config.CLIConfig.Cleanup.Quarantine {
+		removeFn = subscription.QuarantineBadConfigsFromFile
+	}
 	for filePath, badLines := range badByFile {
-		removed, kept, err := subscription.RemoveBadConfigsFromFile(filePath, badLines)
+		removed, kept, err := removeFn(filePath, badLines)
 		if err != nil {
 			logger.Warn("Failed to remove bad configs from file %s: %v", filePath, err)
 			continue
 		}
 		if removed > 0 {
-			logger.Warn("Removed %d bad configs from file %s (kept %d)", removed, filePath, kept)
+			if config.CLIConfig.Cleanup.Quarantine {
+				logger.Warn("Moved %d bad configs from file %s to %s (kept %d)", removed, filePath, filePath+subscription.QuarantineSuffix, kept)
+			} else {
+				logger.Warn("Removed %d bad configs from file %s (kept %d)", removed, filePath, kept)
+			}
 		}
 	}
 }
 
 func updateConfiguration(newConfigs []*models.ProxyConfig, currentConfigs *[]*models.ProxyConfig,
-	xrayRunner *xray.Runner, xrayRunning *bool, proxyChecker *checker.ProxyChecker) error {
-
-	logger.Info("Subscription changed, updating configuration...")
+	xrayRunner *xray.Runner, xrayRunning *bool, proxyChecker *checker.ProxyChecker) (err error) {
 
 	xray.PrepareProxyConfigs(newConfigs)
 
+	diff := xray.DiffConfigs(*currentConfigs, newConfigs)
+	logger.Info("Subscription changed (%s), updating configuration...", diff.Summary())
+	event := subscription.UpdateEvent{
+		Time:         time.Now(),
+		Total:        len(newConfigs),
+		Added:        len(diff.Added),
+		Removed:      len(diff.Removed),
+		Changed:      len(diff.Changed),
+		AddedNames:   proxyNames(diff.Added),
+		RemovedNames: proxyNames(diff.Removed),
+	}
+	defer func() {
+		if err != nil {
+			event.Error = err.Error()
+		}
+		subscription.RecordUpdate(event)
+	}()
+
 	configFile := "xray_config.json"
 	configGenerator := xray.NewConfigGenerator()
 	if err := configGenerator.GenerateAndSaveConfig(
@@ -336,31 +550,62 @@ func updateConfiguration(newConfigs []*models.ProxyConfig, currentConfigs *[]*mo
 		proxyChecker.UpdateProxies(newConfigs)
 		*currentConfigs = newConfigs
 		web.RegisterConfigEndpoints(newConfigs, proxyChecker, config.CLIConfig.Xray.StartPort)
+		web.SetProxiesLoaded(false)
 		logger.Info("Configuration updated: 0 proxies (empty source)")
 		return nil
 	}
 
-	if *xrayRunning {
-		if err := xrayRunner.Stop(); err != nil {
-			return err
-		}
-	}
-
-	if err := xrayRunner.Start(); err != nil {
+	restarted, err := xrayRunner.Apply(diff)
+	if err != nil {
+		*xrayRunning = xrayRunner.Running()
 		return err
 	}
 	*xrayRunning = true
+	event.XrayRestarted = restarted
+	if !restarted {
+		logger.Info("Only proxy metadata changed, keeping xray running")
+	}
 
 	proxyChecker.UpdateProxies(newConfigs)
 
 	*currentConfigs = newConfigs
 
 	web.RegisterConfigEndpoints(newConfigs, proxyChecker, config.CLIConfig.Xray.StartPort)
+	web.SetProxiesLoaded(true)
 
 	logger.Info("Configuration updated: %d proxies", len(newConfigs))
 	return nil
 }
 
+// runSelfTest fetches the check URLs directly before the first iteration and
+// reports the ones that do not work, exiting when fatal is set.
+func runSelfTest(proxyChecker *checker.ProxyChecker, fatal bool) {
+	failed := 0
+	for _, result := range proxyChecker.SelfTest() {
+		if result.Err == nil {
+			logger.Debug("Self-test: %s %s reachable", result.Name, result.URL)
+			continue
+		}
+		failed++
+		logger.Error("Self-test: %s %q is not reachable without a proxy: %v", result.Name, result.URL, result.Err)
+	}
+	if failed == 0 {
+		return
+	}
+	if fatal {
+		logger.Fatal("Self-test failed for %d check URL(s); fix them or disable --proxy-self-test-fatal", failed)
+	}
+	logger.Warn("Self-test failed for %d check URL(s); proxy checks are likely to fail", failed)
+}
+
+func proxyNames(proxies []*models.ProxyConfig) []string {
+	names := make([]string, 0, len(proxies))
+	for _, proxy := range proxies {
+		names = append(names, proxy.Name)
+	}
+	return names
+}
+
 func clearConfiguration(currentConfigs *[]*models.ProxyConfig, xrayRunner *xray.Runner,
 	xrayRunning *bool, proxyChecker *checker.ProxyChecker) error {
 
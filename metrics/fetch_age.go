@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fetchAgeCollector reports the seconds since the last successful fetch of
+// each remote source, computed at scrape time so the value keeps growing
+// while a source is failing.
+type fetchAgeCollector struct {
+	desc *prometheus.Desc
+
+	mu          sync.Mutex
+	lastFetched map[string]fetchAgeEntry
+}
+
+type fetchAgeEntry struct {
+	labels []string
+	at     time.Time
+}
+
+func newFetchAgeCollector(labels []string) *fetchAgeCollector {
+	return &fetchAgeCollector{
+		desc: prometheus.NewDesc(
+			"xray_checker_subscription_last_fetch_age_seconds",
+			"Seconds since the last successful fetch of a remote subscription source",
+			labels,
+			nil,
+		),
+		lastFetched: make(map[string]fetchAgeEntry),
+	}
+}
+
+func (c *fetchAgeCollector) markFetched(labels []string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastFetched[strings.Join(labels, "\x00")] = fetchAgeEntry{labels: labels, at: at}
+}
+
+func (c *fetchAgeCollector) delete(labels []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.lastFetched, strings.Join(labels, "\x00"))
+}
+
+func (c *fetchAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *fetchAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for _, entry := range c.lastFetched {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(entry.at).Seconds(), entry.labels...)
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// subNameLabel is the label carrying a proxy's subscription name.
+const subNameLabel = "sub_name"
+
+// subNameGatherer keeps only the series whose sub_name label equals subName.
+// Families without a matching series, including ones that have no sub_name
+// label at all, are dropped so other tenants' data never leaks through.
+type subNameGatherer struct {
+	gatherer prometheus.Gatherer
+	subName  string
+}
+
+// FilterBySubName wraps g so it only gathers series of subscription subName.
+func FilterBySubName(g prometheus.Gatherer, subName string) prometheus.Gatherer {
+	return subNameGatherer{gatherer: g, subName: subName}
+}
+
+func (g subNameGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	filtered := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		var kept []*dto.Metric
+		for _, metric := range family.GetMetric() {
+			if hasLabel(metric, subNameLabel, g.subName) {
+				kept = append(kept, metric)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		family.Metric = kept
+		filtered = append(filtered, family)
+	}
+	return filtered, err
+}
+
+func hasLabel(metric *dto.Metric, name, value string) bool {
+	for _, pair := range metric.GetLabel() {
+		if pair.GetName() == name {
+			return pair.GetValue() == value
+		}
+	}
+	return false
+}
+
+// Handler serves the exposition of g. A subName query parameter limits it to
+// the series of that subscription.
+func Handler(g prometheus.Gatherer) http.Handler {
+	full := promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subName := r.URL.Query().Get("subName")
+		if subName == "" {
+			full.ServeHTTP(w, r)
+			return
+		}
+		promhttp.HandlerFor(FilterBySubName(g, subName), promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestFilterBySubNameKeepsMatchingSeries(t *testing.T) {
+	status := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_proxy_status"}, []string{"name", "sub_name"})
+	fetches := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_fetch_total"}, []string{"source"})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(status, fetches)
+
+	status.WithLabelValues("a1", "team-a").Set(1)
+	status.WithLabelValues("a2", "team-a").Set(0)
+	status.WithLabelValues("b1", "team-b").Set(1)
+	fetches.WithLabelValues("src").Inc()
+
+	families, err := FilterBySubName(registry, "team-a").Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	if len(families) != 1 || families[0].GetName() != "test_proxy_status" {
+		t.Fatalf("expected only the proxy status family, got %v", families)
+	}
+	for _, metric := range families[0].GetMetric() {
+		if !hasLabel(metric, "sub_name", "team-a") {
+			t.Fatalf("unexpected series %s", metric.String())
+		}
+	}
+	if got := len(families[0].GetMetric()); got != 2 {
+		t.Fatalf("expected 2 team-a series, got %d", got)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(registry).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics?subName=team-b", nil))
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), `name="b1"`) || strings.Contains(string(body), "team-a") || strings.Contains(string(body), "test_fetch_total") {
+		t.Fatalf("expected only team-b series, got:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	Handler(registry).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ = io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), "team-a") || !strings.Contains(string(body), "test_fetch_total") {
+		t.Fatalf("expected full exposition without subName, got:\n%s", body)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"runtime"
+	"sync"
 	"time"
 
 	"xray-checker/logger"
@@ -27,9 +29,19 @@ var (
 	proxyLatency    *prometheus.GaugeVec
 	metricsInstance string
 	hasInstance     bool
+
+	subscriptionFetchTotal  *prometheus.CounterVec
+	subscriptionFetchErrors *prometheus.CounterVec
+	subscriptionFetchAge    *fetchAgeCollector
+	remoteSourceProxies     *prometheus.GaugeVec
+	buildInfo               *prometheus.GaugeVec
+
+	// remoteSourceIDs are the sources with a remoteSourceProxies series.
+	remoteSourceMu  sync.Mutex
+	remoteSourceIDs map[string]bool
 )
 
-func InitMetrics(instance string) {
+func InitMetrics(instance, version string) {
 	metricsInstance = instance
 	hasInstance = instance != ""
 
@@ -53,6 +65,57 @@ func InitMetrics(instance string) {
 		},
 		labels,
 	)
+
+	sourceLabels := []string{"source"}
+	if hasInstance {
+		sourceLabels = append(sourceLabels, "instance")
+	}
+
+	subscriptionFetchTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "xray_checker_subscription_fetch_total",
+			Help: "Number of remote subscription fetch attempts",
+		},
+		sourceLabels,
+	)
+
+	subscriptionFetchErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "xray_checker_subscription_fetch_errors_total",
+			Help: "Number of failed remote subscription fetches",
+		},
+		sourceLabels,
+	)
+
+	subscriptionFetchAge = newFetchAgeCollector(sourceLabels)
+	prometheus.MustRegister(subscriptionFetchAge)
+
+	remoteSourceProxies = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "xray_checker_remote_source_proxies",
+			Help: "Number of loaded proxies each remote subscription source contributes",
+		},
+		sourceLabels,
+	)
+	remoteSourceIDs = make(map[string]bool)
+
+	proxyInfo = newProxyInfoMetric()
+	prometheus.MustRegister(proxyInfo)
+
+	buildLabels := []string{"version", "goversion"}
+	buildValues := []string{version, runtime.Version()}
+	if hasInstance {
+		buildLabels = append(buildLabels, "instance")
+		buildValues = append(buildValues, instance)
+	}
+	buildInfo = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "xray_checker_build_info",
+			Help: "Always 1, labeled with the running version and the Go version it was built with",
+		},
+		buildLabels,
+	)
+	buildInfo.WithLabelValues(buildValues...).Set(1)
 }
 
 func GetProxyStatusMetric() *prometheus.GaugeVec {
@@ -79,6 +142,87 @@ func RecordProxyLatency(protocol, address, name, subName string, value time.Dura
 	proxyLatency.WithLabelValues(buildLabelValues(protocol, address, name, subName)...).Set(float64(value.Milliseconds()))
 }
 
+func GetSubscriptionFetchMetric() *prometheus.CounterVec {
+	return subscriptionFetchTotal
+}
+
+func GetSubscriptionFetchErrorsMetric() *prometheus.CounterVec {
+	return subscriptionFetchErrors
+}
+
+func GetSubscriptionFetchAgeMetric() prometheus.Collector {
+	return subscriptionFetchAge
+}
+
+func GetRemoteSourceProxiesMetric() *prometheus.GaugeVec {
+	return remoteSourceProxies
+}
+
+func GetBuildInfoMetric() *prometheus.GaugeVec {
+	return buildInfo
+}
+
+func sourceLabelValues(sourceID string) []string {
+	labels := []string{sourceID}
+	if hasInstance {
+		labels = append(labels, metricsInstance)
+	}
+	return labels
+}
+
+// RecordSubscriptionFetch counts a fetch attempt of a remote source, keyed by
+// its ID to keep label cardinality low. It is a no-op before InitMetrics.
+func RecordSubscriptionFetch(sourceID string, success bool) {
+	if subscriptionFetchTotal == nil {
+		return
+	}
+	labels := sourceLabelValues(sourceID)
+	subscriptionFetchTotal.WithLabelValues(labels...).Inc()
+	if !success {
+		subscriptionFetchErrors.WithLabelValues(labels...).Inc()
+		return
+	}
+	subscriptionFetchAge.markFetched(labels, time.Now())
+}
+
+// DeleteSubscriptionSource drops all series of a removed remote source.
+func DeleteSubscriptionSource(sourceID string) {
+	if subscriptionFetchTotal == nil {
+		return
+	}
+	labels := sourceLabelValues(sourceID)
+	subscriptionFetchTotal.DeleteLabelValues(labels...)
+	subscriptionFetchErrors.DeleteLabelValues(labels...)
+	subscriptionFetchAge.delete(labels)
+
+	remoteSourceMu.Lock()
+	defer remoteSourceMu.Unlock()
+	remoteSourceProxies.DeleteLabelValues(labels...)
+	delete(remoteSourceIDs, sourceID)
+}
+
+// SetRemoteSourceProxies sets the number of loaded proxies per remote source,
+// keyed by source ID, and drops the series of sources missing from counts. It
+// is a no-op before InitMetrics.
+func SetRemoteSourceProxies(counts map[string]int) {
+	if remoteSourceProxies == nil {
+		return
+	}
+	remoteSourceMu.Lock()
+	defer remoteSourceMu.Unlock()
+	for id := range remoteSourceIDs {
+		if _, ok := counts[id]; !ok {
+			remoteSourceProxies.DeleteLabelValues(sourceLabelValues(id)...)
+		}
+	}
+	ids := make(map[string]bool, len(counts))
+	for id, count := range counts {
+		remoteSourceProxies.WithLabelValues(sourceLabelValues(id)...).Set(float64(count))
+		ids[id] = true
+	}
+	remoteSourceIDs = ids
+}
+
 func DeleteProxyStatus(protocol, address, name, subName string) {
 	proxyStatus.DeleteLabelValues(buildLabelValues(protocol, address, name, subName)...)
 }
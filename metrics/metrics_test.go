@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var testMetricsOnce sync.Once
+
+func initTestMetrics() {
+	testMetricsOnce.Do(func() { InitMetrics("", "test") })
+}
+
+func TestRecordSubscriptionFetch(t *testing.T) {
+	initTestMetrics()
+
+	RecordSubscriptionFetch("src1", true)
+	RecordSubscriptionFetch("src1", false)
+	RecordSubscriptionFetch("src1", false)
+
+	if got := testutil.ToFloat64(subscriptionFetchTotal.WithLabelValues("src1")); got != 3 {
+		t.Fatalf("expected 3 fetches, got %v", got)
+	}
+	if got := testutil.ToFloat64(subscriptionFetchErrors.WithLabelValues("src1")); got != 2 {
+		t.Fatalf("expected 2 errors, got %v", got)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(GetSubscriptionFetchAgeMetric())
+	if count, err := testutil.GatherAndCount(registry, "xray_checker_subscription_last_fetch_age_seconds"); err != nil || count != 1 {
+		t.Fatalf("expected one age series, got %d (%v)", count, err)
+	}
+	families, _ := registry.Gather()
+	if !strings.Contains(families[0].String(), `value:"src1"`) {
+		t.Fatalf("expected source label, got %s", families[0].String())
+	}
+
+	DeleteSubscriptionSource("src1")
+	if count, _ := testutil.GatherAndCount(registry, "xray_checker_subscription_last_fetch_age_seconds"); count != 0 {
+		t.Fatalf("expected age series to be removed, got %d", count)
+	}
+}
+
+func TestSetProxyInfo(t *testing.T) {
+	initTestMetrics()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(GetProxyInfoMetric())
+
+	a := ProxyInfo{StableID: "a1", Name: "node-a", SubName: "sub", Protocol: "vless", Server: "a.example.com"}
+	b := ProxyInfo{StableID: "b2", Name: "node-b", SubName: "sub", Protocol: "trojan", Server: "b.example.com"}
+	SetProxyInfo([]ProxyInfo{a, b})
+	if count, err := testutil.GatherAndCount(registry, "xray_checker_proxy_info"); err != nil || count != 2 {
+		t.Fatalf("expected two info series, got %d (%v)", count, err)
+	}
+	if got := testutil.ToFloat64(proxyInfo.WithLabelValues(a.labelValues()...)); got != 1 {
+		t.Fatalf("expected info value 1, got %v", got)
+	}
+
+	renamed := a
+	renamed.Name = "node-a2"
+	SetProxyInfo([]ProxyInfo{renamed})
+	if count, _ := testutil.GatherAndCount(registry, "xray_checker_proxy_info"); count != 1 {
+		t.Fatalf("expected removed and renamed proxies to leave one series, got %d", count)
+	}
+	families, _ := registry.Gather()
+	if text := families[0].String(); !strings.Contains(text, `value:"node-a2"`) || strings.Contains(text, `value:"node-b"`) {
+		t.Fatalf("unexpected info series: %s", text)
+	}
+
+	SetProxyInfo(nil)
+	if count, _ := testutil.GatherAndCount(registry, "xray_checker_proxy_info"); count != 0 {
+		t.Fatalf("expected no info series, got %d", count)
+	}
+}
+
+func TestSetRemoteSourceProxies(t *testing.T) {
+	initTestMetrics()
+
+	SetRemoteSourceProxies(map[string]int{"full": 3, "empty": 0})
+	if got := testutil.ToFloat64(remoteSourceProxies.WithLabelValues("full")); got != 3 {
+		t.Fatalf("expected 3 proxies for full, got %v", got)
+	}
+	if count := testutil.CollectAndCount(remoteSourceProxies); count != 2 {
+		t.Fatalf("expected a series per source including empty ones, got %d", count)
+	}
+
+	SetRemoteSourceProxies(map[string]int{"full": 1})
+	if count := testutil.CollectAndCount(remoteSourceProxies); count != 1 {
+		t.Fatalf("expected series of dropped sources to be removed, got %d", count)
+	}
+	DeleteSubscriptionSource("full")
+	if count := testutil.CollectAndCount(remoteSourceProxies); count != 0 {
+		t.Fatalf("expected removed source series to be deleted, got %d", count)
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	initTestMetrics()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(GetBuildInfoMetric())
+	if count, err := testutil.GatherAndCount(registry, "xray_checker_build_info"); err != nil || count != 1 {
+		t.Fatalf("expected one build info series, got %d (%v)", count, err)
+	}
+	if got := testutil.ToFloat64(buildInfo.WithLabelValues("test", runtime.Version())); got != 1 {
+		t.Fatalf("expected build info labeled with version and goversion set to 1, got %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ProxyInfo identifies one proxy in the xray_checker_proxy_info series.
+type ProxyInfo struct {
+	StableID string
+	Name     string
+	SubName  string
+	Protocol string
+	Server   string
+}
+
+var (
+	proxyInfo *prometheus.GaugeVec
+
+	// proxyInfoSeries holds the label values currently exported per stable
+	// ID, so SetProxyInfo only touches series that changed.
+	proxyInfoMu     sync.Mutex
+	proxyInfoSeries map[string][]string
+)
+
+func newProxyInfoMetric() *prometheus.GaugeVec {
+	labels := []string{"stable_id", "name", "sub_name", "protocol", "server"}
+	if hasInstance {
+		labels = append(labels, "instance")
+	}
+	proxyInfoSeries = make(map[string][]string)
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "xray_checker_proxy_info",
+			Help: "Constant 1 per loaded proxy, carrying its descriptive labels for joins",
+		},
+		labels,
+	)
+}
+
+func GetProxyInfoMetric() *prometheus.GaugeVec {
+	return proxyInfo
+}
+
+func (p ProxyInfo) labelValues() []string {
+	labels := []string{p.StableID, p.Name, p.SubName, p.Protocol, p.Server}
+	if hasInstance {
+		labels = append(labels, metricsInstance)
+	}
+	return labels
+}
+
+// SetProxyInfo exports one info series per proxy and deletes the series of
+// proxies that are gone or whose labels changed. It is a no-op before
+// InitMetrics.
+func SetProxyInfo(infos []ProxyInfo) {
+	if proxyInfo == nil {
+		return
+	}
+	proxyInfoMu.Lock()
+	defer proxyInfoMu.Unlock()
+
+	next := make(map[string][]string, len(infos))
+	for _, info := range infos {
+		next[info.StableID] = info.labelValues()
+	}
+	for id, labels := range proxyInfoSeries {
+		if current, ok := next[id]; !ok || !equalLabels(current, labels) {
+			proxyInfo.DeleteLabelValues(labels...)
+		}
+	}
+	for _, labels := range next {
+		proxyInfo.WithLabelValues(labels...).Set(1)
+	}
+	proxyInfoSeries = next
+}
+
+func equalLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"unicode"
 )
 
 type ProxyConfig struct {
 	Protocol         string
 	Server           string
+	ResolvedServer   string
 	Port             int
 	Name             string
 	Security         string
@@ -37,6 +39,7 @@ type ProxyConfig struct {
 	AllowInsecure    bool
 	ALPN             []string
 	Index            int
+	LocalPort        int
 	Settings         map[string]string
 	StableID         string
 	RawXhttpSettings string
@@ -76,6 +79,24 @@ func (pc *ProxyConfig) Validate() error {
 	return nil
 }
 
+// DialServer returns the address xray connects to: the resolved IP when
+// domain resolution is enabled, the original server otherwise.
+func (pc *ProxyConfig) DialServer() string {
+	if pc.ResolvedServer != "" {
+		return pc.ResolvedServer
+	}
+	return pc.Server
+}
+
+// InboundPort returns the local SOCKS port xray listens on for this proxy:
+// the port assigned by the port map, or startPort+Index when none is set.
+func (pc *ProxyConfig) InboundPort(startPort int) int {
+	if pc.LocalPort > 0 {
+		return pc.LocalPort
+	}
+	return startPort + pc.Index
+}
+
 func (pc *ProxyConfig) GenerateStableID() string {
 	var idComponents []string
 
@@ -294,3 +315,56 @@ func maskSecret(s string) string {
 	}
 	return s[:2] + "****" + s[len(s)-2:]
 }
+
+// HasNameTag reports whether name carries tag as a standalone word. Names and
+// tags are split on anything that is not a letter or digit, so "BL" matches
+// "DE [BL] 01" but not "CABLE". Matching is case-insensitive.
+func HasNameTag(name, tag string) bool {
+	tagTokens := nameTokens(tag)
+	if len(tagTokens) == 0 {
+		return false
+	}
+	tokens := nameTokens(name)
+	for i := 0; i+len(tagTokens) <= len(tokens); i++ {
+		matched := true
+		for j, want := range tagTokens {
+			if !strings.EqualFold(tokens[i+j], want) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMethodHint returns the check method requested for this proxy by an
+// "[m=<method>]" token in its name, such as "DE 01 [m=download]". It returns
+// "" when there is none; the caller validates the method.
+func (pc *ProxyConfig) CheckMethodHint() string {
+	rest := pc.Name
+	for {
+		start := strings.Index(rest, "[")
+		if start < 0 {
+			return ""
+		}
+		rest = rest[start+1:]
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return ""
+		}
+		key, value, ok := strings.Cut(rest[:end], "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "m") {
+			return strings.ToLower(strings.TrimSpace(value))
+		}
+		rest = rest[end+1:]
+	}
+}
+
+func nameTokens(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
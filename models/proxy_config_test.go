@@ -38,3 +38,44 @@ func TestGenerateStableIDIncludesTransportCriticalFields(t *testing.T) {
 		t.Fatalf("expected different stable IDs for different hosts")
 	}
 }
+
+func TestHasNameTagMatchesWholeWords(t *testing.T) {
+	cases := []struct {
+		name string
+		tag  string
+		want bool
+	}{
+		{name: "BL", tag: "BL", want: true},
+		{name: "DE [BL] 01", tag: "BL", want: true},
+		{name: "nl-bl-fast", tag: "BL", want: true},
+		{name: "CABLE", tag: "BL", want: false},
+		{name: "TUMBLR Node", tag: "BL", want: false},
+		{name: "Fast (cidr)", tag: "CIDR", want: true},
+		{name: "CIDRS", tag: "CIDR", want: false},
+		{name: "Germany White List", tag: "white list", want: true},
+		{name: "Germany", tag: "", want: false},
+	}
+	for _, tc := range cases {
+		if got := HasNameTag(tc.name, tc.tag); got != tc.want {
+			t.Errorf("HasNameTag(%q, %q) = %v, want %v", tc.name, tc.tag, got, tc.want)
+		}
+	}
+}
+
+func TestCheckMethodHint(t *testing.T) {
+	cases := []struct {
+		name   string
+		expect string
+	}{
+		{name: "DE 01", expect: ""},
+		{name: "DE 01 [m=download]", expect: "download"},
+		{name: "[BL] DE [M = DNS]", expect: "dns"},
+		{name: "DE [m=download", expect: ""},
+	}
+	for _, tc := range cases {
+		pc := &ProxyConfig{Name: tc.name}
+		if got := pc.CheckMethodHint(); got != tc.expect {
+			t.Errorf("CheckMethodHint(%q) = %q, want %q", tc.name, got, tc.expect)
+		}
+	}
+}
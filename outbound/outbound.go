@@ -0,0 +1,212 @@
+// Package outbound builds the HTTP clients used for the checker's own
+// downloads (subscriptions, remote sources, geo files). They share one
+// transport, so proxy, TLS, User-Agent and connection limits apply to all of
+// them together. Proxy checks do not use it; they dial through xray.
+package outbound
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout is the client timeout when none is configured.
+const DefaultTimeout = 30 * time.Second
+
+// Options configures outbound HTTP.
+type Options struct {
+	// Timeout applies to clients created without a per-use timeout.
+	Timeout time.Duration
+	// ProxyURL routes requests through an HTTP(S) or SOCKS5 proxy. Empty
+	// uses the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment.
+	ProxyURL string
+	// UserAgent replaces the User-Agent of every request when set.
+	UserAgent string
+	// MaxConcurrent caps in-flight requests across all clients. Zero means
+	// no limit.
+	MaxConcurrent int
+	// ClientCertFile and ClientKeyFile are a PEM certificate and key
+	// presented to hosts requiring mutual TLS. Both or neither must be set.
+	ClientCertFile string
+	ClientKeyFile  string
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string
+	// InsecureSubscriptions skips certificate verification for clients from
+	// NewSubscriptionClient only; other downloads keep verifying.
+	InsecureSubscriptions bool
+}
+
+type state struct {
+	timeout   time.Duration
+	userAgent string
+	transport *http.Transport
+	// subscriptionTransport is transport without certificate verification,
+	// set only when InsecureSubscriptions is enabled.
+	subscriptionTransport *http.Transport
+	slots                 chan struct{}
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	s, _ := newState(Options{})
+	current.Store(s)
+}
+
+// Configure applies opts to every client, including ones created earlier.
+func Configure(opts Options) error {
+	s, err := newState(opts)
+	if err != nil {
+		return err
+	}
+	if old := current.Swap(s); old != nil {
+		old.transport.CloseIdleConnections()
+		if old.subscriptionTransport != nil {
+			old.subscriptionTransport.CloseIdleConnections()
+		}
+	}
+	return nil
+}
+
+func newState(opts Options) (*state, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if raw := strings.TrimSpace(opts.ProxyURL); raw != "" {
+		proxyURL, err := url.Parse(raw)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", raw)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	s := &state{timeout: opts.Timeout, userAgent: opts.UserAgent, transport: transport}
+	if s.timeout <= 0 {
+		s.timeout = DefaultTimeout
+	}
+	if opts.InsecureSubscriptions {
+		insecure := transport.Clone()
+		if insecure.TLSClientConfig == nil {
+			insecure.TLSClientConfig = &tls.Config{}
+		}
+		insecure.TLSClientConfig.InsecureSkipVerify = true
+		s.subscriptionTransport = insecure
+	}
+	if opts.MaxConcurrent > 0 {
+		s.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return s, nil
+}
+
+// newTLSConfig loads the client certificate and CA bundle from opts; nil
+// keeps the transport's default TLS settings.
+func newTLSConfig(opts Options) (*tls.Config, error) {
+	if opts.ClientCertFile == "" && opts.ClientKeyFile == "" && opts.CAFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.ClientCertFile != "" || opts.ClientKeyFile != "" {
+		if opts.ClientCertFile == "" || opts.ClientKeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// NewClient returns a client on the shared transport. A positive timeout
+// overrides the configured one for this client.
+func NewClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = current.Load().timeout
+	}
+	return &http.Client{Timeout: timeout, Transport: roundTripper{}}
+}
+
+// NewSubscriptionClient is NewClient for subscription fetches, which honour
+// InsecureSubscriptions.
+func NewSubscriptionClient(timeout time.Duration) *http.Client {
+	client := NewClient(timeout)
+	client.Transport = roundTripper{subscription: true}
+	return client
+}
+
+// roundTripper resolves the current settings on every request, so clients
+// built before Configure still follow it.
+type roundTripper struct {
+	subscription bool
+}
+
+func (rt roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := current.Load()
+	transport := s.transport
+	if rt.subscription && s.subscriptionTransport != nil {
+		transport = s.subscriptionTransport
+	}
+	if s.userAgent != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", s.userAgent)
+	}
+	if s.slots == nil {
+		return transport.RoundTrip(req)
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		<-s.slots
+		return nil, err
+	}
+	// The slot is held until the body is closed: the download is still in
+	// flight until then.
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() { <-s.slots }}
+	return resp, nil
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	release  func()
+	released atomic.Bool
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.released.CompareAndSwap(false, true) {
+		b.release()
+	}
+	return err
+}
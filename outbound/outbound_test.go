@@ -0,0 +1,182 @@
+package outbound
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigureAppliesSettings(t *testing.T) {
+	t.Cleanup(func() { Configure(Options{}) })
+
+	var gotUA, gotHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		gotHost = r.URL.Host
+	}))
+	t.Cleanup(proxy.Close)
+
+	// Created before Configure: settings are resolved per request.
+	client := NewClient(0)
+	if err := Configure(Options{Timeout: 7 * time.Second, ProxyURL: proxy.URL, UserAgent: "checker-test/1.0"}); err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+	if got := NewClient(0).Timeout; got != 7*time.Second {
+		t.Fatalf("expected configured timeout, got %s", got)
+	}
+	if got := NewClient(90 * time.Second).Timeout; got != 90*time.Second {
+		t.Fatalf("expected per-use timeout override, got %s", got)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://subscription.invalid/sub", nil)
+	req.Header.Set("User-Agent", "builtin")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request through proxy: %v", err)
+	}
+	resp.Body.Close()
+	if gotHost != "subscription.invalid" || gotUA != "checker-test/1.0" {
+		t.Fatalf("expected request via proxy with configured UA, got host=%q ua=%q", gotHost, gotUA)
+	}
+
+	if err := Configure(Options{ProxyURL: "::bad"}); err == nil {
+		t.Fatal("expected an error for an invalid proxy URL")
+	}
+}
+
+func TestMaxConcurrentHoldsSlotUntilBodyClosed(t *testing.T) {
+	t.Cleanup(func() { Configure(Options{}) })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	if err := Configure(Options{MaxConcurrent: 1}); err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+
+	client := NewClient(0)
+	first, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected second request to wait for the slot, got %v", err)
+	}
+
+	first.Body.Close()
+	second, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected slot to be free after closing the body: %v", err)
+	}
+	second.Body.Close()
+}
+
+func TestClientCertificateForMutualTLS(t *testing.T) {
+	t.Cleanup(func() { Configure(Options{}) })
+	dir := t.TempDir()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "checker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	clientCert, _ := x509.ParseCertificate(der)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+
+	if err := Configure(Options{CAFile: caFile}); err != nil {
+		t.Fatalf("configure CA only: %v", err)
+	}
+	if resp, err := NewClient(0).Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the server to reject a request without a client certificate")
+	}
+
+	if err := Configure(Options{ClientCertFile: certFile, ClientKeyFile: keyFile, CAFile: caFile}); err != nil {
+		t.Fatalf("configure client certificate: %v", err)
+	}
+	resp, err := NewClient(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with client certificate: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := Configure(Options{ClientCertFile: certFile}); err == nil {
+		t.Fatal("expected an error for a certificate without a key")
+	}
+	if err := Configure(Options{CAFile: keyFile}); err == nil {
+		t.Fatal("expected an error for a CA bundle without certificates")
+	}
+}
+
+func TestInsecureSubscriptionsOnlyAffectSubscriptionClients(t *testing.T) {
+	t.Cleanup(func() { Configure(Options{}) })
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	if resp, err := NewSubscriptionClient(0).Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected a self-signed certificate to be rejected by default")
+	}
+
+	if err := Configure(Options{InsecureSubscriptions: true}); err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+	resp, err := NewSubscriptionClient(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected subscription fetch to skip verification: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp, err := NewClient(0).Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected other downloads to keep verifying certificates")
+	}
+}
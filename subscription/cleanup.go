@@ -2,16 +2,53 @@ package subscription
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
+// QuarantineSuffix is appended to a source file path to name the sidecar file
+// that receives lines removed in quarantine mode.
+const QuarantineSuffix = ".removed"
+
+// sourceFileMu serializes cleanup and restore, which both read, modify and
+// rewrite a source file and its quarantine sidecar.
+var sourceFileMu sync.Mutex
+
+// ExceedsCleanupLimit reports whether removing toRemove of total proxies in a
+// single pass exceeds maxFraction. A mass failure usually means the checker's
+// own connectivity is broken rather than the nodes, so such passes are skipped.
+// A maxFraction of 0 disables the guard.
+func ExceedsCleanupLimit(toRemove, total int, maxFraction float64) bool {
+	if maxFraction <= 0 || total <= 0 {
+		return false
+	}
+	return float64(toRemove) > float64(total)*maxFraction
+}
+
 func RemoveBadConfigsFromFile(filePath string, badLines map[string]bool) (int, int, error) {
+	return removeBadConfigs(filePath, badLines, false)
+}
+
+// QuarantineBadConfigsFromFile works like RemoveBadConfigsFromFile but appends
+// the removed lines to filePath+QuarantineSuffix so they can be reviewed and
+// restored.
+func QuarantineBadConfigsFromFile(filePath string, badLines map[string]bool) (int, int, error) {
+	return removeBadConfigs(filePath, badLines, true)
+}
+
+func removeBadConfigs(filePath string, badLines map[string]bool, quarantine bool) (int, int, error) {
 	if filePath == "" || len(badLines) == 0 {
 		return 0, 0, nil
 	}
 
+	sourceFileMu.Lock()
+	defer sourceFileMu.Unlock()
+
 	rawData, err := os.ReadFile(filePath)
 	if err != nil {
 		return 0, 0, err
@@ -34,6 +71,7 @@ func RemoveBadConfigsFromFile(filePath string, badLines map[string]bool) (int, i
 	bom := string([]byte{0xEF, 0xBB, 0xBF})
 
 	var kept []string
+	var removedLines []string
 	removed := 0
 
 	for _, line := range lines {
@@ -44,6 +82,7 @@ func RemoveBadConfigsFromFile(filePath string, badLines map[string]bool) (int, i
 		}
 		if badLines[trim] {
 			removed++
+			removedLines = append(removedLines, trim)
 			continue
 		}
 		kept = append(kept, trim)
@@ -61,9 +100,58 @@ func RemoveBadConfigsFromFile(filePath string, badLines map[string]bool) (int, i
 		out = base64.StdEncoding.EncodeToString([]byte(out))
 	}
 
-	if err := os.WriteFile(filePath, []byte(out), 0o644); err != nil {
+	if quarantine {
+		if err := appendQuarantine(filePath+QuarantineSuffix, removedLines); err != nil {
+			return 0, len(kept), fmt.Errorf("failed to write quarantine file: %w", err)
+		}
+	}
+
+	if err := writeFileAtomic(filePath, []byte(out), 0o644); err != nil {
 		return 0, len(kept), err
 	}
 
 	return removed, len(kept), nil
 }
+
+func appendQuarantine(path string, lines []string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var b strings.Builder
+	b.Write(existing)
+	fmt.Fprintf(&b, "# removed %s\n", time.Now().UTC().Format(time.RFC3339))
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return writeFileAtomic(path, []byte(b.String()), 0o644)
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it into
+// place, so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package subscription
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveBadConfigsFromFileDeletesLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("vless://good\nvless://bad\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	removed, kept, err := RemoveBadConfigsFromFile(path, map[string]bool{"vless://bad": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 1 || kept != 1 {
+		t.Fatalf("expected 1 removed and 1 kept, got %d/%d", removed, kept)
+	}
+	if _, err := os.Stat(path + QuarantineSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected no quarantine file, got err=%v", err)
+	}
+}
+
+func TestQuarantineBadConfigsFromFileWritesSidecar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("vless://good\nvless://bad1\nvless://bad2\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	removed, kept, err := QuarantineBadConfigsFromFile(path, map[string]bool{"vless://bad1": true, "vless://bad2": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 2 || kept != 1 {
+		t.Fatalf("expected 2 removed and 1 kept, got %d/%d", removed, kept)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "vless://good" {
+		t.Fatalf("unexpected source content: %q", data)
+	}
+
+	sidecar, err := os.ReadFile(path + QuarantineSuffix)
+	if err != nil {
+		t.Fatalf("expected quarantine file: %v", err)
+	}
+	if !strings.Contains(string(sidecar), "vless://bad1\nvless://bad2\n") {
+		t.Fatalf("unexpected quarantine content: %q", sidecar)
+	}
+
+	if err := os.WriteFile(path, []byte("vless://good\nvless://bad3\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, _, err := QuarantineBadConfigsFromFile(path, map[string]bool{"vless://bad3": true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sidecar, _ = os.ReadFile(path + QuarantineSuffix)
+	if !strings.Contains(string(sidecar), "vless://bad1") || !strings.Contains(string(sidecar), "vless://bad3") {
+		t.Fatalf("expected quarantine file to be appended, got %q", sidecar)
+	}
+}
+
+func TestQuarantineRefusesToEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("vless://bad\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if _, _, err := QuarantineBadConfigsFromFile(path, map[string]bool{"vless://bad": true}); err == nil {
+		t.Fatal("expected error when all configs would be removed")
+	}
+	if _, err := os.Stat(path + QuarantineSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected no quarantine file when nothing was removed, got err=%v", err)
+	}
+}
+
+func TestExceedsCleanupLimit(t *testing.T) {
+	cases := []struct {
+		toRemove, total int
+		maxFraction     float64
+		want            bool
+	}{
+		{toRemove: 2, total: 10, maxFraction: 0.5, want: false},
+		{toRemove: 5, total: 10, maxFraction: 0.5, want: false},
+		{toRemove: 6, total: 10, maxFraction: 0.5, want: true},
+		{toRemove: 10, total: 10, maxFraction: 0.5, want: true},
+		{toRemove: 10, total: 10, maxFraction: 0, want: false},
+		{toRemove: 0, total: 0, maxFraction: 0.5, want: false},
+	}
+	for _, tc := range cases {
+		if got := ExceedsCleanupLimit(tc.toRemove, tc.total, tc.maxFraction); got != tc.want {
+			t.Fatalf("ExceedsCleanupLimit(%d, %d, %v) = %v, want %v", tc.toRemove, tc.total, tc.maxFraction, got, tc.want)
+		}
+	}
+}
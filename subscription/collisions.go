@@ -0,0 +1,115 @@
+package subscription
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"xray-checker/models"
+)
+
+// CollisionMode selects what happens to proxies whose stable ID is already
+// taken by an earlier proxy.
+type CollisionMode string
+
+const (
+	// CollisionWarn keeps every proxy and only reports the collision.
+	CollisionWarn CollisionMode = "warn"
+	// CollisionSuffix keeps every proxy and appends -2, -3... to the stable
+	// IDs of later duplicates so each gets its own status and metrics.
+	CollisionSuffix CollisionMode = "suffix"
+	// CollisionDrop keeps only the first proxy of each stable ID.
+	CollisionDrop CollisionMode = "drop"
+)
+
+// ParseCollisionMode maps a config value to a CollisionMode. Empty means
+// CollisionWarn.
+func ParseCollisionMode(value string) (CollisionMode, error) {
+	switch mode := CollisionMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return CollisionWarn, nil
+	case CollisionWarn, CollisionSuffix, CollisionDrop:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown stable ID collision mode %q, expected warn, suffix or drop", value)
+	}
+}
+
+// StableIDCollision lists the proxies that share one stable ID, in load
+// order.
+type StableIDCollision struct {
+	StableID      string   `json:"stableId"`
+	Names         []string `json:"names"`
+	Subscriptions []string `json:"subscriptions"`
+}
+
+var (
+	lastCollisions   []StableIDCollision
+	lastCollisionsMu sync.RWMutex
+)
+
+// StableIDCollisions returns the collisions found by the last load.
+func StableIDCollisions() []StableIDCollision {
+	lastCollisionsMu.RLock()
+	defer lastCollisionsMu.RUnlock()
+	return append([]StableIDCollision(nil), lastCollisions...)
+}
+
+func recordCollisions(collisions []StableIDCollision) {
+	lastCollisionsMu.Lock()
+	defer lastCollisionsMu.Unlock()
+	lastCollisions = collisions
+}
+
+// ResolveStableIDCollisions finds proxies sharing a stable ID and applies
+// mode to the later ones. Order is kept.
+func ResolveStableIDCollisions(configs []*models.ProxyConfig, mode CollisionMode) ([]*models.ProxyConfig, []StableIDCollision) {
+	byID := make(map[string]int, len(configs))
+	taken := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		if cfg.StableID == "" {
+			cfg.StableID = cfg.GenerateStableID()
+		}
+		taken[cfg.StableID] = true
+	}
+
+	var collisions []StableIDCollision
+	kept := make([]*models.ProxyConfig, 0, len(configs))
+	for _, cfg := range configs {
+		id := cfg.StableID
+		i, seen := byID[id]
+		if !seen {
+			byID[id] = len(collisions)
+			collisions = append(collisions, StableIDCollision{StableID: id})
+			i = byID[id]
+		}
+		collisions[i].Names = append(collisions[i].Names, cfg.Name)
+		collisions[i].Subscriptions = append(collisions[i].Subscriptions, cfg.SubName)
+		if !seen {
+			kept = append(kept, cfg)
+			continue
+		}
+
+		switch mode {
+		case CollisionDrop:
+			continue
+		case CollisionSuffix:
+			for n := len(collisions[i].Names); ; n++ {
+				suffixed := fmt.Sprintf("%s-%d", id, n)
+				if !taken[suffixed] {
+					cfg.StableID = suffixed
+					taken[suffixed] = true
+					break
+				}
+			}
+		}
+		kept = append(kept, cfg)
+	}
+
+	out := collisions[:0]
+	for _, collision := range collisions {
+		if len(collision.Names) > 1 {
+			out = append(out, collision)
+		}
+	}
+	return kept, out
+}
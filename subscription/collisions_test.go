@@ -0,0 +1,110 @@
+package subscription
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"xray-checker/config"
+	"xray-checker/models"
+)
+
+func collidingConfigs() []*models.ProxyConfig {
+	node := func(name, sub string) *models.ProxyConfig {
+		return &models.ProxyConfig{Protocol: "vless", Server: "1.1.1.1", Port: 443, UUID: "11111111-1111-1111-1111-111111111111", Name: name, SubName: sub}
+	}
+	other := &models.ProxyConfig{Protocol: "vless", Server: "2.2.2.2", Port: 443, UUID: "22222222-2222-2222-2222-222222222222", Name: "other"}
+	return []*models.ProxyConfig{node("a", "sub1"), other, node("b", "sub2"), node("c", "sub3")}
+}
+
+func TestResolveStableIDCollisions(t *testing.T) {
+	kept, collisions := ResolveStableIDCollisions(collidingConfigs(), CollisionWarn)
+	if len(kept) != 4 || len(collisions) != 1 {
+		t.Fatalf("warn: expected 4 proxies and 1 collision, got %d and %d", len(kept), len(collisions))
+	}
+	if c := collisions[0]; c.StableID != kept[0].StableID || len(c.Names) != 3 || c.Names[2] != "c" || c.Subscriptions[1] != "sub2" {
+		t.Fatalf("warn: unexpected collision %+v", c)
+	}
+
+	kept, _ = ResolveStableIDCollisions(collidingConfigs(), CollisionDrop)
+	if len(kept) != 2 || kept[0].Name != "a" || kept[1].Name != "other" {
+		t.Fatalf("drop: expected first duplicate and other node, got %+v", kept)
+	}
+
+	kept, collisions = ResolveStableIDCollisions(collidingConfigs(), CollisionSuffix)
+	base := kept[0].StableID
+	if len(kept) != 4 || kept[2].StableID != base+"-2" || kept[3].StableID != base+"-3" {
+		t.Fatalf("suffix: unexpected stable IDs %s, %s, %s", base, kept[2].StableID, kept[3].StableID)
+	}
+	if len(collisions) != 1 || collisions[0].StableID != base {
+		t.Fatalf("suffix: collision should report the original ID, got %+v", collisions)
+	}
+}
+
+func TestResolveCollisionsRecordsCollisions(t *testing.T) {
+	old := config.CLIConfig.Proxy
+	t.Cleanup(func() {
+		config.CLIConfig.Proxy = old
+		recordCollisions(nil)
+	})
+	config.CLIConfig.Proxy.StableIDCollisions = "drop"
+
+	kept := ResolveCollisions(collidingConfigs())
+	if len(kept) != 2 {
+		t.Fatalf("expected duplicates to be dropped, got %d proxies", len(kept))
+	}
+	if got := StableIDCollisions(); len(got) != 1 || len(got[0].Names) != 3 {
+		t.Fatalf("expected the collision to be recorded, got %+v", got)
+	}
+}
+
+func TestInitializeConfigurationResolvesCollisionsAfterDomains(t *testing.T) {
+	stubLookupIP(t, map[string][]net.IP{
+		"node.example.com": {net.ParseIP("203.0.113.10")},
+	})
+	oldProxy, oldSub, oldXray := config.CLIConfig.Proxy, config.CLIConfig.Subscription, config.CLIConfig.Xray
+	t.Cleanup(func() {
+		config.CLIConfig.Proxy, config.CLIConfig.Subscription, config.CLIConfig.Xray = oldProxy, oldSub, oldXray
+		recordCollisions(nil)
+	})
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "proxies.txt")
+	link := "vless://11111111-1111-1111-1111-111111111111@node.example.com:443?type=tcp&security=none"
+	if err := os.WriteFile(source, []byte(link+"#a\n"+link+"#b\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	config.CLIConfig.Subscription.URLs = []string{"file://" + source}
+	config.CLIConfig.Subscription.StartupRetries = 0
+	config.CLIConfig.Proxy.ResolveDomains = true
+	config.CLIConfig.Proxy.ResolveMode = "first"
+	config.CLIConfig.Proxy.StableIDCollisions = "suffix"
+	config.CLIConfig.Xray.StartPort = 10000
+
+	configs, err := InitializeConfiguration(filepath.Join(dir, "xray_config.json"), "test")
+	if err != nil {
+		t.Fatalf("InitializeConfiguration: %v", err)
+	}
+	if len(*configs) != 2 {
+		t.Fatalf("expected 2 proxies, got %d", len(*configs))
+	}
+	first, second := (*configs)[0], (*configs)[1]
+	if first.ResolvedServer != "203.0.113.10" {
+		t.Fatalf("expected the domain to be resolved, got %q", first.ResolvedServer)
+	}
+	if second.StableID != first.StableID+"-2" {
+		t.Fatalf("domain resolution must not undo the collision suffix, got %q and %q", first.StableID, second.StableID)
+	}
+}
+
+func TestParseCollisionMode(t *testing.T) {
+	if mode, err := ParseCollisionMode(""); err != nil || mode != CollisionWarn {
+		t.Fatalf("expected empty to mean warn, got %q (%v)", mode, err)
+	}
+	if mode, err := ParseCollisionMode(" Suffix "); err != nil || mode != CollisionSuffix {
+		t.Fatalf("expected suffix, got %q (%v)", mode, err)
+	}
+	if _, err := ParseCollisionMode("rename"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,87 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"xray-checker/logger"
+)
+
+// SourceEnvPrefix is the prefix a variable needs to be expanded into a
+// subscription source. Limiting expansion keeps unrelated secrets in the
+// environment, such as cloud credentials, out of URLs that API users can add
+// and read back.
+const SourceEnvPrefix = "XRAY_CHECKER_SUB_"
+
+// envPlaceholderRe matches only braced ${NAME} placeholders with conventional
+// variable names, so a bare "$" that may legitimately appear in a URL is left
+// untouched.
+var envPlaceholderRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// redactedQueryValue replaces query values hidden by RedactURLQuery.
+const redactedQueryValue = "[redacted]"
+
+// ExpandSourceEnv substitutes ${NAME} placeholders in a subscription source
+// with environment variable values. Only SourceEnvPrefix variables may be
+// referenced. A reference to an unset variable is an error rather than an
+// empty substitution that would yield a malformed URL.
+func ExpandSourceEnv(source string) (string, error) {
+	matches := envPlaceholderRe.FindAllStringSubmatch(source, -1)
+	if len(matches) == 0 {
+		return source, nil
+	}
+
+	for _, m := range matches {
+		if !strings.HasPrefix(m[1], SourceEnvPrefix) {
+			return "", fmt.Errorf("environment variable %s referenced in subscription source is not allowed, only %s* variables are expanded", m[1], SourceEnvPrefix)
+		}
+		if _, ok := os.LookupEnv(m[1]); !ok {
+			return "", fmt.Errorf("environment variable %s referenced in subscription source is not set", m[1])
+		}
+	}
+
+	expanded := envPlaceholderRe.ReplaceAllStringFunc(source, func(placeholder string) string {
+		return os.Getenv(envPlaceholderRe.FindStringSubmatch(placeholder)[1])
+	})
+	// Log the template rather than the result so secrets stay out of logs.
+	logger.Debug("Expanded environment variables in subscription source %s", source)
+	return expanded, nil
+}
+
+// fetchError drops the request URL from HTTP client errors, which would
+// otherwise carry expanded secrets into stored source errors and API
+// responses.
+func fetchError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// RedactURLQuery masks the query values of a source URL, such as the ?token=
+// of a private raw link, so it can be shown in the API and in errors. Values
+// that are only a ${NAME} placeholder carry no secret and are kept.
+func RedactURLQuery(raw string) string {
+	base, query, ok := strings.Cut(raw, "?")
+	if !ok || query == "" {
+		return raw
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if value == "" || envPlaceholderRe.FindString(value) == value {
+			continue
+		}
+		params[i] = key + "=" + redactedQueryValue
+	}
+	redacted := base + "?" + strings.Join(params, "&")
+	if hasFragment {
+		redacted += "#" + fragment
+	}
+	return redacted
+}
@@ -0,0 +1,166 @@
+package subscription
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandSourceEnv(t *testing.T) {
+	t.Setenv("XRAY_CHECKER_SUB_TEST_TOKEN", "s3cret")
+
+	got, err := ExpandSourceEnv("https://host/sub?token=${XRAY_CHECKER_SUB_TEST_TOKEN}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "https://host/sub?token=s3cret" {
+		t.Fatalf("unexpected expansion: %q", got)
+	}
+
+	plain := "https://host/sub?price=$5&token=$XRAY_CHECKER_SUB_TEST_TOKEN"
+	got, err = ExpandSourceEnv(plain)
+	if err != nil || got != plain {
+		t.Fatalf("expected unbraced placeholders to be left as is, got %q, %v", got, err)
+	}
+}
+
+func TestExpandSourceEnvUnsetVariable(t *testing.T) {
+	_, err := ExpandSourceEnv("https://host/sub?token=${XRAY_CHECKER_SUB_TEST_UNSET}")
+	if err == nil {
+		t.Fatal("expected error for unset variable")
+	}
+	if !strings.Contains(err.Error(), "XRAY_CHECKER_SUB_TEST_UNSET") {
+		t.Fatalf("expected error to name the variable, got %v", err)
+	}
+
+	if _, err := normalizeRemoteURL("https://host/sub?token=${XRAY_CHECKER_SUB_TEST_UNSET}"); err == nil {
+		t.Fatal("expected normalizeRemoteURL to reject an unset variable")
+	}
+	if _, _, err := ReadFromSource("https://host/sub?token=${XRAY_CHECKER_SUB_TEST_UNSET}"); err == nil || !strings.Contains(err.Error(), "XRAY_CHECKER_SUB_TEST_UNSET") {
+		t.Fatalf("expected ReadFromSource to report the unset variable, got %v", err)
+	}
+}
+
+func TestExpandSourceEnvRejectsOtherVariables(t *testing.T) {
+	t.Setenv("XC_TEST_CLOUD_SECRET", "s3cret")
+
+	_, err := ExpandSourceEnv("https://host/sub?token=${XC_TEST_CLOUD_SECRET}")
+	if err == nil || !strings.Contains(err.Error(), SourceEnvPrefix) {
+		t.Fatalf("expected variables without the %s prefix to be rejected, got %v", SourceEnvPrefix, err)
+	}
+	if _, err := normalizeRemoteURL("https://raw.githubusercontent.com/o/r/main/sub.txt?token=${XC_TEST_CLOUD_SECRET}"); err == nil {
+		t.Fatal("expected normalizeRemoteURL to reject a variable without the prefix")
+	}
+}
+
+func TestRemoteSourceKeepsEnvTemplate(t *testing.T) {
+	t.Setenv("XRAY_CHECKER_SUB_TEST_TOKEN", "s3cret")
+	var gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.URL.Query().Get("token")
+		w.Write([]byte("vless://11111111-1111-1111-1111-111111111111@1.1.1.1:443#node\n"))
+	}))
+	defer server.Close()
+
+	// raw.githubusercontent.com keeps its query, which is where panels put
+	// the token.
+	normalized, err := normalizeRemoteURL("https://raw.githubusercontent.com/o/r/main/sub.txt?token=${XRAY_CHECKER_SUB_TEST_TOKEN}")
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if normalized != "https://raw.githubusercontent.com/o/r/main/sub.txt?token=${XRAY_CHECKER_SUB_TEST_TOKEN}" {
+		t.Fatalf("expected the placeholder to be kept, got %q", normalized)
+	}
+
+	dir := t.TempDir()
+	manager, err := NewRemoteManager(filepath.Join(dir, "state.json"), dir)
+	if err != nil {
+		t.Fatalf("NewRemoteManager: %v", err)
+	}
+	src := &RemoteSource{URL: server.URL + "/sub?token=${XRAY_CHECKER_SUB_TEST_TOKEN}", FilePath: filepath.Join(dir, "sub.txt")}
+	if !manager.download(src, true) {
+		t.Fatalf("download failed: %s", src.Error)
+	}
+	if gotToken != "s3cret" {
+		t.Fatalf("expected the token to be expanded at fetch time, got %q", gotToken)
+	}
+	manager.state.Sources = append(manager.state.Sources, *src)
+	if err := manager.saveLocked(); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := os.ReadFile(filepath.Join(dir, "state.json"))
+	if strings.Contains(string(state), "s3cret") {
+		t.Fatalf("the expanded secret must not be persisted:\n%s", state)
+	}
+}
+
+func TestNormalizeRemoteURLKeepsPlaceholderQueryOnAnyHost(t *testing.T) {
+	t.Setenv("XRAY_CHECKER_SUB_TEST_TOKEN", "s3cret")
+	var gotToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.URL.Query().Get("token")
+		w.Write([]byte("vless://11111111-1111-1111-1111-111111111111@1.1.1.1:443#node\n"))
+	}))
+	defer server.Close()
+
+	raw := server.URL + "/sub?token=${XRAY_CHECKER_SUB_TEST_TOKEN}#name"
+	normalized, err := normalizeRemoteURL(raw)
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if want := server.URL + "/sub?token=${XRAY_CHECKER_SUB_TEST_TOKEN}"; normalized != want {
+		t.Fatalf("expected the placeholder query to survive normalization, got %q want %q", normalized, want)
+	}
+
+	dir := t.TempDir()
+	manager, err := NewRemoteManager(filepath.Join(dir, "state.json"), dir)
+	if err != nil {
+		t.Fatalf("NewRemoteManager: %v", err)
+	}
+	src := &RemoteSource{URL: normalized, FilePath: filepath.Join(dir, "sub.txt")}
+	if !manager.download(src, true) {
+		t.Fatalf("download failed: %s", src.Error)
+	}
+	if gotToken != "s3cret" {
+		t.Fatalf("expected the placeholder to be expanded at fetch time, got %q", gotToken)
+	}
+}
+
+func TestRedactURLQuery(t *testing.T) {
+	cases := map[string]string{
+		"https://raw.githubusercontent.com/o/r/main/sub.txt?token=GHSAT0AAA": "https://raw.githubusercontent.com/o/r/main/sub.txt?token=[redacted]",
+		"https://host/sub?token=${XRAY_CHECKER_SUB_TOKEN}&flag":              "https://host/sub?token=${XRAY_CHECKER_SUB_TOKEN}&flag",
+		"https://host/sub?a=1&b=${XRAY_CHECKER_SUB_B}x#name":                 "https://host/sub?a=[redacted]&b=[redacted]#name",
+		"https://host/sub": "https://host/sub",
+	}
+	for in, want := range cases {
+		if got := RedactURLQuery(in); got != want {
+			t.Errorf("RedactURLQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRemoteStateFileIsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(statePath, []byte(`{"intervalSeconds":300}`), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	manager, err := NewRemoteManager(statePath, dir)
+	if err != nil {
+		t.Fatalf("NewRemoteManager: %v", err)
+	}
+	if err := manager.saveLocked(); err != nil {
+		t.Fatalf("saveLocked: %v", err)
+	}
+	info, err := os.Stat(statePath)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("expected the state file to be written 0600, got %o", perm)
+	}
+}
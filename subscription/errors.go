@@ -2,20 +2,88 @@ package subscription
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"strings"
 )
 
+var (
+	// ErrSourceNotFound is returned when a file or folder source does not exist.
+	ErrSourceNotFound = errors.New("source not found")
+	// ErrEmptySource is returned when a source holds no valid proxy configurations.
+	ErrEmptySource = errors.New("no valid proxy configurations found")
+	// ErrFetchFailed is returned when a URL source could not be downloaded.
+	ErrFetchFailed = errors.New("failed to fetch URL content")
+)
+
+// sourceReadError wraps a read error of a local source, marking missing paths
+// with ErrSourceNotFound.
+func sourceReadError(what string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %w: %w", what, ErrSourceNotFound, err)
+	}
+	return fmt.Errorf("%s: %w", what, err)
+}
+
+// httpStatusError reports a URL source answering with a non-200 status.
+type httpStatusError struct {
+	code int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.code)
+}
+
+// isTransientFetchError reports whether err may go away on its own: a network
+// error or a 5xx answer. Client errors, parse errors and empty sources are
+// not retried. Joined errors are transient when any of them is.
+func isTransientFetchError(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if isTransientFetchError(e) {
+				return true
+			}
+		}
+		return false
+	}
+	var status *httpStatusError
+	if errors.As(err, &status) {
+		return status.code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// multiSourceError reports that every subscription failed. It unwraps to the
+// individual source errors so typed checks see all of them.
+type multiSourceError struct {
+	errs []error
+}
+
+func (e *multiSourceError) Error() string {
+	return fmt.Sprintf("failed to fetch any subscription: %v", e.errs)
+}
+
+func (e *multiSourceError) Unwrap() []error {
+	return e.errs
+}
+
 // ShouldTreatAsEmptyResult returns true when source errors should be interpreted
 // as "no active proxies" rather than a fatal runtime condition.
 func ShouldTreatAsEmptyResult(err error) bool {
 	if err == nil {
 		return false
 	}
-	if errors.Is(err, os.ErrNotExist) {
+	if errors.Is(err, ErrEmptySource) || errors.Is(err, ErrSourceNotFound) || errors.Is(err, os.ErrNotExist) {
 		return true
 	}
+	if errors.Is(err, ErrFetchFailed) {
+		return false
+	}
 
+	// Fallback for errors that lost their type along the way.
 	msg := strings.ToLower(err.Error())
 	if strings.Contains(msg, "no valid proxy configurations found") {
 		return true
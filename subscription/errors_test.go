@@ -2,7 +2,9 @@ package subscription
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -42,6 +44,26 @@ func TestShouldTreatAsEmptyResult(t *testing.T) {
 			err:  errors.New("request failed: 500"),
 			want: false,
 		},
+		{
+			name: "typed empty source",
+			err:  fmt.Errorf("%w in folder", ErrEmptySource),
+			want: true,
+		},
+		{
+			name: "typed missing source",
+			err:  sourceReadError("failed to read file", os.ErrNotExist),
+			want: true,
+		},
+		{
+			name: "typed fetch failure",
+			err:  fmt.Errorf("%w: %w", ErrFetchFailed, errors.New("HTTP 502")),
+			want: false,
+		},
+		{
+			name: "all sources failed with one empty",
+			err:  &multiSourceError{errs: []error{fmt.Errorf("a: %w", ErrFetchFailed), fmt.Errorf("b: %w", ErrEmptySource)}},
+			want: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -50,3 +72,17 @@ func TestShouldTreatAsEmptyResult(t *testing.T) {
 		}
 	}
 }
+
+func TestReadFromSourceTypedErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, _, err := ReadFromSource("file://" + filepath.Join(dir, "missing.txt"))
+	if !errors.Is(err, ErrSourceNotFound) {
+		t.Fatalf("expected ErrSourceNotFound for a missing file, got %v", err)
+	}
+
+	_, _, err = ReadFromSource("folder://" + dir)
+	if !errors.Is(err, ErrEmptySource) {
+		t.Fatalf("expected ErrEmptySource for an empty folder, got %v", err)
+	}
+}
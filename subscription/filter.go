@@ -0,0 +1,185 @@
+package subscription
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"xray-checker/config"
+	"xray-checker/logger"
+	"xray-checker/models"
+)
+
+// FilterConfigs applies the configured load-time filters to freshly read
+// configs. Filtered proxies never reach xray, the checker or the API.
+func FilterConfigs(configs []*models.ProxyConfig) []*models.ProxyConfig {
+	kept, excluded := FilterProtocols(configs, config.CLIConfig.Proxy.IncludeProtocols, config.CLIConfig.Proxy.ExcludeProtocols)
+	if len(excluded) > 0 {
+		logger.Info("Excluded %d of %d proxies by protocol filter (%s)", len(configs)-len(kept), len(configs), formatCounts(excluded))
+	}
+
+	if maxProxies := config.CLIConfig.Proxy.MaxProxies; maxProxies > 0 && len(kept) > maxProxies {
+		strategy, err := ParseSampleStrategy(config.CLIConfig.Proxy.MaxStrategy)
+		if err != nil {
+			logger.Warn("%v, using %s", err, SampleFirst)
+			strategy = SampleFirst
+		}
+		logger.Info("Loading %d of %d proxies (--proxy-max, %s)", maxProxies, len(kept), strategy)
+		kept = SampleConfigs(kept, maxProxies, strategy)
+	}
+	return kept
+}
+
+// ResolveCollisions applies the configured stable ID collision mode, records
+// the collisions for the API and logs them. Domain resolution assigns new
+// stable IDs, so this runs on the final list, right before the configs are
+// prepared for xray.
+func ResolveCollisions(configs []*models.ProxyConfig) []*models.ProxyConfig {
+	mode, err := ParseCollisionMode(config.CLIConfig.Proxy.StableIDCollisions)
+	if err != nil {
+		logger.Warn("%v, using %s", err, CollisionWarn)
+		mode = CollisionWarn
+	}
+	kept, collisions := ResolveStableIDCollisions(configs, mode)
+	recordCollisions(collisions)
+	for _, collision := range collisions {
+		logger.Warn("Stable ID %s is shared by %d proxies (%s), %s", collision.StableID, len(collision.Names),
+			strings.Join(collision.Names, ", "), collisionAction(mode))
+	}
+	return kept
+}
+
+func collisionAction(mode CollisionMode) string {
+	switch mode {
+	case CollisionSuffix:
+		return "suffixing later duplicates"
+	case CollisionDrop:
+		return "dropping later duplicates"
+	default:
+		return "they will share status and metrics"
+	}
+}
+
+// SampleStrategy selects which proxies are loaded when more than --proxy-max
+// are available.
+type SampleStrategy string
+
+const (
+	// SampleFirst loads the first proxies in subscription order.
+	SampleFirst SampleStrategy = "first"
+	// SampleRandom loads a random selection on every load.
+	SampleRandom SampleStrategy = "random"
+	// SampleRoundRobin loads the next window of proxies on every load, so all
+	// of them get checked over successive subscription updates.
+	SampleRoundRobin SampleStrategy = "round-robin"
+)
+
+// ParseSampleStrategy maps a config value to a SampleStrategy. Empty means
+// SampleFirst.
+func ParseSampleStrategy(value string) (SampleStrategy, error) {
+	switch strategy := SampleStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "":
+		return SampleFirst, nil
+	case SampleFirst, SampleRandom, SampleRoundRobin:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown max proxies strategy %q, expected first, random or round-robin", value)
+	}
+}
+
+// sampleOffset is where the next SampleRoundRobin window starts. It advances
+// once per load or subscription update, not per check iteration, and is not
+// persisted, so a restart starts over from the first window.
+var (
+	sampleOffset   int
+	sampleOffsetMu sync.Mutex
+)
+
+// shuffle is swapped out in tests.
+var shuffle = rand.Shuffle
+
+// SampleConfigs returns at most limit configs chosen by strategy. The chosen
+// configs keep their relative order, so ports stay stable within a window.
+func SampleConfigs(configs []*models.ProxyConfig, limit int, strategy SampleStrategy) []*models.ProxyConfig {
+	n := len(configs)
+	if limit <= 0 || n <= limit {
+		return configs
+	}
+
+	picked := make([]int, 0, limit)
+	switch strategy {
+	case SampleRandom:
+		order := make([]int, n)
+		for i := range order {
+			order[i] = i
+		}
+		shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+		picked = append(picked, order[:limit]...)
+	case SampleRoundRobin:
+		sampleOffsetMu.Lock()
+		start := sampleOffset % n
+		sampleOffset = (start + limit) % n
+		sampleOffsetMu.Unlock()
+		for i := 0; i < limit; i++ {
+			picked = append(picked, (start+i)%n)
+		}
+	default:
+		for i := 0; i < limit; i++ {
+			picked = append(picked, i)
+		}
+	}
+
+	sort.Ints(picked)
+	out := make([]*models.ProxyConfig, 0, limit)
+	for _, i := range picked {
+		out = append(out, configs[i])
+	}
+	return out
+}
+
+// FilterProtocols keeps configs whose protocol is in include (all when include
+// is empty) and not in exclude. Protocols compare case-insensitively. The
+// second result counts dropped configs per protocol.
+func FilterProtocols(configs []*models.ProxyConfig, include, exclude []string) ([]*models.ProxyConfig, map[string]int) {
+	includeSet := protocolSet(include)
+	excludeSet := protocolSet(exclude)
+	if len(includeSet) == 0 && len(excludeSet) == 0 {
+		return configs, nil
+	}
+
+	kept := make([]*models.ProxyConfig, 0, len(configs))
+	excluded := make(map[string]int)
+	for _, cfg := range configs {
+		protocol := strings.ToLower(cfg.Protocol)
+		if (len(includeSet) > 0 && !includeSet[protocol]) || excludeSet[protocol] {
+			excluded[protocol]++
+			continue
+		}
+		kept = append(kept, cfg)
+	}
+	return kept, excluded
+}
+
+func protocolSet(protocols []string) map[string]bool {
+	set := make(map[string]bool, len(protocols))
+	for _, p := range protocols {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			set[p] = true
+		}
+	}
+	return set
+}
+
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %d", k, counts[k]))
+	}
+	return strings.Join(parts, ", ")
+}
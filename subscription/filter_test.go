@@ -0,0 +1,146 @@
+package subscription
+
+import (
+	"testing"
+	"xray-checker/config"
+	"xray-checker/models"
+)
+
+func mixedProtocolConfigs() []*models.ProxyConfig {
+	return []*models.ProxyConfig{
+		{Protocol: "vless", Name: "v1"},
+		{Protocol: "trojan", Name: "t1"},
+		{Protocol: "shadowsocks", Name: "s1"},
+		{Protocol: "Trojan", Name: "t2"},
+	}
+}
+
+func TestFilterConfigsExcludesTrojan(t *testing.T) {
+	old := config.CLIConfig.Proxy
+	t.Cleanup(func() { config.CLIConfig.Proxy = old })
+	config.CLIConfig.Proxy.ExcludeProtocols = []string{"trojan"}
+
+	kept := FilterConfigs(mixedProtocolConfigs())
+	if len(kept) != 2 || kept[0].Name != "v1" || kept[1].Name != "s1" {
+		t.Fatalf("unexpected configs after excluding trojan: %+v", kept)
+	}
+}
+
+func TestFilterProtocolsIncludeAndExclude(t *testing.T) {
+	kept, excluded := FilterProtocols(mixedProtocolConfigs(), []string{"vless", " TROJAN "}, []string{"vless"})
+	if len(kept) != 2 || kept[0].Name != "t1" || kept[1].Name != "t2" {
+		t.Fatalf("unexpected kept configs: %+v", kept)
+	}
+	if excluded["vless"] != 1 || excluded["shadowsocks"] != 1 {
+		t.Fatalf("unexpected exclusion counts: %v", excluded)
+	}
+
+	all := mixedProtocolConfigs()
+	if kept, excluded := FilterProtocols(all, nil, nil); len(kept) != len(all) || excluded != nil {
+		t.Fatalf("no filters must keep everything: %d kept, %v", len(kept), excluded)
+	}
+}
+
+func numberedConfigs(n int) []*models.ProxyConfig {
+	configs := make([]*models.ProxyConfig, n)
+	for i := range configs {
+		configs[i] = &models.ProxyConfig{Protocol: "vless", Name: string(rune('a' + i))}
+	}
+	return configs
+}
+
+func sampledNames(configs []*models.ProxyConfig) string {
+	var names string
+	for _, cfg := range configs {
+		names += cfg.Name
+	}
+	return names
+}
+
+func TestSampleConfigsFirst(t *testing.T) {
+	if got := sampledNames(SampleConfigs(numberedConfigs(5), 3, SampleFirst)); got != "abc" {
+		t.Fatalf("first strategy picked %q, want abc", got)
+	}
+	if got := sampledNames(SampleConfigs(numberedConfigs(2), 3, SampleFirst)); got != "ab" {
+		t.Fatalf("below the cap everything is kept, got %q", got)
+	}
+}
+
+func TestSampleConfigsRandom(t *testing.T) {
+	prev := shuffle
+	t.Cleanup(func() { shuffle = prev })
+	// Reverse instead of shuffling so the pick is predictable.
+	shuffle = func(n int, swap func(i, j int)) {
+		for i := 0; i < n/2; i++ {
+			swap(i, n-1-i)
+		}
+	}
+
+	if got := sampledNames(SampleConfigs(numberedConfigs(5), 2, SampleRandom)); got != "de" {
+		t.Fatalf("random strategy picked %q, want de in subscription order", got)
+	}
+}
+
+func TestSampleConfigsRoundRobinRotates(t *testing.T) {
+	sampleOffsetMu.Lock()
+	prev := sampleOffset
+	sampleOffset = 0
+	sampleOffsetMu.Unlock()
+	t.Cleanup(func() {
+		sampleOffsetMu.Lock()
+		sampleOffset = prev
+		sampleOffsetMu.Unlock()
+	})
+
+	configs := numberedConfigs(5)
+	var got []string
+	for i := 0; i < 3; i++ {
+		got = append(got, sampledNames(SampleConfigs(configs, 2, SampleRoundRobin)))
+	}
+	want := []string{"ab", "cd", "ae"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("round-robin windows = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFilterConfigsRoundRobinAdvancesPerLoad(t *testing.T) {
+	sampleOffsetMu.Lock()
+	prev := sampleOffset
+	sampleOffset = 0
+	sampleOffsetMu.Unlock()
+	old := config.CLIConfig.Proxy
+	t.Cleanup(func() {
+		sampleOffsetMu.Lock()
+		sampleOffset = prev
+		sampleOffsetMu.Unlock()
+		config.CLIConfig.Proxy = old
+	})
+	config.CLIConfig.Proxy.MaxProxies = 2
+	config.CLIConfig.Proxy.MaxStrategy = "round-robin"
+
+	// Each load or subscription update filters the list once and moves to
+	// the next window; nothing else advances it.
+	configs := numberedConfigs(4)
+	if got := sampledNames(FilterConfigs(configs)); got != "ab" {
+		t.Fatalf("first load kept %q, want ab", got)
+	}
+	if got := sampledNames(FilterConfigs(configs)); got != "cd" {
+		t.Fatalf("first update kept %q, want cd", got)
+	}
+	if got := sampledNames(FilterConfigs(configs)); got != "ab" {
+		t.Fatalf("second update kept %q, want ab", got)
+	}
+}
+
+func TestFilterConfigsCapsProxies(t *testing.T) {
+	old := config.CLIConfig.Proxy
+	t.Cleanup(func() { config.CLIConfig.Proxy = old })
+	config.CLIConfig.Proxy.MaxProxies = 2
+	config.CLIConfig.Proxy.MaxStrategy = "first"
+
+	if got := sampledNames(FilterConfigs(numberedConfigs(4))); got != "ab" {
+		t.Fatalf("FilterConfigs with --proxy-max 2 kept %q", got)
+	}
+}
@@ -0,0 +1,49 @@
+package subscription
+
+import (
+	"sync"
+	"time"
+)
+
+// maxUpdateHistory bounds how many update events are kept in memory.
+const maxUpdateHistory = 100
+
+// UpdateEvent records one applied subscription update.
+type UpdateEvent struct {
+	Time          time.Time `json:"time"`
+	Total         int       `json:"total"`
+	Added         int       `json:"added"`
+	Removed       int       `json:"removed"`
+	Changed       int       `json:"changed"`
+	AddedNames    []string  `json:"addedNames,omitempty"`
+	RemovedNames  []string  `json:"removedNames,omitempty"`
+	XrayRestarted bool      `json:"xrayRestarted"`
+	Error         string    `json:"error,omitempty"`
+}
+
+var (
+	updateHistory   []UpdateEvent
+	updateHistoryMu sync.RWMutex
+)
+
+// RecordUpdate appends an event to the update history, dropping the oldest
+// one once the history is full.
+func RecordUpdate(event UpdateEvent) {
+	updateHistoryMu.Lock()
+	defer updateHistoryMu.Unlock()
+	if len(updateHistory) >= maxUpdateHistory {
+		updateHistory = append(updateHistory[:0], updateHistory[len(updateHistory)-maxUpdateHistory+1:]...)
+	}
+	updateHistory = append(updateHistory, event)
+}
+
+// UpdateHistory returns the recorded update events, newest first.
+func UpdateHistory() []UpdateEvent {
+	updateHistoryMu.RLock()
+	defer updateHistoryMu.RUnlock()
+	out := make([]UpdateEvent, len(updateHistory))
+	for i, event := range updateHistory {
+		out[len(updateHistory)-1-i] = event
+	}
+	return out
+}
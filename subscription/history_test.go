@@ -0,0 +1,34 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateHistoryIsBoundedNewestFirst(t *testing.T) {
+	updateHistoryMu.Lock()
+	prev := updateHistory
+	updateHistory = nil
+	updateHistoryMu.Unlock()
+	t.Cleanup(func() {
+		updateHistoryMu.Lock()
+		updateHistory = prev
+		updateHistoryMu.Unlock()
+	})
+
+	start := time.Now()
+	for i := 0; i < maxUpdateHistory+5; i++ {
+		RecordUpdate(UpdateEvent{Time: start.Add(time.Duration(i) * time.Second), Total: i})
+	}
+
+	history := UpdateHistory()
+	if len(history) != maxUpdateHistory {
+		t.Fatalf("expected history capped at %d, got %d", maxUpdateHistory, len(history))
+	}
+	if history[0].Total != maxUpdateHistory+4 {
+		t.Fatalf("expected newest event first, got total %d", history[0].Total)
+	}
+	if last := history[len(history)-1]; last.Total != 5 {
+		t.Fatalf("expected the oldest events to be dropped, last total %d", last.Total)
+	}
+}
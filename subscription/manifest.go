@@ -0,0 +1,169 @@
+package subscription
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"xray-checker/metrics"
+)
+
+// ManifestEntry describes one remote source in a subscription manifest.
+// Interval is in seconds; zero follows the global update interval. A missing
+// Enabled field means the source is enabled.
+type ManifestEntry struct {
+	URL      string            `json:"url"`
+	Name     string            `json:"name,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Interval int               `json:"interval,omitempty"`
+	Enabled  *bool             `json:"enabled,omitempty"`
+}
+
+func (e ManifestEntry) enabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// LoadManifest reads a manifest file. See ParseManifest for accepted formats.
+func LoadManifest(path string) ([]ManifestEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	entries, err := ParseManifest(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// ParseManifest decodes a JSON array of entries when the file has a .json
+// extension or its content starts with '['. Anything else is read as a plain
+// list of URLs, one per line, with blank lines and # comments ignored.
+func ParseManifest(path string, data []byte) ([]ManifestEntry, error) {
+	trimmed := bytes.TrimSpace(data)
+	if strings.EqualFold(filepath.Ext(path), ".json") || bytes.HasPrefix(trimmed, []byte("[")) {
+		var entries []ManifestEntry
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+		for i, entry := range entries {
+			if strings.TrimSpace(entry.URL) == "" {
+				return nil, fmt.Errorf("entry %d: url is required", i)
+			}
+			if entry.Interval < 0 {
+				return nil, fmt.Errorf("entry %d: interval must not be negative", i)
+			}
+		}
+		return entries, nil
+	}
+
+	var entries []ManifestEntry
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, ManifestEntry{URL: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ApplyManifest reconciles the source list to the manifest, in manifest
+// order. Existing sources keep their download state but take their metadata
+// from the manifest. Enabled sources without a downloaded file are fetched;
+// disabled sources stay tracked but their files are removed so their proxies
+// drop out. Sources missing from the manifest are removed.
+func (m *RemoteManager) ApplyManifest(entries []ManifestEntry) (RemoteState, error) {
+	type wantedSource struct {
+		url   string
+		entry ManifestEntry
+	}
+	wanted := make([]wantedSource, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		u, err := normalizeRemoteURL(entry.URL)
+		if err != nil {
+			return RemoteState{}, fmt.Errorf("invalid URL %q: %v", entry.URL, err)
+		}
+		if seen[u] {
+			return RemoteState{}, fmt.Errorf("duplicate URL %q", RedactURLQuery(entry.URL))
+		}
+		seen[u] = true
+		wanted = append(wanted, wantedSource{url: u, entry: entry})
+	}
+
+	m.mu.Lock()
+	existing := make(map[string]RemoteSource, len(m.state.Sources))
+	for _, src := range m.state.Sources {
+		existing[src.URL] = src
+		if !seen[src.URL] {
+			_ = os.Remove(src.FilePath)
+			metrics.DeleteSubscriptionSource(src.ID)
+		}
+	}
+
+	next := make([]RemoteSource, 0, len(wanted))
+	var pending []RemoteSource
+	for _, w := range wanted {
+		src, ok := existing[w.url]
+		if !ok {
+			id := hashURL(w.url)
+			fileName := buildRemoteFileName(w.url, id)
+			src = RemoteSource{
+				ID:       id,
+				URL:      w.url,
+				FileName: fileName,
+				FilePath: filepath.Join(m.downloadDir, fileName),
+			}
+		}
+		src.Name = strings.TrimSpace(w.entry.Name)
+		src.Headers = copyHeaders(w.entry.Headers)
+		src.IntervalSeconds = w.entry.Interval
+		src.Disabled = !w.entry.enabled()
+
+		if src.Disabled {
+			_ = os.Remove(src.FilePath)
+		} else if _, err := os.Stat(src.FilePath); err != nil {
+			pending = append(pending, src)
+		}
+		next = append(next, src)
+	}
+
+	m.state.Sources = next
+	if err := m.saveLocked(); err != nil {
+		m.mu.Unlock()
+		return RemoteState{}, err
+	}
+	m.mu.Unlock()
+
+	for i := range pending {
+		m.download(&pending[i], true)
+	}
+	m.mergeDownloaded(pending)
+
+	m.mu.Lock()
+	err := m.saveLocked()
+	m.mu.Unlock()
+	if err != nil {
+		return RemoteState{}, err
+	}
+	return m.GetState(), nil
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
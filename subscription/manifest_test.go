@@ -0,0 +1,134 @@
+package subscription
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseManifestFormats(t *testing.T) {
+	entries, err := ParseManifest("sources.json", []byte(`[
+		{"url": "https://example.com/a.txt", "name": "A", "headers": {"X-Token": "t"}, "interval": 60},
+		{"url": "https://example.com/b.txt", "enabled": false}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Name != "A" || entries[0].Headers["X-Token"] != "t" || entries[0].Interval != 60 || !entries[0].enabled() {
+		t.Fatalf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].enabled() {
+		t.Fatal("expected second entry to be disabled")
+	}
+
+	entries, err = ParseManifest("sources.txt", []byte("# comment\nhttps://example.com/a.txt\n\nhttps://example.com/b.txt\n"))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].URL != "https://example.com/b.txt" || !entries[1].enabled() {
+		t.Fatalf("unexpected plain list entries: %+v", entries)
+	}
+
+	if _, err := ParseManifest("sources", []byte(`[{"name": "no url"}]`)); err == nil {
+		t.Fatal("expected entry without url to be rejected")
+	}
+}
+
+func TestApplyManifestEnablesAndDisablesSources(t *testing.T) {
+	var fetches int32
+	var gotHeader atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if v := r.Header.Get("X-Token"); v != "" {
+			gotHeader.Store(v)
+		}
+		_, _ = w.Write([]byte("vmess://example"))
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	downloadDir := filepath.Join(root, "subscriptions")
+	if err := os.MkdirAll(downloadDir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	manager := &RemoteManager{
+		statePath:   filepath.Join(root, ".remote_sources.json"),
+		downloadDir: downloadDir,
+		client:      server.Client(),
+		state:       RemoteState{IntervalSeconds: 300},
+	}
+
+	off := false
+	on := true
+	activeURL := server.URL + "/active.txt"
+	pausedURL := server.URL + "/paused.txt"
+	state, err := manager.ApplyManifest([]ManifestEntry{
+		{URL: activeURL, Name: "Active", Headers: map[string]string{"X-Token": "secret"}},
+		{URL: pausedURL, Enabled: &off},
+	})
+	if err != nil {
+		t.Fatalf("ApplyManifest failed: %v", err)
+	}
+	if len(state.Sources) != 2 {
+		t.Fatalf("expected disabled source to stay tracked, got %d sources", len(state.Sources))
+	}
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Fatalf("expected only the enabled source to be fetched, got %d fetches", got)
+	}
+	if gotHeader.Load() != "secret" {
+		t.Fatalf("expected manifest headers to be sent, got %v", gotHeader.Load())
+	}
+	if saved, err := os.ReadFile(manager.statePath); err != nil || strings.Contains(string(saved), "secret") {
+		t.Fatalf("manifest headers must not be persisted, got %q (err=%v)", saved, err)
+	}
+	if state.Sources[0].Name != "Active" || state.Sources[0].LastUpdated.IsZero() {
+		t.Fatalf("unexpected active source: %+v", state.Sources[0])
+	}
+	if !state.Sources[1].Disabled || !state.Sources[1].LastChecked.IsZero() {
+		t.Fatalf("expected paused source to be disabled and unfetched: %+v", state.Sources[1])
+	}
+	if SourceStatus(state.Sources[1], 0) != RemoteStatusDisabled {
+		t.Fatalf("expected disabled status, got %s", SourceStatus(state.Sources[1], 0))
+	}
+
+	if _, err := manager.CheckUpdates(); err != nil {
+		t.Fatalf("CheckUpdates failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&fetches); got != 2 {
+		t.Fatalf("expected CheckUpdates to skip the disabled source, got %d fetches", got)
+	}
+
+	activePath := state.Sources[0].FilePath
+	state, err = manager.ApplyManifest([]ManifestEntry{
+		{URL: activeURL, Enabled: &off},
+		{URL: pausedURL, Enabled: &on},
+	})
+	if err != nil {
+		t.Fatalf("ApplyManifest failed: %v", err)
+	}
+	if _, err := os.Stat(activePath); !os.IsNotExist(err) {
+		t.Fatalf("disabled source file must be removed, stat err: %v", err)
+	}
+	if state.Sources[1].Disabled || state.Sources[1].LastUpdated.IsZero() {
+		t.Fatalf("expected re-enabled source to be fetched: %+v", state.Sources[1])
+	}
+
+	state, err = manager.ApplyManifest([]ManifestEntry{{URL: activeURL}, {URL: pausedURL}})
+	if err != nil {
+		t.Fatalf("ApplyManifest failed: %v", err)
+	}
+	if _, err := os.Stat(state.Sources[0].FilePath); err != nil {
+		t.Fatalf("re-enabled source must be downloaded again: %v", err)
+	}
+
+	if _, err := manager.ApplyManifest([]ManifestEntry{{URL: activeURL}, {URL: activeURL}}); err == nil {
+		t.Fatal("expected duplicate manifest URLs to be rejected")
+	}
+}
@@ -11,10 +11,10 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 	"xray-checker/config"
 	"xray-checker/logger"
 	"xray-checker/models"
+	"xray-checker/outbound"
 
 	libXray "github.com/xtls/libxray"
 )
@@ -203,7 +203,7 @@ func (p *Parser) Parse(subscriptionData string) (*ParseResult, error) {
 	case "url":
 		result, fetchErr := p.fetchURLContent(subscriptionData)
 		if fetchErr != nil {
-			return nil, fmt.Errorf("failed to fetch URL content: %v", fetchErr)
+			return nil, fmt.Errorf("%w: %w", ErrFetchFailed, fetchErr)
 		}
 		rawData = result.Content
 		subName = result.Name
@@ -226,7 +226,7 @@ func (p *Parser) Parse(subscriptionData string) (*ParseResult, error) {
 		}
 		rawData, err = os.ReadFile(filePath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read file: %v", err)
+			return nil, sourceReadError("failed to read file", err)
 		}
 	case "base64":
 		rawData = []byte(strings.TrimPrefix(subscriptionData, "base64://"))
@@ -285,7 +285,7 @@ func (p *Parser) parseRawData(rawData []byte, sourcePath, subName string) ([]*mo
 		return proxyConfigs, nil
 	}
 
-	return nil, fmt.Errorf("no valid proxy configurations found")
+	return nil, ErrEmptySource
 }
 
 func (p *Parser) parseShareLinksBulk(cleanedData []byte, originalData map[string][]*originalLinkData, subName string) (*ParseResult, error) {
@@ -329,7 +329,7 @@ func (p *Parser) parseShareLinksBulk(cleanedData []byte, originalData map[string
 	}
 
 	if len(proxyConfigs) == 0 {
-		return nil, fmt.Errorf("no valid proxy configurations found")
+		return nil, ErrEmptySource
 	}
 
 	logger.Debug("Bulk parsed proxy configs: %d", len(proxyConfigs))
@@ -429,7 +429,7 @@ func (p *Parser) parseJSONConfigs(data []byte) ([]*models.ProxyConfig, error) {
 	}
 
 	if len(proxyConfigs) == 0 {
-		return nil, fmt.Errorf("no valid proxy configurations found in JSON")
+		return nil, fmt.Errorf("%w in JSON", ErrEmptySource)
 	}
 
 	return proxyConfigs, nil
@@ -465,7 +465,7 @@ func (p *Parser) parseSingleJSONConfig(data []byte) ([]*models.ProxyConfig, erro
 	}
 
 	if len(proxyConfigs) == 0 {
-		return nil, fmt.Errorf("no valid proxy configurations found in single JSON config")
+		return nil, fmt.Errorf("%w in single JSON config", ErrEmptySource)
 	}
 
 	return proxyConfigs, nil
@@ -550,7 +550,7 @@ func (p *Parser) parseShareLinksIndividually(data []byte, originalData map[strin
 	}
 
 	if len(proxyConfigs) == 0 {
-		return nil, fmt.Errorf("no valid proxy configurations found")
+		return nil, ErrEmptySource
 	}
 
 	logger.Debug("Line-by-line parsed proxy configs: %d (skipped lines: %d)", len(proxyConfigs), skipped)
@@ -663,15 +663,14 @@ func (p *Parser) fetchURLContent(source string) (*fetchResult, error) {
 	req.Header.Set("X-Device-Model", "Xray-Checker Pro Max")
 	req.Header.Set("X-Hwid", "0JLQq9Ca0JvQrtCn0Jgg0JHQm9Cp0KLQrCBIV0lE")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := outbound.NewSubscriptionClient(0).Do(req)
 	if err != nil {
-		return nil, err
+		return nil, fetchError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		return nil, &httpStatusError{code: resp.StatusCode}
 	}
 
 	content, err := io.ReadAll(resp.Body)
@@ -1288,18 +1287,22 @@ func (p *Parser) decodeBase64(text string) ([]byte, error) {
 func (p *Parser) parseFolder(folderPath string) ([]*models.ProxyConfig, error) {
 	entries, err := os.ReadDir(folderPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read folder: %v", err)
+		return nil, sourceReadError("failed to read folder", err)
 	}
 
 	var allConfigs []*models.ProxyConfig
 	configIndex := 0
 
+	fileNames := make([]string, 0, len(entries))
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
+		fileNames = append(fileNames, entry.Name())
+	}
+	fileNames = prioritizeFolderFiles(folderPath, fileNames)
 
-		fileName := entry.Name()
+	for _, fileName := range fileNames {
 		ext := strings.ToLower(filepath.Ext(fileName))
 		if ext != ".json" && ext != ".txt" {
 			continue
@@ -1324,6 +1327,9 @@ func (p *Parser) parseFolder(folderPath string) ([]*models.ProxyConfig, error) {
 
 		for _, cfg := range configs {
 			cfg.Index = configIndex
+			if cfg.SourcePath == "" {
+				cfg.SourcePath = filePath
+			}
 			allConfigs = append(allConfigs, cfg)
 			configIndex++
 		}
@@ -1332,7 +1338,7 @@ func (p *Parser) parseFolder(folderPath string) ([]*models.ProxyConfig, error) {
 	}
 
 	if len(allConfigs) == 0 {
-		return nil, fmt.Errorf("no valid proxy configurations found in folder")
+		return nil, fmt.Errorf("%w in folder", ErrEmptySource)
 	}
 
 	logger.Debug("Total configs from folder: %d", len(allConfigs))
@@ -1370,7 +1376,7 @@ func (p *Parser) parseSingleConfigFile(data []byte, startIndex int, sourcePath s
 			}
 
 			if len(proxyConfigs) == 0 {
-				return nil, fmt.Errorf("no valid proxy configurations found")
+				return nil, ErrEmptySource
 			}
 
 			return proxyConfigs, nil
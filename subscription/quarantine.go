@@ -0,0 +1,215 @@
+package subscription
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrQuarantinedLineNotFound is returned when a restore names a line that is
+// not in the quarantine file.
+var ErrQuarantinedLineNotFound = errors.New("quarantined line not found")
+
+// ErrRestoreTooSoon is returned when a line is restored before it has been
+// quarantined for the required time.
+var ErrRestoreTooSoon = errors.New("quarantined too recently")
+
+// QuarantinedLine is one config moved to a quarantine sidecar by cleanup.
+type QuarantinedLine struct {
+	// ID identifies the line without exposing it.
+	ID        string    `json:"id"`
+	Line      string    `json:"line"`
+	RemovedAt time.Time `json:"removedAt,omitempty"`
+}
+
+// QuarantineFile lists the quarantined lines of one source file.
+type QuarantineFile struct {
+	// File is the source file the lines were removed from.
+	File  string            `json:"file"`
+	Lines []QuarantinedLine `json:"lines"`
+}
+
+// QuarantineLineID returns the ID of a quarantined line.
+func QuarantineLineID(line string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(line)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// QuarantineDirs returns the directories that hold the local file sources
+// among sources (file:// and folder:// URLs), where quarantine sidecars live.
+func QuarantineDirs(sources []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, src := range sources {
+		var dir string
+		switch {
+		case strings.HasPrefix(src, "folder://"):
+			dir = strings.TrimPrefix(src, "folder://")
+		case strings.HasPrefix(src, "file://"):
+			dir = filepath.Dir(strings.TrimPrefix(src, "file://"))
+		default:
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// ListQuarantine reads every quarantine sidecar in dirs. Files are sorted by
+// path and lines kept in the order they were removed.
+func ListQuarantine(dirs []string) ([]QuarantineFile, error) {
+	var files []QuarantineFile
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "*"+QuarantineSuffix))
+		if err != nil {
+			return nil, err
+		}
+		for _, sidecar := range matches {
+			lines, err := readQuarantine(sidecar)
+			if err != nil {
+				return nil, err
+			}
+			if len(lines) == 0 {
+				continue
+			}
+			files = append(files, QuarantineFile{File: strings.TrimSuffix(sidecar, QuarantineSuffix), Lines: lines})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].File < files[j].File })
+	return files, nil
+}
+
+func readQuarantine(path string) ([]QuarantinedLine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []QuarantinedLine
+	var removedAt time.Time
+	for _, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if stamp, ok := strings.CutPrefix(line, "# removed "); ok {
+			removedAt, _ = time.Parse(time.RFC3339, stamp)
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, QuarantinedLine{ID: QuarantineLineID(line), Line: line, RemovedAt: removedAt})
+	}
+	return lines, nil
+}
+
+// RestoreQuarantined moves the quarantined line with the given ID back into
+// filePath, keeping the file's base64 encoding. Its other quarantined lines
+// stay in the sidecar. A line quarantined less than minAge ago is refused:
+// it stayed bad long enough to be removed, so putting it straight back only
+// gets it removed again. A minAge of 0 skips the guard.
+func RestoreQuarantined(filePath, id string, minAge time.Duration) (QuarantinedLine, error) {
+	sourceFileMu.Lock()
+	defer sourceFileMu.Unlock()
+
+	sidecar := filePath + QuarantineSuffix
+	lines, err := readQuarantine(sidecar)
+	if errors.Is(err, os.ErrNotExist) {
+		return QuarantinedLine{}, ErrQuarantinedLineNotFound
+	}
+	if err != nil {
+		return QuarantinedLine{}, err
+	}
+	index := -1
+	for i, line := range lines {
+		if line.ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return QuarantinedLine{}, ErrQuarantinedLineNotFound
+	}
+	restored := lines[index]
+	if age := time.Since(restored.RemovedAt); minAge > 0 && !restored.RemovedAt.IsZero() && age < minAge {
+		return QuarantinedLine{}, fmt.Errorf("%w: removed %s ago, restore allowed after %s", ErrRestoreTooSoon, age.Round(time.Second), minAge)
+	}
+
+	if err := appendToSource(filePath, restored.Line); err != nil {
+		return QuarantinedLine{}, err
+	}
+	if err := writeQuarantine(sidecar, append(lines[:index:index], lines[index+1:]...)); err != nil {
+		return QuarantinedLine{}, fmt.Errorf("restored line but failed to update quarantine file: %w", err)
+	}
+	return restored, nil
+}
+
+// appendToSource adds line to a share link file unless it is already there.
+func appendToSource(filePath, line string) error {
+	rawData, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	trimmed := strings.TrimSpace(string(rawData))
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return fmt.Errorf("cannot restore into JSON source %s", filePath)
+	}
+
+	parser := NewParser()
+	decoded := parser.tryDecodeBase64(rawData)
+	isBase64 := trimmed != "" && parser.isLikelyBase64Subscription(rawData, decoded)
+
+	var kept []string
+	for _, existing := range strings.Split(string(decoded), "\n") {
+		existing = strings.TrimSpace(existing)
+		if existing == "" {
+			continue
+		}
+		if existing == line {
+			return nil
+		}
+		kept = append(kept, existing)
+	}
+	kept = append(kept, line)
+
+	out := strings.Join(kept, "\n")
+	if isBase64 {
+		out = base64.StdEncoding.EncodeToString([]byte(out))
+	}
+	return writeFileAtomic(filePath, []byte(out), 0o644)
+}
+
+// writeQuarantine rewrites a sidecar with lines, grouped under their removal
+// times. An empty list removes the sidecar.
+func writeQuarantine(path string, lines []QuarantinedLine) error {
+	if len(lines) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	var b strings.Builder
+	var current time.Time
+	for i, line := range lines {
+		if i == 0 || !line.RemovedAt.Equal(current) {
+			current = line.RemovedAt
+			if !current.IsZero() {
+				fmt.Fprintf(&b, "# removed %s\n", current.UTC().Format(time.RFC3339))
+			}
+		}
+		b.WriteString(line.Line)
+		b.WriteByte('\n')
+	}
+	return writeFileAtomic(path, []byte(b.String()), 0o644)
+}
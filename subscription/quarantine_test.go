@@ -0,0 +1,135 @@
+package subscription
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListAndRestoreQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list.txt")
+	encoded := base64.StdEncoding.EncodeToString([]byte("vless://good\nvless://bad1\nvless://bad2"))
+	if err := os.WriteFile(path, []byte(encoded), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, _, err := QuarantineBadConfigsFromFile(path, map[string]bool{"vless://bad1": true, "vless://bad2": true}); err != nil {
+		t.Fatalf("quarantine failed: %v", err)
+	}
+
+	dirs := QuarantineDirs([]string{"file://" + path, "https://example.com/sub"})
+	if len(dirs) != 1 || dirs[0] != dir {
+		t.Fatalf("unexpected quarantine dirs: %v", dirs)
+	}
+	files, err := ListQuarantine(dirs)
+	if err != nil {
+		t.Fatalf("ListQuarantine: %v", err)
+	}
+	if len(files) != 1 || files[0].File != path || len(files[0].Lines) != 2 {
+		t.Fatalf("unexpected listing: %+v", files)
+	}
+	bad1 := files[0].Lines[0]
+	if bad1.Line != "vless://bad1" || bad1.ID != QuarantineLineID("vless://bad1") || bad1.RemovedAt.IsZero() {
+		t.Fatalf("unexpected line: %+v", bad1)
+	}
+
+	if _, err := RestoreQuarantined(path, bad1.ID, time.Hour); !errors.Is(err, ErrRestoreTooSoon) {
+		t.Fatalf("expected ErrRestoreTooSoon, got %v", err)
+	}
+	if _, err := RestoreQuarantined(path, "unknown", 0); !errors.Is(err, ErrQuarantinedLineNotFound) {
+		t.Fatalf("expected ErrQuarantinedLineNotFound, got %v", err)
+	}
+
+	if _, err := RestoreQuarantined(path, bad1.ID, 0); err != nil {
+		t.Fatalf("RestoreQuarantined: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		t.Fatalf("source must stay base64 encoded: %q", data)
+	}
+	if string(decoded) != "vless://good\nvless://bad1" {
+		t.Fatalf("unexpected source content: %q", decoded)
+	}
+
+	files, _ = ListQuarantine(dirs)
+	if len(files) != 1 || len(files[0].Lines) != 1 || files[0].Lines[0].Line != "vless://bad2" {
+		t.Fatalf("restored line must leave the quarantine: %+v", files)
+	}
+	if _, err := RestoreQuarantined(path, files[0].Lines[0].ID, 0); err != nil {
+		t.Fatalf("RestoreQuarantined: %v", err)
+	}
+	if _, err := os.Stat(path + QuarantineSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected empty quarantine file to be removed, got err=%v", err)
+	}
+	data, _ = os.ReadFile(path)
+	decoded, _ = base64.StdEncoding.DecodeString(string(data))
+	if !strings.HasSuffix(string(decoded), "vless://bad2") {
+		t.Fatalf("unexpected source content: %q", decoded)
+	}
+}
+
+func TestCleanupAndRestoreDoNotLoseLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list.txt")
+	var all []string
+	restoreBad := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		all = append(all, fmt.Sprintf("vless://restore%d", i), fmt.Sprintf("vless://remove%d", i))
+		restoreBad[fmt.Sprintf("vless://restore%d", i)] = true
+	}
+	all = append(all, "vless://good")
+	if err := os.WriteFile(path, []byte(strings.Join(all, "\n")), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, _, err := QuarantineBadConfigsFromFile(path, restoreBad); err != nil {
+		t.Fatalf("quarantine failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := RestoreQuarantined(path, QuarantineLineID(fmt.Sprintf("vless://restore%d", i)), 0); err != nil {
+				t.Errorf("RestoreQuarantined: %v", err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			if _, _, err := QuarantineBadConfigsFromFile(path, map[string]bool{fmt.Sprintf("vless://remove%d", i): true}); err != nil {
+				t.Errorf("quarantine failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, _ := os.ReadFile(path)
+	source := strings.Split(string(data), "\n")
+	if len(source) != 11 {
+		t.Fatalf("expected good plus 10 restored lines in the source, got %q", data)
+	}
+	for _, line := range source {
+		if strings.HasPrefix(line, "vless://remove") {
+			t.Fatalf("removed line left in the source: %q", data)
+		}
+	}
+	lines, err := readQuarantine(path + QuarantineSuffix)
+	if err != nil {
+		t.Fatalf("readQuarantine: %v", err)
+	}
+	if len(lines) != 10 {
+		t.Fatalf("expected the 10 removed lines in the quarantine, got %+v", lines)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line.Line, "vless://remove") {
+			t.Fatalf("unexpected quarantined line: %+v", line)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package subscription
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +17,9 @@ import (
 	"time"
 	"xray-checker/config"
 	"xray-checker/logger"
+	"xray-checker/metrics"
+	"xray-checker/models"
+	"xray-checker/outbound"
 )
 
 type RemoteSource struct {
@@ -27,8 +32,23 @@ type RemoteSource struct {
 	LastChecked  time.Time `json:"lastChecked,omitempty"`
 	LastUpdated  time.Time `json:"lastUpdated,omitempty"`
 	Error        string    `json:"error,omitempty"`
+
+	// Optional metadata set from a subscription manifest. Headers often hold
+	// credentials, so they are never persisted; the manifest is applied again
+	// on every start.
+	Name            string            `json:"name,omitempty"`
+	Headers         map[string]string `json:"-"`
+	IntervalSeconds int               `json:"intervalSeconds,omitempty"`
+	Disabled        bool              `json:"disabled,omitempty"`
 }
 
+const (
+	RemoteStatusOK       = "ok"
+	RemoteStatusEmpty    = "empty"
+	RemoteStatusError    = "error"
+	RemoteStatusDisabled = "disabled"
+)
+
 type RemoteState struct {
 	IntervalSeconds int            `json:"intervalSeconds"`
 	Sources         []RemoteSource `json:"sources"`
@@ -81,7 +101,7 @@ func GetRemoteManager() (*RemoteManager, error) {
 			remoteErr = err
 			return
 		}
-		statePath := remoteStatePath(dir)
+		statePath := resolveRemoteStatePath(dir, config.CLIConfig.Subscription.StatePath)
 		if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
 			remoteErr = err
 			return
@@ -90,28 +110,75 @@ func GetRemoteManager() (*RemoteManager, error) {
 			remoteErr = err
 			return
 		}
-		manager := &RemoteManager{
-			statePath:   statePath,
-			downloadDir: dir,
-			client:      &http.Client{Timeout: 30 * time.Second},
-		}
-		if err := manager.load(); err != nil {
+		manager, err := NewRemoteManager(statePath, dir)
+		if err != nil {
 			remoteErr = err
 			return
 		}
+		if path := strings.TrimSpace(config.CLIConfig.Subscription.Manifest); path != "" {
+			entries, err := LoadManifest(path)
+			if err != nil {
+				remoteErr = err
+				return
+			}
+			if _, err := manager.ApplyManifest(entries); err != nil {
+				remoteErr = err
+				return
+			}
+		}
 		remoteInstance = manager
 	})
 	return remoteInstance, remoteErr
 }
 
+// NewRemoteManager returns a manager keeping its state at statePath and its
+// downloads in downloadDir. Saved state is loaded; a missing state file is
+// created.
+func NewRemoteManager(statePath, downloadDir string) (*RemoteManager, error) {
+	manager := &RemoteManager{
+		statePath:   statePath,
+		downloadDir: downloadDir,
+		client:      outbound.NewSubscriptionClient(0),
+	}
+	if err := manager.load(); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
 func (m *RemoteManager) DownloadDir() string {
 	return m.downloadDir
 }
 
+func (m *RemoteManager) StatePath() string {
+	return m.statePath
+}
+
+// GetState returns a snapshot of the state. Sources and their headers are
+// copied so callers never share mutable data with the manager.
 func (m *RemoteManager) GetState() RemoteState {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.state
+	snapshot := m.state
+	snapshot.Sources = make([]RemoteSource, len(m.state.Sources))
+	copy(snapshot.Sources, m.state.Sources)
+	for i := range snapshot.Sources {
+		snapshot.Sources[i].Headers = copyHeaders(snapshot.Sources[i].Headers)
+	}
+	return snapshot
+}
+
+// SourceByID returns a copy of the source with the given ID.
+func (m *RemoteManager) SourceByID(id string) (RemoteSource, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, src := range m.state.Sources {
+		if src.ID == id {
+			src.Headers = copyHeaders(src.Headers)
+			return src, true
+		}
+	}
+	return RemoteSource{}, false
 }
 
 func (m *RemoteManager) SetInterval(seconds int) {
@@ -188,6 +255,144 @@ func (m *RemoteManager) AddURLs(urls []string) ([]RemoteSource, error) {
 	return added, nil
 }
 
+// ReplaceURLs reconciles the source list to exactly the given URLs, in the
+// given order. Existing sources keep their download state, new sources are
+// downloaded and sources no longer listed are removed along with their files.
+func (m *RemoteManager) ReplaceURLs(urls []string) (RemoteState, error) {
+	normalized := make([]string, 0, len(urls))
+	wanted := make(map[string]bool, len(urls))
+	for _, raw := range urls {
+		u, err := normalizeRemoteURL(raw)
+		if err != nil {
+			return RemoteState{}, fmt.Errorf("invalid URL %q: %v", RedactURLQuery(raw), err)
+		}
+		if wanted[u] {
+			continue
+		}
+		wanted[u] = true
+		normalized = append(normalized, u)
+	}
+
+	m.mu.Lock()
+	existing := make(map[string]RemoteSource, len(m.state.Sources))
+	for _, src := range m.state.Sources {
+		existing[src.URL] = src
+	}
+
+	var removed []RemoteSource
+	for _, src := range m.state.Sources {
+		if !wanted[src.URL] {
+			removed = append(removed, src)
+		}
+	}
+
+	next := make([]RemoteSource, 0, len(normalized))
+	var added []RemoteSource
+	for _, u := range normalized {
+		if src, ok := existing[u]; ok {
+			next = append(next, src)
+			continue
+		}
+		id := hashURL(u)
+		fileName := buildRemoteFileName(u, id)
+		item := RemoteSource{
+			ID:       id,
+			URL:      u,
+			FileName: fileName,
+			FilePath: filepath.Join(m.downloadDir, fileName),
+		}
+		next = append(next, item)
+		added = append(added, item)
+	}
+
+	for _, src := range removed {
+		_ = os.Remove(src.FilePath)
+		metrics.DeleteSubscriptionSource(src.ID)
+	}
+	m.state.Sources = next
+	if err := m.saveLocked(); err != nil {
+		m.mu.Unlock()
+		return RemoteState{}, err
+	}
+	m.mu.Unlock()
+
+	for i := range added {
+		m.download(&added[i], true)
+	}
+	m.mergeDownloaded(added)
+
+	m.mu.Lock()
+	err := m.saveLocked()
+	m.mu.Unlock()
+	if err != nil {
+		return RemoteState{}, err
+	}
+	return m.GetState(), nil
+}
+
+const remoteReachabilityTimeout = 10 * time.Second
+
+// CheckReachable probes each URL (HEAD, falling back to GET) and returns an
+// error listing the ones that clearly cannot be fetched. Invalid URLs are left
+// for AddURLs to report.
+func (m *RemoteManager) CheckReachable(urls []string) error {
+	var failures []string
+	for _, raw := range urls {
+		normalized, err := normalizeRemoteURL(raw)
+		if err != nil {
+			continue
+		}
+		if err := m.probe(normalized); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", RedactURLQuery(normalized), err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("unreachable URL(s): %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (m *RemoteManager) probe(target string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteReachabilityTimeout)
+	defer cancel()
+
+	status, err := m.probeWithMethod(ctx, http.MethodHead, target)
+	if err == nil && status < 400 {
+		return nil
+	}
+	// Many subscription panels reject HEAD; confirm with a GET before failing.
+	status, err = m.probeWithMethod(ctx, http.MethodGet, target)
+	if err != nil {
+		return err
+	}
+	if status >= 400 {
+		return fmt.Errorf("HTTP %d", status)
+	}
+	return nil
+}
+
+func (m *RemoteManager) probeWithMethod(ctx context.Context, method, target string) (int, error) {
+	target, err := ExpandSourceEnv(target)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, fetchError(err)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, fetchError(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+var (
+	ErrRemoteSourceNotFound = errors.New("source not found")
+	ErrAmbiguousSourceMatch = errors.New("match is ambiguous")
+)
+
 func (m *RemoteManager) RemoveByID(id string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -197,6 +402,7 @@ func (m *RemoteManager) RemoveByID(id string) bool {
 		if src.ID == id || src.URL == id {
 			removed = true
 			_ = os.Remove(src.FilePath)
+			metrics.DeleteSubscriptionSource(src.ID)
 			continue
 		}
 		kept = append(kept, src)
@@ -206,10 +412,137 @@ func (m *RemoteManager) RemoveByID(id string) bool {
 	return removed
 }
 
+// RemoveByIndex removes the source at the given zero-based position.
+func (m *RemoteManager) RemoveByIndex(index int) (RemoteSource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if index < 0 || index >= len(m.state.Sources) {
+		return RemoteSource{}, ErrRemoteSourceNotFound
+	}
+	return m.removeAtLocked(index), nil
+}
+
+// RemoveByMatch removes a single source. Exact ID or URL matches win; otherwise
+// match is treated as a URL substring that must identify exactly one source.
+func (m *RemoteManager) RemoveByMatch(match string) (RemoteSource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if match == "" {
+		return RemoteSource{}, ErrRemoteSourceNotFound
+	}
+	for i, src := range m.state.Sources {
+		if src.ID == match || src.URL == match {
+			return m.removeAtLocked(i), nil
+		}
+	}
+
+	found := -1
+	var candidates []string
+	for i, src := range m.state.Sources {
+		if strings.Contains(src.URL, match) {
+			found = i
+			candidates = append(candidates, src.ID)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return RemoteSource{}, ErrRemoteSourceNotFound
+	case 1:
+		return m.removeAtLocked(found), nil
+	default:
+		return RemoteSource{}, fmt.Errorf("%w: %d sources match %q (%s)",
+			ErrAmbiguousSourceMatch, len(candidates), match, strings.Join(candidates, ", "))
+	}
+}
+
+func (m *RemoteManager) removeAtLocked(index int) RemoteSource {
+	src := m.state.Sources[index]
+	_ = os.Remove(src.FilePath)
+	metrics.DeleteSubscriptionSource(src.ID)
+	m.state.Sources = append(m.state.Sources[:index], m.state.Sources[index+1:]...)
+	_ = m.saveLocked()
+	return src
+}
+
+// ProxyCounts attributes proxies to remote sources by matching the proxy's
+// SourcePath against each source's downloaded file. The result is keyed by
+// source ID and contains every known source, including those with no proxies.
+func (m *RemoteManager) ProxyCounts(proxies []*models.ProxyConfig) map[string]int {
+	m.mu.Lock()
+	idByPath := make(map[string]string, len(m.state.Sources))
+	counts := make(map[string]int, len(m.state.Sources))
+	for _, src := range m.state.Sources {
+		idByPath[cleanSourcePath(src.FilePath)] = src.ID
+		counts[src.ID] = 0
+	}
+	m.mu.Unlock()
+
+	for _, proxy := range proxies {
+		if proxy == nil || proxy.SourcePath == "" {
+			continue
+		}
+		if id, ok := idByPath[cleanSourcePath(proxy.SourcePath)]; ok {
+			counts[id]++
+		}
+	}
+	return counts
+}
+
+// RecordProxyCounts exports how many of proxies each remote source
+// contributes as the xray_checker_remote_source_proxies metric.
+func (m *RemoteManager) RecordProxyCounts(proxies []*models.ProxyConfig) {
+	metrics.SetRemoteSourceProxies(m.ProxyCounts(proxies))
+}
+
+// SourceStatus summarizes a source as ok, empty (fetched but yields no
+// proxies), error (last fetch failed) or disabled.
+func SourceStatus(src RemoteSource, proxyCount int) string {
+	if src.Disabled {
+		return RemoteStatusDisabled
+	}
+	if src.Error != "" {
+		return RemoteStatusError
+	}
+	if proxyCount == 0 {
+		return RemoteStatusEmpty
+	}
+	return RemoteStatusOK
+}
+
+func cleanSourcePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// CheckUpdates fetches every enabled source.
 func (m *RemoteManager) CheckUpdates() (int, error) {
+	return m.checkUpdates(func(RemoteSource) bool { return true })
+}
+
+// checkDueUpdates fetches enabled sources whose own interval (or the global
+// one when unset) has elapsed since their last check.
+func (m *RemoteManager) checkDueUpdates(now time.Time) (int, error) {
+	global := m.getGlobalInterval()
+	return m.checkUpdates(func(src RemoteSource) bool {
+		interval := src.IntervalSeconds
+		if interval <= 0 {
+			interval = global
+		}
+		return src.LastChecked.IsZero() || now.Sub(src.LastChecked) >= time.Duration(interval)*time.Second
+	})
+}
+
+func (m *RemoteManager) checkUpdates(due func(RemoteSource) bool) (int, error) {
 	m.mu.Lock()
-	sources := make([]RemoteSource, len(m.state.Sources))
-	copy(sources, m.state.Sources)
+	sources := make([]RemoteSource, 0, len(m.state.Sources))
+	for _, src := range m.state.Sources {
+		if !src.Disabled && due(src) {
+			sources = append(sources, src)
+		}
+	}
 	m.mu.Unlock()
 
 	updated := 0
@@ -235,7 +568,7 @@ func (m *RemoteManager) StartUpdateLoop(stop <-chan struct{}) {
 			}
 			select {
 			case <-time.After(time.Duration(interval) * time.Second):
-				if updated, err := m.CheckUpdates(); err != nil {
+				if updated, err := m.checkDueUpdates(time.Now()); err != nil {
 					logger.Warn("Remote update check failed: %v", err)
 				} else if updated > 0 {
 					logger.Info("Remote subscriptions updated: %d", updated)
@@ -247,37 +580,135 @@ func (m *RemoteManager) StartUpdateLoop(stop <-chan struct{}) {
 	}()
 }
 
+// getInterval returns how long the update loop sleeps: the global interval,
+// shortened to the smallest per-source interval of any enabled source.
 func (m *RemoteManager) getInterval() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	interval := m.state.IntervalSeconds
+	for _, src := range m.state.Sources {
+		if !src.Disabled && src.IntervalSeconds > 0 && (interval <= 0 || src.IntervalSeconds < interval) {
+			interval = src.IntervalSeconds
+		}
+	}
+	return interval
+}
+
+func (m *RemoteManager) getGlobalInterval() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state.IntervalSeconds <= 0 {
+		return 300
+	}
 	return m.state.IntervalSeconds
 }
 
 func (m *RemoteManager) mergeDownloaded(updated []RemoteSource) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	byID := make(map[string]RemoteSource, len(updated))
+	for _, src := range updated {
+		byID[src.ID] = src
+	}
+	// Replace in place so the configured source order (priority) is preserved.
+	for i, src := range m.state.Sources {
+		if next, ok := byID[src.ID]; ok {
+			m.state.Sources[i] = next
+		}
+	}
+}
+
+// Reorder sets the source priority order. ids must name every existing source
+// exactly once.
+func (m *RemoteManager) Reorder(ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(ids) != len(m.state.Sources) {
+		return fmt.Errorf("expected %d source IDs, got %d", len(m.state.Sources), len(ids))
+	}
 	byID := make(map[string]RemoteSource, len(m.state.Sources))
 	for _, src := range m.state.Sources {
 		byID[src.ID] = src
 	}
-	for _, src := range updated {
-		if _, ok := byID[src.ID]; ok {
-			byID[src.ID] = src
+	ordered := make([]RemoteSource, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("duplicate source ID: %s", id)
 		}
+		src, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("unknown source ID: %s", id)
+		}
+		seen[id] = true
+		ordered = append(ordered, src)
 	}
-	m.state.Sources = m.state.Sources[:0]
-	for _, src := range byID {
-		m.state.Sources = append(m.state.Sources, src)
+
+	m.state.Sources = ordered
+	return m.saveLocked()
+}
+
+// orderedFileNames returns downloaded file names in source priority order.
+func (m *RemoteManager) orderedFileNames() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.state.Sources))
+	for _, src := range m.state.Sources {
+		names = append(names, filepath.Base(src.FilePath))
+	}
+	return names
+}
+
+// prioritizeFolderFiles orders file names in folderPath so that remote sources
+// come first in their configured order, followed by the remaining files in
+// their original (alphabetical) order.
+func prioritizeFolderFiles(folderPath string, names []string) []string {
+	if remoteInstance == nil || cleanSourcePath(remoteInstance.downloadDir) != cleanSourcePath(folderPath) {
+		return names
 	}
+	return orderByPriority(names, remoteInstance.orderedFileNames())
+}
+
+func orderByPriority(names []string, priority []string) []string {
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+	out := make([]string, 0, len(names))
+	used := make(map[string]bool, len(names))
+	for _, name := range priority {
+		if present[name] && !used[name] {
+			out = append(out, name)
+			used[name] = true
+		}
+	}
+	for _, name := range names {
+		if !used[name] {
+			out = append(out, name)
+		}
+	}
+	return out
 }
 
 func (m *RemoteManager) download(src *RemoteSource, force bool) bool {
-	req, err := http.NewRequest("GET", src.URL, nil)
+	defer func() { metrics.RecordSubscriptionFetch(src.ID, src.Error == "") }()
+
+	target, err := ExpandSourceEnv(src.URL)
 	if err != nil {
 		src.Error = err.Error()
 		src.LastChecked = time.Now()
 		return false
 	}
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		src.Error = fetchError(err).Error()
+		src.LastChecked = time.Now()
+		return false
+	}
+	for name, value := range src.Headers {
+		req.Header.Set(name, value)
+	}
 	if !force {
 		if src.ETag != "" {
 			req.Header.Set("If-None-Match", src.ETag)
@@ -289,7 +720,7 @@ func (m *RemoteManager) download(src *RemoteSource, force bool) bool {
 
 	resp, err := m.client.Do(req)
 	if err != nil {
-		src.Error = err.Error()
+		src.Error = fetchError(err).Error()
 		src.LastChecked = time.Now()
 		return false
 	}
@@ -357,14 +788,53 @@ func (m *RemoteManager) saveLocked() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(m.statePath, payload, 0o644)
+	// Source URLs can carry tokens, so the state is readable by its owner only.
+	return writeFileAtomic(m.statePath, payload, 0o600)
 }
 
+// normalizeRemoteURL validates raw and returns its canonical form. ${NAME}
+// placeholders are kept in the result, so state and API responses only ever
+// hold the template; they are expanded when the source is fetched.
 func normalizeRemoteURL(raw string) (string, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return "", fmt.Errorf("empty url")
 	}
+	expanded, err := ExpandSourceEnv(raw)
+	if err != nil {
+		return "", err
+	}
+	if expanded != raw {
+		if _, err := normalizeRemoteURL(expanded); err != nil {
+			return "", err
+		}
+	}
+
+	// Swap placeholders for inert tokens so they survive parsing and
+	// normalization, then put them back.
+	placeholders := envPlaceholderRe.FindAllString(raw, -1)
+	for i, placeholder := range placeholders {
+		raw = strings.Replace(raw, placeholder, placeholderToken(i), 1)
+	}
+	normalized, err := normalizeParsedURL(raw)
+	if err != nil {
+		return "", err
+	}
+	for i, placeholder := range placeholders {
+		normalized = strings.Replace(normalized, placeholderToken(i), placeholder, 1)
+	}
+	return normalized, nil
+}
+
+// placeholderTokenPrefix starts every token standing in for a ${VAR}
+// placeholder during normalization.
+const placeholderTokenPrefix = "xcenvplaceholder"
+
+func placeholderToken(i int) string {
+	return fmt.Sprintf("%s%dx", placeholderTokenPrefix, i)
+}
+
+func normalizeParsedURL(raw string) (string, error) {
 	parsed, err := url.Parse(raw)
 	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
 		return "", fmt.Errorf("invalid url")
@@ -373,19 +843,35 @@ func normalizeRemoteURL(raw string) (string, error) {
 		return "", fmt.Errorf("unsupported scheme")
 	}
 
+	keepQuery := false
 	host := strings.ToLower(parsed.Host)
-	if host == "github.com" {
+	switch host {
+	case "github.com", "www.github.com":
+		// github.com/<owner>/<repo>/{blob,raw}/<ref>/<path> -> raw.githubusercontent.com
 		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
-		if len(parts) >= 5 && parts[2] == "blob" {
-			rawHost := "raw.githubusercontent.com"
-			rawPath := "/" + strings.Join(append(parts[:2], parts[3:]...), "/")
-			parsed.Host = rawHost
+		if len(parts) >= 5 && (parts[2] == "blob" || parts[2] == "raw") {
+			rawPath := "/" + strings.Join(append(parts[:2:2], parts[3:]...), "/")
+			parsed.Host = "raw.githubusercontent.com"
 			parsed.Path = rawPath
+			parsed.RawPath = ""
+			keepQuery = true
 		}
+	case "raw.githubusercontent.com", "gist.github.com", "gist.githubusercontent.com":
+		// Private raw links carry their access token in the query; gist URLs
+		// are kept as-is.
+		keepQuery = true
+	}
+
+	// A query holding a placeholder carries a secret the source needs, such
+	// as ?token=${XRAY_CHECKER_SUB_TOKEN}, so it is kept on any host.
+	if strings.Contains(parsed.RawQuery, placeholderTokenPrefix) {
+		keepQuery = true
 	}
 
 	parsed.Fragment = ""
-	parsed.RawQuery = ""
+	if !keepQuery {
+		parsed.RawQuery = ""
+	}
 	return parsed.String(), nil
 }
 
@@ -431,34 +917,59 @@ func remoteStatePath(downloadDir string) string {
 	return filepath.Join(parent, ".remote_sources.json")
 }
 
-func migrateLegacyStateFile(downloadDir, statePath string) error {
-	legacyPath := filepath.Join(downloadDir, ".remote_sources.json")
-	if legacyPath == statePath {
-		return nil
+// resolveRemoteStatePath returns the explicit state path when configured
+// (relative paths are resolved against the download directory) and the
+// default location next to the download directory otherwise.
+func resolveRemoteStatePath(downloadDir, override string) string {
+	override = strings.TrimSpace(override)
+	if override == "" {
+		return remoteStatePath(downloadDir)
 	}
+	if filepath.IsAbs(override) {
+		return filepath.Clean(override)
+	}
+	return filepath.Join(downloadDir, override)
+}
 
+// migrateLegacyStateFile moves state from previous default locations (the
+// download directory's parent, then inside it) to statePath if it doesn't
+// exist yet.
+func migrateLegacyStateFile(downloadDir, statePath string) error {
 	if _, err := os.Stat(statePath); err == nil {
 		return nil
 	} else if !os.IsNotExist(err) {
 		return err
 	}
 
-	if _, err := os.Stat(legacyPath); err != nil {
-		if os.IsNotExist(err) {
-			return nil
+	candidates := []string{
+		remoteStatePath(downloadDir),
+		filepath.Join(downloadDir, ".remote_sources.json"),
+	}
+	for _, legacyPath := range candidates {
+		if filepath.Clean(legacyPath) == filepath.Clean(statePath) {
+			continue
 		}
-		return err
+		if _, err := os.Stat(legacyPath); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		return moveStateFile(legacyPath, statePath)
 	}
+	return nil
+}
 
+func moveStateFile(legacyPath, statePath string) error {
 	if err := os.Rename(legacyPath, statePath); err == nil {
-		return nil
+		return os.Chmod(statePath, 0o600)
 	}
 
 	data, err := os.ReadFile(legacyPath)
 	if err != nil {
 		return err
 	}
-	if err := os.WriteFile(statePath, data, 0o644); err != nil {
+	if err := os.WriteFile(statePath, data, 0o600); err != nil {
 		return err
 	}
 	_ = os.Remove(legacyPath)
@@ -1,12 +1,20 @@
 package subscription
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+	"xray-checker/metrics"
+	"xray-checker/models"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestRemoteStatePathUsesParentDirectory(t *testing.T) {
@@ -89,3 +97,450 @@ func TestAddURLsDoesNotDeadlock(t *testing.T) {
 		t.Fatal("AddURLs timed out, possible deadlock")
 	}
 }
+
+func TestProxyCountsAttributesBySourcePath(t *testing.T) {
+	root := t.TempDir()
+	downloadDir := filepath.Join(root, "subscriptions")
+
+	manager := &RemoteManager{
+		statePath:   filepath.Join(root, ".remote_sources.json"),
+		downloadDir: downloadDir,
+		state: RemoteState{
+			IntervalSeconds: 300,
+			Sources: []RemoteSource{
+				{ID: "full", FilePath: filepath.Join(downloadDir, "full.txt")},
+				{ID: "empty", FilePath: filepath.Join(downloadDir, "empty.txt")},
+				{ID: "broken", FilePath: filepath.Join(downloadDir, "broken.txt"), Error: "HTTP 500"},
+			},
+		},
+	}
+
+	proxies := []*models.ProxyConfig{
+		{Name: "a", SourcePath: filepath.Join(downloadDir, "full.txt")},
+		{Name: "b", SourcePath: filepath.Join(downloadDir, ".", "full.txt")},
+		{Name: "local", SourcePath: filepath.Join(root, "local.txt")},
+		{Name: "inline"},
+	}
+
+	counts := manager.ProxyCounts(proxies)
+	if counts["full"] != 2 {
+		t.Fatalf("expected 2 proxies for full source, got %d", counts["full"])
+	}
+	if count, ok := counts["empty"]; !ok || count != 0 {
+		t.Fatalf("expected empty source to be reported with 0 proxies, got %d (present=%v)", count, ok)
+	}
+
+	state := manager.GetState()
+	want := map[string]string{
+		"full":   RemoteStatusOK,
+		"empty":  RemoteStatusEmpty,
+		"broken": RemoteStatusError,
+	}
+	for _, src := range state.Sources {
+		if got := SourceStatus(src, counts[src.ID]); got != want[src.ID] {
+			t.Fatalf("source %s: got status %q, want %q", src.ID, got, want[src.ID])
+		}
+	}
+}
+
+func TestRecordProxyCountsExportsAttribution(t *testing.T) {
+	metrics.InitMetrics("", "test")
+	downloadDir := t.TempDir()
+	manager := &RemoteManager{
+		downloadDir: downloadDir,
+		state: RemoteState{Sources: []RemoteSource{
+			{ID: "full", FilePath: filepath.Join(downloadDir, "full.txt")},
+			{ID: "empty", FilePath: filepath.Join(downloadDir, "empty.txt")},
+		}},
+	}
+	manager.RecordProxyCounts([]*models.ProxyConfig{
+		{Name: "a", SourcePath: filepath.Join(downloadDir, "full.txt")},
+		{Name: "b", SourcePath: filepath.Join(downloadDir, "full.txt")},
+		{Name: "local", SourcePath: filepath.Join(t.TempDir(), "local.txt")},
+	})
+
+	gauge := metrics.GetRemoteSourceProxiesMetric()
+	if got := testutil.ToFloat64(gauge.WithLabelValues("full")); got != 2 {
+		t.Fatalf("expected 2 proxies attributed to full, got %v", got)
+	}
+	if got := testutil.ToFloat64(gauge.WithLabelValues("empty")); got != 0 {
+		t.Fatalf("expected 0 proxies for a source that yields nothing, got %v", got)
+	}
+}
+
+func TestReorderPersistsSourceOrder(t *testing.T) {
+	root := t.TempDir()
+	statePath := filepath.Join(root, ".remote_sources.json")
+	manager := &RemoteManager{
+		statePath:   statePath,
+		downloadDir: filepath.Join(root, "subscriptions"),
+		state: RemoteState{
+			IntervalSeconds: 300,
+			Sources: []RemoteSource{
+				{ID: "a", FilePath: "a.txt"},
+				{ID: "b", FilePath: "b.txt"},
+				{ID: "c", FilePath: "c.txt"},
+			},
+		},
+	}
+
+	if err := manager.Reorder([]string{"a", "b"}); err == nil {
+		t.Fatal("expected error when not all sources are listed")
+	}
+	if err := manager.Reorder([]string{"a", "b", "x"}); err == nil {
+		t.Fatal("expected error for unknown source ID")
+	}
+	if err := manager.Reorder([]string{"a", "a", "b"}); err == nil {
+		t.Fatal("expected error for duplicate source ID")
+	}
+
+	if err := manager.Reorder([]string{"c", "a", "b"}); err != nil {
+		t.Fatalf("reorder failed: %v", err)
+	}
+
+	reloaded := &RemoteManager{statePath: statePath}
+	if err := reloaded.load(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	var got []string
+	for _, src := range reloaded.GetState().Sources {
+		got = append(got, src.ID)
+	}
+	if strings.Join(got, ",") != "c,a,b" {
+		t.Fatalf("unexpected persisted order: %v", got)
+	}
+
+	manager.mergeDownloaded([]RemoteSource{{ID: "a", FilePath: "a.txt", Error: "boom"}})
+	got = got[:0]
+	for _, src := range manager.GetState().Sources {
+		got = append(got, src.ID)
+	}
+	if strings.Join(got, ",") != "c,a,b" {
+		t.Fatalf("merge must preserve source order, got %v", got)
+	}
+}
+
+func TestOrderByPriority(t *testing.T) {
+	names := []string{"aa_local.txt", "b1_remote.txt", "c2_remote.txt", "zz_local.txt"}
+	got := orderByPriority(names, []string{"c2_remote.txt", "missing.txt", "b1_remote.txt"})
+	want := "c2_remote.txt,b1_remote.txt,aa_local.txt,zz_local.txt"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("unexpected order: got %v, want %s", got, want)
+	}
+}
+
+func TestNormalizeRemoteURLGitHubForms(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "blob",
+			in:   "https://github.com/owner/repo/blob/main/subs/list.txt",
+			want: "https://raw.githubusercontent.com/owner/repo/main/subs/list.txt",
+		},
+		{
+			name: "raw",
+			in:   "https://github.com/owner/repo/raw/main/subs/list.txt",
+			want: "https://raw.githubusercontent.com/owner/repo/main/subs/list.txt",
+		},
+		{
+			name: "raw refs heads",
+			in:   "https://github.com/owner/repo/raw/refs/heads/main/list.txt",
+			want: "https://raw.githubusercontent.com/owner/repo/refs/heads/main/list.txt",
+		},
+		{
+			name: "private raw token survives",
+			in:   "https://raw.githubusercontent.com/owner/private/main/list.txt?token=GHSAT0AAA#frag",
+			want: "https://raw.githubusercontent.com/owner/private/main/list.txt?token=GHSAT0AAA",
+		},
+		{
+			name: "blob with token",
+			in:   "https://github.com/owner/private/blob/main/list.txt?token=abc",
+			want: "https://raw.githubusercontent.com/owner/private/main/list.txt?token=abc",
+		},
+		{
+			name: "gist",
+			in:   "https://gist.github.com/user/0123456789abcdef/raw/list.txt",
+			want: "https://gist.github.com/user/0123456789abcdef/raw/list.txt",
+		},
+		{
+			name: "gist raw host",
+			in:   "https://gist.githubusercontent.com/user/0123456789abcdef/raw/abc/list.txt?x=1",
+			want: "https://gist.githubusercontent.com/user/0123456789abcdef/raw/abc/list.txt?x=1",
+		},
+		{
+			name: "repo root untouched",
+			in:   "https://github.com/owner/repo",
+			want: "https://github.com/owner/repo",
+		},
+		{
+			name: "other host drops query",
+			in:   "https://example.com/sub?ref=abc#name",
+			want: "https://example.com/sub",
+		},
+	}
+
+	for _, tc := range cases {
+		got, err := normalizeRemoteURL(tc.in)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckReachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.txt":
+			w.WriteHeader(http.StatusOK)
+		case "/get-only.txt":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	manager := &RemoteManager{client: server.Client()}
+
+	if err := manager.CheckReachable([]string{server.URL + "/ok.txt", server.URL + "/get-only.txt"}); err != nil {
+		t.Fatalf("expected reachable URLs to pass, got %v", err)
+	}
+
+	err := manager.CheckReachable([]string{server.URL + "/ok.txt", server.URL + "/missing.txt"})
+	if err == nil {
+		t.Fatal("expected error for missing URL")
+	}
+	if !strings.Contains(err.Error(), "missing.txt") || strings.Contains(err.Error(), "ok.txt") {
+		t.Fatalf("error should list only the unreachable URL, got %v", err)
+	}
+}
+
+func TestRemoveByMatchAndIndex(t *testing.T) {
+	root := t.TempDir()
+	newManager := func() *RemoteManager {
+		return &RemoteManager{
+			statePath: filepath.Join(root, ".remote_sources.json"),
+			state: RemoteState{
+				IntervalSeconds: 300,
+				Sources: []RemoteSource{
+					{ID: "id-a", URL: "https://a.example.com/sub/one.txt"},
+					{ID: "id-b", URL: "https://b.example.com/sub/two.txt"},
+					{ID: "id-c", URL: "https://c.example.org/list.txt"},
+				},
+			},
+		}
+	}
+
+	manager := newManager()
+	if _, err := manager.RemoveByMatch("example.com"); !errors.Is(err, ErrAmbiguousSourceMatch) {
+		t.Fatalf("expected ambiguous match error, got %v", err)
+	}
+	if got := len(manager.GetState().Sources); got != 3 {
+		t.Fatalf("ambiguous match must not remove anything, have %d sources", got)
+	}
+	if _, err := manager.RemoveByMatch("nothing-here"); !errors.Is(err, ErrRemoteSourceNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	removed, err := manager.RemoveByMatch("example.org")
+	if err != nil || removed.ID != "id-c" {
+		t.Fatalf("expected unique substring to remove id-c, got %v (%v)", removed.ID, err)
+	}
+
+	// Exact ID wins even if it is also a substring of another source URL.
+	removed, err = manager.RemoveByMatch("id-a")
+	if err != nil || removed.ID != "id-a" {
+		t.Fatalf("expected exact ID match to remove id-a, got %v (%v)", removed.ID, err)
+	}
+
+	manager = newManager()
+	if _, err := manager.RemoveByIndex(3); !errors.Is(err, ErrRemoteSourceNotFound) {
+		t.Fatalf("expected out-of-range index to fail, got %v", err)
+	}
+	removed, err = manager.RemoveByIndex(1)
+	if err != nil || removed.ID != "id-b" {
+		t.Fatalf("expected index 1 to remove id-b, got %v (%v)", removed.ID, err)
+	}
+	if got := len(manager.GetState().Sources); got != 2 {
+		t.Fatalf("expected 2 sources after removal, got %d", got)
+	}
+}
+
+func TestReplaceURLsReconcilesSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("vmess://example"))
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	downloadDir := filepath.Join(root, "subscriptions")
+	if err := os.MkdirAll(downloadDir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+
+	keepURL := server.URL + "/keep.txt"
+	dropURL := server.URL + "/drop.txt"
+	dropPath := filepath.Join(downloadDir, "drop.txt")
+	if err := os.WriteFile(dropPath, []byte("vmess://old"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	manager := &RemoteManager{
+		statePath:   filepath.Join(root, ".remote_sources.json"),
+		downloadDir: downloadDir,
+		client:      server.Client(),
+		state: RemoteState{
+			IntervalSeconds: 300,
+			Sources: []RemoteSource{
+				{ID: "keep", URL: keepURL, FilePath: filepath.Join(downloadDir, "keep.txt"), ETag: `"v1"`},
+				{ID: "drop", URL: dropURL, FilePath: dropPath},
+			},
+		},
+	}
+
+	newURL := server.URL + "/new.txt"
+	state, err := manager.ReplaceURLs([]string{newURL, keepURL, newURL})
+	if err != nil {
+		t.Fatalf("ReplaceURLs failed: %v", err)
+	}
+
+	if len(state.Sources) != 2 {
+		t.Fatalf("expected 2 sources, got %d", len(state.Sources))
+	}
+	if state.Sources[0].URL != newURL || state.Sources[1].URL != keepURL {
+		t.Fatalf("unexpected source order: %s, %s", state.Sources[0].URL, state.Sources[1].URL)
+	}
+	if state.Sources[1].ETag != `"v1"` {
+		t.Fatalf("existing source must keep its state, got etag %q", state.Sources[1].ETag)
+	}
+	if state.Sources[0].LastUpdated.IsZero() {
+		t.Fatal("new source should have been downloaded")
+	}
+	if _, err := os.Stat(dropPath); !os.IsNotExist(err) {
+		t.Fatalf("removed source file must be deleted, stat err: %v", err)
+	}
+
+	if _, err := manager.ReplaceURLs([]string{"ftp://bad"}); err == nil {
+		t.Fatal("expected invalid URL to be rejected")
+	}
+	if got := len(manager.GetState().Sources); got != 2 {
+		t.Fatalf("rejected replace must not change sources, have %d", got)
+	}
+}
+
+func TestResolveRemoteStatePathOverride(t *testing.T) {
+	root := t.TempDir()
+	downloadDir := filepath.Join(root, "subscriptions")
+
+	if got := resolveRemoteStatePath(downloadDir, ""); got != remoteStatePath(downloadDir) {
+		t.Fatalf("empty override must keep default path, got %q", got)
+	}
+	if got, want := resolveRemoteStatePath(downloadDir, "state/remote.json"), filepath.Join(downloadDir, "state", "remote.json"); got != want {
+		t.Fatalf("relative override: got %q, want %q", got, want)
+	}
+	abs := filepath.Join(root, "elsewhere", "remote.json")
+	if got := resolveRemoteStatePath(downloadDir, abs); got != abs {
+		t.Fatalf("absolute override: got %q, want %q", got, abs)
+	}
+}
+
+func TestMigrateStateFileToOverride(t *testing.T) {
+	root := t.TempDir()
+	downloadDir := filepath.Join(root, "subscriptions")
+	if err := os.MkdirAll(downloadDir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+
+	defaultPath := remoteStatePath(downloadDir)
+	payload := []byte(`{"intervalSeconds":120,"sources":[]}`)
+	if err := os.WriteFile(defaultPath, payload, 0o644); err != nil {
+		t.Fatalf("write default state failed: %v", err)
+	}
+
+	override := resolveRemoteStatePath(downloadDir, ".remote_sources.json")
+	if err := migrateLegacyStateFile(downloadDir, override); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	got, err := os.ReadFile(override)
+	if err != nil {
+		t.Fatalf("read migrated state failed: %v", err)
+	}
+	if string(got) != string(payload) {
+		t.Fatalf("migrated payload mismatch: got %q", string(got))
+	}
+	if _, err := os.Stat(defaultPath); !os.IsNotExist(err) {
+		t.Fatalf("old default state must be moved, stat err: %v", err)
+	}
+}
+
+// Run with -race: GetState callers must not observe manager mutations.
+func TestGetStateConcurrentWithCheckUpdates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("vmess://example"))
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	downloadDir := filepath.Join(root, "subscriptions")
+	if err := os.MkdirAll(downloadDir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+
+	sources := make([]RemoteSource, 0, 4)
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("s%d.txt", i)
+		sources = append(sources, RemoteSource{
+			ID:       name,
+			URL:      server.URL + "/" + name,
+			FilePath: filepath.Join(downloadDir, name),
+		})
+	}
+	manager := &RemoteManager{
+		statePath:   filepath.Join(root, ".remote_sources.json"),
+		downloadDir: downloadDir,
+		client:      server.Client(),
+		state:       RemoteState{IntervalSeconds: 300, Sources: sources},
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				state := manager.GetState()
+				for _, src := range state.Sources {
+					_ = src.Error + src.ETag
+					_ = src.LastChecked.IsZero()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := manager.CheckUpdates(); err != nil {
+			t.Fatalf("CheckUpdates failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
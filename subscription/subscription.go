@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 	"xray-checker/config"
 	"xray-checker/logger"
 	"xray-checker/models"
@@ -36,25 +38,40 @@ type subscriptionResult struct {
 }
 
 func InitializeConfiguration(configFile string, version string) (*[]*models.ProxyConfig, error) {
-	configs, err := ReadFromMultipleSources(config.CLIConfig.Subscription.URLs)
+	configs, err := fetchWithRetry(
+		config.CLIConfig.Subscription.URLs,
+		config.CLIConfig.Subscription.StartupRetries,
+		time.Duration(config.CLIConfig.Subscription.StartupRetryDelay)*time.Second,
+		ReadFromMultipleSources,
+	)
 	if err != nil {
 		if ShouldTreatAsEmptyResult(err) {
-			logger.Warn("Subscription source is empty/unavailable, starting with empty proxy list: %v", err)
+			logger.Warn("No proxies loaded: subscription source is empty/unavailable, starting with empty proxy list: %v", err)
 			empty := []*models.ProxyConfig{}
 			return &empty, nil
 		}
 		return nil, err
 	}
 
-	proxyConfigs := configs
+	if _, err := ParseSampleStrategy(config.CLIConfig.Proxy.MaxStrategy); err != nil {
+		return nil, err
+	}
+	if _, err := ParseCollisionMode(config.CLIConfig.Proxy.StableIDCollisions); err != nil {
+		return nil, err
+	}
+	proxyConfigs := FilterConfigs(configs)
 
 	if config.CLIConfig.Proxy.ResolveDomains {
-		proxyConfigs, err = ResolveDomainsForConfigs(configs)
+		mode, err := ParseResolveMode(config.CLIConfig.Proxy.ResolveMode)
 		if err != nil {
 			return nil, err
 		}
+		var failures []ResolveFailure
+		proxyConfigs, failures = ResolveDomainsForConfigs(proxyConfigs, mode)
+		logResolveFailures(failures)
 	}
 
+	proxyConfigs = ResolveCollisions(proxyConfigs)
 	xray.PrepareProxyConfigs(proxyConfigs)
 
 	configGenerator := xray.NewConfigGenerator()
@@ -70,6 +87,26 @@ func InitializeConfiguration(configFile string, version string) (*[]*models.Prox
 	return &proxyConfigs, nil
 }
 
+// fetchWithRetry calls fetch up to retries more times while it fails with a
+// transient error, waiting delay between attempts. Sources are often briefly
+// unavailable at boot, so startup gives them a short grace period before
+// settling for the error; errors that a retry cannot fix are returned at
+// once.
+func fetchWithRetry(
+	urls []string,
+	retries int,
+	delay time.Duration,
+	fetch func([]string) ([]*models.ProxyConfig, error),
+) ([]*models.ProxyConfig, error) {
+	configs, err := fetch(urls)
+	for attempt := 1; err != nil && isTransientFetchError(err) && attempt <= retries; attempt++ {
+		logger.Warn("Subscription fetch failed (attempt %d/%d), retrying in %s: %v", attempt, retries+1, delay, err)
+		time.Sleep(delay)
+		configs, err = fetch(urls)
+	}
+	return configs, err
+}
+
 func ReadFromMultipleSources(urls []string) ([]*models.ProxyConfig, error) {
 	if len(urls) == 0 {
 		return nil, fmt.Errorf("no subscription URLs provided")
@@ -117,7 +154,7 @@ func ReadFromMultipleSources(urls []string) ([]*models.ProxyConfig, error) {
 	wg.Wait()
 
 	var allConfigs []*models.ProxyConfig
-	var errors []error
+	var errs []error
 	var firstName string
 	successCount := 0
 
@@ -125,7 +162,7 @@ func ReadFromMultipleSources(urls []string) ([]*models.ProxyConfig, error) {
 		result := resultMap[url]
 		if result.Error != nil {
 			logger.Warn("Failed to fetch subscription %s: %v", result.URL, result.Error)
-			errors = append(errors, fmt.Errorf("%s: %v", result.URL, result.Error))
+			errs = append(errs, fmt.Errorf("%s: %w", result.URL, result.Error))
 			continue
 		}
 		logger.Debug("Fetched %d proxies from %s (name: %s)", len(result.Configs), result.URL, result.Name)
@@ -137,7 +174,7 @@ func ReadFromMultipleSources(urls []string) ([]*models.ProxyConfig, error) {
 	}
 
 	if successCount == 0 {
-		return nil, fmt.Errorf("failed to fetch any subscription: %v", errors)
+		return nil, &multiSourceError{errs: errs}
 	}
 
 	if firstName != "" {
@@ -153,6 +190,10 @@ func ReadFromMultipleSources(urls []string) ([]*models.ProxyConfig, error) {
 }
 
 func ReadFromSource(source string) ([]*models.ProxyConfig, string, error) {
+	source, err := ExpandSourceEnv(source)
+	if err != nil {
+		return nil, "", err
+	}
 	parser := NewParser()
 	result, err := parser.Parse(source)
 	if err != nil {
@@ -161,21 +202,112 @@ func ReadFromSource(source string) ([]*models.ProxyConfig, string, error) {
 	return result.Configs, result.Name, nil
 }
 
-func ResolveDomainsForConfigs(configs []*models.ProxyConfig) ([]*models.ProxyConfig, error) {
+// lookupIP is swapped out in tests.
+var lookupIP = net.LookupIP
+
+// ResolveFailure records a server domain that could not be resolved.
+type ResolveFailure struct {
+	Server string
+	Err    error
+}
+
+func (f ResolveFailure) Error() string {
+	return fmt.Sprintf("%s: %v", f.Server, f.Err)
+}
+
+// ResolveMode selects what happens when a domain resolves to several IPs.
+type ResolveMode string
+
+const (
+	// ResolveFirst uses the first address the resolver returns.
+	ResolveFirst ResolveMode = "first"
+	// ResolveExpand creates one config per address.
+	ResolveExpand ResolveMode = "expand"
+	// ResolveRoundRobin uses a different address on each resolution pass.
+	ResolveRoundRobin ResolveMode = "round-robin"
+)
+
+// ParseResolveMode maps a config value to a ResolveMode. Empty means
+// ResolveFirst.
+func ParseResolveMode(value string) (ResolveMode, error) {
+	switch mode := ResolveMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return ResolveFirst, nil
+	case ResolveFirst, ResolveExpand, ResolveRoundRobin:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown resolve mode %q, expected first, expand or round-robin", value)
+	}
+}
+
+// roundRobinNext holds the next address index per config for
+// ResolveRoundRobin, keyed by the config's domain-based stable ID. Domains
+// are resolved once per load or subscription update, so that is when the
+// index advances; it is not persisted and starts over on restart.
+var (
+	roundRobinNext = make(map[string]int)
+	roundRobinMu   sync.Mutex
+)
+
+func nextRoundRobin(key string, n int) int {
+	roundRobinMu.Lock()
+	defer roundRobinMu.Unlock()
+	i := roundRobinNext[key] % n
+	roundRobinNext[key] = i + 1
+	return i
+}
+
+// ResolveDomainsForConfigs resolves server domains and records the IP in
+// ResolvedServer, keeping Server as the original domain. mode decides how a
+// domain with several addresses is handled; only ResolveExpand produces more
+// than one config, and those get IP-based stable IDs and numbered names. Each
+// domain is resolved independently: configs whose domain fails are returned
+// unchanged and the failure is reported once per domain, so callers can go on
+// with the rest.
+func ResolveDomainsForConfigs(configs []*models.ProxyConfig, mode ResolveMode) ([]*models.ProxyConfig, []ResolveFailure) {
 	var out []*models.ProxyConfig
+	var failures []ResolveFailure
+	lookups := make(map[string][]net.IP)
+	failed := make(map[string]bool)
 	for _, cfg := range configs {
 		if ip := net.ParseIP(cfg.Server); ip != nil {
 			out = append(out, cfg)
 			continue
 		}
-
-		ips, err := net.LookupIP(cfg.Server)
-		if err != nil || len(ips) == 0 {
-			logger.Warn("Failed to resolve domain %s: %v", cfg.Server, err)
+		if failed[cfg.Server] {
 			out = append(out, cfg)
 			continue
 		}
 
+		ips, ok := lookups[cfg.Server]
+		if !ok {
+			var err error
+			ips, err = lookupIP(cfg.Server)
+			if err == nil && len(ips) == 0 {
+				err = fmt.Errorf("no addresses found")
+			}
+			if err != nil {
+				failed[cfg.Server] = true
+				failures = append(failures, ResolveFailure{Server: cfg.Server, Err: err})
+				out = append(out, cfg)
+				continue
+			}
+			lookups[cfg.Server] = ips
+		}
+
+		if mode != ResolveExpand || len(ips) == 1 {
+			clone := *cfg
+			clone.StableID = clone.GenerateStableID()
+			ip := ips[0]
+			if mode == ResolveRoundRobin && len(ips) > 1 {
+				sorted := sortedIPs(ips)
+				ip = sorted[nextRoundRobin(clone.StableID, len(sorted))]
+			}
+			clone.ResolvedServer = ip.String()
+			out = append(out, &clone)
+			continue
+		}
+
 		type resolvedConfig struct {
 			config   *models.ProxyConfig
 			stableID string
@@ -184,8 +316,11 @@ func ResolveDomainsForConfigs(configs []*models.ProxyConfig) ([]*models.ProxyCon
 
 		for _, ip := range ips {
 			clone := *cfg
-			clone.Server = ip.String()
-			clone.StableID = clone.GenerateStableID()
+			clone.ResolvedServer = ip.String()
+			// Expanded configs share the domain; tell them apart by IP.
+			byIP := clone
+			byIP.Server = clone.ResolvedServer
+			clone.StableID = byIP.GenerateStableID()
 			resolved = append(resolved, resolvedConfig{
 				config:   &clone,
 				stableID: clone.StableID,
@@ -197,11 +332,30 @@ func ResolveDomainsForConfigs(configs []*models.ProxyConfig) ([]*models.ProxyCon
 		})
 
 		for i, item := range resolved {
-			if len(ips) > 1 {
-				item.config.Name = fmt.Sprintf("%s #%d", cfg.Name, i+1)
-			}
+			item.config.Name = fmt.Sprintf("%s #%d", cfg.Name, i+1)
 			out = append(out, item.config)
 		}
 	}
-	return out, nil
+	return out, failures
+}
+
+func sortedIPs(ips []net.IP) []net.IP {
+	sorted := append([]net.IP(nil), ips...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+	return sorted
+}
+
+// logResolveFailures warns about unresolved domains without flooding the log
+// when many fail at once.
+func logResolveFailures(failures []ResolveFailure) {
+	const maxListed = 5
+	for i, f := range failures {
+		if i == maxListed {
+			logger.Warn("Failed to resolve %d more domain(s)", len(failures)-maxListed)
+			break
+		}
+		logger.Warn("Failed to resolve domain %s, keeping it unresolved: %v", f.Server, f.Err)
+	}
 }
@@ -0,0 +1,230 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"xray-checker/models"
+)
+
+func stubLookupIP(t *testing.T, records map[string][]net.IP) {
+	t.Helper()
+	prev := lookupIP
+	lookupIP = func(host string) ([]net.IP, error) {
+		if ips, ok := records[host]; ok {
+			return ips, nil
+		}
+		return nil, fmt.Errorf("no such host: %s", host)
+	}
+	t.Cleanup(func() { lookupIP = prev })
+}
+
+func TestResolveDomainsForConfigsKeepsServer(t *testing.T) {
+	stubLookupIP(t, map[string][]net.IP{
+		"node.example.com": {net.ParseIP("203.0.113.10")},
+	})
+
+	domain := &models.ProxyConfig{Protocol: "vless", Server: "node.example.com", Port: 443, Name: "domain", UUID: "u1"}
+	literal := &models.ProxyConfig{Protocol: "vless", Server: "198.51.100.1", Port: 443, Name: "ip", UUID: "u2"}
+	wantID := domain.GenerateStableID()
+
+	out, failures := ResolveDomainsForConfigs([]*models.ProxyConfig{domain, literal}, ResolveFirst)
+	if len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 configs, got %d", len(out))
+	}
+	if out[0].Server != "node.example.com" || out[0].ResolvedServer != "203.0.113.10" {
+		t.Fatalf("unexpected resolved config: server=%q resolved=%q", out[0].Server, out[0].ResolvedServer)
+	}
+	if out[0].StableID != wantID {
+		t.Fatalf("stable ID must be based on the original server, got %q want %q", out[0].StableID, wantID)
+	}
+	if out[0].DialServer() != "203.0.113.10" {
+		t.Fatalf("expected xray to dial the resolved IP, got %q", out[0].DialServer())
+	}
+	if out[1].ResolvedServer != "" || out[1].DialServer() != "198.51.100.1" {
+		t.Fatalf("IP literal must be left as is: %+v", out[1])
+	}
+}
+
+func TestResolveDomainsForConfigsPartialFailure(t *testing.T) {
+	stubLookupIP(t, map[string][]net.IP{
+		"good.example.com": {net.ParseIP("203.0.113.20")},
+	})
+
+	configs := []*models.ProxyConfig{
+		{Protocol: "vless", Server: "good.example.com", Port: 443, Name: "good", UUID: "u1"},
+		{Protocol: "vless", Server: "bogus.invalid", Port: 443, Name: "bogus-1", UUID: "u2"},
+		{Protocol: "vless", Server: "bogus.invalid", Port: 8443, Name: "bogus-2", UUID: "u3"},
+	}
+
+	out, failures := ResolveDomainsForConfigs(configs, ResolveFirst)
+	if len(out) != 3 {
+		t.Fatalf("expected all 3 configs to be kept, got %d", len(out))
+	}
+	if out[0].ResolvedServer != "203.0.113.20" {
+		t.Fatalf("resolvable domain was not resolved: %+v", out[0])
+	}
+	for _, cfg := range out[1:] {
+		if cfg.ResolvedServer != "" || cfg.Server != "bogus.invalid" {
+			t.Fatalf("unresolvable config must be kept unchanged: %+v", cfg)
+		}
+	}
+	if len(failures) != 1 {
+		t.Fatalf("expected one failure per domain, got %v", failures)
+	}
+	if failures[0].Server != "bogus.invalid" || failures[0].Err == nil {
+		t.Fatalf("unexpected failure: %+v", failures[0])
+	}
+}
+
+func TestResolveDomainsForConfigsExpand(t *testing.T) {
+	stubLookupIP(t, map[string][]net.IP{
+		"cdn.example.com": {net.ParseIP("203.0.113.2"), net.ParseIP("203.0.113.1")},
+	})
+
+	cfg := &models.ProxyConfig{Protocol: "vless", Server: "cdn.example.com", Port: 443, Name: "cdn", UUID: "u1"}
+
+	first, _ := ResolveDomainsForConfigs([]*models.ProxyConfig{cfg}, ResolveFirst)
+	if len(first) != 1 || first[0].ResolvedServer != "203.0.113.2" || first[0].StableID != cfg.GenerateStableID() {
+		t.Fatalf("first mode must keep one domain-based config: %+v", first)
+	}
+
+	out, failures := ResolveDomainsForConfigs([]*models.ProxyConfig{cfg}, ResolveExpand)
+	if len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected one config per IP, got %d", len(out))
+	}
+	seen := make(map[string]bool)
+	for _, c := range out {
+		if c.Server != "cdn.example.com" {
+			t.Fatalf("expanded config must keep the domain: %+v", c)
+		}
+		byIP := *c
+		byIP.Server = c.ResolvedServer
+		if c.StableID != byIP.GenerateStableID() {
+			t.Fatalf("expanded stable ID must include the IP: %+v", c)
+		}
+		seen[c.StableID] = true
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expanded configs must have distinct stable IDs: %v", seen)
+	}
+
+	again, _ := ResolveDomainsForConfigs([]*models.ProxyConfig{cfg}, ResolveExpand)
+	for i := range out {
+		if again[i].StableID != out[i].StableID || again[i].Name != out[i].Name {
+			t.Fatalf("expansion must be stable across passes: %+v vs %+v", again[i], out[i])
+		}
+	}
+}
+
+func TestResolveDomainsForConfigsRoundRobin(t *testing.T) {
+	stubLookupIP(t, map[string][]net.IP{
+		"rr.example.com": {net.ParseIP("203.0.113.2"), net.ParseIP("203.0.113.1")},
+	})
+
+	cfg := &models.ProxyConfig{Protocol: "vless", Server: "rr.example.com", Port: 443, Name: "rr", UUID: "u1"}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		out, _ := ResolveDomainsForConfigs([]*models.ProxyConfig{cfg}, ResolveRoundRobin)
+		if len(out) != 1 {
+			t.Fatalf("round-robin must keep one config, got %d", len(out))
+		}
+		if out[0].StableID != cfg.GenerateStableID() {
+			t.Fatalf("round-robin stable ID must stay domain-based: %q", out[0].StableID)
+		}
+		got = append(got, out[0].ResolvedServer)
+	}
+	if got[0] == got[1] || got[0] != got[2] {
+		t.Fatalf("expected addresses to rotate, got %v", got)
+	}
+}
+
+func TestResolveRoundRobinAdvancesOncePerUpdate(t *testing.T) {
+	stubLookupIP(t, map[string][]net.IP{
+		"rr-a.example.com": {net.ParseIP("203.0.113.21"), net.ParseIP("203.0.113.22")},
+		"rr-b.example.com": {net.ParseIP("203.0.113.31"), net.ParseIP("203.0.113.32")},
+	})
+	configs := []*models.ProxyConfig{
+		{Protocol: "vless", Server: "rr-a.example.com", Port: 443, Name: "a", UUID: "u1"},
+		{Protocol: "vless", Server: "rr-b.example.com", Port: 443, Name: "b", UUID: "u2"},
+	}
+
+	// Each call stands for one load or subscription update: every domain
+	// moves to its next address exactly once, independently of the others.
+	var got []string
+	for i := 0; i < 3; i++ {
+		out, _ := ResolveDomainsForConfigs(configs, ResolveRoundRobin)
+		got = append(got, out[0].ResolvedServer+","+out[1].ResolvedServer)
+	}
+	want := []string{
+		"203.0.113.21,203.0.113.31",
+		"203.0.113.22,203.0.113.32",
+		"203.0.113.21,203.0.113.31",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("round-robin addresses per update = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestParseResolveMode(t *testing.T) {
+	for value, want := range map[string]ResolveMode{"": ResolveFirst, "Expand": ResolveExpand, "round-robin": ResolveRoundRobin} {
+		got, err := ParseResolveMode(value)
+		if err != nil || got != want {
+			t.Fatalf("ParseResolveMode(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseResolveMode("random"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+func TestFetchWithRetry(t *testing.T) {
+	want := []*models.ProxyConfig{{Name: "node"}}
+	calls := 0
+	fetch := func(urls []string) ([]*models.ProxyConfig, error) {
+		calls++
+		if calls < 3 {
+			return nil, fmt.Errorf("%w: %w", ErrFetchFailed, &httpStatusError{code: http.StatusServiceUnavailable})
+		}
+		return want, nil
+	}
+
+	got, err := fetchWithRetry([]string{"https://example.com/sub"}, 3, 0, fetch)
+	if err != nil || len(got) != 1 || calls != 3 {
+		t.Fatalf("expected success on the third attempt, got %v, err=%v after %d calls", got, err, calls)
+	}
+
+	calls = 0
+	_, err = fetchWithRetry(nil, 2, 0, func(urls []string) ([]*models.ProxyConfig, error) {
+		calls++
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	})
+	if err == nil || calls != 3 {
+		t.Fatalf("expected network errors to be retried, got %v after %d calls", err, calls)
+	}
+
+	for _, permanent := range []error{
+		ErrEmptySource,
+		fmt.Errorf("%w: %w", ErrFetchFailed, &httpStatusError{code: http.StatusNotFound}),
+	} {
+		calls = 0
+		_, err = fetchWithRetry(nil, 2, 0, func(urls []string) ([]*models.ProxyConfig, error) {
+			calls++
+			return nil, permanent
+		})
+		if err != permanent || calls != 1 {
+			t.Fatalf("expected %v to be returned without retrying, got %v after %d calls", permanent, err, calls)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package subscription
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"xray-checker/logger"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+type watchTarget struct {
+	dir  string
+	file string // empty when the whole directory is watched
+}
+
+// WatchFileSources watches local file:// and folder:// sources and calls
+// onChange once per burst of filesystem events, after debounce has elapsed
+// without further events. It returns an error when no watch could be
+// established, so the caller can rely on scheduled polling instead.
+func WatchFileSources(sources []string, debounce time.Duration, onChange func(), stop <-chan struct{}) error {
+	targets := fileWatchTargets(sources)
+	if len(targets) == 0 {
+		return fmt.Errorf("no local file sources to watch")
+	}
+	if debounce <= 0 {
+		debounce = time.Second
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	watchedDirs := make(map[string]bool)
+	for _, target := range targets {
+		if watchedDirs[target.dir] {
+			continue
+		}
+		if err := watcher.Add(target.dir); err != nil {
+			logger.Warn("Failed to watch %s: %v", target.dir, err)
+			continue
+		}
+		watchedDirs[target.dir] = true
+	}
+	if len(watchedDirs) == 0 {
+		_ = watcher.Close()
+		return fmt.Errorf("failed to watch any local source")
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var timer *time.Timer
+		var fire <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isRelevantWatchEvent(event, targets) {
+					continue
+				}
+				logger.Debug("Local subscription change detected: %s %s", event.Op, event.Name)
+				if timer == nil {
+					timer = time.NewTimer(debounce)
+				} else {
+					if !timer.Stop() {
+						select {
+						case <-timer.C:
+						default:
+						}
+					}
+					timer.Reset(debounce)
+				}
+				fire = timer.C
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("Local subscription watcher error: %v", err)
+			case <-fire:
+				fire = nil
+				onChange()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func fileWatchTargets(sources []string) []watchTarget {
+	var targets []watchTarget
+	for _, src := range sources {
+		var path string
+		switch {
+		case strings.HasPrefix(src, "file://"):
+			path = strings.TrimPrefix(src, "file://")
+		case strings.HasPrefix(src, "folder://"):
+			path = strings.TrimPrefix(src, "folder://")
+		default:
+			continue
+		}
+		path = filepath.Clean(path)
+
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			targets = append(targets, watchTarget{dir: path})
+			continue
+		}
+		// Watch the parent directory so editors that replace files atomically
+		// (write temp + rename) are still picked up.
+		targets = append(targets, watchTarget{dir: filepath.Dir(path), file: filepath.Base(path)})
+	}
+	return targets
+}
+
+func isRelevantWatchEvent(event fsnotify.Event, targets []watchTarget) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	dir := filepath.Dir(filepath.Clean(event.Name))
+	base := filepath.Base(event.Name)
+	for _, target := range targets {
+		if target.dir != dir {
+			continue
+		}
+		if target.file != "" {
+			if target.file == base {
+				return true
+			}
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(base))
+		if ext == ".txt" || ext == ".json" {
+			return true
+		}
+	}
+	return false
+}
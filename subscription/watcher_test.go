@@ -0,0 +1,54 @@
+package subscription
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchFileSourcesDebouncesChanges(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "list.txt")
+	if err := os.WriteFile(path, []byte("vless://a"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	var calls atomic.Int32
+	stop := make(chan struct{})
+	defer close(stop)
+
+	if err := WatchFileSources([]string{"file://" + path}, 100*time.Millisecond, func() {
+		calls.Add(1)
+	}, stop); err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(path, []byte("vless://b"), 0o644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := os.WriteFile(filepath.Join(root, "unrelated.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected exactly one debounced reload, got %d", got)
+	}
+}
+
+func TestWatchFileSourcesWithoutLocalSources(t *testing.T) {
+	err := WatchFileSources([]string{"https://example.com/sub"}, time.Second, func() {}, make(chan struct{}))
+	if err == nil {
+		t.Fatal("expected error when there are no local sources")
+	}
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"xray-checker/logger"
+)
+
+// ParseAccessLogLevel maps a --web-access-log value to the level requests are
+// logged at. "off" (or empty) returns logger.LevelNone.
+func ParseAccessLogLevel(value string) (logger.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "off":
+		return logger.LevelNone, nil
+	case "info":
+		return logger.LevelInfo, nil
+	case "debug":
+		return logger.LevelDebug, nil
+	default:
+		return logger.LevelNone, fmt.Errorf("unknown access log level %q, expected off, info or debug", value)
+	}
+}
+
+// AccessLogMiddleware logs method, path, status, response bytes and duration
+// of every request at level, so it follows the configured log level like any
+// other line. Only the path is logged: query strings can carry tokens. Event
+// streams are logged once, when they end. LevelNone returns next unchanged.
+func AccessLogMiddleware(level logger.Level) func(http.Handler) http.Handler {
+	if level == logger.LevelNone {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	logf := logger.Info
+	if level == logger.LevelDebug {
+		logf = logger.Debug
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &accessLogWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			logf("HTTP %s %s %d %dB %s", r.Method, r.URL.Path, rec.status, rec.bytes, time.Since(start).Round(time.Microsecond))
+		})
+	}
+}
+
+type accessLogWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (a *accessLogWriter) WriteHeader(status int) {
+	if !a.wroteHeader {
+		a.status = status
+		a.wroteHeader = true
+	}
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *accessLogWriter) Write(b []byte) (int, error) {
+	a.wroteHeader = true
+	n, err := a.ResponseWriter.Write(b)
+	a.bytes += int64(n)
+	return n, err
+}
+
+func (a *accessLogWriter) Flush() {
+	if f, ok := a.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (a *accessLogWriter) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
@@ -5,10 +5,16 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/url"
+	htmltemplate "html/template"
+	"math"
 	"net/http"
+	"net/url"
+	"runtime"
+	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -23,31 +29,93 @@ import (
 var openAPISpec []byte
 
 type ProxyInfo struct {
-	Index     int    `json:"index"`
-	StableID  string `json:"stableId"`
-	Name      string `json:"name"`
-	SubName   string `json:"subName"`
-	Server    string `json:"server"`
-	Port      int    `json:"port"`
-	Protocol  string `json:"protocol"`
-	ProxyPort int    `json:"proxyPort"`
-	Online    bool   `json:"online"`
-	LatencyMs int64  `json:"latencyMs"`
-	Config    string `json:"config,omitempty"`
+	Index          int    `json:"index"`
+	StableID       string `json:"stableId"`
+	Name           string `json:"name"`
+	SubName        string `json:"subName"`
+	Server         string `json:"server"`
+	ResolvedServer string `json:"resolvedServer,omitempty"`
+	Port           int    `json:"port"`
+	Protocol       string `json:"protocol"`
+	ProxyPort      int    `json:"proxyPort"`
+	Online         bool   `json:"online"`
+	State          string `json:"state"`
+	LatencyMs      int64  `json:"latencyMs"`
+	BadSinceSec    int64  `json:"badSinceSec,omitempty"`
+	LastError      string `json:"lastError,omitempty"`
+	UDPExitIP      string `json:"udpExitIp,omitempty"`
+	Config         string `json:"config,omitempty"`
 }
 
 type PublicProxyInfo struct {
-	StableID  string `json:"stableId"`
-	Name      string `json:"name"`
-	Online    bool   `json:"online"`
-	LatencyMs int64  `json:"latencyMs"`
+	StableID     string `json:"stableId"`
+	Name         string `json:"name"`
+	Online       bool   `json:"online"`
+	State        string `json:"state"`
+	LatencyMs    int64  `json:"latencyMs"`
+	EMALatencyMs int64  `json:"emaLatencyMs,omitempty"`
+	BadSinceSec  int64  `json:"badSinceSec,omitempty"`
 }
 
 type StatusResponse struct {
 	Total        int   `json:"total"`
 	Online       int   `json:"online"`
 	Offline      int   `json:"offline"`
+	Unknown      int   `json:"unknown"`
+	Pending      int   `json:"pending"`
+	Degraded     int   `json:"degraded"`
 	AvgLatencyMs int64 `json:"avgLatencyMs"`
+	P50LatencyMs int64 `json:"p50LatencyMs"`
+	P90LatencyMs int64 `json:"p90LatencyMs"`
+	P99LatencyMs int64 `json:"p99LatencyMs"`
+
+	ByProtocol map[string]ProtocolStatus `json:"byProtocol"`
+}
+
+// ProtocolStatus counts the proxies of one protocol.
+type ProtocolStatus struct {
+	Total  int `json:"total"`
+	Online int `json:"online"`
+}
+
+const (
+	ProxyStateUnknown = "unknown"
+	ProxyStateOnline  = "online"
+	ProxyStateOffline = "offline"
+	// ProxyStateDegraded marks a proxy whose check succeeded but exceeded
+	// the latency cap. Its online flag is false, but the status summary
+	// counts it as degraded, not offline.
+	ProxyStateDegraded = "degraded"
+	// ProxyStatePendingUp marks an offline or unchecked proxy whose passing
+	// checks have not yet reached --proxy-debounce-up; it counts as offline.
+	ProxyStatePendingUp = checker.PendingUp
+	// ProxyStatePendingDown marks an online proxy whose failing checks have
+	// not yet reached --proxy-debounce-down; it counts as online.
+	ProxyStatePendingDown = checker.PendingDown
+)
+
+// trackedState refines state with the debounce and degraded states the
+// checker tracks for the proxy.
+func trackedState(proxyChecker *checker.ProxyChecker, stableID, state string) string {
+	if pending := proxyChecker.GetPendingStateByStableID(stableID); pending != "" {
+		return pending
+	}
+	if proxyChecker.IsDegradedByStableID(stableID) {
+		return ProxyStateDegraded
+	}
+	return state
+}
+
+// proxyState maps a status lookup to a tri-state so proxies that have not been
+// checked yet are not reported as offline.
+func proxyState(online bool, err error) string {
+	if err != nil {
+		return ProxyStateUnknown
+	}
+	if online {
+		return ProxyStateOnline
+	}
+	return ProxyStateOffline
 }
 
 type ConfigResponse struct {
@@ -66,6 +134,34 @@ type SystemInfoResponse struct {
 	Uptime    string `json:"uptime"`
 	UptimeSec int64  `json:"uptimeSec"`
 	Instance  string `json:"instance"`
+	Paused    bool   `json:"paused"`
+}
+
+// ScheduleResponse reports when checks and subscription updates run next.
+// Next run times are omitted when the job is not scheduled, such as
+// subscription updates with --subscription-update off.
+type ScheduleResponse struct {
+	CheckIntervalSec              int        `json:"checkIntervalSec"`
+	NextCheckAt                   *time.Time `json:"nextCheckAt,omitempty"`
+	SubscriptionUpdateIntervalSec int        `json:"subscriptionUpdateIntervalSec,omitempty"`
+	NextSubscriptionUpdateAt      *time.Time `json:"nextSubscriptionUpdateAt,omitempty"`
+}
+
+type PauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+type ReloadAssetsResponse struct {
+	Files          []string `json:"files"`
+	CustomTemplate bool     `json:"customTemplate"`
+	CustomCSS      bool     `json:"customCss"`
+}
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
 }
 
 type SystemIPResponse struct {
@@ -79,26 +175,42 @@ type APIResponse struct {
 }
 
 type RemoteSourceInfo struct {
-	ID          string `json:"id"`
-	URL         string `json:"url"`
-	FileName    string `json:"fileName"`
-	LastChecked string `json:"lastChecked,omitempty"`
-	LastUpdated string `json:"lastUpdated,omitempty"`
-	Error       string `json:"error,omitempty"`
+	ID              string `json:"id"`
+	URL             string `json:"url"`
+	Name            string `json:"name,omitempty"`
+	FileName        string `json:"fileName"`
+	Enabled         bool   `json:"enabled"`
+	IntervalSeconds int    `json:"intervalSeconds,omitempty"`
+	LastChecked     string `json:"lastChecked,omitempty"`
+	LastUpdated     string `json:"lastUpdated,omitempty"`
+	Error           string `json:"error,omitempty"`
+	ProxyCount      int    `json:"proxyCount"`
+	LastStatus      string `json:"lastStatus"`
 }
 
 type RemoteStateResponse struct {
 	IntervalSeconds int                `json:"intervalSeconds"`
 	DownloadDir     string             `json:"downloadDir"`
+	StatePath       string             `json:"statePath"`
 	Sources         []RemoteSourceInfo `json:"sources"`
 }
 
 type rankedProxy struct {
 	proxy   *models.ProxyConfig
 	latency time.Duration
+	score   time.Duration
 	key     string
 }
 
+// rank returns the composite score when the selector has computed one and
+// falls back to the raw latency otherwise.
+func (r rankedProxy) rank() time.Duration {
+	if r.score > 0 {
+		return r.score
+	}
+	return r.latency
+}
+
 type keyStatusCounts struct {
 	online  int
 	offline int
@@ -121,13 +233,23 @@ type activeEntry struct {
 }
 
 type stableTopBLSelector struct {
-	limit         int
-	mu            sync.Mutex
-	emaByKey      map[string]time.Duration
-	active        map[string]*activeEntry
-	published     []string
-	lastPublished time.Time
-	hadEmergency  bool
+	limit           int
+	latencyWeight   float64
+	stabilityWeight float64
+	blTag           string
+	cidrTag         string
+	pins            []string
+	pinsInQuota     bool
+	pinnedKeys      map[string]struct{}
+	mu              sync.Mutex
+	emaByKey        map[string]time.Duration
+	restoredEMA     map[string]time.Duration // stable ID -> EMA loaded from the latency store
+	latencyStore    *checker.LatencyStore
+	lossByKey       map[string]float64
+	active          map[string]*activeEntry
+	published       []string
+	lastPublished   time.Time
+	hadEmergency    bool
 }
 
 const (
@@ -137,8 +259,15 @@ const (
 	topBLReplaceMinMs   = 50 * time.Millisecond
 	topBLReplaceMinGain = 0.20
 	topBLBadStreakLimit = 2
-	topBLQuota          = 10
-	topCIDRQuota        = 10
+	// topBLStabilityPenalty is the latency-equivalent cost of a fully unstable
+	// key (loss ratio 1.0) at stability weight 1.0.
+	topBLStabilityPenalty = 200 * time.Millisecond
+	topBLLatencyWeight    = 1.0
+	topBLStabilityWeight  = 1.0
+	topBLQuota            = 10
+	topCIDRQuota          = 10
+	topBLDefaultTag       = "BL"
+	topCIDRDefaultTag     = "CIDR"
 )
 
 func writeJSON(w http.ResponseWriter, data interface{}) {
@@ -158,22 +287,34 @@ func writeError(w http.ResponseWriter, message string, code int) {
 	})
 }
 
-func toProxyInfo(proxy *models.ProxyConfig, online bool, latency time.Duration, startPort int) ProxyInfo {
+func toProxyInfo(proxy *models.ProxyConfig, online bool, latency time.Duration, statusErr error, startPort int) ProxyInfo {
 	return ProxyInfo{
-		Index:     proxy.Index,
-		StableID:  proxy.StableID,
-		Name:      sanitizeText(proxy.Name),
-		SubName:   proxy.SubName,
-		Server:    sanitizeText(proxy.Server),
-		Port:      proxy.Port,
-		Protocol:  proxy.Protocol,
-		ProxyPort: startPort + proxy.Index,
-		Online:    online,
-		LatencyMs: latency.Milliseconds(),
-		Config:    sanitizeConfig(proxy.SourceLine),
+		Index:          proxy.Index,
+		StableID:       proxy.StableID,
+		Name:           sanitizeJSONText(proxy.Name),
+		SubName:        proxy.SubName,
+		Server:         sanitizeJSONText(proxy.Server),
+		ResolvedServer: sanitizeJSONText(proxy.ResolvedServer),
+		Port:           proxy.Port,
+		Protocol:       proxy.Protocol,
+		ProxyPort:      proxy.InboundPort(startPort),
+		Online:         online,
+		State:          proxyState(online, statusErr),
+		LatencyMs:      latency.Milliseconds(),
+		Config:         sanitizeConfig(proxy.SourceLine),
 	}
 }
 
+// badSinceSeconds returns how long the proxy has been failing or slow, or 0
+// when it is healthy.
+func badSinceSeconds(proxyChecker *checker.ProxyChecker, stableID string) int64 {
+	since, ok := proxyChecker.GetBadSinceByStableID(stableID)
+	if !ok {
+		return 0
+	}
+	return int64(time.Since(since).Seconds())
+}
+
 // APIPublicProxiesHandler returns public info for all proxies (no auth required)
 // @Summary List all proxies (public)
 // @Description Returns a list of all proxies with status (no sensitive data, no auth)
@@ -188,13 +329,20 @@ func APIPublicProxiesHandler(proxyChecker *checker.ProxyChecker) http.HandlerFun
 		result := make([]PublicProxyInfo, 0, len(proxies))
 
 		for _, proxy := range proxies {
-			status, latency, _ := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
-			result = append(result, PublicProxyInfo{
-				StableID:  proxy.StableID,
-				Name:      sanitizeText(proxy.Name),
-				Online:    status,
-				LatencyMs: latency.Milliseconds(),
-			})
+			status, latency, err := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
+			info := PublicProxyInfo{
+				StableID:    proxy.StableID,
+				Name:        sanitizeJSONText(proxy.Name),
+				Online:      status,
+				State:       proxyState(status, err),
+				LatencyMs:   latency.Milliseconds(),
+				BadSinceSec: badSinceSeconds(proxyChecker, proxy.StableID),
+			}
+			info.State = trackedState(proxyChecker, proxy.StableID, info.State)
+			if ema, ok := proxyChecker.GetEMALatencyByStableID(proxy.StableID); ok {
+				info.EMALatencyMs = ema.Milliseconds()
+			}
+			result = append(result, info)
 		}
 
 		writeJSON(w, result)
@@ -203,20 +351,82 @@ func APIPublicProxiesHandler(proxyChecker *checker.ProxyChecker) http.HandlerFun
 
 // APIProxiesHandler returns info for all proxies
 // @Summary List all proxies
-// @Description Returns a list of all proxies with status information
+// @Description Returns a list of all proxies with status information. The weak ETag changes when a check iteration finishes or the proxy list is updated; send it in If-None-Match to get 304 while nothing changed.
 // @Tags proxies
 // @Produce json
+// @Param If-None-Match header string false "ETag of a previous response"
 // @Success 200 {array} ProxyInfo
+// @Success 304 "Not modified"
 // @Router /api/v1/proxies [get]
 func APIProxiesHandler(proxyChecker *checker.ProxyChecker, startPort int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if notModified(w, r, checkerETag(proxyChecker)) {
+			return
+		}
 		proxies := proxyChecker.GetProxies()
 		logger.Debug("API proxies requested: %d", len(proxies))
-		result := make([]ProxyInfo, 0, len(proxies))
+		writeJSON(w, proxyInfos(proxyChecker, proxies, startPort))
+	}
+}
+
+// proxyInfos returns the full info of each proxy, as served to authenticated
+// clients.
+func proxyInfos(proxyChecker *checker.ProxyChecker, proxies []*models.ProxyConfig, startPort int) []ProxyInfo {
+	result := make([]ProxyInfo, 0, len(proxies))
+	for _, proxy := range proxies {
+		status, latency, err := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
+		info := toProxyInfo(proxy, status, latency, err, startPort)
+		info.BadSinceSec = badSinceSeconds(proxyChecker, proxy.StableID)
+		info.LastError = sanitizeJSONText(proxyChecker.GetLastErrorByStableID(proxy.StableID))
+		info.UDPExitIP = proxyChecker.GetUDPExitIPByStableID(proxy.StableID)
+		info.State = trackedState(proxyChecker, proxy.StableID, info.State)
+		result = append(result, info)
+	}
+	return result
+}
 
+// APIOfflineProxiesHandler returns the proxies that are currently offline
+// @Summary List offline proxies
+// @Description Returns proxies whose last check failed, excluding degraded proxies and new proxies still within the offline grace period
+// @Tags proxies
+// @Produce json
+// @Param minDownSec query int false "Only return proxies that have been down for at least this many seconds"
+// @Success 200 {array} ProxyInfo
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/proxies/offline [get]
+func APIOfflineProxiesHandler(proxyChecker *checker.ProxyChecker, startPort int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var minDown time.Duration
+		if value := r.URL.Query().Get("minDownSec"); value != "" {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				writeError(w, "minDownSec must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			minDown = time.Duration(seconds) * time.Second
+		}
+
+		proxies := proxyChecker.GetProxies()
+		result := make([]ProxyInfo, 0)
 		for _, proxy := range proxies {
-			status, latency, _ := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
-			result = append(result, toProxyInfo(proxy, status, latency, startPort))
+			status, latency, err := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
+			if err != nil || status ||
+				proxyChecker.InOfflineGrace(proxy.StableID) ||
+				proxyChecker.IsDegradedByStableID(proxy.StableID) {
+				continue
+			}
+			if minDown > 0 {
+				since, ok := proxyChecker.GetBadSinceByStableID(proxy.StableID)
+				if !ok || time.Since(since) < minDown {
+					continue
+				}
+			}
+			info := toProxyInfo(proxy, status, latency, err, startPort)
+			info.BadSinceSec = badSinceSeconds(proxyChecker, proxy.StableID)
+			info.LastError = sanitizeJSONText(proxyChecker.GetLastErrorByStableID(proxy.StableID))
+			info.UDPExitIP = proxyChecker.GetUDPExitIPByStableID(proxy.StableID)
+			info.State = trackedState(proxyChecker, proxy.StableID, info.State)
+			result = append(result, info)
 		}
 
 		writeJSON(w, result)
@@ -253,51 +463,110 @@ func APIProxyHandler(proxyChecker *checker.ProxyChecker, startPort int) http.Han
 			return
 		}
 
-		status, latency, _ := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
-		writeJSON(w, toProxyInfo(proxy, status, latency, startPort))
+		status, latency, err := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
+		info := toProxyInfo(proxy, status, latency, err, startPort)
+		info.BadSinceSec = badSinceSeconds(proxyChecker, proxy.StableID)
+		info.LastError = sanitizeJSONText(proxyChecker.GetLastErrorByStableID(proxy.StableID))
+		info.UDPExitIP = proxyChecker.GetUDPExitIPByStableID(proxy.StableID)
+		info.State = trackedState(proxyChecker, proxy.StableID, info.State)
+		writeJSON(w, info)
 	}
 }
 
 // APIStatusHandler returns system status summary
 // @Summary Get system status
-// @Description Returns summary statistics about all proxies
+// @Description Returns summary statistics about all proxies. Supports If-None-Match with the same weak ETag as /api/v1/proxies.
 // @Tags status
 // @Produce json
+// @Param If-None-Match header string false "ETag of a previous response"
 // @Success 200 {object} StatusResponse
+// @Success 304 "Not modified"
 // @Router /api/v1/status [get]
 func APIStatusHandler(proxyChecker *checker.ProxyChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		proxies := proxyChecker.GetProxies()
+		if notModified(w, r, checkerETag(proxyChecker)) {
+			return
+		}
+		writeJSON(w, statusSummary(proxyChecker, proxyChecker.GetProxies()))
+	}
+}
 
-		var online, offline int
-		var totalLatency int64
-		var latencyCount int
+// statusSummary counts proxies by state and summarizes their latencies.
+func statusSummary(proxyChecker *checker.ProxyChecker, proxies []*models.ProxyConfig) StatusResponse {
+	var online, offline, unknown, pending, degraded int
+	var latencies []int64
+	byProtocol := make(map[string]ProtocolStatus)
 
-		for _, proxy := range proxies {
-			status, latency, _ := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
-			if status {
-				online++
-				if latency > 0 {
-					totalLatency += latency.Milliseconds()
-					latencyCount++
-				}
-			} else {
-				offline++
+	for _, proxy := range proxies {
+		status, latency, err := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
+		countProtocol(byProtocol, proxy.Protocol, err == nil && status)
+		if err != nil {
+			unknown++
+			continue
+		}
+		if status {
+			online++
+			if latency > 0 {
+				latencies = append(latencies, latency.Milliseconds())
 			}
+		} else if proxyChecker.InOfflineGrace(proxy.StableID) {
+			pending++
+		} else if proxyChecker.IsDegradedByStableID(proxy.StableID) {
+			degraded++
+		} else {
+			offline++
 		}
+	}
 
-		var avgLatency int64
-		if latencyCount > 0 {
-			avgLatency = totalLatency / int64(latencyCount)
+	var avgLatency int64
+	if len(latencies) > 0 {
+		var total int64
+		for _, ms := range latencies {
+			total += ms
 		}
+		avgLatency = total / int64(len(latencies))
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	return StatusResponse{
+		Total:        len(proxies),
+		Online:       online,
+		Offline:      offline,
+		Unknown:      unknown,
+		Pending:      pending,
+		Degraded:     degraded,
+		AvgLatencyMs: avgLatency,
+		P50LatencyMs: percentile(latencies, 50),
+		P90LatencyMs: percentile(latencies, 90),
+		P99LatencyMs: percentile(latencies, 99),
+		ByProtocol:   byProtocol,
+	}
+}
 
-		writeJSON(w, StatusResponse{
-			Total:        len(proxies),
-			Online:       online,
-			Offline:      offline,
-			AvgLatencyMs: avgLatency,
-		})
+// countProtocol adds one proxy of protocol to the breakdown. Protocol names
+// are lowercased so "VLESS" and "vless" share an entry.
+func countProtocol(byProtocol map[string]ProtocolStatus, protocol string, online bool) {
+	protocol = strings.ToLower(strings.TrimSpace(protocol))
+	if protocol == "" {
+		protocol = "unknown"
+	}
+	entry := byProtocol[protocol]
+	entry.Total++
+	if online {
+		entry.Online++
+	}
+	byProtocol[protocol] = entry
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values, or 0
+// when there are none.
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
 	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
 }
 
 // APIConfigHandler returns current configuration
@@ -307,22 +576,35 @@ func APIStatusHandler(proxyChecker *checker.ProxyChecker) http.HandlerFunc {
 // @Produce json
 // @Success 200 {object} ConfigResponse
 // @Router /api/v1/config [get]
-func APIConfigHandler(proxyChecker *checker.ProxyChecker) http.HandlerFunc {
+func APIConfigHandler(proxyChecker *checker.ProxyChecker, scheduler *checker.CheckScheduler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		subNames := CollectSubscriptionNames(proxyChecker.GetProxies())
-		writeJSON(w, ConfigResponse{
-			CheckInterval:              config.CLIConfig.Proxy.CheckInterval,
-			CheckMethod:                config.CLIConfig.Proxy.CheckMethod,
-			Timeout:                    config.CLIConfig.Proxy.Timeout,
-			StartPort:                  config.CLIConfig.Xray.StartPort,
-			SubscriptionUpdate:         config.CLIConfig.Subscription.Update,
-			SubscriptionUpdateInterval: config.CLIConfig.Subscription.UpdateInterval,
-			SimulateLatency:            config.CLIConfig.Proxy.SimulateLatency,
-			SubscriptionNames:          subNames,
-		})
+		writeJSON(w, currentConfig(proxyChecker.GetProxies(), scheduler))
+	}
+}
+
+func currentConfig(proxies []*models.ProxyConfig, scheduler *checker.CheckScheduler) ConfigResponse {
+	return ConfigResponse{
+		CheckInterval:              checkInterval(scheduler),
+		CheckMethod:                config.CLIConfig.Proxy.CheckMethod,
+		Timeout:                    config.CLIConfig.Proxy.Timeout,
+		StartPort:                  config.CLIConfig.Xray.StartPort,
+		SubscriptionUpdate:         config.CLIConfig.Subscription.Update,
+		SubscriptionUpdateInterval: config.CLIConfig.Subscription.UpdateInterval,
+		SimulateLatency:            config.CLIConfig.Proxy.SimulateLatency,
+		SubscriptionNames:          CollectSubscriptionNames(proxies),
 	}
 }
 
+// checkInterval returns the proxy check interval in seconds. The scheduler
+// holds it once it can change at runtime; without one the configured value
+// applies.
+func checkInterval(scheduler *checker.CheckScheduler) int {
+	if scheduler == nil {
+		return config.CLIConfig.Proxy.CheckInterval
+	}
+	return scheduler.Interval()
+}
+
 func CollectSubscriptionNames(proxies []*models.ProxyConfig) []string {
 	seen := make(map[string]bool)
 	var names []string
@@ -342,18 +624,185 @@ func CollectSubscriptionNames(proxies []*models.ProxyConfig) []string {
 // @Produce json
 // @Success 200 {object} SystemInfoResponse
 // @Router /api/v1/system/info [get]
-func APISystemInfoHandler(version string, startTime time.Time) http.HandlerFunc {
+func APISystemInfoHandler(version string, startTime time.Time, proxyChecker *checker.ProxyChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, systemInfo(version, startTime, proxyChecker))
+	}
+}
+
+func systemInfo(version string, startTime time.Time, proxyChecker *checker.ProxyChecker) SystemInfoResponse {
+	uptime := time.Since(startTime)
+	return SystemInfoResponse{
+		Version:   version,
+		Uptime:    formatDuration(uptime),
+		UptimeSec: int64(uptime.Seconds()),
+		Instance:  config.CLIConfig.Metrics.Instance,
+		Paused:    proxyChecker.IsPaused(),
+	}
+}
+
+// APISystemScheduleHandler returns the check and subscription update schedule
+// @Summary Get schedule
+// @Description Returns the current check and subscription update intervals and when each runs next
+// @Tags system
+// @Produce json
+// @Success 200 {object} ScheduleResponse
+// @Router /api/v1/system/schedule [get]
+func APISystemScheduleHandler(checks, updates *checker.CheckScheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resp ScheduleResponse
+		if checks != nil {
+			resp.CheckIntervalSec = checks.Interval()
+			resp.NextCheckAt = nextRunTime(checks)
+		}
+		if updates != nil {
+			resp.SubscriptionUpdateIntervalSec = updates.Interval()
+			resp.NextSubscriptionUpdateAt = nextRunTime(updates)
+		}
+		writeJSON(w, resp)
+	}
+}
+
+func nextRunTime(scheduler *checker.CheckScheduler) *time.Time {
+	next := scheduler.NextRun()
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// APISystemPauseHandler pauses proxy checks
+// @Summary Pause checks
+// @Description Enters maintenance mode: check iterations are skipped while the API keeps serving last known data
+// @Tags system
+// @Produce json
+// @Success 200 {object} PauseResponse
+// @Router /api/v1/system/pause [post]
+func APISystemPauseHandler(proxyChecker *checker.ProxyChecker) http.HandlerFunc {
+	return setPausedHandler(proxyChecker, true)
+}
+
+// APISystemResumeHandler resumes proxy checks
+// @Summary Resume checks
+// @Description Leaves maintenance mode
+// @Tags system
+// @Produce json
+// @Success 200 {object} PauseResponse
+// @Router /api/v1/system/resume [post]
+func APISystemResumeHandler(proxyChecker *checker.ProxyChecker) http.HandlerFunc {
+	return setPausedHandler(proxyChecker, false)
+}
+
+// APISystemReloadAssetsHandler reloads custom web assets
+// @Summary Reload custom assets
+// @Description Re-reads the custom assets directory so template and static changes take effect without a restart. On failure the previous assets stay in use.
+// @Tags system
+// @Produce json
+// @Success 200 {object} ReloadAssetsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/system/reload-assets [post]
+func APISystemReloadAssetsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		uptime := time.Since(startTime)
-		writeJSON(w, SystemInfoResponse{
-			Version:   version,
-			Uptime:    formatDuration(uptime),
-			UptimeSec: int64(uptime.Seconds()),
-			Instance:  config.CLIConfig.Metrics.Instance,
+		if r.Method != http.MethodPost {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		loader, err := ReloadAssetLoader()
+		switch {
+		case errors.Is(err, ErrAssetsNotConfigured):
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, ErrAssetReloadInProgress):
+			writeError(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			logger.Warn("Custom assets reload failed, keeping previous assets: %v", err)
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logger.Info("Custom assets reloaded via API")
+		writeJSON(w, ReloadAssetsResponse{
+			Files:          loader.FileNames(),
+			CustomTemplate: loader.HasCustomTemplate(),
+			CustomCSS:      loader.HasCustomCSS(),
 		})
 	}
 }
 
+func setPausedHandler(proxyChecker *checker.ProxyChecker, paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		proxyChecker.SetPaused(paused)
+		if paused {
+			logger.Info("Proxy checks paused via API")
+		} else {
+			logger.Info("Proxy checks resumed via API")
+		}
+		writeJSON(w, PauseResponse{Paused: paused})
+	}
+}
+
+// NewVersionInfo builds version metadata from ldflags values, falling back to
+// the VCS stamps embedded by the Go toolchain when they are not set.
+func NewVersionInfo(version, commit, buildDate string) VersionResponse {
+	info := VersionResponse{
+		Version:   version,
+		GoVersion: runtime.Version(),
+		GitCommit: commit,
+		BuildDate: buildDate,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.GitCommit == "" {
+					info.GitCommit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+		if info.Version == "" || info.Version == "unknown" {
+			if v := bi.Main.Version; v != "" && v != "(devel)" {
+				info.Version = v
+			}
+		}
+	}
+
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// APIVersionHandler returns build metadata
+// @Summary Get version
+// @Description Returns version, Go version, git commit and build date
+// @Tags system
+// @Produce json
+// @Success 200 {object} VersionResponse
+// @Router /api/v1/version [get]
+func APIVersionHandler(info VersionResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, info)
+	}
+}
+
 // APISystemIPHandler returns current IP
 // @Summary Get current IP
 // @Description Returns the current detected IP address
@@ -398,16 +847,32 @@ func APIOpenAPIHandler() http.HandlerFunc {
 	}
 }
 
-func APIDocsHandler() http.HandlerFunc {
+// APIDocsHandler serves Swagger UI. Assets are loaded from assetsURL when set
+// (e.g. a CDN) and from /static/ otherwise; when neither source has them the
+// page explains how to fix it and links the raw spec instead.
+func APIDocsHandler(assetsURL string) http.HandlerFunc {
+	assetsURL = strings.TrimSpace(assetsURL)
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Write([]byte(swaggerUIHTML))
+		_ = swaggerUITmpl.Execute(w, struct {
+			AssetsURL       string
+			AssetsAvailable bool
+		}{
+			AssetsURL:       assetsURL,
+			AssetsAvailable: assetsURL != "" || hasStaticAsset("swagger-ui-bundle.js") && hasStaticAsset("swagger-ui.css"),
+		})
 	}
 }
 
 // APITopBLSubscriptionHandler returns base64-encoded subscription with top fastest BL and CIDR configs.
 func APITopBLSubscriptionHandler(proxyChecker *checker.ProxyChecker, requiredToken string) http.HandlerFunc {
 	selector := newStableTopBLSelector(topBLQuota + topCIDRQuota)
+	selector.setWeights(config.CLIConfig.Web.TopBLLatencyWeight, config.CLIConfig.Web.TopBLStabilityWeight)
+	selector.setTags(config.CLIConfig.Web.TopBLTag, config.CLIConfig.Web.TopCIDRTag)
+	selector.setPins(config.CLIConfig.Web.TopBLPins, config.CLIConfig.Web.TopBLPinsInQuota)
+	if proxyChecker != nil {
+		selector.setLatencyStore(proxyChecker.LatencyStore())
+	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -422,7 +887,21 @@ func APITopBLSubscriptionHandler(proxyChecker *checker.ProxyChecker, requiredTok
 			}
 		}
 
-		links := selector.Next(proxyChecker.GetProxies(), proxyChecker.GetProxyStatusByStableID, time.Now())
+		groupTag := ""
+		if group := strings.TrimSpace(r.URL.Query().Get("group")); group != "" {
+			tag, ok := selector.groupTag(group)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			groupTag = tag
+		}
+
+		proxies := proxyChecker.GetProxies()
+		links := selector.Next(proxies, proxyChecker.GetProxyStatusByStableID, time.Now())
+		if groupTag != "" {
+			links = filterLinksByTag(links, proxies, groupTag)
+		}
 
 		payload := strings.Join(links, "\n")
 		encoded := base64.StdEncoding.EncodeToString([]byte(payload))
@@ -448,10 +927,76 @@ func newStableTopBLSelector(limit int) *stableTopBLSelector {
 		limit = 10
 	}
 	return &stableTopBLSelector{
-		limit:    limit,
-		emaByKey: make(map[string]time.Duration),
-		active:   make(map[string]*activeEntry),
+		limit:           limit,
+		latencyWeight:   topBLLatencyWeight,
+		stabilityWeight: topBLStabilityWeight,
+		blTag:           topBLDefaultTag,
+		cidrTag:         topCIDRDefaultTag,
+		emaByKey:        make(map[string]time.Duration),
+		lossByKey:       make(map[string]float64),
+		active:          make(map[string]*activeEntry),
+	}
+}
+
+// setWeights overrides the composite score weights. Non-positive latency weight
+// and negative stability weight fall back to defaults.
+func (s *stableTopBLSelector) setWeights(latencyWeight, stabilityWeight float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if latencyWeight <= 0 {
+		latencyWeight = topBLLatencyWeight
+	}
+	if stabilityWeight < 0 {
+		stabilityWeight = topBLStabilityWeight
+	}
+	s.latencyWeight = latencyWeight
+	s.stabilityWeight = stabilityWeight
+}
+
+// setTags overrides the name tags that mark BL and CIDR nodes. Blank tags fall
+// back to defaults.
+func (s *stableTopBLSelector) setTags(blTag, cidrTag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if strings.TrimSpace(blTag) == "" {
+		blTag = topBLDefaultTag
+	}
+	if strings.TrimSpace(cidrTag) == "" {
+		cidrTag = topCIDRDefaultTag
+	}
+	s.blTag = blTag
+	s.cidrTag = cidrTag
+}
+
+// groupTag maps a ?group= value to the configured BL or CIDR tag it names,
+// compared case-insensitively.
+func (s *stableTopBLSelector) groupTag(group string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range []string{s.blTag, s.cidrTag} {
+		if strings.EqualFold(strings.TrimSpace(group), strings.TrimSpace(tag)) {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+// filterLinksByTag keeps the published links whose proxy carries tag. Links of
+// proxies that are no longer loaded are dropped.
+func filterLinksByTag(links []string, proxies []*models.ProxyConfig, tag string) []string {
+	tagged := make(map[string]bool, len(proxies))
+	for _, proxy := range proxies {
+		if proxy != nil && models.HasNameTag(proxy.Name, tag) {
+			tagged[sanitizeConfig(proxy.SourceLine)] = true
+		}
+	}
+	out := make([]string, 0, len(links))
+	for _, link := range links {
+		if tagged[link] {
+			out = append(out, link)
+		}
 	}
+	return out
 }
 
 func (s *stableTopBLSelector) Next(
@@ -459,21 +1004,28 @@ func (s *stableTopBLSelector) Next(
 	statusFn func(string) (bool, time.Duration, error),
 	now time.Time,
 ) []string {
-	selection := selectTopBLAndCIDRByLatency(proxies, statusFn, topBLQuota, topCIDRQuota)
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	pinned, unpinned := s.splitPinned(proxies, statusFn)
+	selection := selectTopBLAndCIDRByLatency(unpinned, statusFn, s.blTag, s.cidrTag, topBLQuota, topCIDRQuota)
+
 	// Keep previous published list when all BL metrics are n/a.
-	if selection.totalBL > 0 && selection.naCount == selection.totalBL && len(s.published) > 0 {
+	if len(pinned) == 0 && selection.totalBL > 0 && selection.naCount == selection.totalBL && len(s.published) > 0 {
 		return append([]string(nil), s.published...)
 	}
 
+	limit := s.limit
+	if s.pinsInQuota {
+		limit = max(s.limit-len(pinned), 0)
+	}
+	s.updateStability(selection.keyStates)
 	ranked := s.applyEMA(selection.proxies)
-	s.reconcileActive(ranked, selection.keyStates, now)
+	s.saveEMA(ranked)
+	s.reconcileActive(ranked, selection.keyStates, limit, now)
 
-	activeRanked := s.activeRanked()
-	proposedLinks := linksFromRanked(activeRanked)
+	activeRanked := s.activeRanked(limit)
+	proposedLinks := append(linksFromRanked(pinned), linksFromRanked(activeRanked)...)
 	if len(proposedLinks) == 0 && len(s.published) > 0 {
 		return append([]string(nil), s.published...)
 	}
@@ -495,6 +1047,8 @@ func (s *stableTopBLSelector) Next(
 func selectTopBLAndCIDRByLatency(
 	proxies []*models.ProxyConfig,
 	statusFn func(string) (bool, time.Duration, error),
+	blTag string,
+	cidrTag string,
 	blLimit int,
 	cidrLimit int,
 ) topSelectionResult {
@@ -517,9 +1071,8 @@ func selectTopBLAndCIDRByLatency(
 			continue
 		}
 
-		nameUpper := strings.ToUpper(proxy.Name)
-		hasBL := strings.Contains(nameUpper, "BL")
-		hasCIDR := strings.Contains(nameUpper, "CIDR")
+		hasBL := models.HasNameTag(proxy.Name, blTag)
+		hasCIDR := models.HasNameTag(proxy.Name, cidrTag)
 		if !hasBL && !hasCIDR {
 			continue
 		}
@@ -582,9 +1135,8 @@ func selectTopBLAndCIDRByLatency(
 			continue
 		}
 
-		nameUpper := strings.ToUpper(item.proxy.Name)
-		hasBL := strings.Contains(nameUpper, "BL")
-		hasCIDR := strings.Contains(nameUpper, "CIDR")
+		hasBL := models.HasNameTag(item.proxy.Name, blTag)
+		hasCIDR := models.HasNameTag(item.proxy.Name, cidrTag)
 		if !hasBL && !hasCIDR {
 			continue
 		}
@@ -628,6 +1180,9 @@ func (s *stableTopBLSelector) applyEMA(proxies []rankedProxy) []rankedProxy {
 		key := p.key
 		rawMs := p.latency
 		prev, ok := s.emaByKey[key]
+		if !ok {
+			prev, ok = s.restoredEMA[p.proxy.StableID]
+		}
 		var ema time.Duration
 		if !ok || prev <= 0 {
 			ema = rawMs
@@ -639,6 +1194,7 @@ func (s *stableTopBLSelector) applyEMA(proxies []rankedProxy) []rankedProxy {
 		ranked = append(ranked, rankedProxy{
 			proxy:   p.proxy,
 			latency: ema,
+			score:   s.compositeScore(key, ema),
 			key:     key,
 		})
 	}
@@ -647,7 +1203,35 @@ func (s *stableTopBLSelector) applyEMA(proxies []rankedProxy) []rankedProxy {
 	return ranked
 }
 
-func (s *stableTopBLSelector) reconcileActive(ranked []rankedProxy, keyStates map[string]keyStatusCounts, now time.Time) {
+// updateStability folds the current online/offline outcome of every BL/CIDR key
+// into an exponentially smoothed loss ratio.
+func (s *stableTopBLSelector) updateStability(keyStates map[string]keyStatusCounts) {
+	for key, st := range keyStates {
+		sample := 1.0
+		if st.online > 0 {
+			sample = 0
+		}
+		prev, ok := s.lossByKey[key]
+		if !ok {
+			s.lossByKey[key] = sample
+			continue
+		}
+		s.lossByKey[key] = (1.0-topBLEMAAlpha)*prev + topBLEMAAlpha*sample
+	}
+}
+
+// compositeScore combines smoothed latency with the key's loss ratio. Latency
+// stays dominant by default: a fully unstable key costs topBLStabilityPenalty.
+func (s *stableTopBLSelector) compositeScore(key string, ema time.Duration) time.Duration {
+	score := s.latencyWeight*float64(ema) +
+		s.stabilityWeight*s.lossByKey[key]*float64(topBLStabilityPenalty)
+	if score < 1 {
+		return 1
+	}
+	return time.Duration(score)
+}
+
+func (s *stableTopBLSelector) reconcileActive(ranked []rankedProxy, keyStates map[string]keyStatusCounts, limit int, now time.Time) {
 	byKey := make(map[string]rankedProxy, len(ranked))
 	for _, r := range ranked {
 		if _, exists := byKey[r.key]; !exists {
@@ -674,7 +1258,7 @@ func (s *stableTopBLSelector) reconcileActive(ranked []rankedProxy, keyStates ma
 	}
 
 	for _, c := range ranked {
-		if len(s.active) >= s.limit {
+		if len(s.active) >= limit {
 			break
 		}
 		if _, exists := s.active[c.key]; exists {
@@ -691,7 +1275,7 @@ func (s *stableTopBLSelector) reconcileActive(ranked []rankedProxy, keyStates ma
 		if worstEntry == nil {
 			break
 		}
-		if !isSignificantImprovement(c.latency, worstEntry.item.latency) {
+		if !isSignificantImprovement(c.rank(), worstEntry.item.rank()) {
 			continue
 		}
 		delete(s.active, worstKey)
@@ -729,14 +1313,14 @@ func isSignificantImprovement(candidate, current time.Duration) bool {
 	return ratioGain >= topBLReplaceMinGain
 }
 
-func (s *stableTopBLSelector) activeRanked() []rankedProxy {
+func (s *stableTopBLSelector) activeRanked(limit int) []rankedProxy {
 	ranked := make([]rankedProxy, 0, len(s.active))
 	for _, entry := range s.active {
 		ranked = append(ranked, entry.item)
 	}
 	sort.Slice(ranked, func(i, j int) bool { return isBetterCandidate(ranked[i], ranked[j]) })
-	if len(ranked) > s.limit {
-		ranked = ranked[:s.limit]
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
 	}
 	return ranked
 }
@@ -756,6 +1340,7 @@ func linksFromRanked(ranked []rankedProxy) []string {
 func selectTopBLByLatency(
 	proxies []*models.ProxyConfig,
 	statusFn func(string) (bool, time.Duration, error),
+	blTag string,
 	limit int,
 ) topSelectionResult {
 	if limit <= 0 {
@@ -773,7 +1358,7 @@ func selectTopBLByLatency(
 		if !isAllowedForSubscription(proxy) {
 			continue
 		}
-		if !strings.Contains(strings.ToUpper(proxy.Name), "BL") {
+		if !models.HasNameTag(proxy.Name, blTag) {
 			continue
 		}
 		result.totalBL++
@@ -859,8 +1444,8 @@ func dedupKey(proxy *models.ProxyConfig) string {
 }
 
 func isBetterCandidate(left, right rankedProxy) bool {
-	if left.latency != right.latency {
-		return left.latency < right.latency
+	if left.rank() != right.rank() {
+		return left.rank() < right.rank()
 	}
 
 	leftName := strings.ToLower(strings.TrimSpace(left.proxy.Name))
@@ -907,7 +1492,7 @@ func isTrueLike(value string) bool {
 	return v == "1" || v == "true"
 }
 
-func APIRemoteSourcesHandler(manager *subscription.RemoteManager) http.HandlerFunc {
+func APIRemoteSourcesHandler(manager *subscription.RemoteManager, proxyChecker *checker.ProxyChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if manager == nil {
 			writeError(w, "Remote subscriptions not configured", http.StatusBadRequest)
@@ -916,23 +1501,7 @@ func APIRemoteSourcesHandler(manager *subscription.RemoteManager) http.HandlerFu
 
 		switch r.Method {
 		case http.MethodGet:
-			state := manager.GetState()
-			resp := RemoteStateResponse{
-				IntervalSeconds: state.IntervalSeconds,
-				DownloadDir:     manager.DownloadDir(),
-				Sources:         make([]RemoteSourceInfo, 0, len(state.Sources)),
-			}
-			for _, src := range state.Sources {
-				resp.Sources = append(resp.Sources, RemoteSourceInfo{
-					ID:          src.ID,
-					URL:         src.URL,
-					FileName:    src.FileName,
-					LastChecked: formatTime(src.LastChecked),
-					LastUpdated: formatTime(src.LastUpdated),
-					Error:       src.Error,
-				})
-			}
-			writeJSON(w, resp)
+			writeJSON(w, buildRemoteStateResponse(manager, manager.GetState(), proxyChecker))
 			return
 		case http.MethodPost:
 			var req struct {
@@ -950,6 +1519,12 @@ func APIRemoteSourcesHandler(manager *subscription.RemoteManager) http.HandlerFu
 				writeError(w, "No URLs provided", http.StatusBadRequest)
 				return
 			}
+			if !isTrueLike(r.URL.Query().Get("force")) {
+				if err := manager.CheckReachable(req.URLs); err != nil {
+					writeError(w, err.Error(), http.StatusUnprocessableEntity)
+					return
+				}
+			}
 			added, err := manager.AddURLs(req.URLs)
 			if err != nil {
 				writeError(w, err.Error(), http.StatusInternalServerError)
@@ -957,20 +1532,65 @@ func APIRemoteSourcesHandler(manager *subscription.RemoteManager) http.HandlerFu
 			}
 			writeJSON(w, added)
 			return
+		case http.MethodPut:
+			var req struct {
+				URLs []string `json:"urls"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.URLs == nil {
+				writeError(w, "urls is required", http.StatusBadRequest)
+				return
+			}
+			state, err := manager.ReplaceURLs(req.URLs)
+			if err != nil {
+				writeError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, buildRemoteStateResponse(manager, state, proxyChecker))
+			return
 		case http.MethodDelete:
-			id := r.URL.Query().Get("id")
+			query := r.URL.Query()
+			id := query.Get("id")
 			if id == "" {
-				id = r.URL.Query().Get("url")
+				id = query.Get("url")
 			}
-			if id == "" {
-				writeError(w, "id or url is required", http.StatusBadRequest)
+			if id != "" {
+				if !manager.RemoveByID(id) {
+					writeError(w, "source not found", http.StatusNotFound)
+					return
+				}
+				writeJSON(w, map[string]string{"status": "removed"})
+				return
+			}
+
+			var removed subscription.RemoteSource
+			var err error
+			switch {
+			case query.Get("index") != "":
+				index, convErr := strconv.Atoi(query.Get("index"))
+				if convErr != nil {
+					writeError(w, "index must be an integer", http.StatusBadRequest)
+					return
+				}
+				removed, err = manager.RemoveByIndex(index)
+			case query.Get("match") != "":
+				removed, err = manager.RemoveByMatch(query.Get("match"))
+			default:
+				writeError(w, "id, url, index or match is required", http.StatusBadRequest)
 				return
 			}
-			if !manager.RemoveByID(id) {
-				writeError(w, "source not found", http.StatusNotFound)
+			if errors.Is(err, subscription.ErrAmbiguousSourceMatch) {
+				writeError(w, err.Error(), http.StatusConflict)
 				return
 			}
-			writeJSON(w, map[string]string{"status": "removed"})
+			if err != nil {
+				writeError(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			writeJSON(w, map[string]string{"status": "removed", "id": removed.ID})
 			return
 		default:
 			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -1004,6 +1624,88 @@ func APIRemoteIntervalHandler(manager *subscription.RemoteManager) http.HandlerF
 	}
 }
 
+// APICheckIntervalHandler changes the proxy check interval at runtime. The
+// change is kept in memory only.
+func APICheckIntervalHandler(scheduler *checker.CheckScheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			IntervalSeconds int `json:"intervalSeconds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.IntervalSeconds <= 0 {
+			writeError(w, "Interval must be greater than 0", http.StatusBadRequest)
+			return
+		}
+		if err := scheduler.Reschedule(req.IntervalSeconds); err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logger.Info("Proxy check interval changed to %ds", req.IntervalSeconds)
+		writeJSON(w, map[string]int{"intervalSeconds": req.IntervalSeconds})
+	}
+}
+
+func buildRemoteStateResponse(manager *subscription.RemoteManager, state subscription.RemoteState, proxyChecker *checker.ProxyChecker) RemoteStateResponse {
+	var counts map[string]int
+	if proxyChecker != nil {
+		counts = manager.ProxyCounts(proxyChecker.GetProxies())
+	}
+	resp := RemoteStateResponse{
+		IntervalSeconds: state.IntervalSeconds,
+		DownloadDir:     manager.DownloadDir(),
+		StatePath:       manager.StatePath(),
+		Sources:         make([]RemoteSourceInfo, 0, len(state.Sources)),
+	}
+	for _, src := range state.Sources {
+		resp.Sources = append(resp.Sources, RemoteSourceInfo{
+			ID:              src.ID,
+			URL:             subscription.RedactURLQuery(src.URL),
+			Name:            src.Name,
+			FileName:        src.FileName,
+			Enabled:         !src.Disabled,
+			IntervalSeconds: src.IntervalSeconds,
+			LastChecked:     formatTime(src.LastChecked),
+			LastUpdated:     formatTime(src.LastUpdated),
+			Error:           src.Error,
+			ProxyCount:      counts[src.ID],
+			LastStatus:      subscription.SourceStatus(src, counts[src.ID]),
+		})
+	}
+	return resp
+}
+
+func APIRemoteOrderHandler(manager *subscription.RemoteManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if manager == nil {
+			writeError(w, "Remote subscriptions not configured", http.StatusBadRequest)
+			return
+		}
+		if r.Method != http.MethodPut {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := manager.Reorder(req.IDs); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string][]string{"ids": req.IDs})
+	}
+}
+
 func APIRemoteRefreshHandler(manager *subscription.RemoteManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if manager == nil {
@@ -1023,6 +1725,38 @@ func APIRemoteRefreshHandler(manager *subscription.RemoteManager) http.HandlerFu
 	}
 }
 
+// @Summary Subscription update history
+// @Description Returns recent applied subscription updates, newest first
+// @Tags subscriptions
+// @Produce json
+// @Success 200 {array} subscription.UpdateEvent
+// @Router /api/v1/subscriptions/history [get]
+func APISubscriptionHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, subscription.UpdateHistory())
+	}
+}
+
+// @Summary Stable ID collisions
+// @Description Returns groups of loaded proxies that share a stable ID, found by the last subscription load
+// @Tags subscriptions
+// @Produce json
+// @Success 200 {array} subscription.StableIDCollision
+// @Router /api/v1/subscriptions/collisions [get]
+func APIStableIDCollisionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, subscription.StableIDCollisions())
+	}
+}
+
 func formatTime(t time.Time) string {
 	if t.IsZero() {
 		return ""
@@ -1030,7 +1764,10 @@ func formatTime(t time.Time) string {
 	return t.Format(time.RFC3339)
 }
 
-const swaggerUIHTML = `<!DOCTYPE html>
+// swaggerUITmpl renders Swagger UI. The base path is detected from the last
+// "/api/v1/docs" segment of the current URL so the page keeps working behind
+// any combination of --metrics-base-path and reverse-proxy prefixes.
+var swaggerUITmpl = htmltemplate.Must(htmltemplate.New("docs").Parse(`<!DOCTYPE html>
 <html lang="en">
 <head>
   <meta charset="UTF-8">
@@ -1039,30 +1776,47 @@ const swaggerUIHTML = `<!DOCTYPE html>
   <style>
     body { margin: 0; padding: 0; }
     .swagger-ui .topbar { display: none; }
+    .docs-unavailable { font-family: sans-serif; max-width: 640px; margin: 48px auto; padding: 0 16px; }
   </style>
-  <script>
-    // Detect base path from current URL (e.g., /xray/api/v1/docs -> /xray)
-    (function() {
-      const path = window.location.pathname;
-      const apiIdx = path.indexOf('/api/v1/docs');
-      const basePath = apiIdx > 0 ? path.substring(0, apiIdx) : '';
-      document.write('<link rel="stylesheet" href="' + basePath + '/static/swagger-ui.css">');
-    })();
-  </script>
 </head>
 <body>
   <div id="swagger-ui"></div>
+  <div id="docs-unavailable" class="docs-unavailable" hidden>
+    <h1>API docs unavailable</h1>
+    <p>Swagger UI assets could not be loaded. Set <code>--web-docs-assets-url</code> to a Swagger UI CDN, or read the raw <a id="spec-link" href="openapi.yaml">OpenAPI spec</a>.</p>
+  </div>
   <script>
     (function() {
       const path = window.location.pathname;
-      const apiIdx = path.indexOf('/api/v1/docs');
-      const basePath = apiIdx > 0 ? path.substring(0, apiIdx) : '';
+      const apiIdx = path.lastIndexOf('/api/v1/docs');
+      const basePath = apiIdx >= 0 ? path.substring(0, apiIdx) : '';
+      const specURL = basePath + '/api/v1/openapi.yaml';
+      const configured = {{.AssetsURL}};
+      const assetsBase = configured ? configured.replace(/\/+$/, '') : basePath + '/static';
+
+      function unavailable() {
+        document.getElementById('swagger-ui').hidden = true;
+        document.getElementById('spec-link').href = specURL;
+        document.getElementById('docs-unavailable').hidden = false;
+      }
+
+      const assetsAvailable = {{.AssetsAvailable}};
+      if (!assetsAvailable) {
+        unavailable();
+        return;
+      }
+
+      const css = document.createElement('link');
+      css.rel = 'stylesheet';
+      css.href = assetsBase + '/swagger-ui.css';
+      document.head.appendChild(css);
 
       const script = document.createElement('script');
-      script.src = basePath + '/static/swagger-ui-bundle.js';
+      script.src = assetsBase + '/swagger-ui-bundle.js';
+      script.onerror = unavailable;
       script.onload = function() {
         SwaggerUIBundle({
-          url: './openapi.yaml',
+          url: specURL,
           dom_id: '#swagger-ui',
           presets: [SwaggerUIBundle.presets.apis, SwaggerUIBundle.SwaggerUIStandalonePreset],
           layout: 'BaseLayout'
@@ -1072,4 +1826,4 @@ const swaggerUIHTML = `<!DOCTYPE html>
     })();
   </script>
 </body>
-</html>`
+</html>`))
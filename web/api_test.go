@@ -0,0 +1,848 @@
+package web
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"xray-checker/checker"
+	"xray-checker/config"
+	"xray-checker/metrics"
+	"xray-checker/models"
+	"xray-checker/subscription"
+)
+
+var testMetricsOnce sync.Once
+
+func initTestMetrics() {
+	testMetricsOnce.Do(func() { metrics.InitMetrics("test", "test") })
+}
+
+func TestAPIVersionHandler(t *testing.T) {
+	info := NewVersionInfo("v1.2.3", "abc123", "2025-01-01T00:00:00Z")
+	if info.GoVersion != runtime.Version() {
+		t.Fatalf("expected go version %q, got %q", runtime.Version(), info.GoVersion)
+	}
+
+	rec := httptest.NewRecorder()
+	APIVersionHandler(info).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Success bool            `json:"success"`
+		Data    VersionResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !resp.Success || resp.Data.Version != "v1.2.3" || resp.Data.GitCommit != "abc123" || resp.Data.BuildDate != "2025-01-01T00:00:00Z" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	APIVersionHandler(info).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/version", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestNewVersionInfoFillsUnknown(t *testing.T) {
+	info := NewVersionInfo("unknown", "", "")
+	if info.GitCommit == "" || info.BuildDate == "" || info.Version == "" {
+		t.Fatalf("expected fallback values, got %+v", info)
+	}
+}
+
+func TestConfigStatusHandlerFormats(t *testing.T) {
+	initTestMetrics()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	closedPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	proxy := newTestProxy("Offline", "vless://offline")
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{proxy}, closedPort, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	pc.CheckProxy(proxy)
+
+	handler := ConfigStatusHandler(pc)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config/"+proxy.StableID, nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "Failed" {
+		t.Fatalf("unexpected plaintext response: %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config/"+proxy.StableID+"?format=json", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON content type, got %q", ct)
+	}
+	var resp ConfigStatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.Online || resp.LastChecked == nil {
+		t.Fatalf("unexpected JSON response: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config/missing?format=json", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown config, got %d", rec.Code)
+	}
+}
+
+func TestAPIStatusHandlerOfflineGrace(t *testing.T) {
+	initTestMetrics()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	closedPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	proxy := newTestProxy("New", "vless://new")
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{proxy}, closedPort, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	pc.CheckProxy(proxy)
+
+	status := func() StatusResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		APIStatusHandler(pc).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+		var resp struct {
+			Data StatusResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		return resp.Data
+	}
+
+	if got := status(); got.Offline != 1 || got.Pending != 0 {
+		t.Fatalf("without grace a failing proxy must be offline: %+v", got)
+	}
+
+	pc.SetOfflineGrace(time.Minute)
+	if got := status(); got.Offline != 0 || got.Pending != 1 {
+		t.Fatalf("a just-added proxy must be pending within grace: %+v", got)
+	}
+}
+
+func TestAPIProxiesHandlerETag(t *testing.T) {
+	initTestMetrics()
+	proxy := newTestProxy("Node", "vless://node")
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{proxy}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+
+	get := func(handler http.Handler, path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get(APIProxiesHandler(pc, 10000), "/api/v1/proxies", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected 200 with a weak ETag, got %d %q", first.Code, etag)
+	}
+
+	rec := get(APIProxiesHandler(pc, 10000), "/api/v1/proxies", etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("unchanged state must return an empty 304, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get(APIStatusHandler(pc), "/api/v1/status", etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("status: unchanged state must return 304, got %d", rec.Code)
+	}
+
+	pc.CheckProxy(proxy)
+	rec = get(APIProxiesHandler(pc, 10000), "/api/v1/proxies", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("a result recorded mid-iteration must change the ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	etag = rec.Header().Get("ETag")
+
+	pc.UpdateProxies([]*models.ProxyConfig{proxy, newTestProxy("Other", "vless://other")})
+	rec = get(APIProxiesHandler(pc, 10000), "/api/v1/proxies", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("a proxy list update must change the ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestAPIOfflineProxiesHandlerMinDown(t *testing.T) {
+	initTestMetrics()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	closedPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	down := newTestProxy("Down", "vless://down")
+	unchecked := newTestProxy("Unchecked", "vless://unchecked")
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{down, unchecked}, closedPort, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	pc.CheckProxy(down)
+
+	offline := func(query string) []ProxyInfo {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		APIOfflineProxiesHandler(pc, 10000).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/proxies/offline"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("query %q: expected 200, got %d", query, rec.Code)
+		}
+		var resp struct {
+			Data []ProxyInfo `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		return resp.Data
+	}
+
+	if got := offline(""); len(got) != 1 || got[0].StableID != down.StableID {
+		t.Fatalf("expected only the failed proxy, got %+v", got)
+	}
+	if got := offline("?minDownSec=0"); len(got) != 1 {
+		t.Fatalf("minDownSec=0 must not filter, got %+v", got)
+	}
+	if got := offline("?minDownSec=3600"); len(got) != 0 {
+		t.Fatalf("a proxy down for under an hour must be filtered, got %+v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	APIOfflineProxiesHandler(pc, 10000).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/proxies/offline?minDownSec=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative minDownSec, got %d", rec.Code)
+	}
+}
+
+func TestAPIStatusHandlerByProtocol(t *testing.T) {
+	initTestMetrics()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	closedPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	vless := newTestProxy("VLESS Node", "vless://a")
+	trojan := newTestProxy("Trojan Node", "trojan://b")
+	trojan.Protocol = "trojan"
+	trojan.StableID = trojan.GenerateStableID()
+	unchecked := newTestProxy("Trojan Fresh", "trojan://c")
+	unchecked.Protocol = "Trojan"
+	unchecked.StableID = unchecked.GenerateStableID()
+
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{vless, trojan, unchecked}, closedPort, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	pc.CheckProxy(vless)
+	pc.CheckProxy(trojan)
+
+	rec := httptest.NewRecorder()
+	APIStatusHandler(pc).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+	var resp struct {
+		Data StatusResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	want := map[string]ProtocolStatus{
+		"vless":  {Total: 1, Online: 0},
+		"trojan": {Total: 2, Online: 0},
+	}
+	if !reflect.DeepEqual(resp.Data.ByProtocol, want) {
+		t.Fatalf("unexpected breakdown %+v", resp.Data.ByProtocol)
+	}
+	if resp.Data.Total != 3 || resp.Data.Offline != 2 || resp.Data.Unknown != 1 {
+		t.Fatalf("top-level counts must stay unchanged: %+v", resp.Data)
+	}
+
+	// A mixed fleet where only trojan is blocked.
+	byProtocol := make(map[string]ProtocolStatus)
+	for _, p := range []struct {
+		protocol string
+		online   bool
+	}{
+		{"vless", true}, {"vless", true}, {"vless", false},
+		{"trojan", false}, {"trojan", false},
+		{"shadowsocks", true},
+		{"", false},
+	} {
+		countProtocol(byProtocol, p.protocol, p.online)
+	}
+	want = map[string]ProtocolStatus{
+		"vless":       {Total: 3, Online: 2},
+		"trojan":      {Total: 2, Online: 0},
+		"shadowsocks": {Total: 1, Online: 1},
+		"unknown":     {Total: 1, Online: 0},
+	}
+	if !reflect.DeepEqual(byProtocol, want) {
+		t.Fatalf("unexpected breakdown %+v", byProtocol)
+	}
+}
+
+func TestPrefixServeMuxServesBarePrefix(t *testing.T) {
+	mux, err := NewPrefixServeMux("/checker-a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mux.Handle("/api/v1/status", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("status:" + r.URL.Path))
+	}))
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("index"))
+	}))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/checker-a", http.StatusOK, "index"},
+		{"/checker-a/", http.StatusOK, "index"},
+		{"/checker-a/api/v1/status", http.StatusOK, "status:/api/v1/status"},
+		{"/checker-ab", http.StatusNotFound, ""},
+		{"/", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d (Location %q)", tc.path, tc.code, rec.Code, rec.Header().Get("Location"))
+		}
+		if tc.body != "" && rec.Body.String() != tc.body {
+			t.Fatalf("%s: expected body %q, got %q", tc.path, tc.body, rec.Body.String())
+		}
+	}
+}
+
+func TestNewPrefixServeMuxPrefixNormalization(t *testing.T) {
+	for _, prefix := range []string{"", "/"} {
+		mux, err := NewPrefixServeMux(prefix)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", prefix, err)
+		}
+		mux.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d", prefix, rec.Code)
+		}
+	}
+
+	if _, err := NewPrefixServeMux("/checker-a/"); err == nil {
+		t.Fatal("expected error for trailing slash")
+	}
+}
+
+func TestIndexHandlerFiltersBySubscription(t *testing.T) {
+	p1 := newTestProxy("Alpha Node", "vless://alpha")
+	p1.SubName = "first"
+	p1.Server = "10.0.0.1"
+	p2 := newTestProxy("Beta Node", "vless://beta")
+	p2.SubName = "second"
+	p2.Server = "10.0.0.2"
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{p1, p2}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+
+	oldWeb := config.CLIConfig.Web
+	defer func() { config.CLIConfig.Web = oldWeb }()
+	config.CLIConfig.Web.Public = true
+	config.CLIConfig.Web.ShowServerDetails = true
+
+	rec := httptest.NewRecorder()
+	IndexHandler("test", pc, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?sub=first", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Alpha Node") {
+		t.Fatal("expected proxy from the selected subscription")
+	}
+	if strings.Contains(body, "Beta Node") {
+		t.Fatal("unexpected proxy from another subscription")
+	}
+	if strings.Contains(body, "10.0.0.1") {
+		t.Fatal("public mode must not expose server details")
+	}
+	if !strings.Contains(body, `<option value="second">`) {
+		t.Fatal("expected subscription selector with all subscription names")
+	}
+}
+
+func TestIndexHandlerSubscriptionNameOverride(t *testing.T) {
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{newTestProxy("Node", "vless://node")}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+
+	oldName := subscription.GetSubscriptionName()
+	defer subscription.SetSubscriptionName(oldName)
+	subscription.SetSubscriptionName("Detected Sub")
+
+	oldWeb := config.CLIConfig.Web
+	defer func() { config.CLIConfig.Web = oldWeb }()
+	config.CLIConfig.Web.Public = true
+
+	render := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		IndexHandler("test", pc, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	if body := render(); !strings.Contains(body, "Detected Sub") {
+		t.Fatal("expected the detected name without an override")
+	}
+
+	config.CLIConfig.Web.SubscriptionNameOverride = "My Brand"
+	body := render()
+	if !strings.Contains(body, "My Brand") {
+		t.Fatal("expected the override name")
+	}
+	if strings.Contains(body, "Detected Sub") {
+		t.Fatal("the override must take precedence over the detected name")
+	}
+}
+
+func TestProxyNameQuotesSurviveJSON(t *testing.T) {
+	const name = `Joe's "Fast" Node</script>`
+	proxy := newTestProxy(name+"\x07", "vless://quoted")
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{proxy}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+
+	rec := httptest.NewRecorder()
+	APIProxiesHandler(pc, 10000).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/proxies", nil))
+	var resp struct {
+		Data []ProxyInfo `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Name != name {
+		t.Fatalf("expected the name intact without control chars, got %+v", resp.Data)
+	}
+
+	oldWeb := config.CLIConfig.Web
+	defer func() { config.CLIConfig.Web = oldWeb }()
+	config.CLIConfig.Web.Public = true
+	rec = httptest.NewRecorder()
+	IndexHandler("test", pc, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := rec.Body.String()
+	if strings.Contains(body, "Node</script>") {
+		t.Fatal("the name must not be able to close the page's script")
+	}
+	if !strings.Contains(body, `Joe's \"Fast\" Node\u003c/script\u003e`) {
+		t.Fatal("expected the JSON-escaped name in the page data")
+	}
+
+	html := htmlEndpoints([]EndpointInfo{{Name: name}})
+	if got := html[0].Name; strings.ContainsAny(got, `'"`) {
+		t.Fatalf("HTML template names must not carry quotes, got %q", got)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := make([]int64, 100)
+	for i := range values {
+		values[i] = int64(i + 1)
+	}
+
+	cases := []struct {
+		p    float64
+		want int64
+	}{{50, 50}, {90, 90}, {99, 99}, {100, 100}, {0, 1}}
+	for _, tc := range cases {
+		if got := percentile(values, tc.p); got != tc.want {
+			t.Errorf("percentile(1..100, %v) = %d, want %d", tc.p, got, tc.want)
+		}
+	}
+
+	if got := percentile([]int64{10, 20, 1000}, 50); got != 20 {
+		t.Errorf("median of a skewed set = %d, want 20", got)
+	}
+	if got := percentile(nil, 90); got != 0 {
+		t.Errorf("percentile of no values = %d, want 0", got)
+	}
+}
+
+func TestSortEndpointsByStatus(t *testing.T) {
+	endpoints := []EndpointInfo{
+		{Name: "offline-b", Status: false},
+		{Name: "slow", Status: true, Latency: 300 * time.Millisecond},
+		{Name: "unmeasured", Status: true},
+		{Name: "fast-b", Status: true, Latency: 100 * time.Millisecond},
+		{Name: "offline-a", Status: false},
+		{Name: "fast-a", Status: true, Latency: 100 * time.Millisecond},
+	}
+
+	sortEndpointsByStatus(endpoints)
+
+	want := []string{"fast-a", "fast-b", "slow", "unmeasured", "offline-a", "offline-b"}
+	for i, ep := range endpoints {
+		if ep.Name != want[i] {
+			t.Fatalf("position %d: got %q, want %q (full order %+v)", i, ep.Name, want[i], endpoints)
+		}
+	}
+}
+
+func TestAPISystemScheduleHandler(t *testing.T) {
+	checks := checker.NewCheckScheduler(3600, func() {})
+	if err := checks.StartDelayed(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer checks.Stop()
+
+	schedule := func(updates *checker.CheckScheduler) (ScheduleResponse, string) {
+		rec := httptest.NewRecorder()
+		APISystemScheduleHandler(checks, updates).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/schedule", nil))
+		var resp struct {
+			Data ScheduleResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		return resp.Data, rec.Body.String()
+	}
+
+	got, body := schedule(nil)
+	if got.CheckIntervalSec != 3600 || got.NextCheckAt == nil {
+		t.Fatalf("unexpected check schedule: %s", body)
+	}
+	if until := time.Until(*got.NextCheckAt); until <= 0 || until > time.Hour {
+		t.Fatalf("next check must be within one interval, got %s", until)
+	}
+	if strings.Contains(body, "subscriptionUpdate") {
+		t.Fatalf("subscription update fields must be omitted when updates are off: %s", body)
+	}
+
+	updates := checker.NewCheckScheduler(600, func() {})
+	if err := updates.StartDelayed(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer updates.Stop()
+	got, body = schedule(updates)
+	if got.SubscriptionUpdateIntervalSec != 600 || got.NextSubscriptionUpdateAt == nil || time.Until(*got.NextSubscriptionUpdateAt) > 10*time.Minute {
+		t.Fatalf("unexpected subscription update schedule: %s", body)
+	}
+}
+
+func TestAPICheckIntervalHandlerUpdatesConfig(t *testing.T) {
+	checks := checker.NewCheckScheduler(3600, func() {})
+	if err := checks.StartDelayed(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer checks.Stop()
+	configured := config.CLIConfig.Proxy.CheckInterval
+
+	rec := httptest.NewRecorder()
+	APICheckIntervalHandler(checks).ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/config/check-interval", strings.NewReader(`{"intervalSeconds":90}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	pc := checker.NewProxyChecker(nil, 10000, "", 1, "", "", 1, 1, "status", 1)
+	rec = httptest.NewRecorder()
+	APIConfigHandler(pc, checks).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+	var resp struct {
+		Data ConfigResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if resp.Data.CheckInterval != 90 {
+		t.Fatalf("expected the config to report the new interval, got %d", resp.Data.CheckInterval)
+	}
+	if config.CLIConfig.Proxy.CheckInterval != configured {
+		t.Fatal("the interval change must stay in the scheduler")
+	}
+}
+
+func TestAPISystemPauseResume(t *testing.T) {
+	pc := checker.NewProxyChecker(nil, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	info := APISystemInfoHandler("test", time.Now(), pc)
+
+	readPaused := func() bool {
+		rec := httptest.NewRecorder()
+		info.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/info", nil))
+		var resp struct {
+			Data SystemInfoResponse `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		return resp.Data.Paused
+	}
+
+	if readPaused() {
+		t.Fatal("expected checks to be running initially")
+	}
+
+	rec := httptest.NewRecorder()
+	APISystemPauseHandler(pc).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/pause", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for GET, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	APISystemPauseHandler(pc).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/system/pause", nil))
+	if rec.Code != http.StatusOK || !pc.IsPaused() || !readPaused() {
+		t.Fatalf("expected paused state after pause, code=%d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	APISystemResumeHandler(pc).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/system/resume", nil))
+	if rec.Code != http.StatusOK || pc.IsPaused() || readPaused() {
+		t.Fatalf("expected running state after resume, code=%d", rec.Code)
+	}
+}
+
+func TestAPISystemReloadAssets(t *testing.T) {
+	prev := GetAssetLoader()
+	t.Cleanup(func() { globalAssetLoader.Store(prev) })
+
+	reload := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		APISystemReloadAssetsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/system/reload-assets", nil))
+		return rec
+	}
+
+	if err := InitAssetLoader(""); err != nil {
+		t.Fatalf("InitAssetLoader failed: %v", err)
+	}
+	if rec := reload(); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without custom assets path, got %d", rec.Code)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "custom.css"), []byte("body{}"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := InitAssetLoader(dir); err != nil {
+		t.Fatalf("InitAssetLoader failed: %v", err)
+	}
+	if GetAssetLoader().HasCustomTemplate() {
+		t.Fatal("expected no custom template initially")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>{{.Version}}</p>"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	rec := reload()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data ReloadAssetsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !resp.Data.CustomTemplate || !resp.Data.CustomCSS || len(resp.Data.Files) != 2 {
+		t.Fatalf("unexpected reload response: %+v", resp.Data)
+	}
+	loaded := GetAssetLoader()
+
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>{{.Broken</p>"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if rec := reload(); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for broken template, got %d", rec.Code)
+	}
+	if GetAssetLoader() != loaded {
+		t.Fatal("failed reload must keep the previous assets")
+	}
+
+	assetReloadMu.Lock()
+	rec = reload()
+	assetReloadMu.Unlock()
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 while another reload runs, got %d", rec.Code)
+	}
+}
+
+func TestAPIDocsHandlerAssetsSource(t *testing.T) {
+	render := func(assetsURL string) string {
+		rec := httptest.NewRecorder()
+		APIDocsHandler(assetsURL).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/xray/api/v1/docs", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	local := render("")
+	if !strings.Contains(local, `const configured = "";`) {
+		t.Fatal("expected bundled assets to be used by default")
+	}
+	if !strings.Contains(local, "const assetsAvailable =  true ;") {
+		t.Fatal("expected bundled swagger assets to be reported as available")
+	}
+
+	cdn := render("https://cdn.example.com/swagger-ui/")
+	if !strings.Contains(cdn, `const configured = "https://cdn.example.com/swagger-ui/";`) {
+		t.Fatalf("expected configured assets URL to be embedded, got:\n%s", cdn)
+	}
+}
+
+func TestOpenAPISpecCoversAPIRoutes(t *testing.T) {
+	// Meta endpoints that serve the documentation itself.
+	exempt := map[string]bool{
+		"/api/v1/docs":         true,
+		"/api/v1/openapi.yaml": true,
+	}
+
+	specPaths := OpenAPIPaths()
+	if len(specPaths) == 0 {
+		t.Fatal("no paths parsed from embedded OpenAPI spec")
+	}
+	documented := func(pattern string) bool {
+		for _, p := range specPaths {
+			if p == pattern || strings.HasSuffix(pattern, "/") && strings.HasPrefix(p, pattern) && len(p) > len(pattern) {
+				return true
+			}
+		}
+		return false
+	}
+
+	routes := APIRoutes(APIDependencies{Docs: true, TopBL: true})
+	for _, route := range routes {
+		if exempt[route.Pattern] {
+			continue
+		}
+		if !documented(route.Pattern) {
+			t.Errorf("route %s is not documented in openapi.yaml", route.Pattern)
+		}
+	}
+
+	for _, p := range specPaths {
+		found := false
+		for _, route := range routes {
+			if route.Pattern == p || strings.HasSuffix(route.Pattern, "/") && strings.HasPrefix(p, route.Pattern) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("openapi.yaml documents %s but no such route is registered", p)
+		}
+	}
+}
+
+func TestParseOpenAPIPaths(t *testing.T) {
+	spec := []byte(`openapi: 3.0.3
+servers:
+  - url: /
+paths:
+  # comment
+  /a:
+    get:
+      summary: A
+  "/b/{id}":
+    post:
+      summary: B
+components:
+  schemas:
+    Thing:
+      type: object
+`)
+	got := parseOpenAPIPaths(spec)
+	if len(got) != 2 || got[0] != "/a" || got[1] != "/b/{id}" {
+		t.Fatalf("unexpected paths: %v", got)
+	}
+}
+
+func TestToProxyInfoIncludesResolvedServer(t *testing.T) {
+	proxy := &models.ProxyConfig{Protocol: "vless", Server: "node.example.com", ResolvedServer: "203.0.113.10", Port: 443, Name: "n"}
+	info := toProxyInfo(proxy, true, 0, nil, 10000)
+	if info.Server != "node.example.com" || info.ResolvedServer != "203.0.113.10" {
+		t.Fatalf("unexpected server fields: server=%q resolved=%q", info.Server, info.ResolvedServer)
+	}
+	if got := serverInfo(proxy); got != "node.example.com:443 (203.0.113.10)" {
+		t.Fatalf("unexpected dashboard server info: %q", got)
+	}
+}
+
+func TestReadyHandlerReflectsLoadedProxies(t *testing.T) {
+	t.Cleanup(func() { SetProxiesLoaded(false) })
+
+	SetProxiesLoaded(false)
+	rec := httptest.NewRecorder()
+	ReadyHandler()(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "no proxies loaded") {
+		t.Fatalf("expected 503 without proxies, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	SetProxiesLoaded(true)
+	rec = httptest.NewRecorder()
+	ReadyHandler()(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with proxies, got %d", rec.Code)
+	}
+}
+
+func TestAPIDashboardHandler(t *testing.T) {
+	initTestMetrics()
+	first := newTestProxy("Node", "vless://node")
+	first.SubName = "Main"
+	second := newTestProxy("Other", "vless://other")
+	second.SubName = "Backup"
+	pc := checker.NewProxyChecker([]*models.ProxyConfig{first, second}, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+
+	rec := httptest.NewRecorder()
+	APIDashboardHandler("v9.9.9", time.Now().Add(-time.Minute), pc, nil, 10000).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/dashboard", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, section := range []string{"status", "config", "systemInfo", "proxies"} {
+		if _, ok := resp.Data[section]; !ok {
+			t.Fatalf("dashboard is missing %q: %s", section, rec.Body.String())
+		}
+	}
+
+	var dashboard DashboardResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &APIResponse{Data: &dashboard}); err != nil {
+		t.Fatalf("decode dashboard: %v", err)
+	}
+	if dashboard.Status.Total != len(dashboard.Proxies) || dashboard.Status.Unknown != 2 {
+		t.Fatalf("status %+v does not match %d proxies", dashboard.Status, len(dashboard.Proxies))
+	}
+	if !reflect.DeepEqual(dashboard.Config.SubscriptionNames, []string{"Main", "Backup"}) {
+		t.Fatalf("expected subscription names of the listed proxies, got %v", dashboard.Config.SubscriptionNames)
+	}
+	if dashboard.SystemInfo.Version != "v9.9.9" || dashboard.SystemInfo.UptimeSec < 60 {
+		t.Fatalf("unexpected system info %+v", dashboard.SystemInfo)
+	}
+	if dashboard.Proxies[0].StableID != first.StableID || dashboard.Proxies[0].Config == "" {
+		t.Fatalf("expected full proxy info in listed order, got %+v", dashboard.Proxies[0])
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -40,7 +42,7 @@ func TestSelectTopBLByLatencyFiltersAndSorts(t *testing.T) {
 			return false, 0, fmt.Errorf("unknown stable id")
 		}
 		return v.online, v.latency, v.err
-	}, 10)
+	}, topBLDefaultTag, 10)
 
 	if len(got.proxies) != 2 {
 		t.Fatalf("expected 2 proxies, got %d", len(got.proxies))
@@ -54,6 +56,19 @@ func TestSelectTopBLByLatencyFiltersAndSorts(t *testing.T) {
 	}
 }
 
+func TestSelectTopBLByLatencyUsesConfiguredTag(t *testing.T) {
+	fast := newTestProxy("FAST DE", "vless://fast")
+	bl := newTestProxy("BL NL", "vless://bl")
+
+	got := selectTopBLByLatency([]*models.ProxyConfig{fast, bl}, func(string) (bool, time.Duration, error) {
+		return true, 50 * time.Millisecond, nil
+	}, "FAST", 10)
+
+	if got.totalBL != 1 || len(got.proxies) != 1 || got.proxies[0].proxy != fast {
+		t.Fatalf("expected only the proxy tagged FAST, got %d of %d", len(got.proxies), got.totalBL)
+	}
+}
+
 func TestSelectTopBLByLatencyLimit(t *testing.T) {
 	proxies := make([]*models.ProxyConfig, 0, 12)
 	latencyByID := make(map[string]time.Duration, 12)
@@ -66,7 +81,7 @@ func TestSelectTopBLByLatencyLimit(t *testing.T) {
 
 	got := selectTopBLByLatency(proxies, func(stableID string) (bool, time.Duration, error) {
 		return true, latencyByID[stableID], nil
-	}, 10)
+	}, topBLDefaultTag, 10)
 
 	if len(got.proxies) != 10 {
 		t.Fatalf("expected 10 proxies, got %d", len(got.proxies))
@@ -103,7 +118,7 @@ func TestSelectTopBLByLatencyDeduplicatesByStableID(t *testing.T) {
 
 	got := selectTopBLByLatency([]*models.ProxyConfig{base, duplicate, other}, func(stableID string) (bool, time.Duration, error) {
 		return true, status[stableID], nil
-	}, 10)
+	}, topBLDefaultTag, 10)
 
 	if len(got.proxies) != 2 {
 		t.Fatalf("expected 2 proxies after dedup, got %d", len(got.proxies))
@@ -133,7 +148,7 @@ func TestSelectTopBLAndCIDRByLatencyQuotas(t *testing.T) {
 
 	got := selectTopBLAndCIDRByLatency(proxies, func(stableID string) (bool, time.Duration, error) {
 		return true, latencyByID[stableID], nil
-	}, 10, 10)
+	}, topBLDefaultTag, topCIDRDefaultTag, 10, 10)
 
 	if len(got.proxies) != 20 {
 		t.Fatalf("expected 20 proxies total, got %d", len(got.proxies))
@@ -168,7 +183,7 @@ func TestSelectTopBLAndCIDRByLatencySkipsInsecureConfigs(t *testing.T) {
 		blSecure, blBadAllow, blBadInsecure, cidrSecure,
 	}, func(stableID string) (bool, time.Duration, error) {
 		return true, 50 * time.Millisecond, nil
-	}, 10, 10)
+	}, topBLDefaultTag, topCIDRDefaultTag, 10, 10)
 
 	if len(got.proxies) != 2 {
 		t.Fatalf("expected 2 allowed proxies, got %d", len(got.proxies))
@@ -182,6 +197,26 @@ func TestSelectTopBLAndCIDRByLatencySkipsInsecureConfigs(t *testing.T) {
 	}
 }
 
+func TestSelectTopBLAndCIDRByLatencyIgnoresTagSubstrings(t *testing.T) {
+	bl := newTestProxy("BL Node", "vless://bl")
+	cable := newTestProxy("CABLE Node", "vless://cable")
+	custom := newTestProxy("WL Node", "vless://wl")
+
+	statusFn := func(stableID string) (bool, time.Duration, error) {
+		return true, 50 * time.Millisecond, nil
+	}
+
+	got := selectTopBLAndCIDRByLatency([]*models.ProxyConfig{bl, cable, custom}, statusFn, topBLDefaultTag, topCIDRDefaultTag, 10, 10)
+	if len(got.proxies) != 1 || got.proxies[0].proxy.StableID != bl.StableID {
+		t.Fatalf("expected only BL Node to be selected, got %d proxies", len(got.proxies))
+	}
+
+	got = selectTopBLAndCIDRByLatency([]*models.ProxyConfig{bl, cable, custom}, statusFn, "wl", topCIDRDefaultTag, 10, 10)
+	if len(got.proxies) != 1 || got.proxies[0].proxy.StableID != custom.StableID {
+		t.Fatalf("expected only WL Node to be selected with custom tag, got %d proxies", len(got.proxies))
+	}
+}
+
 func TestAPITopBLSubscriptionHandlerToken(t *testing.T) {
 	pc := checker.NewProxyChecker(nil, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
 	handler := APITopBLSubscriptionHandler(pc, "super-secret-token")
@@ -208,6 +243,71 @@ func TestAPITopBLSubscriptionHandlerToken(t *testing.T) {
 	}
 }
 
+func TestAPIRoutesTopBLDisabled(t *testing.T) {
+	pc := checker.NewProxyChecker(nil, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	serve := func(enabled bool) int {
+		mux := http.NewServeMux()
+		for _, route := range APIRoutes(APIDependencies{ProxyChecker: pc, TopBL: enabled, TopBLToken: "token"}) {
+			if route.Pattern == DefaultTopBLPath && !enabled {
+				t.Fatal("top BL route must not be registered when disabled")
+			}
+			mux.Handle(route.Pattern, route.Handler)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DefaultTopBLPath+"?token=token", nil))
+		return rec.Code
+	}
+
+	if code := serve(true); code != http.StatusOK {
+		t.Fatalf("expected 200 when enabled, got %d", code)
+	}
+	if code := serve(false); code != http.StatusNotFound {
+		t.Fatalf("expected 404 when disabled, got %d", code)
+	}
+}
+
+func TestAPITopBLSubscriptionHandlerGroup(t *testing.T) {
+	pc := checker.NewProxyChecker(nil, 10000, "http://127.0.0.1:1", 1, "http://example.com", "", 1, 1, "status", 1)
+	handler := APITopBLSubscriptionHandler(pc, "")
+
+	for _, group := range []string{"cidr", "BL"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/public/subscriptions/top-bl?group="+group, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 for group %q, got %d", group, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/public/subscriptions/top-bl?group=fast", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown group, got %d", rec.Code)
+	}
+}
+
+func TestFilterLinksByTag(t *testing.T) {
+	bl := newTestProxy("DE [BL] 01", "vless://bl")
+	cidr := newTestProxy("NL CIDR 02", "vless://cidr")
+	both := newTestProxy("BL CIDR 03", "vless://both")
+	proxies := []*models.ProxyConfig{bl, cidr, both}
+	links := []string{"vless://bl", "vless://cidr", "vless://both", "vless://gone"}
+
+	selector := newStableTopBLSelector(20)
+	tag, ok := selector.groupTag("cidr")
+	if !ok || tag != topCIDRDefaultTag {
+		t.Fatalf("groupTag(cidr) = %q, %v", tag, ok)
+	}
+
+	got := filterLinksByTag(links, proxies, tag)
+	if strings.Join(got, ",") != "vless://cidr,vless://both" {
+		t.Fatalf("unexpected CIDR group: %v", got)
+	}
+	got = filterLinksByTag(links, proxies, topBLDefaultTag)
+	if strings.Join(got, ",") != "vless://bl,vless://both" {
+		t.Fatalf("unexpected BL group: %v", got)
+	}
+}
+
 func TestStableTopBLSelectorKeepsPublishedWhenAllNA(t *testing.T) {
 	selector := newStableTopBLSelector(10)
 	now := time.Now()
@@ -273,6 +373,121 @@ func TestStableTopBLSelectorHysteresisAndHold(t *testing.T) {
 	}
 }
 
+func TestStableTopBLSelectorStabilityFlipsWinner(t *testing.T) {
+	steady := newTestProxy("BL Steady", "vless://steady")
+	jittery := newTestProxy("BL Jittery", "vless://jittery")
+	proxies := []*models.ProxyConfig{steady, jittery}
+
+	run := func(stabilityWeight float64) rankedProxy {
+		selector := newStableTopBLSelector(1)
+		selector.setWeights(1.0, stabilityWeight)
+		now := time.Now()
+		for i := 0; i < 6; i++ {
+			jitteryOnline := i%2 == 0
+			selector.Next(proxies, func(stableID string) (bool, time.Duration, error) {
+				if stableID == steady.StableID {
+					return true, 100 * time.Millisecond, nil
+				}
+				return jitteryOnline, 90 * time.Millisecond, nil
+			}, now.Add(time.Duration(i)*time.Minute))
+		}
+		ranked := selector.applyEMA([]rankedProxy{
+			{proxy: steady, latency: 100 * time.Millisecond, key: dedupKey(steady)},
+			{proxy: jittery, latency: 90 * time.Millisecond, key: dedupKey(jittery)},
+		})
+		return ranked[0]
+	}
+
+	if got := run(0); got.proxy.StableID != jittery.StableID {
+		t.Fatalf("expected latency-only ranking to prefer jittery node, got %s", got.proxy.Name)
+	}
+	if got := run(1.0); got.proxy.StableID != steady.StableID {
+		t.Fatalf("expected stability-weighted ranking to prefer steady node, got %s", got.proxy.Name)
+	}
+}
+
+func TestCompositeScoreKeepsLatencyDominantByDefault(t *testing.T) {
+	selector := newStableTopBLSelector(1)
+	selector.lossByKey["a"] = 0
+	selector.lossByKey["b"] = 0.3
+
+	fast := selector.compositeScore("b", 50*time.Millisecond)
+	slow := selector.compositeScore("a", 300*time.Millisecond)
+	if fast >= slow {
+		t.Fatalf("expected much faster node to win despite some loss: fast=%s slow=%s", fast, slow)
+	}
+}
+
+func TestStableTopBLSelectorPinnedAlwaysIncludedWhenOnline(t *testing.T) {
+	selector := newStableTopBLSelector(1)
+	now := time.Now()
+
+	fast := newTestProxy("BL Fast", "vless://fast")
+	pinned := newTestProxy("BL Slow [PIN]", "vless://pinned")
+	proxies := []*models.ProxyConfig{fast, pinned}
+	pinnedOnline := true
+	statusFn := func(stableID string) (bool, time.Duration, error) {
+		if stableID == pinned.StableID {
+			return pinnedOnline, 900 * time.Millisecond, nil
+		}
+		return true, 50 * time.Millisecond, nil
+	}
+
+	selector.setPins([]string{"PIN"}, false)
+	out := selector.Next(proxies, statusFn, now)
+	if len(out) != 2 || out[0] != sanitizeConfig(pinned.SourceLine) || out[1] != sanitizeConfig(fast.SourceLine) {
+		t.Fatalf("expected pinned node on top of the limit, got %v", out)
+	}
+
+	// The pinned node drops out while offline and is back as soon as it
+	// recovers, without waiting for the batch interval.
+	pinnedOnline = false
+	out = selector.Next(proxies, statusFn, now.Add(time.Minute))
+	if len(out) != 1 || out[0] != sanitizeConfig(fast.SourceLine) {
+		t.Fatalf("expected offline pinned node to be left out, got %v", out)
+	}
+	pinnedOnline = true
+	out = selector.Next(proxies, statusFn, now.Add(2*time.Minute))
+	if len(out) != 2 || out[0] != sanitizeConfig(pinned.SourceLine) {
+		t.Fatalf("expected recovered pinned node to be published immediately, got %v", out)
+	}
+}
+
+func TestStableTopBLSelectorPinnedNotEvicted(t *testing.T) {
+	selector := newStableTopBLSelector(2)
+	now := time.Now()
+
+	pinned := newTestProxy("BL Pinned", "vless://pinned")
+	other := newTestProxy("BL Other", "vless://other")
+	challenger := newTestProxy("BL Challenger", "vless://challenger")
+	selector.setPins([]string{pinned.StableID}, true)
+
+	out := selector.Next([]*models.ProxyConfig{pinned, other}, func(stableID string) (bool, time.Duration, error) {
+		return true, 500 * time.Millisecond, nil
+	}, now)
+	if len(out) != 2 {
+		t.Fatalf("expected 2 links, got %v", out)
+	}
+
+	// A much faster challenger after the hold may replace the unpinned node
+	// but never the pinned one, and pins count against the limit.
+	proxies := []*models.ProxyConfig{pinned, other, challenger}
+	for i := 1; i <= 3; i++ {
+		out = selector.Next(proxies, func(stableID string) (bool, time.Duration, error) {
+			if stableID == challenger.StableID {
+				return true, 20 * time.Millisecond, nil
+			}
+			return true, 800 * time.Millisecond, nil
+		}, now.Add(time.Duration(i)*3*time.Hour))
+		if len(out) != 2 || out[0] != sanitizeConfig(pinned.SourceLine) {
+			t.Fatalf("iteration %d: expected pinned node to stay first within the limit, got %v", i, out)
+		}
+	}
+	if out[1] != sanitizeConfig(challenger.SourceLine) {
+		t.Fatalf("expected challenger to take the unpinned slot, got %v", out)
+	}
+}
+
 func newTestProxy(name, sourceLine string) *models.ProxyConfig {
 	testProxySeq++
 	p := &models.ProxyConfig{
@@ -286,3 +501,22 @@ func newTestProxy(name, sourceLine string) *models.ProxyConfig {
 	p.StableID = p.GenerateStableID()
 	return p
 }
+
+func TestStableTopBLSelectorDoesNotWriteLatencyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "latency.json")
+	store, _ := checker.NewLatencyStore(path)
+	selector := newStableTopBLSelector(10)
+	selector.setLatencyStore(store)
+
+	p1 := newTestProxy("BL One", "vless://one")
+	selector.Next([]*models.ProxyConfig{p1}, func(string) (bool, time.Duration, error) {
+		return true, 100 * time.Millisecond, nil
+	}, time.Now())
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("a selection must not write the store, stat err=%v", err)
+	}
+	if got := store.Load(TopBLLatencySection)[p1.StableID]; got != 100*time.Millisecond {
+		t.Fatalf("expected the selection to hand its averages to the store, got %s", got)
+	}
+}
@@ -1,11 +1,15 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"xray-checker/logger"
 )
@@ -18,9 +22,51 @@ type AssetLoader struct {
 	enabled        bool
 }
 
-var globalAssetLoader *AssetLoader
+var (
+	globalAssetLoader atomic.Pointer[AssetLoader]
+	assetReloadMu     sync.Mutex
+)
+
+var (
+	ErrAssetsNotConfigured   = errors.New("custom assets path is not configured")
+	ErrAssetReloadInProgress = errors.New("asset reload already in progress")
+)
 
 func InitAssetLoader(customPath string) error {
+	loader, err := newAssetLoader(customPath)
+	if err != nil {
+		return err
+	}
+	globalAssetLoader.Store(loader)
+	return nil
+}
+
+// ReloadAssetLoader re-reads custom assets from the configured path. The new
+// assets replace the current ones only if they load cleanly; on error the
+// previous assets stay in use. Concurrent reloads are rejected.
+func ReloadAssetLoader() (*AssetLoader, error) {
+	if !assetReloadMu.TryLock() {
+		return nil, ErrAssetReloadInProgress
+	}
+	defer assetReloadMu.Unlock()
+
+	current := GetAssetLoader()
+	if current == nil || !current.enabled {
+		return nil, ErrAssetsNotConfigured
+	}
+	loader, err := newAssetLoader(current.basePath)
+	if err != nil {
+		return nil, err
+	}
+	globalAssetLoader.Store(loader)
+	return loader, nil
+}
+
+func GetAssetLoader() *AssetLoader {
+	return globalAssetLoader.Load()
+}
+
+func newAssetLoader(customPath string) (*AssetLoader, error) {
 	loader := &AssetLoader{
 		basePath: customPath,
 		files:    make(map[string][]byte),
@@ -29,16 +75,10 @@ func InitAssetLoader(customPath string) error {
 
 	if loader.enabled {
 		if err := loader.load(); err != nil {
-			return err
+			return nil, err
 		}
 	}
-
-	globalAssetLoader = loader
-	return nil
-}
-
-func GetAssetLoader() *AssetLoader {
-	return globalAssetLoader
+	return loader, nil
 }
 
 func (a *AssetLoader) load() error {
@@ -134,6 +174,16 @@ func (a *AssetLoader) HasCustomCSS() bool {
 	return a.hasCustomCSS
 }
 
+// FileNames returns the names of the loaded custom assets in sorted order.
+func (a *AssetLoader) FileNames() []string {
+	names := make([]string, 0, len(a.files))
+	for name := range a.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (a *AssetLoader) GetFile(name string) ([]byte, bool) {
 	data, exists := a.files[name]
 	return data, exists
@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http"
+	"time"
+	"xray-checker/checker"
+)
+
+// DashboardResponse bundles what the dashboard loads on start. Every section
+// is built from one snapshot of the proxy list, so the counts in Status match
+// the entries in Proxies.
+type DashboardResponse struct {
+	Status     StatusResponse     `json:"status"`
+	Config     ConfigResponse     `json:"config"`
+	SystemInfo SystemInfoResponse `json:"systemInfo"`
+	Proxies    []ProxyInfo        `json:"proxies"`
+}
+
+// APIDashboardHandler returns status, config, system info and proxies at once
+// @Summary Get dashboard data
+// @Description Returns the payloads of /api/v1/status, /api/v1/config, /api/v1/system/info and /api/v1/proxies in one response, built from the same proxy list. Like those endpoints it requires authentication.
+// @Tags status
+// @Produce json
+// @Success 200 {object} DashboardResponse
+// @Router /api/v1/dashboard [get]
+func APIDashboardHandler(version string, startTime time.Time, proxyChecker *checker.ProxyChecker, scheduler *checker.CheckScheduler, startPort int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		proxies := proxyChecker.GetProxies()
+		writeJSON(w, DashboardResponse{
+			Status:     statusSummary(proxyChecker, proxies),
+			Config:     currentConfig(proxies, scheduler),
+			SystemInfo: systemInfo(version, startTime, proxyChecker),
+			Proxies:    proxyInfos(proxyChecker, proxies, startPort),
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"xray-checker/checker"
+)
+
+// checkerETag returns a weak ETag of the checker's results, built from the
+// proxy list generation and the version of the recorded results.
+func checkerETag(pc *checker.ProxyChecker) string {
+	generation, results := pc.StateVersion()
+	return fmt.Sprintf(`W/"%d-%d"`, generation, results)
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match
+// already matches it, writes 304 and reports true. Comparison is weak, as
+// RFC 9110 requires for If-None-Match.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"xray-checker/checker"
 	"xray-checker/config"
@@ -30,9 +32,11 @@ type EndpointInfo struct {
 	Latency    time.Duration
 	StableID   string
 	Config     string
+	SubName    string
+	BadSince   time.Duration
 }
 
-func IndexHandler(version string, proxyChecker *checker.ProxyChecker) http.HandlerFunc {
+func IndexHandler(version string, proxyChecker *checker.ProxyChecker, scheduler *checker.CheckScheduler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" {
 			http.NotFound(w, r)
@@ -46,6 +50,22 @@ func IndexHandler(version string, proxyChecker *checker.ProxyChecker) http.Handl
 		copy(allEndpoints, registeredEndpoints)
 		endpointsMu.RUnlock()
 
+		subscriptionNames := CollectSubscriptionNames(proxyChecker.GetProxies())
+		selectedSub := strings.TrimSpace(r.URL.Query().Get("sub"))
+		if selectedSub != "" {
+			filtered := make([]EndpointInfo, 0, len(allEndpoints))
+			for _, ep := range allEndpoints {
+				if ep.SubName == selectedSub {
+					filtered = append(filtered, ep)
+				}
+			}
+			allEndpoints = filtered
+		}
+
+		if config.CLIConfig.Web.EndpointOrder != EndpointOrderConfig {
+			sortEndpointsByStatus(allEndpoints)
+		}
+
 		isPublic := config.CLIConfig.Web.Public
 		showServerDetails := config.CLIConfig.Web.ShowServerDetails
 		if isPublic {
@@ -62,6 +82,7 @@ func IndexHandler(version string, proxyChecker *checker.ProxyChecker) http.Handl
 					Status:   ep.Status,
 					Latency:  ep.Latency,
 					StableID: ep.StableID,
+					BadSince: ep.BadSince,
 				}
 			}
 		}
@@ -72,11 +93,12 @@ func IndexHandler(version string, proxyChecker *checker.ProxyChecker) http.Handl
 			Version:                    version,
 			Host:                       config.CLIConfig.Metrics.Host,
 			Port:                       config.CLIConfig.Metrics.Port,
-			CheckInterval:              config.CLIConfig.Proxy.CheckInterval,
+			CheckInterval:              checkInterval(scheduler),
 			IPCheckUrl:                 config.CLIConfig.Proxy.IpCheckUrl,
 			CheckMethod:                config.CLIConfig.Proxy.CheckMethod,
 			StatusCheckUrl:             config.CLIConfig.Proxy.StatusCheckUrl,
 			DownloadUrl:                config.CLIConfig.Proxy.DownloadUrl,
+			DNSCheckDomain:             config.CLIConfig.Proxy.DNSCheckDomain,
 			SimulateLatency:            config.CLIConfig.Proxy.SimulateLatency,
 			Timeout:                    config.CLIConfig.Proxy.Timeout,
 			SubscriptionUpdate:         config.CLIConfig.Subscription.Update,
@@ -84,11 +106,16 @@ func IndexHandler(version string, proxyChecker *checker.ProxyChecker) http.Handl
 			StartPort:                  config.CLIConfig.Xray.StartPort,
 			Instance:                   config.CLIConfig.Metrics.Instance,
 			PushUrl:                    metrics.GetPushURL(config.CLIConfig.Metrics.PushURL),
-			Endpoints:                  endpoints,
+			Endpoints:                  htmlEndpoints(endpoints),
 			EndpointsJSON:              endpointsJSON,
 			ShowServerDetails:          showServerDetails,
 			IsPublic:                   isPublic,
-			SubscriptionName:           subscription.GetSubscriptionName(),
+			SubscriptionName:           displaySubscriptionName(),
+			BasePath:                   normalizeBasePath(config.CLIConfig.Metrics.BasePath),
+			AutoRefreshSeconds:         max(config.CLIConfig.Web.AutoRefreshSeconds, 0),
+			SubscriptionNames:          subscriptionNames,
+			SelectedSubscription:       selectedSub,
+			EndpointOrder:              config.CLIConfig.Web.EndpointOrder,
 		}
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -100,17 +127,49 @@ func IndexHandler(version string, proxyChecker *checker.ProxyChecker) http.Handl
 	}
 }
 
+// displaySubscriptionName returns the configured subscription name override,
+// falling back to the name detected from the subscription.
+func displaySubscriptionName() string {
+	if name := strings.TrimSpace(config.CLIConfig.Web.SubscriptionNameOverride); name != "" {
+		return name
+	}
+	return subscription.GetSubscriptionName()
+}
+
+// serverInfo renders server:port, followed by the resolved IP when the
+// server is a domain that was resolved.
+func serverInfo(proxy *models.ProxyConfig) string {
+	info := fmt.Sprintf("%s:%d", proxy.Server, proxy.Port)
+	if proxy.ResolvedServer != "" {
+		info += " (" + proxy.ResolvedServer + ")"
+	}
+	return info
+}
+
 type endpointView struct {
-	Name       string `json:"name"`
-	StableID   string `json:"stableId"`
-	Status     bool   `json:"status"`
-	Latency    string `json:"latency"`
-	LatencyMs  int64  `json:"latencyMs"`
-	Index      int    `json:"index"`
-	URL        string `json:"url,omitempty"`
-	ServerInfo string `json:"serverInfo,omitempty"`
-	ProxyPort  int    `json:"proxyPort,omitempty"`
-	Config     string `json:"config,omitempty"`
+	Name        string `json:"name"`
+	StableID    string `json:"stableId"`
+	Status      bool   `json:"status"`
+	Latency     string `json:"latency"`
+	LatencyMs   int64  `json:"latencyMs"`
+	Index       int    `json:"index"`
+	URL         string `json:"url,omitempty"`
+	ServerInfo  string `json:"serverInfo,omitempty"`
+	ProxyPort   int    `json:"proxyPort,omitempty"`
+	Config      string `json:"config,omitempty"`
+	BadSinceSec int64  `json:"badSinceSec,omitempty"`
+}
+
+// htmlEndpoints returns endpoints with names and server info sanitized for
+// direct use in HTML templates. EndpointsJSON keeps the original text.
+func htmlEndpoints(endpoints []EndpointInfo) []EndpointInfo {
+	out := make([]EndpointInfo, len(endpoints))
+	for i, ep := range endpoints {
+		ep.Name = sanitizeText(ep.Name)
+		ep.ServerInfo = sanitizeText(ep.ServerInfo)
+		out[i] = ep
+	}
+	return out
 }
 
 func buildEndpointsJSON(endpoints []EndpointInfo, showServerDetails bool, isPublic bool) template.JS {
@@ -121,19 +180,20 @@ func buildEndpointsJSON(endpoints []EndpointInfo, showServerDetails bool, isPubl
 			latency = fmt.Sprintf("%dms", ep.Latency.Milliseconds())
 		}
 		item := endpointView{
-			Name:      sanitizeText(ep.Name),
-			StableID:  ep.StableID,
-			Status:    ep.Status,
-			Latency:   latency,
-			LatencyMs: ep.Latency.Milliseconds(),
-			Index:     ep.Index,
+			Name:        sanitizeJSONText(ep.Name),
+			StableID:    ep.StableID,
+			Status:      ep.Status,
+			Latency:     latency,
+			LatencyMs:   ep.Latency.Milliseconds(),
+			Index:       ep.Index,
+			BadSinceSec: int64(ep.BadSince.Seconds()),
 		}
 		if !isPublic {
 			item.URL = ep.URL
 			item.Config = sanitizeConfig(ep.Config)
 		}
 		if showServerDetails {
-			item.ServerInfo = sanitizeText(ep.ServerInfo)
+			item.ServerInfo = sanitizeJSONText(ep.ServerInfo)
 			item.ProxyPort = ep.ProxyPort
 		}
 		view = append(view, item)
@@ -153,6 +213,28 @@ func HealthHandler() http.HandlerFunc {
 	}
 }
 
+// proxiesLoaded backs ReadyHandler. It is false until the first proxies are
+// loaded and again whenever the subscription yields none.
+var proxiesLoaded atomic.Bool
+
+// SetProxiesLoaded records whether the current configuration has any proxies.
+func SetProxiesLoaded(loaded bool) {
+	proxiesLoaded.Store(loaded)
+}
+
+// ReadyHandler reports 503 while no proxies are loaded, so orchestrators can
+// tell an empty or unavailable subscription apart from a working checker.
+func ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !proxiesLoaded.Load() {
+			http.Error(w, "no proxies loaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}
+}
+
 func BasicAuthMiddleware(username, password string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -167,23 +249,58 @@ func BasicAuthMiddleware(username, password string) func(http.Handler) http.Hand
 	}
 }
 
+// ConfigStatusResponse is returned by the config status path with ?format=json.
+type ConfigStatusResponse struct {
+	Online      bool       `json:"online"`
+	LatencyMs   int64      `json:"latencyMs"`
+	LastChecked *time.Time `json:"lastChecked,omitempty"`
+}
+
 func ConfigStatusHandler(proxyChecker *checker.ProxyChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		jsonFormat := strings.EqualFold(r.URL.Query().Get("format"), "json")
+		fail := func(msg string, code int) {
+			if jsonFormat {
+				writeError(w, msg, code)
+				return
+			}
+			http.Error(w, msg, code)
+		}
+
 		path := r.URL.Path[len("/config/"):]
 		if path == "" {
-			http.Error(w, "Config path is required", http.StatusBadRequest)
+			fail("Config path is required", http.StatusBadRequest)
 			return
 		}
 
 		found, exists := proxyChecker.GetProxyByStableID(path)
 		if !exists {
-			http.Error(w, "Config not found", http.StatusNotFound)
+			fail("Config not found", http.StatusNotFound)
 			return
 		}
 
 		status, latency, err := proxyChecker.GetProxyStatusByStableID(found.StableID)
 		if err != nil {
-			http.Error(w, "Status not available", http.StatusNotFound)
+			fail("Status not available", http.StatusNotFound)
+			return
+		}
+
+		code := http.StatusOK
+		if !status {
+			code = http.StatusServiceUnavailable
+		}
+
+		if jsonFormat {
+			resp := ConfigStatusResponse{
+				Online:    status,
+				LatencyMs: latency.Milliseconds(),
+			}
+			if ts, ok := proxyChecker.GetLastCheckedByStableID(found.StableID); ok {
+				resp.LastChecked = &ts
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			json.NewEncoder(w).Encode(resp)
 			return
 		}
 
@@ -191,16 +308,37 @@ func ConfigStatusHandler(proxyChecker *checker.ProxyChecker) http.HandlerFunc {
 			time.Sleep(time.Duration(latency))
 		}
 
+		w.WriteHeader(code)
 		if status {
-			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("OK"))
 		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
 			w.Write([]byte("Failed"))
 		}
 	}
 }
 
+// EndpointOrderConfig keeps dashboard endpoints in config order instead of
+// sorting them by status.
+const EndpointOrderConfig = "config"
+
+// sortEndpointsByStatus orders endpoints online first, then by ascending
+// latency with unmeasured latency last, then by name.
+func sortEndpointsByStatus(endpoints []EndpointInfo) {
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		if a.Status != b.Status {
+			return a.Status
+		}
+		if a.Latency != b.Latency {
+			if a.Latency == 0 || b.Latency == 0 {
+				return b.Latency == 0
+			}
+			return a.Latency < b.Latency
+		}
+		return a.Name < b.Name
+	})
+}
+
 func RegisterConfigEndpoints(proxies []*models.ProxyConfig, proxyChecker *checker.ProxyChecker, startPort int) {
 	endpoints := make([]EndpointInfo, 0, len(proxies))
 
@@ -210,20 +348,26 @@ func RegisterConfigEndpoints(proxies []*models.ProxyConfig, proxyChecker *checke
 		}
 
 		endpoint := fmt.Sprintf("./config/%s", proxy.StableID)
-		displayName := sanitizeText(proxy.Name)
+		displayName := sanitizeJSONText(proxy.Name)
 
 		status, latency, _ := proxyChecker.GetProxyStatusByStableID(proxy.StableID)
+		var badSince time.Duration
+		if since, ok := proxyChecker.GetBadSinceByStableID(proxy.StableID); ok {
+			badSince = time.Since(since)
+		}
 
 		endpoints = append(endpoints, EndpointInfo{
 			Name:       displayName,
-			ServerInfo: sanitizeText(fmt.Sprintf("%s:%d", proxy.Server, proxy.Port)),
+			ServerInfo: sanitizeJSONText(serverInfo(proxy)),
 			URL:        endpoint,
-			ProxyPort:  startPort + proxy.Index,
+			ProxyPort:  proxy.InboundPort(startPort),
 			Index:      proxy.Index,
 			Status:     status,
 			Latency:    latency,
 			StableID:   proxy.StableID,
 			Config:     proxy.SourceLine,
+			SubName:    proxy.SubName,
+			BadSince:   badSince,
 		})
 	}
 
@@ -238,6 +382,7 @@ type PrefixServeMux struct {
 }
 
 func NewPrefixServeMux(prefix string) (*PrefixServeMux, error) {
+	prefix = normalizeBasePath(prefix)
 	if strings.HasSuffix(prefix, "/") {
 		return nil, fmt.Errorf("served url path prefix '%s' should not ends with a '/'", prefix)
 	}
@@ -247,14 +392,44 @@ func NewPrefixServeMux(prefix string) (*PrefixServeMux, error) {
 	}, nil
 }
 
+func (pm *PrefixServeMux) Prefix() string {
+	return pm.prefix
+}
+
+// normalizeBasePath treats "/" as no prefix and adds a missing leading slash.
+func normalizeBasePath(prefix string) string {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "/" {
+		return ""
+	}
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
+
 func (pm *PrefixServeMux) Handle(pattern string, handler http.Handler) {
 	pm.mux.Handle(pm.prefix+pattern, http.StripPrefix(pm.prefix, handler))
 }
 
 func (pm *PrefixServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == pm.prefix || strings.HasPrefix(r.URL.Path, pm.prefix+"/") {
+	if pm.prefix == "" {
+		pm.mux.ServeHTTP(w, r)
+		return
+	}
+
+	switch {
+	case r.URL.Path == pm.prefix:
+		// Serve the bare prefix as the index directly instead of letting
+		// ServeMux redirect to prefix+"/", which loops behind reverse proxies
+		// that strip trailing slashes.
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = pm.prefix + "/"
+		r2.URL.RawPath = ""
+		pm.mux.ServeHTTP(w, r2)
+	case strings.HasPrefix(r.URL.Path, pm.prefix+"/"):
 		pm.mux.ServeHTTP(w, r)
-	} else {
+	default:
 		http.NotFound(w, r)
 	}
 }
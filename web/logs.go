@@ -0,0 +1,134 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"xray-checker/logger"
+)
+
+// logStreamBuffer is how many lines a slow stream client may lag behind
+// before lines are dropped for it.
+const logStreamBuffer = 256
+
+// defaultLogsLimit is how many lines the logs endpoint returns without a
+// limit parameter.
+const defaultLogsLimit = 200
+
+var (
+	logURLPattern  = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"']+`)
+	logUUIDPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+)
+
+// redactLogLine hides secrets that log messages may carry: URL credentials,
+// paths and queries (subscription tokens) and proxy UUIDs.
+func redactLogLine(line logger.Line) logger.Line {
+	line.Message = logURLPattern.ReplaceAllStringFunc(line.Message, redactURL)
+	line.Message = logUUIDPattern.ReplaceAllString(line.Message, redactedValue)
+	return line
+}
+
+// logLevelFilter parses the optional level query parameter. Lines more
+// verbose than the returned level are skipped.
+func logLevelFilter(r *http.Request) logger.Level {
+	if value := r.URL.Query().Get("level"); value != "" {
+		return logger.ParseLevel(value)
+	}
+	return logger.LevelDebug
+}
+
+func lineAllowed(line logger.Line, minLevel logger.Level) bool {
+	return logger.ParseLevel(line.Level) <= minLevel
+}
+
+// APISystemLogsHandler returns the most recent log lines
+// @Summary Recent logs
+// @Description Returns the most recent log lines from the in-memory buffer, oldest first and newest last. Secrets are redacted.
+// @Tags system
+// @Produce json
+// @Param limit query int false "Maximum number of lines, capped at the buffer size (default 200)"
+// @Param level query string false "Most verbose level to return (error, warn, info, debug)"
+// @Success 200 {array} logger.Line
+// @Router /api/v1/system/logs [get]
+func APISystemLogsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		limit := defaultLogsLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				writeError(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		minLevel := logLevelFilter(r)
+
+		// Filter the whole buffer first so limit counts returned lines.
+		all := logger.Recent(0)
+		lines := make([]logger.Line, 0, min(limit, len(all)))
+		for i := len(all) - 1; i >= 0 && len(lines) < limit; i-- {
+			if lineAllowed(all[i], minLevel) {
+				lines = append(lines, redactLogLine(all[i]))
+			}
+		}
+		slices.Reverse(lines)
+		writeJSON(w, lines)
+	}
+}
+
+// APISystemLogsStreamHandler streams new log lines as server-sent events
+// @Summary Stream logs
+// @Description Streams log lines as they are written, as server-sent events with one JSON line per event. Secrets are redacted.
+// @Tags system
+// @Produce text/event-stream
+// @Param level query string false "Most verbose level to send (error, warn, info, debug)"
+// @Success 200 {object} logger.Line
+// @Router /api/v1/system/logs/stream [get]
+func APISystemLogsStreamHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		minLevel := logLevelFilter(r)
+		lines, unsubscribe := logger.Subscribe(logStreamBuffer)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case line := <-lines:
+				if !lineAllowed(line, minLevel) {
+					continue
+				}
+				data, err := json.Marshal(redactLogLine(line))
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
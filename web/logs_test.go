@@ -0,0 +1,105 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"xray-checker/logger"
+)
+
+func TestAPISystemLogsStreamDeliversRedactedLines(t *testing.T) {
+	server := httptest.NewServer(APISystemLogsStreamHandler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "?level=info")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream, got %q", ct)
+	}
+
+	// The subscription is in place once headers are flushed.
+	logger.Debug("filtered debug line")
+	logger.Info("Fetched https://sub.example.com/secret-token for 11111111-2222-3333-4444-555555555555")
+
+	lines := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				lines <- data
+				return
+			}
+		}
+	}()
+
+	select {
+	case data := <-lines:
+		var line logger.Line
+		if err := json.Unmarshal([]byte(data), &line); err != nil {
+			t.Fatalf("decode event %q: %v", data, err)
+		}
+		if line.Level != "info" || !strings.HasPrefix(line.Message, "Fetched https://sub.example.com/") {
+			t.Fatalf("expected the info line first, got %+v", line)
+		}
+		if strings.Contains(line.Message, "secret-token") || strings.Contains(line.Message, "11111111-2222") {
+			t.Fatalf("expected secrets to be redacted, got %q", line.Message)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no log line received")
+	}
+}
+
+func TestAPISystemLogsHandlerOrderAndLimit(t *testing.T) {
+	t.Cleanup(func() { logger.SetBufferSize(logger.DefaultBufferSize) })
+	logger.SetBufferSize(4)
+	for _, msg := range []string{"first", "second", "third", "fourth", "fifth"} {
+		logger.Info("%s", msg)
+	}
+	logger.Warn("warned")
+
+	get := func(query string) []logger.Line {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		APISystemLogsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/logs"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %q, got %d", query, rec.Code)
+		}
+		var resp struct {
+			Data []logger.Line `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Data
+	}
+	messages := func(lines []logger.Line) string {
+		out := make([]string, len(lines))
+		for i, line := range lines {
+			out[i] = line.Message
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := messages(get("?limit=2")); got != "fifth,warned" {
+		t.Fatalf("expected the 2 newest lines, newest last, got %s", got)
+	}
+	if got := messages(get("?limit=100")); got != "third,fourth,fifth,warned" {
+		t.Fatalf("expected limit to be capped at the buffer, got %s", got)
+	}
+	if got := messages(get("?level=warn")); got != "warned" {
+		t.Fatalf("expected level filtering, got %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	APISystemLogsHandler()(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/logs?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for limit=0, got %d", rec.Code)
+	}
+}
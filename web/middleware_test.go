@@ -0,0 +1,225 @@
+package web
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"xray-checker/logger"
+)
+
+func TestGzipMiddlewareCompressesJSON(t *testing.T) {
+	payload := strings.Repeat(`{"name":"proxy","online":true},`, 100)
+	handler := GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(payload))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/proxies", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	gr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader failed: %v", err)
+	}
+	body, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("decompress failed: %v", err)
+	}
+	if string(body) != payload {
+		t.Fatalf("decompressed body mismatch")
+	}
+}
+
+func TestGzipMiddlewareSkips(t *testing.T) {
+	cases := []struct {
+		name           string
+		acceptEncoding string
+		accept         string
+		contentType    string
+		encoding       string
+	}{
+		{name: "no accept-encoding", contentType: "application/json"},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0", contentType: "application/json"},
+		{name: "event stream", acceptEncoding: "gzip", accept: "text/event-stream", contentType: "text/event-stream"},
+		{name: "already encoded", acceptEncoding: "gzip", contentType: "text/plain", encoding: "br"},
+		{name: "compressed content", acceptEncoding: "gzip", contentType: "image/png"},
+	}
+
+	for _, tc := range cases {
+		handler := GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tc.contentType)
+			if tc.encoding != "" {
+				w.Header().Set("Content-Encoding", tc.encoding)
+			}
+			w.Write([]byte("plain"))
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		}
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Encoding"); got != tc.encoding {
+			t.Fatalf("%s: unexpected Content-Encoding %q", tc.name, got)
+		}
+		if rec.Body.String() != "plain" {
+			t.Fatalf("%s: body was modified: %q", tc.name, rec.Body.String())
+		}
+	}
+}
+
+func TestCORSMiddlewareOrigins(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, "ok")
+	})
+	handler := CORSMiddleware([]string{"https://dash.example.com"}, "/api/")(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/status", nil)
+	req.Header.Set("Origin", "https://dash.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+		t.Fatalf("expected allowed origin header, got %q", got)
+	}
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/api/v1/subscriptions/remote", nil)
+	preflight.Header.Set("Origin", "https://dash.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, preflight)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for preflight, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Header().Get("Access-Control-Allow-Methods"), http.MethodDelete) {
+		t.Fatalf("expected DELETE in allowed methods, got %q", rec.Header().Get("Access-Control-Allow-Methods"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/status", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected no CORS header for disallowed origin, got %q", got)
+	}
+
+	preflight.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, preflight)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for disallowed preflight, got %d", rec.Code)
+	}
+}
+
+func TestCORSMiddlewareDisabledByDefault(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := CORSMiddleware(nil, "/api/")(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/status", nil)
+	req.Header.Set("Origin", "https://dash.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected no CORS headers when disabled, got %q", got)
+	}
+}
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	handler := ReadOnlyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for method, want := range map[string]int{
+		http.MethodGet:     http.StatusNoContent,
+		http.MethodHead:    http.StatusNoContent,
+		http.MethodOptions: http.StatusNoContent,
+		http.MethodPost:    http.StatusForbidden,
+		http.MethodPut:     http.StatusForbidden,
+		http.MethodDelete:  http.StatusForbidden,
+		http.MethodPatch:   http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/api/v1/system/pause", nil))
+		if rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d", method, want, rec.Code)
+		}
+	}
+}
+
+func TestAPIRoutesReadOnly(t *testing.T) {
+	routeFor := func(routes []Route, pattern string) Route {
+		for _, route := range routes {
+			if route.Pattern == pattern {
+				return route
+			}
+		}
+		t.Fatalf("route %s not found", pattern)
+		return Route{}
+	}
+
+	body := `{"url":"https://example.com/sub"}`
+	routes := APIRoutes(APIDependencies{ReadOnly: true})
+	rec := httptest.NewRecorder()
+	routeFor(routes, "/api/v1/subscriptions/remote").Handler.ServeHTTP(rec,
+		httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/remote", strings.NewReader(body)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for POST in read-only mode, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	routeFor(routes, "/api/v1/subscriptions/history").Handler.ServeHTTP(rec,
+		httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/history", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected GET to keep working in read-only mode, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	routeFor(APIRoutes(APIDependencies{}), "/api/v1/subscriptions/remote").Handler.ServeHTTP(rec,
+		httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/remote", strings.NewReader(body)))
+	if rec.Code == http.StatusForbidden {
+		t.Fatal("POST must not be blocked outside read-only mode")
+	}
+}
+
+func TestAccessLogMiddlewareLogsRequest(t *testing.T) {
+	lines, stop := logger.Subscribe(8)
+	defer stop()
+
+	handler := AccessLogMiddleware(logger.LevelInfo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/public/subscriptions/top-bl?token=secret", nil))
+
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line.Message, "HTTP GET /api/v1/public/subscriptions/top-bl 418 5B ") {
+			t.Fatalf("unexpected access log line %q", line.Message)
+		}
+		if strings.Contains(line.Message, "secret") {
+			t.Fatalf("query strings must not be logged: %q", line.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an access log line")
+	}
+
+	if _, err := ParseAccessLogLevel("verbose"); err == nil {
+		t.Fatal("expected an error for an unknown access log level")
+	}
+}
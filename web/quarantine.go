@@ -0,0 +1,111 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"xray-checker/config"
+	"xray-checker/subscription"
+)
+
+// QuarantineRestoreRequest names a quarantined line to move back into its
+// source file.
+type QuarantineRestoreRequest struct {
+	File  string `json:"file"`
+	ID    string `json:"id"`
+	Force bool   `json:"force"`
+}
+
+// APIQuarantineHandler lists configs quarantined by cleanup
+// @Summary List quarantined configs
+// @Description Returns the lines cleanup moved to quarantine sidecars, grouped by source file. User IDs, passwords and keys are redacted; use the line ID to restore.
+// @Tags subscriptions
+// @Produce json
+// @Success 200 {array} subscription.QuarantineFile
+// @Router /api/v1/subscriptions/quarantine [get]
+func APIQuarantineHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		files, err := subscription.ListQuarantine(subscription.QuarantineDirs(config.CLIConfig.Subscription.URLs))
+		if err != nil {
+			writeError(w, "Failed to read quarantine: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result := make([]subscription.QuarantineFile, 0, len(files))
+		for _, file := range files {
+			for i := range file.Lines {
+				file.Lines[i].Line = redactSubscriptionContent(file.Lines[i].Line)
+			}
+			result = append(result, file)
+		}
+		writeJSON(w, result)
+	}
+}
+
+// APIQuarantineRestoreHandler moves a quarantined config back into its source
+// @Summary Restore a quarantined config
+// @Description Moves a quarantined line back into its source file. Lines quarantined less than the cleanup threshold ago are refused unless force is set, since they would be removed again.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param request body QuarantineRestoreRequest true "File and line ID from the quarantine listing"
+// @Success 200 {object} subscription.QuarantinedLine
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/subscriptions/quarantine/restore [post]
+func APIQuarantineRestoreHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req QuarantineRestoreRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.File == "" || req.ID == "" {
+			writeError(w, "file and id are required", http.StatusBadRequest)
+			return
+		}
+
+		// Only files that have a quarantine sidecar in a source directory
+		// can be written to.
+		files, err := subscription.ListQuarantine(subscription.QuarantineDirs(config.CLIConfig.Subscription.URLs))
+		if err != nil {
+			writeError(w, "Failed to read quarantine: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		known := false
+		for _, file := range files {
+			if file.File == req.File {
+				known = true
+				break
+			}
+		}
+		if !known {
+			writeError(w, "No quarantine for this file", http.StatusNotFound)
+			return
+		}
+
+		minAge := time.Duration(config.CLIConfig.Cleanup.Threshold) * time.Second
+		if req.Force {
+			minAge = 0
+		}
+		restored, err := subscription.RestoreQuarantined(req.File, req.ID, minAge)
+		switch {
+		case errors.Is(err, subscription.ErrQuarantinedLineNotFound):
+			writeError(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, subscription.ErrRestoreTooSoon):
+			writeError(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			writeError(w, "Failed to restore: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		restored.Line = redactSubscriptionContent(restored.Line)
+		writeJSON(w, restored)
+	}
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xray-checker/config"
+	"xray-checker/subscription"
+)
+
+func TestAPIQuarantineListAndRestore(t *testing.T) {
+	oldSub, oldCleanup := config.CLIConfig.Subscription, config.CLIConfig.Cleanup
+	defer func() { config.CLIConfig.Subscription, config.CLIConfig.Cleanup = oldSub, oldCleanup }()
+
+	const uuid = "0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"
+	bad := "vless://" + uuid + "@bad.example.com:443?type=tcp&encryption=mlkem768x25519plus.native.0rtt.mlkemclientkey#Bad"
+	legacySS := "ss://" + base64.StdEncoding.EncodeToString([]byte("aes-256-gcm:sspass@ss.example.com:8388")) + "#SS"
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("vless://good\n"+bad+"\n"+legacySS+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := subscription.QuarantineBadConfigsFromFile(path, map[string]bool{bad: true, legacySS: true}); err != nil {
+		t.Fatal(err)
+	}
+	config.CLIConfig.Subscription.URLs = []string{"file://" + path}
+	config.CLIConfig.Cleanup.Threshold = 3600
+
+	rec := httptest.NewRecorder()
+	APIQuarantineHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/quarantine", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	for _, secret := range []string{uuid, "mlkemclientkey", strings.TrimSuffix(strings.TrimPrefix(legacySS, "ss://"), "#SS")} {
+		if strings.Contains(rec.Body.String(), secret) {
+			t.Fatalf("listing must redact %q: %s", secret, rec.Body.String())
+		}
+	}
+	var resp struct {
+		Data []subscription.QuarantineFile `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 1 || len(resp.Data[0].Lines) != 2 {
+		t.Fatalf("unexpected listing: %+v", resp.Data)
+	}
+	id := resp.Data[0].Lines[0].ID
+
+	var restored *httptest.ResponseRecorder
+	restore := func(body string) int {
+		restored = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/quarantine/restore", strings.NewReader(body))
+		APIQuarantineRestoreHandler().ServeHTTP(restored, req)
+		return restored.Code
+	}
+	if code := restore(`{"file":"/etc/passwd","id":"` + id + `"}`); code != http.StatusNotFound {
+		t.Fatalf("files without a quarantine must be refused, got %d", code)
+	}
+	if code := restore(`{"file":"` + path + `","id":"` + id + `"}`); code != http.StatusConflict {
+		t.Fatalf("recently quarantined line must be refused, got %d", code)
+	}
+	if code := restore(`{"file":"` + path + `","id":"` + id + `","force":true}`); code != http.StatusOK {
+		t.Fatalf("forced restore: expected 200, got %d", code)
+	}
+	if strings.Contains(restored.Body.String(), uuid) || strings.Contains(restored.Body.String(), "mlkemclientkey") {
+		t.Fatalf("restore response must be redacted: %s", restored.Body.String())
+	}
+	legacyID := resp.Data[0].Lines[1].ID
+	if code := restore(`{"file":"` + path + `","id":"` + legacyID + `","force":true}`); code != http.StatusOK {
+		t.Fatalf("forced restore of legacy ss: expected 200, got %d", code)
+	}
+	if strings.Contains(restored.Body.String(), "sspass") || strings.Contains(restored.Body.String(), strings.TrimSuffix(strings.TrimPrefix(legacySS, "ss://"), "#SS")) {
+		t.Fatalf("legacy ss restore response must be redacted: %s", restored.Body.String())
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), bad) {
+		t.Fatalf("expected line restored to source, got %q", data)
+	}
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+	"xray-checker/subscription"
+)
+
+var (
+	// rawUserInfoPattern matches the credentials of share links such as
+	// trojan://password@host or ss://method:password@host.
+	rawUserInfoPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^@/\s]+@`)
+	// rawVMessPattern matches vmess links, whose base64 payload holds the
+	// user ID.
+	rawVMessPattern = regexp.MustCompile(`(?i)vmess://[A-Za-z0-9+/=_-]+`)
+	// rawSSPattern matches ss:// links up to their fragment. Legacy links
+	// without "@" are base64(method:password@host:port) as a whole.
+	rawSSPattern = regexp.MustCompile(`(?i)ss://[^\s"'#]+`)
+	// rawEncryptionPattern matches the VLESS encryption parameter, which
+	// holds the client key for ML-KEM encryption.
+	rawEncryptionPattern = regexp.MustCompile(`(?i)([?&]encryption=)([^&#\s"']+)`)
+	// rawSecretFieldPattern matches secret fields of JSON and YAML
+	// (Clash-style) subscriptions.
+	rawSecretFieldPattern = regexp.MustCompile(`(?i)("?\b(?:id|uuid|password|pass|user|private-?key|public-?key|short-?id|pre-?shared-?key|secret-?key|seed|auth-str)"?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,}\]]+)`)
+)
+
+// APIRemoteSourceRawHandler returns the downloaded content of a remote source
+// @Summary Get raw remote source content
+// @Description Returns the last downloaded content of a remote subscription source as text, with user IDs, passwords and keys redacted. Base64 subscriptions are decoded first.
+// @Tags subscriptions
+// @Produce plain
+// @Param id path string true "Remote source ID"
+// @Success 200 {string} string
+// @Failure 404 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Router /api/v1/subscriptions/remote/{id}/raw [get]
+func APIRemoteSourceRawHandler(manager *subscription.RemoteManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if manager == nil {
+			writeError(w, "Remote subscriptions not configured", http.StatusBadRequest)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, "/api/v1/subscriptions/remote/")
+		id, ok := strings.CutSuffix(rest, "/raw")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			writeError(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		src, exists := manager.SourceByID(id)
+		if !exists {
+			writeError(w, "Remote source not found", http.StatusNotFound)
+			return
+		}
+		data, err := os.ReadFile(src.FilePath)
+		if errors.Is(err, os.ErrNotExist) || src.FilePath == "" {
+			writeError(w, "Remote source has not been downloaded", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeError(w, "Failed to read remote source: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if decoded, ok := decodeBase64Subscription(data); ok {
+			data = decoded
+		}
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			writeError(w, "Remote source content is binary", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		_, _ = w.Write([]byte(redactSubscriptionContent(string(data))))
+	}
+}
+
+// decodeBase64Subscription decodes content that is a base64-encoded list of
+// share links, the usual subscription format.
+func decodeBase64Subscription(data []byte) ([]byte, bool) {
+	compact := strings.Join(strings.Fields(string(data)), "")
+	if compact == "" {
+		return nil, false
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		decoded, err := encoding.DecodeString(compact)
+		if err == nil && utf8.Valid(decoded) && bytes.Contains(decoded, []byte("://")) {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+// redactSubscriptionContent hides credentials in share links and in JSON or
+// YAML subscriptions, leaving hosts, ports and parameters readable.
+func redactSubscriptionContent(content string) string {
+	content = rawVMessPattern.ReplaceAllString(content, "vmess://"+redactedValue)
+	content = rawSSPattern.ReplaceAllStringFunc(content, func(link string) string {
+		if strings.Contains(link, "@") {
+			return link
+		}
+		return link[:len("ss://")] + redactedValue
+	})
+	content = rawEncryptionPattern.ReplaceAllStringFunc(content, func(param string) string {
+		match := rawEncryptionPattern.FindStringSubmatch(param)
+		if strings.EqualFold(match[2], "none") {
+			return param
+		}
+		return match[1] + redactedValue
+	})
+	content = rawUserInfoPattern.ReplaceAllString(content, "${1}"+redactedValue+"@")
+	content = rawSecretFieldPattern.ReplaceAllString(content, "${1}"+redactedValue)
+	return logUUIDPattern.ReplaceAllString(content, redactedValue)
+}
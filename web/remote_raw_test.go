@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xray-checker/subscription"
+)
+
+func TestAPIRemoteSourceRawHandlerRedacts(t *testing.T) {
+	dir := t.TempDir()
+	const uuid = "0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"
+	legacySS := base64.StdEncoding.EncodeToString([]byte("aes-256-gcm:sspass@ss.example.com:8388"))
+	links := "vless://" + uuid + "@vless.example.com:443?type=tcp&security=reality#Node%201\n" +
+		"trojan://trojanpass@trojan.example.com:443#Node%202\n" +
+		"ss://" + legacySS + "#Node%203\n" +
+		"vless://" + uuid + "@mlkem.example.com:443?encryption=mlkem768x25519plus.native.0rtt.mlkemclientkey&type=tcp#Node%204\n"
+	plainPath := filepath.Join(dir, "plain.txt")
+	encodedPath := filepath.Join(dir, "encoded.txt")
+	binaryPath := filepath.Join(dir, "binary.bin")
+	if err := os.WriteFile(plainPath, []byte(links), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(encodedPath, []byte(base64.StdEncoding.EncodeToString([]byte(links))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(binaryPath, []byte{0x1f, 0x8b, 0x00, 0xff}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	state := subscription.RemoteState{Sources: []subscription.RemoteSource{
+		{ID: "plain", FilePath: plainPath},
+		{ID: "encoded", FilePath: encodedPath},
+		{ID: "binary", FilePath: binaryPath},
+		{ID: "pending", FilePath: filepath.Join(dir, "missing.txt")},
+	}}
+	statePath := filepath.Join(dir, "state.json")
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(statePath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manager, err := subscription.NewRemoteManager(statePath, dir)
+	if err != nil {
+		t.Fatalf("NewRemoteManager: %v", err)
+	}
+	handler := APIRemoteSourceRawHandler(manager)
+
+	get := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions/remote/"+id+"/raw", nil))
+		return rec
+	}
+
+	for _, id := range []string{"plain", "encoded"} {
+		rec := get(id)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", id, rec.Code)
+		}
+		body := rec.Body.String()
+		if strings.Contains(body, uuid) || strings.Contains(body, "trojanpass") || strings.Contains(body, legacySS) || strings.Contains(body, "mlkemclientkey") {
+			t.Fatalf("%s: secrets must be redacted, got:\n%s", id, body)
+		}
+		if !strings.Contains(body, "vless.example.com:443") || !strings.Contains(body, "trojan://"+redactedValue+"@trojan.example.com") ||
+			!strings.Contains(body, "ss://"+redactedValue+"#Node%203") || !strings.Contains(body, "?encryption="+redactedValue+"&type=tcp") {
+			t.Fatalf("%s: hosts must stay readable, got:\n%s", id, body)
+		}
+	}
+
+	if rec := get("binary"); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("binary content: expected 415, got %d", rec.Code)
+	}
+	if rec := get("pending"); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing file: expected 404, got %d", rec.Code)
+	}
+	if rec := get("unknown"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown source: expected 404, got %d", rec.Code)
+	}
+}
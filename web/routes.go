@@ -0,0 +1,145 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+	"xray-checker/checker"
+	"xray-checker/subscription"
+)
+
+// DefaultTopBLPath is where the top BL subscription is served when no path is
+// configured.
+const DefaultTopBLPath = "/api/v1/public/subscriptions/top-bl"
+
+// Route is a single API endpoint. Public routes are served without auth.
+// Mutating routes change state on POST, PUT or DELETE and reject those
+// methods in read-only mode.
+type Route struct {
+	Pattern  string
+	Handler  http.Handler
+	Public   bool
+	Mutating bool
+}
+
+// APIDependencies carries what the API handlers are built from.
+type APIDependencies struct {
+	Version        string
+	Commit         string
+	BuildDate      string
+	StartTime      time.Time
+	StartPort      int
+	ProxyChecker   *checker.ProxyChecker
+	CheckScheduler *checker.CheckScheduler
+	// UpdateScheduler runs subscription updates; nil when they are off.
+	UpdateScheduler *checker.CheckScheduler
+	RemoteManager   *subscription.RemoteManager
+	TopBL           bool
+	TopBLPath       string
+	TopBLToken      string
+	Docs            bool
+	DocsAssetsURL   string
+	ReadOnly        bool
+	XrayConfigPath  string
+}
+
+// APIRoutes returns every /api/ endpoint. It is the single place API routes
+// are declared, so tests can check them against the OpenAPI spec.
+func APIRoutes(deps APIDependencies) []Route {
+	pc := deps.ProxyChecker
+	remote := deps.RemoteManager
+
+	routes := []Route{
+		{Pattern: "/api/v1/public/proxies", Handler: APIPublicProxiesHandler(pc), Public: true},
+		{Pattern: "/api/v1/version", Handler: APIVersionHandler(NewVersionInfo(deps.Version, deps.Commit, deps.BuildDate)), Public: true},
+
+		{Pattern: "/api/v1/proxies/offline", Handler: APIOfflineProxiesHandler(pc, deps.StartPort)},
+		{Pattern: "/api/v1/proxies/", Handler: APIProxyHandler(pc, deps.StartPort)},
+		{Pattern: "/api/v1/proxies", Handler: APIProxiesHandler(pc, deps.StartPort)},
+		{Pattern: "/api/v1/config", Handler: APIConfigHandler(pc, deps.CheckScheduler)},
+		{Pattern: "/api/v1/config/check-interval", Handler: APICheckIntervalHandler(deps.CheckScheduler), Mutating: true},
+		{Pattern: "/api/v1/status", Handler: APIStatusHandler(pc)},
+		{Pattern: "/api/v1/dashboard", Handler: APIDashboardHandler(deps.Version, deps.StartTime, pc, deps.CheckScheduler, deps.StartPort)},
+		{Pattern: "/api/v1/system/info", Handler: APISystemInfoHandler(deps.Version, deps.StartTime, pc)},
+		{Pattern: "/api/v1/system/config", Handler: APISystemConfigHandler()},
+		{Pattern: "/api/v1/system/xray-config", Handler: APIXrayConfigHandler(deps.XrayConfigPath)},
+		{Pattern: "/api/v1/system/schedule", Handler: APISystemScheduleHandler(deps.CheckScheduler, deps.UpdateScheduler)},
+		{Pattern: "/api/v1/system/pause", Handler: APISystemPauseHandler(pc), Mutating: true},
+		{Pattern: "/api/v1/system/resume", Handler: APISystemResumeHandler(pc), Mutating: true},
+		{Pattern: "/api/v1/system/reload-assets", Handler: APISystemReloadAssetsHandler(), Mutating: true},
+		{Pattern: "/api/v1/system/logs", Handler: APISystemLogsHandler()},
+		{Pattern: "/api/v1/system/logs/stream", Handler: APISystemLogsStreamHandler()},
+		{Pattern: "/api/v1/system/ip", Handler: APISystemIPHandler(pc)},
+		{Pattern: "/api/v1/subscriptions/remote", Handler: APIRemoteSourcesHandler(remote, pc), Mutating: true},
+		{Pattern: "/api/v1/subscriptions/remote/interval", Handler: APIRemoteIntervalHandler(remote), Mutating: true},
+		{Pattern: "/api/v1/subscriptions/remote/refresh", Handler: APIRemoteRefreshHandler(remote), Mutating: true},
+		{Pattern: "/api/v1/subscriptions/remote/order", Handler: APIRemoteOrderHandler(remote), Mutating: true},
+		{Pattern: "/api/v1/subscriptions/remote/", Handler: APIRemoteSourceRawHandler(remote)},
+		{Pattern: "/api/v1/subscriptions/history", Handler: APISubscriptionHistoryHandler()},
+		{Pattern: "/api/v1/subscriptions/collisions", Handler: APIStableIDCollisionsHandler()},
+		{Pattern: "/api/v1/subscriptions/quarantine", Handler: APIQuarantineHandler()},
+		{Pattern: "/api/v1/subscriptions/quarantine/restore", Handler: APIQuarantineRestoreHandler(), Mutating: true},
+		{Pattern: "/api/v1/openapi.yaml", Handler: APIOpenAPIHandler()},
+	}
+	if deps.TopBL {
+		// Built only when enabled, so a disabled endpoint keeps no selector.
+		routes = append(routes, Route{Pattern: NormalizeTopBLPath(deps.TopBLPath), Handler: APITopBLSubscriptionHandler(pc, deps.TopBLToken), Public: true})
+	}
+	if deps.Docs {
+		routes = append(routes, Route{Pattern: "/api/v1/docs", Handler: APIDocsHandler(deps.DocsAssetsURL)})
+	}
+	if deps.ReadOnly {
+		for i := range routes {
+			if routes[i].Mutating {
+				routes[i].Handler = ReadOnlyMiddleware(routes[i].Handler)
+			}
+		}
+	}
+	return routes
+}
+
+// NormalizeTopBLPath falls back to DefaultTopBLPath and adds a missing leading
+// slash.
+func NormalizeTopBLPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return DefaultTopBLPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// OpenAPIPaths lists the path keys declared in the embedded OpenAPI spec, in
+// document order. It reads only the two-space indented keys of the top-level
+// "paths" mapping, which is all the spec's layout needs.
+func OpenAPIPaths() []string {
+	return parseOpenAPIPaths(openAPISpec)
+}
+
+func parseOpenAPIPaths(spec []byte) []string {
+	var paths []string
+	inPaths := false
+	scanner := bufio.NewScanner(bytes.NewReader(spec))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			inPaths = line == "paths:"
+			continue
+		}
+		if !inPaths || strings.HasPrefix(line, "   ") || !strings.HasSuffix(line, ":") {
+			continue
+		}
+		key := strings.TrimSuffix(trimmed, ":")
+		key = strings.Trim(key, `"'`)
+		paths = append(paths, key)
+	}
+	return paths
+}
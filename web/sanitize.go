@@ -2,26 +2,18 @@ package web
 
 import "strings"
 
+// sanitizeText makes a value safe to place in any HTML template context: on
+// top of sanitizeJSONText it drops quotes and backslashes and collapses
+// whitespace. Values served as JSON only need sanitizeJSONText.
 func sanitizeText(value string) string {
+	value = stripControlChars(value)
 	if value == "" {
 		return ""
 	}
 
-	// Ensure valid UTF-8 and strip control chars that can break parsing or JS in templates.
-	value = strings.ToValidUTF8(value, "")
-	value = strings.Map(func(r rune) rune {
-		if r < 32 {
-			return -1
-		}
-		return r
-	}, value)
-
 	value = strings.ReplaceAll(value, "\\", " ")
 	value = strings.ReplaceAll(value, "\"", " ")
 	value = strings.ReplaceAll(value, "'", " ")
-	value = strings.ReplaceAll(value, "\t", " ")
-	value = strings.ReplaceAll(value, "\r", " ")
-	value = strings.ReplaceAll(value, "\n", " ")
 	value = strings.TrimSpace(value)
 
 	for strings.Contains(value, "  ") {
@@ -31,17 +23,29 @@ func sanitizeText(value string) string {
 	return value
 }
 
+// sanitizeJSONText cleans a value served through encoding/json, which
+// escapes quotes, backslashes and HTML-significant characters itself, so
+// names keep their apostrophes and quotes.
+func sanitizeJSONText(value string) string {
+	return strings.TrimSpace(stripControlChars(value))
+}
+
 func sanitizeConfig(value string) string {
+	// Keep original payload as much as possible, only ensure valid UTF-8 and remove control chars.
+	return strings.TrimSpace(stripControlChars(value))
+}
+
+// stripControlChars ensures valid UTF-8 and strips control chars that can
+// break parsing or JS in templates.
+func stripControlChars(value string) string {
 	if value == "" {
 		return ""
 	}
-	// Keep original payload as much as possible, only ensure valid UTF-8 and remove control chars.
 	value = strings.ToValidUTF8(value, "")
-	value = strings.Map(func(r rune) rune {
+	return strings.Map(func(r rune) rune {
 		if r < 32 {
 			return -1
 		}
 		return r
 	}, value)
-	return strings.TrimSpace(value)
 }
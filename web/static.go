@@ -11,6 +11,19 @@ import (
 //go:embed static/*
 var staticFiles embed.FS
 
+// hasStaticAsset reports whether name can be served from /static/, either
+// from the embedded files or from custom assets.
+func hasStaticAsset(name string) bool {
+	if _, err := fs.Stat(staticFiles, path.Join("static", name)); err == nil {
+		return true
+	}
+	if loader := GetAssetLoader(); loader != nil && loader.IsEnabled() {
+		_, ok := loader.GetFile(name)
+		return ok
+	}
+	return false
+}
+
 func StaticHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		filePath := strings.TrimPrefix(r.URL.Path, "/static/")
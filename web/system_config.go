@@ -0,0 +1,123 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"xray-checker/config"
+)
+
+// redactedValue replaces secrets in the effective configuration.
+const redactedValue = "[redacted]"
+
+// secretURLFlags hold URLs whose credentials, path or query may carry
+// secrets (subscription tokens, pushgateway auth).
+var secretURLFlags = map[string]bool{
+	"subscription-url": true,
+	"metrics-push-url": true,
+	"outbound-proxy":   true,
+}
+
+type SystemConfigResponse struct {
+	Version string         `json:"version"`
+	Flags   map[string]any `json:"flags"`
+}
+
+// APISystemConfigHandler returns the full effective configuration
+// @Summary Get effective configuration
+// @Description Returns every CLI flag with its effective value, keyed by flag name. Passwords, tokens and secret parts of URLs are redacted.
+// @Tags system
+// @Produce json
+// @Success 200 {object} SystemConfigResponse
+// @Router /api/v1/system/config [get]
+func APISystemConfigHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, SystemConfigResponse{
+			Version: config.Version,
+			Flags:   effectiveFlags(&config.CLIConfig),
+		})
+	}
+}
+
+// effectiveFlags walks the kong CLI struct and collects each flag's value
+// under its flag name, redacting secrets.
+func effectiveFlags(cli *config.CLI) map[string]any {
+	flags := make(map[string]any)
+	collectFlags(reflect.ValueOf(cli).Elem(), flags)
+	return flags
+}
+
+var versionFlagType = reflect.TypeOf(config.VersionFlag(""))
+
+func collectFlags(v reflect.Value, flags map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Type == versionFlagType {
+			continue
+		}
+		if _, ok := field.Tag.Lookup("embed"); ok && field.Type.Kind() == reflect.Struct {
+			collectFlags(v.Field(i), flags)
+			continue
+		}
+		name := field.Tag.Get("name")
+		if name == "" {
+			continue
+		}
+		flags[name] = redactFlag(name, v.Field(i).Interface())
+	}
+}
+
+func redactFlag(name string, value any) any {
+	switch {
+	case strings.Contains(name, "password") || strings.Contains(name, "token"):
+		if s, ok := value.(string); ok && s == "" {
+			return ""
+		}
+		return redactedValue
+	case secretURLFlags[name]:
+		switch v := value.(type) {
+		case string:
+			return redactURL(v)
+		case []string:
+			out := make([]string, len(v))
+			for i, s := range v {
+				out[i] = redactURL(s)
+			}
+			return out
+		}
+	}
+	return value
+}
+
+// hostURLSchemes are the schemes whose host is a plain address that is safe
+// to show. Other schemes, such as base64:// sources or ss:// and vless://
+// links, may carry their whole payload where a host would be.
+var hostURLSchemes = map[string]bool{
+	"http": true, "https": true, "socks5": true, "socks5h": true,
+}
+
+// redactURL keeps the scheme and host of a remote URL and hides credentials,
+// path and query. Local file:// and folder:// sources are kept as is; any
+// other scheme is redacted entirely.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if strings.HasPrefix(raw, "file://") || strings.HasPrefix(raw, "folder://") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || !hostURLSchemes[strings.ToLower(u.Scheme)] {
+		if scheme, _, ok := strings.Cut(raw, "://"); ok && err == nil {
+			return scheme + "://" + redactedValue
+		}
+		return redactedValue
+	}
+	redacted := u.Scheme + "://" + u.Host
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		redacted += "/" + redactedValue
+	}
+	return redacted
+}
@@ -42,6 +42,7 @@ type PageData struct {
 	CheckMethod                string
 	StatusCheckUrl             string
 	DownloadUrl                string
+	DNSCheckDomain             string
 	Timeout                    int
 	SubscriptionUpdate         bool
 	SubscriptionUpdateInterval int
@@ -53,6 +54,11 @@ type PageData struct {
 	ShowServerDetails          bool
 	IsPublic                   bool
 	SubscriptionName           string
+	BasePath                   string
+	AutoRefreshSeconds         int
+	SubscriptionNames          []string
+	SelectedSubscription       string
+	EndpointOrder              string
 }
 
 func RenderIndex(w io.Writer, data PageData) error {
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"time"
+	"xray-checker/checker"
+)
+
+// TopBLLatencySection is the LatencyStore section holding the top BL
+// selector's moving averages, keyed by stable ID.
+const TopBLLatencySection = "top-bl"
+
+// setLatencyStore makes the selector start from the averages saved in store
+// and hand them back on every selection. The checker writes the store after
+// each check iteration, so requests never touch the disk. A nil store
+// disables persistence.
+func (s *stableTopBLSelector) setLatencyStore(store *checker.LatencyStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencyStore = store
+	s.restoredEMA = nil
+	if store != nil {
+		s.restoredEMA = store.Load(TopBLLatencySection)
+	}
+}
+
+// saveEMA stores the averages of the ranked proxies by stable ID. Ranking
+// keys carry credentials, so they never reach the disk.
+func (s *stableTopBLSelector) saveEMA(ranked []rankedProxy) {
+	if s.latencyStore == nil {
+		return
+	}
+	values := make(map[string]time.Duration, len(ranked))
+	for _, p := range ranked {
+		values[p.proxy.StableID] = s.emaByKey[p.key]
+	}
+	s.latencyStore.Set(TopBLLatencySection, values)
+}
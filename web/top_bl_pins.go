@@ -0,0 +1,100 @@
+package web
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"xray-checker/models"
+)
+
+// setPins sets the stable IDs or name tags of nodes that the top BL
+// subscription always lists while they are online. With inQuota pinned nodes
+// take slots from the regular limit; otherwise they are listed on top of it.
+func (s *stableTopBLSelector) setPins(pins []string, inQuota bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pins = s.pins[:0]
+	for _, pin := range pins {
+		if pin = strings.TrimSpace(pin); pin != "" {
+			s.pins = append(s.pins, pin)
+		}
+	}
+	s.pinsInQuota = inQuota
+}
+
+// isPinned reports whether proxy matches a pin by stable ID or name tag.
+func (s *stableTopBLSelector) isPinned(proxy *models.ProxyConfig) bool {
+	for _, pin := range s.pins {
+		if proxy.StableID == pin || models.HasNameTag(proxy.Name, pin) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPinned separates pinned proxies from the rest. Online pinned proxies
+// are returned ranked by latency, one per dedup key; pinned proxies that are
+// offline or n/a are left out of both results. Pinned keys never enter the
+// active set, so hold and hysteresis do not apply to them, and a change in the
+// online pinned set is published immediately.
+func (s *stableTopBLSelector) splitPinned(
+	proxies []*models.ProxyConfig,
+	statusFn func(string) (bool, time.Duration, error),
+) ([]rankedProxy, []*models.ProxyConfig) {
+	if len(s.pins) == 0 {
+		return nil, proxies
+	}
+
+	unpinned := make([]*models.ProxyConfig, 0, len(proxies))
+	byKey := make(map[string]rankedProxy)
+	for _, proxy := range proxies {
+		if proxy == nil {
+			continue
+		}
+		if proxy.StableID == "" {
+			proxy.StableID = proxy.GenerateStableID()
+		}
+		if !s.isPinned(proxy) {
+			unpinned = append(unpinned, proxy)
+			continue
+		}
+		if strings.TrimSpace(proxy.SourceLine) == "" || !isAllowedForSubscription(proxy) {
+			continue
+		}
+		online, latency, err := statusFn(proxy.StableID)
+		if err != nil || !online {
+			continue
+		}
+		candidate := rankedProxy{proxy: proxy, latency: latency, key: dedupKey(proxy)}
+		if existing, ok := byKey[candidate.key]; !ok || isBetterCandidate(candidate, existing) {
+			byKey[candidate.key] = candidate
+		}
+	}
+
+	pinned := make([]rankedProxy, 0, len(byKey))
+	keys := make(map[string]struct{}, len(byKey))
+	for key, item := range byKey {
+		pinned = append(pinned, item)
+		keys[key] = struct{}{}
+		delete(s.active, key)
+	}
+	sort.Slice(pinned, func(i, j int) bool { return isBetterCandidate(pinned[i], pinned[j]) })
+
+	if !sameKeySet(keys, s.pinnedKeys) {
+		s.hadEmergency = true
+	}
+	s.pinnedKeys = keys
+	return pinned, unpinned
+}
+
+func sameKeySet(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
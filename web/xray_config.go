@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// xrayConfigSecretKeys are the keys whose string values carry credentials in
+// an xray config: user IDs, passwords, REALITY keys, VLESS encryption client
+// keys and WireGuard/xhttp secrets. Keys are compared case-insensitively.
+var xrayConfigSecretKeys = map[string]bool{
+	"id":           true,
+	"encryption":   true,
+	"password":     true,
+	"pass":         true,
+	"user":         true,
+	"publickey":    true,
+	"privatekey":   true,
+	"shortid":      true,
+	"presharedkey": true,
+	"secretkey":    true,
+	"seed":         true,
+}
+
+// APIXrayConfigHandler returns the generated xray config
+// @Summary Get generated xray config
+// @Description Returns the xray config generated from the loaded proxies, with user IDs, passwords and keys redacted.
+// @Tags system
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/system/xray-config [get]
+func APIXrayConfigHandler(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, "Xray config has not been generated", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeError(w, "Failed to read xray config: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var cfg any
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			writeError(w, "Invalid xray config: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, redactXrayConfig(cfg))
+	}
+}
+
+// redactXrayConfig replaces the non-empty string value of every secret key,
+// at any depth, with redactedValue. A VLESS "encryption": "none" carries no
+// key and is kept.
+func redactXrayConfig(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if s, ok := item.(string); ok && s != "" && xrayConfigSecretKeys[strings.ToLower(key)] {
+				if strings.EqualFold(key, "encryption") && strings.EqualFold(s, "none") {
+					continue
+				}
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactXrayConfig(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactXrayConfig(item)
+		}
+	}
+	return value
+}
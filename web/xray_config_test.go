@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xray-checker/models"
+	"xray-checker/xray"
+)
+
+func TestAPIXrayConfigRedactsSecrets(t *testing.T) {
+	proxies := []*models.ProxyConfig{
+		{Protocol: "vless", Name: "reality", Server: "1.1.1.1", Port: 443, UUID: "11111111-aaaa-bbbb-cccc-222222222222",
+			Security: "reality", SNI: "example.com", PublicKey: "realitypubkey", ShortID: "abcdef01"},
+		{Protocol: "vmess", Name: "vmess", Server: "1.1.1.2", Port: 443, UUID: "33333333-dddd-eeee-ffff-444444444444", Index: 1},
+		{Protocol: "vless", Name: "mlkem", Server: "1.1.1.5", Port: 443, UUID: "55555555-aaaa-bbbb-cccc-666666666666",
+			Encryption: "mlkem768x25519plus.native.0rtt.mlkemclientkey", Index: 4},
+		{Protocol: "trojan", Name: "trojan", Server: "1.1.1.3", Port: 443, Password: "trojansecret", Index: 2},
+		{Protocol: "shadowsocks", Name: "ss", Server: "1.1.1.4", Port: 8388, Method: "aes-256-gcm", Password: "sssecret", Index: 3},
+	}
+	path := filepath.Join(t.TempDir(), "xray_config.json")
+	if err := xray.NewConfigGenerator().GenerateAndSaveConfig(proxies, 10000, path, "none"); err != nil {
+		t.Fatalf("generate config: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	APIXrayConfigHandler(path)(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/xray-config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, secret := range []string{"11111111-aaaa", "33333333-dddd", "trojansecret", "sssecret", "realitypubkey", "abcdef01", "mlkemclientkey"} {
+		if strings.Contains(body, secret) {
+			t.Fatalf("secret %q leaked in %s", secret, body)
+		}
+	}
+	for _, kept := range []string{"1.1.1.4", "aes-256-gcm", "example.com", redactedValue, `"encryption":"none"`} {
+		if !strings.Contains(body, kept) {
+			t.Fatalf("expected %q in %s", kept, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	APIXrayConfigHandler(filepath.Join(t.TempDir(), "missing.json"))(rec, httptest.NewRequest(http.MethodGet, "/api/v1/system/xray-config", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing config, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,202 @@
+package xray
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"xray-checker/logger"
+)
+
+// binaryVersionTimeout bounds the "version" and "run -test" calls.
+const binaryVersionTimeout = 10 * time.Second
+
+// ValidateBinary checks that path (a file path or a name looked up in PATH)
+// is an executable xray-compatible binary and returns the first line of its
+// "version" output.
+func ValidateBinary(path string) (string, error) {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		switch {
+		case errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist):
+			return "", fmt.Errorf("xray binary %q not found", path)
+		case errors.Is(err, fs.ErrPermission):
+			return "", fmt.Errorf("xray binary %q is not executable", path)
+		}
+		return "", fmt.Errorf("xray binary %q is not usable: %v", path, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), binaryVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, resolved, "version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("xray binary %q failed to report its version: %v", path, err)
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(version), nil
+}
+
+// process is an external xray binary running a config file.
+type process struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	// stopping is set before a deliberate stop, so the exit is not reported
+	// as a crash.
+	mu       sync.Mutex
+	stopping bool
+}
+
+// testBinaryConfig asks binary to validate configFile without running it.
+func testBinaryConfig(binary, configFile string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), binaryVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, binary, "run", "-test", "-c", configFile).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error testing config with %s: %v: %s", binary, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// startProcess runs binary with configFile. Its output is forwarded to the
+// logger like the embedded core's.
+func startProcess(binary, configFile string) (*process, error) {
+	cmd := exec.Command(binary, "run", "-c", configFile)
+	out := &lineLogger{}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting Xray binary %s: %v", binary, err)
+	}
+	p := &process{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		err := cmd.Wait()
+		p.mu.Lock()
+		stopping := p.stopping
+		p.mu.Unlock()
+		if !stopping {
+			logger.Error("Xray binary %s exited: %v", binary, err)
+		}
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// exited reports whether the process has exited, deliberately or not.
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// inboundReadyTimeout bounds how long a started binary may take to accept
+// connections on its inbounds.
+const inboundReadyTimeout = 10 * time.Second
+
+// waitForInbound waits until the first checker inbound in configFile accepts
+// TCP connections, so checks do not race the binary's startup. Xray opens
+// all inbounds together, so one is enough. It fails when the process exits
+// or the port stays closed for timeout.
+func (p *process) waitForInbound(configFile string, timeout time.Duration) error {
+	addr, err := firstInboundAddr(configFile)
+	if err != nil || addr == "" {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if p.exited() {
+			return fmt.Errorf("xray binary exited during startup")
+		}
+		conn, err := net.DialTimeout("tcp", addr, 200*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("xray inbound %s not accepting connections after %s", addr, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// firstInboundAddr returns the address of the first socks inbound with a
+// fixed port in configFile, or "" when there is none.
+func firstInboundAddr(configFile string) (string, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading config file: %v", err)
+	}
+	var cfg struct {
+		Inbounds []struct {
+			Listen   string      `json:"listen"`
+			Port     interface{} `json:"port"`
+			Protocol string      `json:"protocol"`
+		} `json:"inbounds"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", fmt.Errorf("error decoding config: %v", err)
+	}
+	for _, in := range cfg.Inbounds {
+		port, ok := in.Port.(float64)
+		if in.Protocol != "socks" || !ok {
+			continue
+		}
+		host := in.Listen
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "127.0.0.1"
+		}
+		return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+	}
+	return "", nil
+}
+
+// stop kills the process and waits for it to exit.
+func (p *process) stop() error {
+	p.mu.Lock()
+	p.stopping = true
+	p.mu.Unlock()
+	select {
+	case <-p.done:
+		return nil
+	default:
+	}
+	if err := p.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-p.done
+	return nil
+}
+
+// lineLogger forwards each line written to it with forwardLog.
+type lineLogger struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *lineLogger) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf.Write(b)
+	for {
+		line, err := l.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write.
+			l.buf.Reset()
+			l.buf.WriteString(line)
+			return len(b), nil
+		}
+		forwardLog(line)
+	}
+}
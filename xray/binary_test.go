@@ -0,0 +1,144 @@
+package xray
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateBinaryMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "xray-missing")
+	_, err := ValidateBinary(missing)
+	if err == nil || !strings.Contains(err.Error(), missing) || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected a clear not found error naming the binary, got %v", err)
+	}
+
+	notExecutable := filepath.Join(t.TempDir(), "xray")
+	if err := os.WriteFile(notExecutable, []byte("not a binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateBinary(notExecutable); err == nil || !strings.Contains(err.Error(), "not executable") {
+		t.Fatalf("expected a not executable error, got %v", err)
+	}
+}
+
+func TestRunnerExternalBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the fake binary")
+	}
+	binary := filepath.Join(t.TempDir(), "fake-xray")
+	script := `#!/bin/sh
+case "$1" in
+version) echo "Xray 9.9.9 (fake)"; echo "second line" ;;
+run) [ "$2" = "-test" ] && exit 0; exec sleep 60 ;;
+esac
+`
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := ValidateBinary(binary)
+	if err != nil || version != "Xray 9.9.9 (fake)" {
+		t.Fatalf("expected the first version line, got %q (%v)", version, err)
+	}
+
+	runner := NewRunner(writeTestConfig(t, "{}"), binary)
+	if err := runner.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	t.Cleanup(func() { runner.Stop() })
+	first := runner.process
+	if !runner.Running() {
+		t.Fatal("expected the runner to report the process running")
+	}
+
+	if err := runner.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if runner.process == first {
+		t.Fatal("reload must start a new process")
+	}
+	select {
+	case <-first.done:
+	default:
+		t.Fatal("reload must stop the old process")
+	}
+
+	if err := runner.Stop(); err != nil || runner.Running() {
+		t.Fatalf("expected the process stopped, got running=%v (%v)", runner.Running(), err)
+	}
+}
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeFakeBinary(t *testing.T, run string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the fake binary")
+	}
+	binary := filepath.Join(t.TempDir(), "fake-xray")
+	script := "#!/bin/sh\n[ \"$2\" = \"-test\" ] && exit 0\n" + run + "\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return binary
+}
+
+func TestRunnerExternalBinaryCrashIsNotRunning(t *testing.T) {
+	runner := NewRunner(writeTestConfig(t, "{}"), writeFakeBinary(t, "sleep 0.2; exit 1"))
+	if err := runner.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	t.Cleanup(func() { runner.Stop() })
+	crashed := runner.process
+
+	deadline := time.Now().Add(3 * time.Second)
+	for runner.Running() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if runner.Running() {
+		t.Fatal("a crashed binary must not be reported running")
+	}
+
+	started, err := runner.Apply(ConfigDiff{})
+	if err != nil || !started || runner.process == crashed {
+		t.Fatalf("apply must start a new process after a crash, got started=%v (%v)", started, err)
+	}
+}
+
+func TestRunnerExternalBinaryWaitsForInbound(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	inbound := fmt.Sprintf(`{"inbounds":[{"listen":"127.0.0.1","port":%d,"protocol":"socks"}]}`, port)
+
+	runner := NewRunner(writeTestConfig(t, inbound), writeFakeBinary(t, "exec sleep 60"))
+	if err := runner.Start(); err != nil {
+		t.Fatalf("start must succeed once the inbound accepts, got %v", err)
+	}
+	runner.Stop()
+	ln.Close()
+
+	runner = NewRunner(writeTestConfig(t, inbound), writeFakeBinary(t, "sleep 0.3; exit 1"))
+	err = runner.Start()
+	if err == nil || !strings.Contains(err.Error(), "exited during startup") {
+		t.Fatalf("expected a binary that never opens its inbound to fail to start, got %v", err)
+	}
+	if runner.Running() {
+		t.Fatal("a failed start must not leave the runner running")
+	}
+}
@@ -25,7 +25,11 @@ func (g *ConfigGenerator) GenerateConfig(proxies []*models.ProxyConfig, startPor
 		"routing":   g.generateRouting(proxies),
 	}
 
-	return json.MarshalIndent(config, "", "  ")
+	configBytes, err := json.MarshalIndent(config, "", "  ")
+	if err != nil || configTemplate == nil {
+		return configBytes, err
+	}
+	return mergeTemplate(configBytes, configTemplate)
 }
 
 func (g *ConfigGenerator) GenerateAndSaveConfig(proxies []*models.ProxyConfig, startPort int, filename string, xrayLogLevel string) error {
@@ -67,7 +71,7 @@ func (g *ConfigGenerator) generateInbounds(proxies []*models.ProxyConfig, startP
 	for _, proxy := range proxies {
 		inbound := map[string]interface{}{
 			"listen":   "127.0.0.1",
-			"port":     startPort + proxy.Index,
+			"port":     proxy.InboundPort(startPort),
 			"protocol": "socks",
 			"tag":      fmt.Sprintf("%s_%s_%d_Inbound", proxy.Name, proxy.Protocol, proxy.Index),
 			"sniffing": map[string]interface{}{
@@ -76,8 +80,11 @@ func (g *ConfigGenerator) generateInbounds(proxies []*models.ProxyConfig, startP
 				"routeOnly":    true,
 			},
 			"settings": map[string]interface{}{
-				"auth":      "noauth",
-				"udp":       true,
+				"auth": "noauth",
+				"udp":  true,
+				// Address handed out for UDP ASSOCIATE, so UDP probes reach the
+				// relay on the loopback inbound.
+				"ip":        "127.0.0.1",
 				"userLevel": 0,
 			},
 		}
@@ -132,7 +139,7 @@ func (g *ConfigGenerator) generateProxyOutbound(proxy *models.ProxyConfig) map[s
 		}
 		outbound["settings"] = map[string]interface{}{
 			"vnext": []map[string]interface{}{
-				{"address": proxy.Server, "port": proxy.Port, "users": []map[string]interface{}{user}},
+				{"address": proxy.DialServer(), "port": proxy.Port, "users": []map[string]interface{}{user}},
 			},
 		}
 
@@ -140,7 +147,7 @@ func (g *ConfigGenerator) generateProxyOutbound(proxy *models.ProxyConfig) map[s
 		outbound["settings"] = map[string]interface{}{
 			"vnext": []map[string]interface{}{
 				{
-					"address": proxy.Server,
+					"address": proxy.DialServer(),
 					"port":    proxy.Port,
 					"users": []map[string]interface{}{
 						{
@@ -156,7 +163,7 @@ func (g *ConfigGenerator) generateProxyOutbound(proxy *models.ProxyConfig) map[s
 
 	case "trojan":
 		server := map[string]interface{}{
-			"address":  proxy.Server,
+			"address":  proxy.DialServer(),
 			"port":     proxy.Port,
 			"password": proxy.Password,
 		}
@@ -171,7 +178,7 @@ func (g *ConfigGenerator) generateProxyOutbound(proxy *models.ProxyConfig) map[s
 		outbound["settings"] = map[string]interface{}{
 			"servers": []map[string]interface{}{
 				{
-					"address":  proxy.Server,
+					"address":  proxy.DialServer(),
 					"port":     proxy.Port,
 					"method":   proxy.Method,
 					"password": proxy.Password,
@@ -193,10 +200,14 @@ func (g *ConfigGenerator) generateStreamSettings(proxy *models.ProxyConfig) map[
 
 	security := normalizeStreamSecurity(proxy.Security, proxy.Name)
 
+	sockopt := map[string]interface{}{}
+	if outboundDomainStrategy != "" {
+		sockopt["domainStrategy"] = outboundDomainStrategy
+	}
 	ss := map[string]interface{}{
 		"network":  network,
 		"security": security,
-		"sockopt":  map[string]interface{}{},
+		"sockopt":  sockopt,
 	}
 
 	if security == "tls" {
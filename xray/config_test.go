@@ -1,6 +1,12 @@
 package xray
 
-import "testing"
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"xray-checker/models"
+)
 
 func TestNormalizeStreamSecurity(t *testing.T) {
 	cases := []struct {
@@ -23,3 +29,104 @@ func TestNormalizeStreamSecurity(t *testing.T) {
 		}
 	}
 }
+
+func TestUseAddressFamilySetsSockoptDomainStrategy(t *testing.T) {
+	defer UseAddressFamily("tcp")
+
+	g := NewConfigGenerator()
+	proxy := &models.ProxyConfig{Protocol: "vless", Server: "example.com", Port: 443}
+
+	UseAddressFamily("tcp4")
+	sockopt := g.generateStreamSettings(proxy)["sockopt"].(map[string]interface{})
+	if got := sockopt["domainStrategy"]; got != "ForceIPv4" {
+		t.Fatalf("tcp4: domainStrategy = %v, want ForceIPv4", got)
+	}
+
+	UseAddressFamily("tcp")
+	sockopt = g.generateStreamSettings(proxy)["sockopt"].(map[string]interface{})
+	if _, ok := sockopt["domainStrategy"]; ok {
+		t.Fatal("tcp must keep xray's default domain strategy")
+	}
+}
+
+func TestConfigTemplateMergesDNS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "template.json")
+	tmpl := `{
+		"dns": {"servers": ["1.1.1.1"], "queryStrategy": "UseIPv4"},
+		"log": {"loglevel": "debug", "access": "/tmp/access.log"},
+		"outbounds": [{"tag": "dns-out", "protocol": "dns"}],
+		"routing": {"domainStrategy": "IPIfNonMatch", "rules": [{"type": "field", "ip": ["10.0.0.0/8"], "outboundTag": "block"}]}
+	}`
+	if err := os.WriteFile(path, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := UseConfigTemplate(path); err != nil {
+		t.Fatalf("UseConfigTemplate: %v", err)
+	}
+	defer UseConfigTemplate("")
+
+	proxies := []*models.ProxyConfig{{Name: "p", Protocol: "vless", Server: "example.com", Port: 443, UUID: "id"}}
+	out, err := NewConfigGenerator().GenerateConfig(proxies, 10000, "warning")
+	if err != nil {
+		t.Fatalf("GenerateConfig: %v", err)
+	}
+
+	var config struct {
+		DNS struct {
+			Servers []string `json:"servers"`
+		} `json:"dns"`
+		Log       map[string]string `json:"log"`
+		Inbounds  []map[string]interface{}
+		Outbounds []map[string]interface{}
+		Routing   struct {
+			DomainStrategy string                   `json:"domainStrategy"`
+			Rules          []map[string]interface{} `json:"rules"`
+		} `json:"routing"`
+	}
+	if err := json.Unmarshal(out, &config); err != nil {
+		t.Fatalf("merged config is not valid JSON: %v", err)
+	}
+	if len(config.DNS.Servers) != 1 || config.DNS.Servers[0] != "1.1.1.1" {
+		t.Fatalf("dns servers = %v, want [1.1.1.1]", config.DNS.Servers)
+	}
+	if config.Log["loglevel"] != "warning" || config.Log["access"] != "/tmp/access.log" {
+		t.Fatalf("log = %v, want generated loglevel and template access", config.Log)
+	}
+	if len(config.Inbounds) != 1 || config.Inbounds[0]["tag"] != "p_vless_0_Inbound" {
+		t.Fatalf("inbounds = %v, want the generated inbound only", config.Inbounds)
+	}
+	var tags []interface{}
+	for _, o := range config.Outbounds {
+		tags = append(tags, o["tag"])
+	}
+	if len(tags) != 4 || tags[2] != "p_0" || tags[3] != "dns-out" {
+		t.Fatalf("outbound tags = %v, want generated outbounds then dns-out", tags)
+	}
+	if config.Routing.DomainStrategy != "IPIfNonMatch" {
+		t.Fatalf("routing domainStrategy = %q, want IPIfNonMatch", config.Routing.DomainStrategy)
+	}
+	if len(config.Routing.Rules) != 3 || config.Routing.Rules[0]["outboundTag"] != "block" {
+		t.Fatalf("rules = %v, want template rule first then generated rules", config.Routing.Rules)
+	}
+}
+
+func TestConfigTemplateRejectsClobbering(t *testing.T) {
+	proxies := []*models.ProxyConfig{{Name: "p", Protocol: "vless", Server: "example.com", Port: 443, UUID: "id", Index: 0}}
+	generated, err := NewConfigGenerator().GenerateConfig(proxies, 10000, "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"outbound tag": `{"outbounds": [{"tag": "p_0", "protocol": "freedom"}]}`,
+		"inbound tag":  `{"inbounds": [{"tag": "p_vless_0_Inbound", "port": 20000, "protocol": "socks"}]}`,
+		"inbound port": `{"inbounds": [{"tag": "extra", "port": 10000, "protocol": "socks"}]}`,
+		"untagged":     `{"outbounds": [{"protocol": "freedom"}]}`,
+		"not object":   `{"inbounds": {"tag": "extra"}}`,
+	}
+	for name, tmpl := range cases {
+		if _, err := mergeTemplate(generated, []byte(tmpl)); err == nil {
+			t.Errorf("%s: mergeTemplate succeeded, want error", name)
+		}
+	}
+}
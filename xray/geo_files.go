@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"xray-checker/logger"
+	"xray-checker/outbound"
 )
 
 const (
@@ -46,10 +47,8 @@ func NewGeoFileManager(baseDir string) *GeoFileManager {
 	}
 
 	return &GeoFileManager{
-		baseDir: baseDir,
-		httpClient: &http.Client{
-			Timeout: geoDownloadTimeout,
-		},
+		baseDir:    baseDir,
+		httpClient: outbound.NewClient(geoDownloadTimeout),
 	}
 }
 
@@ -0,0 +1,91 @@
+package xray
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"xray-checker/logger"
+)
+
+const (
+	// xrayLogBurst is how many xray lines are forwarded per xrayLogWindow;
+	// the rest are counted and reported as suppressed.
+	xrayLogBurst  = 100
+	xrayLogWindow = time.Second
+)
+
+var xrayLogs = &logThrottle{limit: xrayLogBurst, window: xrayLogWindow, now: time.Now}
+
+// forwardLog passes an xray log line (from the embedded core or an external
+// binary) to the checker's logger with an [xray] prefix, at the level its
+// severity maps to. Which lines xray emits is set by --xray-log-level.
+func forwardLog(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.Contains(line, "deprecated") {
+		return
+	}
+	ok, suppressed := xrayLogs.allow()
+	if suppressed > 0 {
+		logger.Warn("[xray] %d lines suppressed", suppressed)
+	}
+	if !ok {
+		return
+	}
+	switch xrayLineLevel(line) {
+	case logger.LevelError:
+		logger.Error("[xray] %s", line)
+	case logger.LevelWarn:
+		logger.Warn("[xray] %s", line)
+	case logger.LevelInfo:
+		logger.Info("[xray] %s", line)
+	default:
+		logger.Debug("[xray] %s", line)
+	}
+}
+
+// xrayLineLevel maps the severity tag of an xray log line to a logger level.
+// Untagged lines, such as access logs, are debug.
+func xrayLineLevel(line string) logger.Level {
+	switch {
+	case strings.Contains(line, "[Error]"):
+		return logger.LevelError
+	case strings.Contains(line, "[Warning]"):
+		return logger.LevelWarn
+	case strings.Contains(line, "[Info]"):
+		return logger.LevelInfo
+	default:
+		return logger.LevelDebug
+	}
+}
+
+// logThrottle lets limit lines through per window.
+type logThrottle struct {
+	mu          sync.Mutex
+	limit       int
+	window      time.Duration
+	now         func() time.Time
+	windowStart time.Time
+	count       int
+	suppressed  int
+}
+
+// allow reports whether another line may be logged, and how many lines were
+// suppressed in the window that just ended, once per window.
+func (t *logThrottle) allow() (bool, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var reported int
+	if now := t.now(); now.Sub(t.windowStart) >= t.window {
+		reported = t.suppressed
+		t.windowStart = now
+		t.count = 0
+		t.suppressed = 0
+	}
+	if t.count >= t.limit {
+		t.suppressed++
+		return false, reported
+	}
+	t.count++
+	return true, reported
+}
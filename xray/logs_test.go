@@ -0,0 +1,62 @@
+package xray
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"xray-checker/logger"
+
+	"github.com/xtls/xray-core/common/log"
+)
+
+func TestXrayLogsForwardedToLogger(t *testing.T) {
+	lines, stop := logger.Subscribe(8)
+	defer stop()
+
+	log.Record(&log.GeneralMessage{Severity: log.Severity_Error, Content: "outbound failed"})
+
+	select {
+	case line := <-lines:
+		if line.Level != logger.LevelError.String() || !strings.HasPrefix(line.Message, "[xray] ") || !strings.Contains(line.Message, "outbound failed") {
+			t.Fatalf("unexpected forwarded line %+v", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected xray output in the logger")
+	}
+
+	(&lineLogger{}).Write([]byte("2024/01/01 00:00:00 [Warning] from binary\npartial"))
+	select {
+	case line := <-lines:
+		if line.Level != logger.LevelWarn.String() || line.Message != "[xray] 2024/01/01 00:00:00 [Warning] from binary" {
+			t.Fatalf("unexpected forwarded binary line %+v", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected binary output in the logger")
+	}
+}
+
+func TestLogThrottle(t *testing.T) {
+	now := time.Unix(1000, 0)
+	throttle := &logThrottle{limit: 2, window: time.Second, now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := throttle.allow(); !ok {
+			t.Fatalf("line %d must pass within the limit", i)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := throttle.allow(); ok {
+			t.Fatal("lines over the limit must be suppressed")
+		}
+	}
+
+	now = now.Add(time.Second)
+	ok, suppressed := throttle.allow()
+	if !ok || suppressed != 3 {
+		t.Fatalf("expected the next window to pass and report 3 suppressed, got %v %d", ok, suppressed)
+	}
+	if _, suppressed := throttle.allow(); suppressed != 0 {
+		t.Fatalf("suppressed lines must be reported once, got %d", suppressed)
+	}
+}
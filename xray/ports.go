@@ -0,0 +1,138 @@
+package xray
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"xray-checker/models"
+)
+
+// PortMap assigns each proxy a local inbound port keyed by its stable ID, so a
+// node keeps its port when other proxies are added, removed or reordered.
+// Ports of proxies that disappear are freed and reused for new ones.
+type PortMap struct {
+	mu        sync.Mutex
+	path      string
+	startPort int
+	ports     map[string]int
+}
+
+type portMapFile struct {
+	StartPort int            `json:"startPort"`
+	Ports     map[string]int `json:"ports"`
+}
+
+// NewPortMap creates a port map handing out ports from startPort. When path
+// is set, the map is loaded from it and saved back on every change; a map
+// saved with another start port is discarded.
+func NewPortMap(startPort int, path string) (*PortMap, error) {
+	m := &PortMap{path: path, startPort: startPort, ports: make(map[string]int)}
+	if path == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored portMapFile
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("invalid port map %s: %w", path, err)
+	}
+	if stored.StartPort != startPort {
+		return m, nil
+	}
+	used := make(map[int]bool, len(stored.Ports))
+	for id, port := range stored.Ports {
+		if id == "" || port < startPort || port > 65535 || used[port] {
+			continue
+		}
+		used[port] = true
+		m.ports[id] = port
+	}
+	return m, nil
+}
+
+// Assign sets LocalPort on every proxy. Proxies with a known stable ID keep
+// their port; new ones get the lowest free port. Stable IDs not in proxies
+// are dropped from the map.
+func (m *PortMap) Assign(proxies []*models.ProxyConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	present := make(map[string]bool, len(proxies))
+	for _, proxy := range proxies {
+		present[proxy.StableID] = true
+	}
+	changed := false
+	for id := range m.ports {
+		if !present[id] {
+			delete(m.ports, id)
+			changed = true
+		}
+	}
+
+	used := make(map[int]bool, len(m.ports))
+	for _, port := range m.ports {
+		used[port] = true
+	}
+	next := m.startPort
+	allocate := func() int {
+		for used[next] {
+			next++
+		}
+		used[next] = true
+		return next
+	}
+
+	assigned := make(map[string]bool, len(proxies))
+	for _, proxy := range proxies {
+		port, ok := m.ports[proxy.StableID]
+		switch {
+		case ok && !assigned[proxy.StableID]:
+		case ok:
+			// A duplicate stable ID still needs its own inbound.
+			port = allocate()
+		default:
+			port = allocate()
+			m.ports[proxy.StableID] = port
+			changed = true
+		}
+		assigned[proxy.StableID] = true
+		proxy.LocalPort = port
+	}
+
+	if changed {
+		return m.saveLocked()
+	}
+	return nil
+}
+
+// Port returns the port assigned to stableID.
+func (m *PortMap) Port(stableID string) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	port, ok := m.ports[stableID]
+	return port, ok
+}
+
+func (m *PortMap) saveLocked() error {
+	if m.path == "" {
+		return nil
+	}
+	payload, err := json.MarshalIndent(portMapFile{StartPort: m.startPort, Ports: m.ports}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(m.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(m.path, payload, 0o644)
+}
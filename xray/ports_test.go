@@ -0,0 +1,113 @@
+package xray
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"xray-checker/models"
+)
+
+func portTestProxies() (a, b, c *models.ProxyConfig) {
+	a = testProxy("a", "11111111-1111-1111-1111-111111111111")
+	b = testProxy("b", "22222222-2222-2222-2222-222222222222")
+	c = testProxy("c", "33333333-3333-3333-3333-333333333333")
+	for _, p := range []*models.ProxyConfig{a, b, c} {
+		p.StableID = p.GenerateStableID()
+	}
+	return a, b, c
+}
+
+func TestPortMapKeepsPortWhenOtherProxyRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ports.json")
+	m, err := NewPortMap(10000, path)
+	if err != nil {
+		t.Fatalf("NewPortMap failed: %v", err)
+	}
+	a, b, c := portTestProxies()
+	if err := m.Assign([]*models.ProxyConfig{a, b, c}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if a.LocalPort != 10000 || b.LocalPort != 10001 || c.LocalPort != 10002 {
+		t.Fatalf("expected ports in load order, got %d %d %d", a.LocalPort, b.LocalPort, c.LocalPort)
+	}
+
+	_, _, c2 := portTestProxies()
+	c2.Index = 1
+	if err := m.Assign([]*models.ProxyConfig{a, c2}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if c2.LocalPort != 10002 || c2.InboundPort(10000) != 10002 {
+		t.Fatalf("expected c to keep port 10002 after b was removed, got %d", c2.LocalPort)
+	}
+	if _, ok := m.Port(b.StableID); ok {
+		t.Fatal("expected the removed proxy to be dropped from the map")
+	}
+
+	// b's freed port goes to the next new proxy.
+	d := testProxy("d", "44444444-4444-4444-4444-444444444444")
+	d.StableID = d.GenerateStableID()
+	if err := m.Assign([]*models.ProxyConfig{a, c2, d}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if d.LocalPort != 10001 {
+		t.Fatalf("expected d to reuse port 10001, got %d", d.LocalPort)
+	}
+
+	reloaded, err := NewPortMap(10000, path)
+	if err != nil {
+		t.Fatalf("reloading port map failed: %v", err)
+	}
+	if port, ok := reloaded.Port(c.StableID); !ok || port != 10002 {
+		t.Fatalf("expected persisted port 10002 for c, got %d (%v)", port, ok)
+	}
+
+	moved, err := NewPortMap(20000, path)
+	if err != nil {
+		t.Fatalf("reloading port map failed: %v", err)
+	}
+	if _, ok := moved.Port(c.StableID); ok {
+		t.Fatal("a map saved for another start port must be discarded")
+	}
+}
+
+func TestPortMapDuplicateStableID(t *testing.T) {
+	m, err := NewPortMap(10000, "")
+	if err != nil {
+		t.Fatalf("NewPortMap failed: %v", err)
+	}
+	a, _, _ := portTestProxies()
+	dup := *a
+	if err := m.Assign([]*models.ProxyConfig{a, &dup}); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if a.LocalPort == dup.LocalPort {
+		t.Fatalf("duplicates must not share port %d", a.LocalPort)
+	}
+}
+
+func TestNewPortMapRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ports.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPortMap(10000, path); err == nil {
+		t.Fatal("expected an error for a corrupt port map")
+	}
+}
+
+func TestDiffConfigsReorderKeepsAssignedPorts(t *testing.T) {
+	a, b, _ := portTestProxies()
+	a.LocalPort, b.LocalPort = 10000, 10001
+	na, nb := *a, *b
+	na.Index, nb.Index = 1, 0
+
+	diff := DiffConfigs([]*models.ProxyConfig{a, b}, []*models.ProxyConfig{&nb, &na})
+	if !diff.Empty() || diff.NeedsRestart() {
+		t.Fatalf("a reorder with stable ports must not need a restart: %+v", diff)
+	}
+
+	nb.LocalPort = 10005
+	if diff := DiffConfigs([]*models.ProxyConfig{a, b}, []*models.ProxyConfig{&nb, &na}); !diff.NeedsRestart() {
+		t.Fatal("a port change must need a restart")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"os"
-	"strings"
 
 	"xray-checker/logger"
 
@@ -17,14 +16,7 @@ import (
 type filteredLogHandler struct{}
 
 func (h *filteredLogHandler) Handle(msg log.Message) {
-	msgStr := msg.String()
-	if strings.Contains(msgStr, "deprecated") {
-		return
-	}
-	if strings.HasPrefix(msgStr, "[Warning]") {
-		return
-	}
-	logger.Debug("xray: %s", msgStr)
+	forwardLog(msg.String())
 }
 
 func init() {
@@ -34,33 +26,101 @@ func init() {
 type Runner struct {
 	instance   *core.Instance
 	configFile string
+	// binaryPath selects an external xray binary instead of the embedded
+	// core; process is its running instance.
+	binaryPath string
+	process    *process
 }
 
-func NewRunner(configFile string) *Runner {
+// NewRunner returns a runner for configFile. An empty binaryPath runs the
+// embedded Xray core; otherwise that binary is run with "run -c configFile",
+// so any xray-compatible build can be used. Check it with ValidateBinary
+// first.
+func NewRunner(configFile, binaryPath string) *Runner {
 	return &Runner{
 		configFile: configFile,
+		binaryPath: binaryPath,
 	}
 }
 
 func (r *Runner) Start() error {
-	configBytes, err := os.ReadFile(r.configFile)
-	if err != nil {
-		return fmt.Errorf("error reading config file: %v", err)
+	if r.binaryPath != "" {
+		if r.process != nil {
+			// A crashed process is still set; clear it before starting over.
+			r.process.stop()
+			r.process = nil
+		}
+		proc, err := startProcess(r.binaryPath, r.configFile)
+		if err != nil {
+			return err
+		}
+		if err := proc.waitForInbound(r.configFile, inboundReadyTimeout); err != nil {
+			proc.stop()
+			return err
+		}
+		r.process = proc
+		logger.Debug("Xray process started")
+		return nil
 	}
 
-	xrayConfig, err := serial.DecodeJSONConfig(bytes.NewReader(configBytes))
+	instance, err := r.newInstance()
 	if err != nil {
-		return fmt.Errorf("error decoding config: %v", err)
+		return err
 	}
 
-	coreConfig, err := xrayConfig.Build()
-	if err != nil {
-		return fmt.Errorf("error building config: %v", err)
+	if err := instance.Start(); err != nil {
+		return fmt.Errorf("error starting Xray: %v", err)
 	}
 
-	instance, err := core.New(coreConfig)
+	r.instance = instance
+	logger.Debug("Xray instance started")
+
+	return nil
+}
+
+// Running reports whether an xray instance is running. An external binary
+// that exited on its own is not running.
+func (r *Runner) Running() bool {
+	return r.instance != nil || (r.process != nil && !r.process.exited())
+}
+
+// Apply brings xray in line with the config file after a subscription
+// update. When xray is running and the diff does not need a restart it is left
+// alone; otherwise xray is reloaded, or started when it is not running. The
+// result reports whether an instance was started.
+func (r *Runner) Apply(diff ConfigDiff) (bool, error) {
+	if !r.Running() {
+		return true, r.Start()
+	}
+	if !diff.NeedsRestart() {
+		return false, nil
+	}
+	return true, r.Reload()
+}
+
+// Reload replaces the running instance with one built from the config file.
+// The new config is decoded and built before the old instance is stopped, so
+// a broken config keeps the old instance running and tunnels only drop for
+// the handover itself.
+func (r *Runner) Reload() error {
+	if r.binaryPath != "" {
+		if err := testBinaryConfig(r.binaryPath, r.configFile); err != nil {
+			return err
+		}
+		if err := r.Stop(); err != nil {
+			return err
+		}
+		return r.Start()
+	}
+
+	instance, err := r.newInstance()
 	if err != nil {
-		return fmt.Errorf("error creating Xray instance: %v", err)
+		return err
+	}
+
+	if err := r.Stop(); err != nil {
+		instance.Close()
+		return err
 	}
 
 	if err := instance.Start(); err != nil {
@@ -68,12 +128,43 @@ func (r *Runner) Start() error {
 	}
 
 	r.instance = instance
-	logger.Debug("Xray instance started")
+	logger.Debug("Xray instance reloaded")
 
 	return nil
 }
 
+func (r *Runner) newInstance() (*core.Instance, error) {
+	configBytes, err := os.ReadFile(r.configFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	xrayConfig, err := serial.DecodeJSONConfig(bytes.NewReader(configBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding config: %v", err)
+	}
+
+	coreConfig, err := xrayConfig.Build()
+	if err != nil {
+		return nil, fmt.Errorf("error building config: %v", err)
+	}
+
+	instance, err := core.New(coreConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Xray instance: %v", err)
+	}
+	return instance, nil
+}
+
 func (r *Runner) Stop() error {
+	if r.process != nil {
+		err := r.process.stop()
+		r.process = nil
+		if err != nil {
+			return fmt.Errorf("error stopping Xray: %v", err)
+		}
+		logger.Debug("Xray process stopped")
+	}
 	if r.instance != nil {
 		err := r.instance.Close()
 		r.instance = nil
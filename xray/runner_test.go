@@ -0,0 +1,97 @@
+package xray
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"xray-checker/models"
+)
+
+func startTestRunner(t *testing.T, proxies []*models.ProxyConfig) (*Runner, string, int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	configFile := filepath.Join(t.TempDir(), "xray_config.json")
+	PrepareProxyConfigs(proxies)
+	if err := NewConfigGenerator().GenerateAndSaveConfig(proxies, port, configFile, "none"); err != nil {
+		t.Fatalf("generate config failed: %v", err)
+	}
+	runner := NewRunner(configFile, "")
+	if err := runner.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	t.Cleanup(func() { runner.Stop() })
+	return runner, configFile, port
+}
+
+func TestRunnerApplySkipsRestartForNameOnlyChange(t *testing.T) {
+	old := []*models.ProxyConfig{testProxy("a", "11111111-1111-1111-1111-111111111111")}
+	runner, configFile, port := startTestRunner(t, old)
+	instance := runner.instance
+
+	renamed := *old[0]
+	renamed.Name = "a renamed"
+	next := []*models.ProxyConfig{&renamed}
+	PrepareProxyConfigs(next)
+	if err := NewConfigGenerator().GenerateAndSaveConfig(next, port, configFile, "none"); err != nil {
+		t.Fatalf("generate config failed: %v", err)
+	}
+
+	restarted, err := runner.Apply(DiffConfigs(old, next))
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if restarted || runner.instance != instance {
+		t.Fatal("a name-only change must not restart xray")
+	}
+}
+
+func TestRunnerApplyReloadsForSettingsChange(t *testing.T) {
+	old := []*models.ProxyConfig{testProxy("a", "11111111-1111-1111-1111-111111111111")}
+	runner, configFile, port := startTestRunner(t, old)
+	instance := runner.instance
+
+	moved := *old[0]
+	moved.ResolvedServer = "203.0.113.1"
+	next := []*models.ProxyConfig{&moved}
+	PrepareProxyConfigs(next)
+	if err := NewConfigGenerator().GenerateAndSaveConfig(next, port, configFile, "none"); err != nil {
+		t.Fatalf("generate config failed: %v", err)
+	}
+
+	restarted, err := runner.Apply(DiffConfigs(old, next))
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if !restarted || runner.instance == instance || !runner.Running() {
+		t.Fatal("a new server address must reload xray")
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("reloaded inbound not listening: %v", err)
+	}
+	conn.Close()
+}
+
+func TestRunnerReloadKeepsInstanceOnBrokenConfig(t *testing.T) {
+	runner, configFile, _ := startTestRunner(t, []*models.ProxyConfig{testProxy("a", "11111111-1111-1111-1111-111111111111")})
+	instance := runner.instance
+
+	if err := os.WriteFile(configFile, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := runner.Reload(); err == nil {
+		t.Fatal("expected reload of a broken config to fail")
+	}
+	if runner.instance != instance {
+		t.Fatal("a broken config must leave the running instance alone")
+	}
+}
@@ -0,0 +1,161 @@
+package xray
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// configTemplate is the raw user fragment merged into every generated config;
+// nil disables merging. It is kept as JSON so each merge works on a fresh copy.
+var configTemplate []byte
+
+// UseConfigTemplate loads the xray config fragment at path and merges it into
+// every generated config. An empty path disables the template.
+//
+// Top-level sections the checker does not generate (dns, policy, ...) are
+// taken as is. Template routing rules are placed before the checker's rules
+// and other routing keys replace the generated ones. Template inbounds and
+// outbounds are appended and must not reuse a tag or port the checker
+// generates. Keys in the log section are kept, except the log level.
+func UseConfigTemplate(path string) error {
+	if path == "" {
+		configTemplate = nil
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %v", err)
+	}
+	// Catch shape errors at startup rather than on the first reload.
+	if _, err := parseTemplate(data); err != nil {
+		return err
+	}
+	configTemplate = data
+	return nil
+}
+
+func parseTemplate(data []byte) (map[string]interface{}, error) {
+	var tmpl map[string]interface{}
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("template is not a JSON object: %v", err)
+	}
+	for _, key := range []string{"inbounds", "outbounds"} {
+		if _, err := templateObjects(tmpl, key); err != nil {
+			return nil, err
+		}
+	}
+	if raw, ok := tmpl["log"]; ok {
+		if _, ok := raw.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("template log must be an object")
+		}
+	}
+	if raw, ok := tmpl["routing"]; ok {
+		routing, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("template routing must be an object")
+		}
+		if _, err := templateObjects(routing, "rules"); err != nil {
+			return nil, fmt.Errorf("template routing: %v", err)
+		}
+	}
+	return tmpl, nil
+}
+
+// templateObjects returns m[key] as a list of objects; a missing key is an
+// empty list.
+func templateObjects(m map[string]interface{}, key string) ([]interface{}, error) {
+	raw, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array", key)
+	}
+	for i, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%s[%d] must be an object", key, i)
+		}
+	}
+	return list, nil
+}
+
+// mergeTemplate merges the template into a generated config and returns the
+// result as indented JSON.
+func mergeTemplate(generated []byte, template []byte) ([]byte, error) {
+	var config map[string]interface{}
+	if err := json.Unmarshal(generated, &config); err != nil {
+		return nil, err
+	}
+	tmpl, err := parseTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range tmpl {
+		switch key {
+		case "log":
+			log := config["log"].(map[string]interface{})
+			for k, v := range value.(map[string]interface{}) {
+				if k != "loglevel" {
+					log[k] = v
+				}
+			}
+		case "inbounds", "outbounds":
+			merged, err := appendTagged(config, key, value.([]interface{}))
+			if err != nil {
+				return nil, err
+			}
+			config[key] = merged
+		case "routing":
+			routing := config["routing"].(map[string]interface{})
+			for k, v := range value.(map[string]interface{}) {
+				if k == "rules" {
+					rules := append([]interface{}{}, v.([]interface{})...)
+					routing["rules"] = append(rules, routing["rules"].([]interface{})...)
+					continue
+				}
+				routing[k] = v
+			}
+		default:
+			config[key] = value
+		}
+	}
+
+	return json.MarshalIndent(config, "", "  ")
+}
+
+// appendTagged appends template entries to config[key], refusing any that
+// would shadow a generated entry by tag or, for inbounds, by port.
+func appendTagged(config map[string]interface{}, key string, extra []interface{}) ([]interface{}, error) {
+	existing, _ := config[key].([]interface{})
+	tags := make(map[string]bool, len(existing))
+	ports := make(map[string]bool, len(existing))
+	for _, item := range existing {
+		entry := item.(map[string]interface{})
+		if tag, ok := entry["tag"].(string); ok {
+			tags[tag] = true
+		}
+		if port, ok := entry["port"]; ok {
+			ports[fmt.Sprint(port)] = true
+		}
+	}
+	for i, item := range extra {
+		entry := item.(map[string]interface{})
+		tag, _ := entry["tag"].(string)
+		if tag == "" {
+			return nil, fmt.Errorf("template %s[%d] has no tag", key, i)
+		}
+		if tags[tag] {
+			return nil, fmt.Errorf("template %s[%d] reuses tag %q used by the checker", key, i, tag)
+		}
+		tags[tag] = true
+		if key == "inbounds" {
+			if port, ok := entry["port"]; ok && ports[fmt.Sprint(port)] {
+				return nil, fmt.Errorf("template inbounds[%d] reuses port %v used by the checker", i, port)
+			}
+		}
+	}
+	return append(existing, extra...), nil
+}
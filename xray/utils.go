@@ -1,9 +1,37 @@
 package xray
 
 import (
+	"fmt"
+	"reflect"
+	"xray-checker/logger"
 	"xray-checker/models"
 )
 
+var portMap *PortMap
+
+// UsePortMap makes PrepareProxyConfigs assign inbound ports from m instead of
+// deriving them from the proxy index.
+func UsePortMap(m *PortMap) {
+	portMap = m
+}
+
+// outboundDomainStrategy is the sockopt domainStrategy of proxy outbounds;
+// empty keeps xray's default.
+var outboundDomainStrategy string
+
+// UseAddressFamily makes proxy outbounds reach their servers over one address
+// family: "tcp4" forces IPv4, "tcp6" forces IPv6 and "tcp" allows both.
+func UseAddressFamily(network string) {
+	switch network {
+	case "tcp4":
+		outboundDomainStrategy = "ForceIPv4"
+	case "tcp6":
+		outboundDomainStrategy = "ForceIPv6"
+	default:
+		outboundDomainStrategy = ""
+	}
+}
+
 func PrepareProxyConfigs(proxies []*models.ProxyConfig) {
 	for i := range proxies {
 		proxies[i].Index = i
@@ -12,41 +40,126 @@ func PrepareProxyConfigs(proxies []*models.ProxyConfig) {
 			proxies[i].StableID = proxies[i].GenerateStableID()
 		}
 	}
+	if portMap != nil {
+		if err := portMap.Assign(proxies); err != nil {
+			logger.Warn("Failed to save port map: %v", err)
+		}
+	}
 }
 
-func IsConfigsEqual(old, new []*models.ProxyConfig) bool {
-	if len(old) != len(new) {
-		return false
-	}
+// ConfigChange pairs the old and new version of a proxy with the same
+// stable ID.
+type ConfigChange struct {
+	Old *models.ProxyConfig
+	New *models.ProxyConfig
+}
+
+// ConfigDiff lists proxies that differ between two config sets, matched by
+// stable ID.
+type ConfigDiff struct {
+	Added   []*models.ProxyConfig
+	Removed []*models.ProxyConfig
+	Changed []ConfigChange
+	restart bool
+}
 
-	oldMap := make(map[string]bool)
-	newMap := make(map[string]bool)
+// Empty reports whether the two config sets hold the same proxies. A pure
+// reorder is not a change.
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
 
+// NeedsRestart reports whether xray has to be restarted to apply the diff.
+// It is false when only metadata that xray does not use changed (name,
+// subscription name, source line) and every proxy kept its inbound port.
+func (d ConfigDiff) NeedsRestart() bool {
+	return d.restart
+}
+
+// Summary describes the diff in one line for logging.
+func (d ConfigDiff) Summary() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed", len(d.Added), len(d.Removed), len(d.Changed))
+}
+
+// DiffConfigs compares two config sets by stable ID, in the order the proxies
+// appear. Proxies with the same stable ID are changed when any other field
+// differs, including the resolved address.
+func DiffConfigs(old, new []*models.ProxyConfig) ConfigDiff {
+	oldByID := make(map[string]*models.ProxyConfig, len(old))
+	oldPos := make(map[string]int, len(old))
+	for i, cfg := range old {
+		id := stableID(cfg)
+		oldByID[id] = cfg
+		oldPos[id] = i
+	}
+	newIDs := make(map[string]bool, len(new))
+
+	var diff ConfigDiff
+	for i, cfg := range new {
+		id := stableID(cfg)
+		newIDs[id] = true
+		prev, ok := oldByID[id]
+		if !ok {
+			diff.Added = append(diff.Added, cfg)
+			continue
+		}
+		if movedPort(prev, oldPos[id], cfg, i) {
+			diff.restart = true
+		}
+		if !reflect.DeepEqual(diffView(prev, false), diffView(cfg, false)) {
+			diff.Changed = append(diff.Changed, ConfigChange{Old: prev, New: cfg})
+			if !reflect.DeepEqual(diffView(prev, true), diffView(cfg, true)) {
+				diff.restart = true
+			}
+		}
+	}
 	for _, cfg := range old {
-		if cfg.StableID == "" {
-			cfg.StableID = cfg.GenerateStableID()
+		if !newIDs[stableID(cfg)] {
+			diff.Removed = append(diff.Removed, cfg)
 		}
-		oldMap[cfg.StableID] = true
 	}
+	if len(diff.Added) > 0 || len(diff.Removed) > 0 {
+		diff.restart = true
+	}
+	return diff
+}
 
-	for _, cfg := range new {
-		if cfg.StableID == "" {
-			cfg.StableID = cfg.GenerateStableID()
-		}
-		newMap[cfg.StableID] = true
+func IsConfigsEqual(old, new []*models.ProxyConfig) bool {
+	if len(old) != len(new) {
+		return false
 	}
+	return DiffConfigs(old, new).Empty()
+}
 
-	for id := range oldMap {
-		if !newMap[id] {
-			return false
-		}
+func stableID(cfg *models.ProxyConfig) string {
+	if cfg.StableID == "" {
+		cfg.StableID = cfg.GenerateStableID()
 	}
+	return cfg.StableID
+}
 
-	for id := range newMap {
-		if !oldMap[id] {
-			return false
-		}
+// movedPort reports whether a proxy's inbound port changed. Without assigned
+// ports the port follows the position.
+func movedPort(prev *models.ProxyConfig, prevPos int, cfg *models.ProxyConfig, pos int) bool {
+	if prev.LocalPort > 0 && cfg.LocalPort > 0 {
+		return prev.LocalPort != cfg.LocalPort
 	}
+	return prevPos != pos
+}
 
-	return true
+// diffView returns a copy of cfg without the fields assigned by
+// PrepareProxyConfigs. With xrayOnly it also drops metadata that does not end
+// up in the xray config.
+func diffView(cfg *models.ProxyConfig, xrayOnly bool) models.ProxyConfig {
+	c := *cfg
+	c.Index = 0
+	c.LocalPort = 0
+	c.StableID = ""
+	if xrayOnly {
+		c.Name = ""
+		c.SubName = ""
+		c.SourceLine = ""
+		c.SourcePath = ""
+	}
+	return c
 }
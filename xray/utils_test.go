@@ -0,0 +1,79 @@
+package xray
+
+import (
+	"testing"
+	"xray-checker/models"
+)
+
+func testProxy(name, uuid string) *models.ProxyConfig {
+	return &models.ProxyConfig{Protocol: "vless", Server: "node.example.com", Port: 443, Name: name, UUID: uuid}
+}
+
+func TestDiffConfigsAddRemove(t *testing.T) {
+	a := testProxy("a", "11111111-1111-1111-1111-111111111111")
+	b := testProxy("b", "22222222-2222-2222-2222-222222222222")
+	c := testProxy("c", "33333333-3333-3333-3333-333333333333")
+
+	diff := DiffConfigs([]*models.ProxyConfig{a, b}, []*models.ProxyConfig{a, c})
+	if len(diff.Added) != 1 || diff.Added[0] != c {
+		t.Fatalf("expected c added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != b {
+		t.Fatalf("expected b removed, got %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 0 || !diff.NeedsRestart() {
+		t.Fatalf("adding and removing proxies must need a restart: %+v", diff)
+	}
+	if diff.Summary() != "1 added, 1 removed, 0 changed" {
+		t.Fatalf("unexpected summary %q", diff.Summary())
+	}
+}
+
+func TestDiffConfigsModify(t *testing.T) {
+	a := testProxy("a", "11111111-1111-1111-1111-111111111111")
+	a.StableID = a.GenerateStableID()
+	a.Index = 0
+
+	resolved := *a
+	resolved.ResolvedServer = "203.0.113.1"
+	diff := DiffConfigs([]*models.ProxyConfig{a}, []*models.ProxyConfig{&resolved})
+	if len(diff.Changed) != 1 || diff.Changed[0].Old != a || diff.Changed[0].New != &resolved {
+		t.Fatalf("expected a changed, got %+v", diff.Changed)
+	}
+	if !diff.NeedsRestart() {
+		t.Fatal("a new resolved address must need a restart")
+	}
+
+	renamed := *a
+	renamed.Name = "a renamed"
+	renamed.SubName = "sub"
+	diff = DiffConfigs([]*models.ProxyConfig{a}, []*models.ProxyConfig{&renamed})
+	if len(diff.Changed) != 1 || diff.Empty() {
+		t.Fatalf("a rename must be reported as a change: %+v", diff)
+	}
+	if diff.NeedsRestart() {
+		t.Fatal("a metadata-only change must not need a restart")
+	}
+
+	fresh := testProxy("a", "11111111-1111-1111-1111-111111111111")
+	if diff := DiffConfigs([]*models.ProxyConfig{a}, []*models.ProxyConfig{fresh}); !diff.Empty() {
+		t.Fatalf("index and stable ID assignment must not count as a change: %+v", diff)
+	}
+}
+
+func TestDiffConfigsReorder(t *testing.T) {
+	a := testProxy("a", "11111111-1111-1111-1111-111111111111")
+	b := testProxy("b", "22222222-2222-2222-2222-222222222222")
+
+	diff := DiffConfigs([]*models.ProxyConfig{a, b}, []*models.ProxyConfig{b, a})
+	if !diff.Empty() || !IsConfigsEqual([]*models.ProxyConfig{a, b}, []*models.ProxyConfig{b, a}) {
+		t.Fatalf("a pure reorder is not a change: %+v", diff)
+	}
+
+	renamed := *b
+	renamed.Name = "b renamed"
+	diff = DiffConfigs([]*models.ProxyConfig{a, b}, []*models.ProxyConfig{&renamed, a})
+	if !diff.NeedsRestart() {
+		t.Fatal("moved proxies change inbound ports and must need a restart")
+	}
+}